@@ -17,6 +17,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"time"
@@ -65,11 +66,17 @@ func main() {
 	}
 
 	// Auto-migrate database schema
-	if err := db.AutoMigrate(&domain.User{}, &domain.SleepLog{}); err != nil {
+	if err := db.AutoMigrate(&domain.User{}, &domain.SleepLog{}, &domain.SleepStage{}, &domain.Tag{}, &domain.SleepLogFactor{}, &domain.SleepGoal{}, &domain.InsightsCacheEntry{}); err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 	log.Println("Database migration completed")
 
+	// Keep the predefined factor tags up to date
+	tagRepo := repository.NewTagRepository(db)
+	if err := tagRepo.EnsureCatalogue(ctx, domain.CatalogueTags()); err != nil {
+		log.Fatalf("Failed to create tag catalogue: %v", err)
+	}
+
 	if cfg.Seed {
 		log.Println("Seeding database with sample data (SEED=true)...")
 		if err := seed.Run(db); err != nil {
@@ -80,17 +87,68 @@ func main() {
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	sleepLogRepo := repository.NewSleepLogRepository(db)
+	goalRepo := repository.NewSleepGoalRepository(db)
+	insightsCacheRepo := repository.NewInsightsCacheRepository(db)
+
+	// Load the scoring model of the overall sleep score
+	scoringConfig := service.DefaultScoringConfig()
+	if cfg.OverallScoreRegularity == string(domain.RegularitySourceSRI) {
+		scoringConfig.RegularitySource = domain.RegularitySourceSRI
+	}
+	if cfg.ScoringModelFile != "" {
+		scoringConfig, err = service.LoadScoringConfig(cfg.ScoringModelFile, scoringConfig)
+		if err != nil {
+			log.Fatalf("Failed to load scoring model: %v", err)
+		}
+	}
+	scoringModel, err := service.NewScoringModel(scoringConfig)
+	if err != nil {
+		log.Fatalf("Invalid scoring model: %v", err)
+	}
+	log.Printf("Using scoring model %s@%s", scoringModel.Name(), scoringModel.Version())
 
 	// Initialize services
 	userService := service.NewUserService(userRepo)
-	sleepLogService := service.NewSleepLogService(sleepLogRepo, userRepo)
+	sleepLogService := service.InvalidateInsightsOnChange(service.NewSleepLogService(sleepLogRepo, userRepo, tagRepo), insightsCacheRepo)
+	tagService := service.NewTagService(tagRepo, userRepo)
+	goalService := service.NewSleepGoalService(goalRepo, userRepo)
 	chronotypeService := service.NewChronotypeService(sleepLogRepo, userRepo)
-	metricsService := service.NewMetricsService(sleepLogRepo, userRepo)
-
-	// Initialize OpenAI client (may be nil if not configured)
-	openaiClient := llm.NewOpenAIClient(cfg.OpenAIAPIKey, cfg.OpenAISleepInsightsModel, promptProvider)
-	if openaiClient == nil {
-		log.Println("Warning: OpenAI API key not configured, insights endpoint will be unavailable")
+	metricsService := service.NewMetricsService(sleepLogRepo, userRepo, goalRepo, scoringModel)
+	factorImpactService := service.NewFactorImpactService(sleepLogRepo, userRepo)
+	anomalyService := service.NewAnomalyService(sleepLogRepo, userRepo)
+	debtService := service.NewSleepDebtService(sleepLogRepo, userRepo, goalRepo)
+	cohortService := service.NewCohortService(userRepo, metricsService, chronotypeService, cfg.CohortMinSize, cfg.CohortMaxUsers, cfg.CohortSnapshotTTL)
+	importService := service.NewImportService(sleepLogService, userRepo)
+
+	// Purge soft-deleted sleep logs once their retention period has passed
+	retentionService := service.NewRetentionService(sleepLogRepo, cfg.SleepLogRetentionDays)
+	go retentionService.Run(ctx, cfg.SleepLogPurgeInterval)
+
+	// Initialize the LLM provider (unavailable if not configured)
+	var insightsFallback llm.InsightsLLM
+	if cfg.InsightsFallback {
+		insightsFallback = llm.NewRulesClient()
+	}
+	llmClient, err := llm.NewProvider(cfg.LLMProvider, buildLLMProviderConfig(cfg), promptProvider)
+	if errors.Is(err, llm.ErrLLMUnavailable) {
+		if insightsFallback != nil {
+			log.Printf("Warning: %v, insights will be written by the fixed rules", err)
+		} else {
+			log.Printf("Warning: %v, insights endpoint will be unavailable", err)
+		}
+		llmClient = llm.Unavailable(err)
+	} else if err != nil {
+		log.Fatalf("Invalid LLM provider: %v", err)
+	} else {
+		log.Printf("Using LLM provider %s", cfg.LLMProvider)
+		llmClient = llm.WithResilience(llmClient, llm.ResilienceConfig{
+			Timeout:          cfg.LLMTimeout,
+			MaxRetries:       cfg.LLMMaxRetries,
+			BaseDelay:        cfg.LLMRetryBaseDelay,
+			MaxDelay:         cfg.LLMRetryMaxDelay,
+			BreakerThreshold: cfg.LLMBreakerThreshold,
+			BreakerCooldown:  cfg.LLMBreakerCooldown,
+		})
 	}
 
 	// Initialize Langfuse client (logs its own status)
@@ -102,15 +160,20 @@ func main() {
 	})
 
 	// Initialize insights service
-	insightsService := service.NewInsightsService(chronotypeService, metricsService, openaiClient, sleepLogRepo, userRepo)
+	insightsService := service.NewInsightsService(chronotypeService, metricsService, anomalyService, debtService, llmClient, insightsFallback, sleepLogRepo, userRepo, goalRepo, insightsCacheRepo)
 
 	// Initialize handlers
 	userHandler := handler.NewUserHandler(userService)
 	sleepLogHandler := handler.NewSleepLogHandler(sleepLogService)
-	insightsHandler := handler.NewInsightsHandler(chronotypeService, metricsService, insightsService, langfuseClient)
+	insightsHandler := handler.NewInsightsHandler(chronotypeService, metricsService, factorImpactService, anomalyService, debtService, insightsService, langfuseClient)
+	importHandler := handler.NewImportHandler(importService)
+	tagHandler := handler.NewTagHandler(tagService)
+	goalHandler := handler.NewSleepGoalHandler(goalService)
+	adminHandler := handler.NewAdminHandler(metricsService)
+	cohortHandler := handler.NewCohortHandler(cohortService)
 
 	// Setup router
-	router := api.NewRouter(userHandler, sleepLogHandler, insightsHandler)
+	router := api.NewRouter(userHandler, sleepLogHandler, insightsHandler, importHandler, tagHandler, goalHandler, adminHandler, cohortHandler, cfg.AdminAPIKey)
 	routerHandler := router.Setup()
 
 	// Start server
@@ -121,6 +184,37 @@ func main() {
 	}
 }
 
+// buildLLMProviderConfig returns the settings of the configured LLM provider.
+func buildLLMProviderConfig(cfg *config.Config) llm.ProviderConfig {
+	switch cfg.LLMProvider {
+	case llm.ProviderOpenAICompatible:
+		return llm.ProviderConfig{
+			APIKey:  cfg.OpenAICompatibleAPIKey,
+			Model:   cfg.OpenAICompatibleModel,
+			BaseURL: cfg.OpenAICompatibleBaseURL,
+		}
+	case llm.ProviderAnthropic:
+		return llm.ProviderConfig{
+			APIKey:  cfg.AnthropicAPIKey,
+			Model:   cfg.AnthropicModel,
+			BaseURL: cfg.AnthropicBaseURL,
+		}
+	case llm.ProviderAzureOpenAI:
+		return llm.ProviderConfig{
+			APIKey:     cfg.AzureOpenAIAPIKey,
+			Model:      cfg.AzureOpenAIDeployment,
+			BaseURL:    cfg.AzureOpenAIEndpoint,
+			APIVersion: cfg.AzureOpenAIAPIVersion,
+		}
+	default:
+		return llm.ProviderConfig{
+			APIKey:  cfg.OpenAIAPIKey,
+			Model:   cfg.OpenAISleepInsightsModel,
+			BaseURL: cfg.OpenAIBaseURL,
+		}
+	}
+}
+
 func buildSystemPromptProvider(cfg *config.Config) llm.SystemPromptProvider {
 	localPath := cfg.LangfusePromptSavePath
 	if localPath == "" {
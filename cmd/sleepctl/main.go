@@ -0,0 +1,127 @@
+// Command sleepctl provides maintenance commands for the sleep tracker.
+//
+// Usage:
+//
+//	sleepctl import -user <uuid> -source apple_health|fitbit|google_fit -file <path> [-dry-run] [-timezone <IANA>]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/config"
+	"github.com/blaisecz/sleep-tracker/internal/importer"
+	"github.com/blaisecz/sleep-tracker/internal/repository"
+	"github.com/blaisecz/sleep-tracker/internal/service"
+	"github.com/google/uuid"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "import":
+		runImport(os.Args[2:])
+	case "help", "-h", "--help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: sleepctl <command> [flags]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "  import    Import sleep logs from an Apple Health, Fitbit or Google Fit export")
+}
+
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	userFlag := fs.String("user", "", "ID of the user to import for (required unless -dry-run)")
+	sourceFlag := fs.String("source", "", "export format: apple_health, fitbit or google_fit")
+	fileFlag := fs.String("file", "", "path to the export file")
+	dryRun := fs.Bool("dry-run", false, "print the parsed sessions as JSON instead of storing them")
+	timezone := fs.String("timezone", "UTC", "timezone for local timestamps in -dry-run mode (otherwise the user's timezone is used)")
+	fs.Parse(args)
+
+	source, err := importer.ParseSource(*sourceFlag)
+	if err != nil {
+		log.Fatalf("Invalid -source: %v", err)
+	}
+	if *fileFlag == "" {
+		log.Fatal("-file is required")
+	}
+	f, err := os.Open(*fileFlag)
+	if err != nil {
+		log.Fatalf("Failed to open export: %v", err)
+	}
+	defer f.Close()
+
+	if *dryRun {
+		loc, err := time.LoadLocation(*timezone)
+		if err != nil {
+			log.Fatalf("Invalid -timezone: %v", err)
+		}
+		items, err := importer.Parse(source, f, loc)
+		if err != nil {
+			log.Fatalf("Failed to parse export: %v", err)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(items); err != nil {
+			log.Fatalf("Failed to write sessions: %v", err)
+		}
+		return
+	}
+
+	userID, err := uuid.Parse(*userFlag)
+	if err != nil {
+		log.Fatalf("Invalid -user: %v", err)
+	}
+
+	cfg := config.Load()
+	db, err := config.NewDatabase(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	sleepLogRepo := repository.NewSleepLogRepository(db)
+	tagRepo := repository.NewTagRepository(db)
+	insightsCacheRepo := repository.NewInsightsCacheRepository(db)
+	sleepLogService := service.InvalidateInsightsOnChange(service.NewSleepLogService(sleepLogRepo, userRepo, tagRepo), insightsCacheRepo)
+	importService := service.NewImportService(sleepLogService, userRepo)
+
+	results, err := importService.Import(context.Background(), userID, source, f)
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+
+	var created, existing, failed int
+	for i, result := range results {
+		switch {
+		case result.Err != nil:
+			failed++
+			log.Printf("Session %d: %v", i, result.Err)
+		case result.Existing:
+			existing++
+		default:
+			created++
+		}
+	}
+	fmt.Printf("Imported %d sessions: %d created, %d already present, %d failed\n", len(results), created, existing, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
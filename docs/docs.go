@@ -15,88 +15,86 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/users": {
-            "post": {
-                "description": "Register a new user with their preferred timezone. The timezone is used for displaying sleep times in local format.",
-                "consumes": [
-                    "application/json"
-                ],
+        "/admin/cohorts/metrics": {
+            "get": {
+                "description": "Distributions (percentiles) of per-user metrics over the users matching the filter that have sleep in the window. Cohorts below the minimum size are not reported. Cohorts are built from a bounded number of users and cached for a while, so the window is the one of the cached cohort. Requires the admin API key in the X-Admin-Key header.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "admin"
                 ],
-                "summary": "Create user",
+                "summary": "Get cohort metrics",
                 "parameters": [
                     {
-                        "description": "User data",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.CreateUserRequest"
-                        }
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "Europe/Prague",
+                        "description": "IANA timezone of the users",
+                        "name": "timezone",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "early_bird",
+                            "intermediate",
+                            "night_owl"
+                        ],
+                        "type": "string",
+                        "description": "Chronotype over the window",
+                        "name": "chronotype",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "caffeine",
+                        "description": "Tag recorded in the window",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 365,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 30,
+                        "description": "Number of days to analyze",
+                        "name": "window_days",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "User created successfully",
+                    "200": {
+                        "description": "Cohort distributions",
                         "schema": {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.UserResponse"
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.CohortMetricsResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid request (malformed JSON or invalid timezone)",
+                        "description": "Invalid query parameters",
                         "schema": {
                             "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
                         }
                     },
-                    "500": {
-                        "description": "Server error",
+                    "401": {
+                        "description": "Missing or invalid admin API key",
                         "schema": {
                             "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
                         }
-                    }
-                }
-            }
-        },
-        "/users/{userId}": {
-            "get": {
-                "description": "Retrieve user details including timezone preference.",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "users"
-                ],
-                "summary": "Get user",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "format": "uuid",
-                        "example": "550e8400-e29b-41d4-a716-446655440000",
-                        "description": "User UUID",
-                        "name": "userId",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "User details",
-                        "schema": {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.UserResponse"
-                        }
                     },
-                    "400": {
-                        "description": "Invalid UUID format",
+                    "403": {
+                        "description": "Admin API disabled",
                         "schema": {
                             "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
                         }
                     },
-                    "404": {
-                        "description": "User not found",
+                    "422": {
+                        "description": "Cohort too small",
                         "schema": {
                             "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
                         }
@@ -110,17 +108,24 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{userId}/sleep-logs": {
+        "/admin/users/{userId}/sleep/cohort-ranks": {
             "get": {
-                "description": "Fetch paginated sleep history. Filter by date range. Results sorted by start_at descending (newest first).",
+                "description": "Percentile ranks of the user's metrics within the cohort matching the filter: the percentage of the cohort with a lower value, counting ties as half. Cohorts below the minimum size are not reported. The cohort is shared with the metrics endpoint and cached for a while. Requires the admin API key in the X-Admin-Key header, since building a cohort computes the metrics of every user in it.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "sleep-logs"
+                    "admin"
                 ],
-                "summary": "List sleep logs",
+                "summary": "Get cohort percentile ranks",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
                     {
                         "type": "string",
                         "format": "uuid",
@@ -132,41 +137,44 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "format": "date-time",
-                        "example": "2024-01-01T00:00:00Z",
-                        "description": "Start of date range (RFC3339, UTC recommended for consistent filtering)",
-                        "name": "from",
+                        "example": "Europe/Prague",
+                        "description": "IANA timezone of the users",
+                        "name": "timezone",
                         "in": "query"
                     },
                     {
+                        "enum": [
+                            "early_bird",
+                            "intermediate",
+                            "night_owl"
+                        ],
                         "type": "string",
-                        "format": "date-time",
-                        "example": "2024-01-31T23:59:59Z",
-                        "description": "End of date range (RFC3339, UTC recommended for consistent filtering)",
-                        "name": "to",
+                        "description": "Chronotype over the window",
+                        "name": "chronotype",
                         "in": "query"
                     },
                     {
-                        "maximum": 100,
-                        "minimum": 1,
-                        "type": "integer",
-                        "default": 20,
-                        "description": "Results per page (1-100)",
-                        "name": "limit",
+                        "type": "string",
+                        "example": "caffeine",
+                        "description": "Tag recorded in the window",
+                        "name": "tag",
                         "in": "query"
                     },
                     {
-                        "type": "string",
-                        "description": "Cursor from previous response's next_cursor",
-                        "name": "cursor",
+                        "maximum": 365,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 30,
+                        "description": "Number of days to analyze",
+                        "name": "window_days",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Sleep logs with pagination",
+                        "description": "Percentile ranks",
                         "schema": {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepLogListResponse"
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.CohortRanksResponse"
                         }
                     },
                     "400": {
@@ -175,12 +183,30 @@ const docTemplate = `{
                             "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
                         }
                     },
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin API disabled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
                     "404": {
                         "description": "User not found",
                         "schema": {
                             "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
                         }
                     },
+                    "422": {
+                        "description": "Cohort too small",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
                     "500": {
                         "description": "Server error",
                         "schema": {
@@ -188,9 +214,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/admin/users/{userId}/sleep/scores/compare": {
             "post": {
-                "description": "Log a sleep session. Use client_request_id for safe retries (idempotency). Returns 200 if duplicate request, 201 if new.",
+                "description": "Score the same window of a user's sleep with two scoring models side by side. The baseline defaults to the active model. Requires the admin API key in the X-Admin-Key header.",
                 "consumes": [
                     "application/json"
                 ],
@@ -198,9 +226,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "sleep-logs"
+                    "admin"
                 ],
-                "summary": "Record sleep",
+                "summary": "Compare scoring models",
                 "parameters": [
                     {
                         "type": "string",
@@ -212,30 +240,43 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Sleep session data",
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Models to compare",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.CreateSleepLogRequest"
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.ScoreComparisonRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Existing log returned (idempotent duplicate)",
+                        "description": "Scores of both models",
                         "schema": {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepLogResponse"
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.ScoreComparisonResponse"
                         }
                     },
-                    "201": {
-                        "description": "New sleep log created",
+                    "400": {
+                        "description": "Invalid request",
                         "schema": {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepLogResponse"
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
                         }
                     },
-                    "400": {
-                        "description": "Invalid request body or parameters",
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin API disabled",
                         "schema": {
                             "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
                         }
@@ -246,8 +287,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
                         }
                     },
-                    "409": {
-                        "description": "Sleep period overlaps with existing log",
+                    "422": {
+                        "description": "Validation error",
                         "schema": {
                             "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
                         }
@@ -261,9 +302,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{userId}/sleep-logs/{logId}": {
-            "put": {
-                "description": "Update an existing sleep session. All fields are optional - only provided fields will be updated.",
+        "/users": {
+            "post": {
+                "description": "Register a new user with their preferred timezone. The timezone is used for displaying sleep times in local format.",
                 "consumes": [
                     "application/json"
                 ],
@@ -271,59 +312,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "sleep-logs"
+                    "users"
                 ],
-                "summary": "Update sleep log",
+                "summary": "Create user",
                 "parameters": [
                     {
-                        "type": "string",
-                        "format": "uuid",
-                        "example": "550e8400-e29b-41d4-a716-446655440000",
-                        "description": "User UUID",
-                        "name": "userId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "format": "uuid",
-                        "example": "660e8400-e29b-41d4-a716-446655440001",
-                        "description": "Sleep Log UUID",
-                        "name": "logId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Fields to update",
+                        "description": "User data",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.UpdateSleepLogRequest"
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.CreateUserRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Updated sleep log",
+                    "201": {
+                        "description": "User created successfully",
                         "schema": {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepLogResponse"
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.UserResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid request body or parameters",
-                        "schema": {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
-                        }
-                    },
-                    "404": {
-                        "description": "User or sleep log not found",
-                        "schema": {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
-                        }
-                    },
-                    "409": {
-                        "description": "Sleep period overlaps with existing log",
+                        "description": "Invalid request (malformed JSON or invalid timezone)",
                         "schema": {
                             "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
                         }
@@ -337,16 +348,16 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{userId}/sleep/chronotype": {
+        "/users/{userId}": {
             "get": {
-                "description": "Compute the user's chronotype based on their sleep patterns over a configurable window.",
+                "description": "Retrieve user details including timezone preference.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "sleep-insights"
+                    "users"
                 ],
-                "summary": "Get user chronotype",
+                "summary": "Get user",
                 "parameters": [
                     {
                         "type": "string",
@@ -356,35 +367,17 @@ const docTemplate = `{
                         "name": "userId",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "maximum": 365,
-                        "minimum": 1,
-                        "type": "integer",
-                        "default": 30,
-                        "description": "Number of days to analyze",
-                        "name": "window_days",
-                        "in": "query"
-                    },
-                    {
-                        "maximum": 100,
-                        "minimum": 1,
-                        "type": "integer",
-                        "default": 7,
-                        "description": "Minimum sleep logs required",
-                        "name": "min_sleeps",
-                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Chronotype analysis result",
+                        "description": "User details",
                         "schema": {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.ChronotypeResult"
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.UserResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid query parameters",
+                        "description": "Invalid UUID format",
                         "schema": {
                             "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
                         }
@@ -404,16 +397,16 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{userId}/sleep/insights": {
+        "/users/{userId}/goals": {
             "get": {
-                "description": "Generate comprehensive sleep insights using chronotype, metrics, and LLM analysis.",
+                "description": "Get the sleep goals in force. Without goals, metrics use a 7-hour target.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "sleep-insights"
+                    "goals"
                 ],
-                "summary": "Get LLM-powered sleep insights",
+                "summary": "Get sleep goals",
                 "parameters": [
                     {
                         "type": "string",
@@ -427,35 +420,33 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Sleep insights with LLM analysis",
+                        "description": "Sleep goals in force",
                         "schema": {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.InsightsResponse"
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepGoalResponse"
                         }
                     },
-                    "404": {
-                        "description": "User not found",
+                    "400": {
+                        "description": "Invalid UUID format",
                         "schema": {
                             "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
                         }
                     },
-                    "500": {
-                        "description": "Server error",
+                    "404": {
+                        "description": "User not found or no goals set",
                         "schema": {
                             "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
                         }
                     },
-                    "503": {
-                        "description": "LLM service unavailable",
+                    "500": {
+                        "description": "Server error",
                         "schema": {
                             "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
                         }
                     }
                 }
-            }
-        },
-        "/users/{userId}/sleep/insights/feedback": {
-            "post": {
-                "description": "Submit a user rating and optional comment for a previous insights response.",
+            },
+            "put": {
+                "description": "Replace the sleep goals from now on. The previous goals are kept in the history, so past windows are still scored against the goals in force at the time.",
                 "consumes": [
                     "application/json"
                 ],
@@ -463,9 +454,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "sleep-insights"
+                    "goals"
                 ],
-                "summary": "Submit feedback on sleep insights",
+                "summary": "Set sleep goals",
                 "parameters": [
                     {
                         "type": "string",
@@ -477,18 +468,21 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Feedback request",
-                        "name": "body",
+                        "description": "Sleep goals",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_api_handler.FeedbackRequest"
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepGoalRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "Feedback submitted"
+                    "200": {
+                        "description": "Sleep goals in force",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepGoalResponse"
+                        }
                     },
                     "400": {
                         "description": "Invalid request",
@@ -496,6 +490,59 @@ const docTemplate = `{
                             "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
                         }
                     },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "End the sleep goals in force, so the defaults apply from now on. The goals stay in the history.",
+                "tags": [
+                    "goals"
+                ],
+                "summary": "Remove sleep goals",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Sleep goals removed"
+                    },
+                    "400": {
+                        "description": "Invalid UUID format",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found or no goals set",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
                     "500": {
                         "description": "Server error",
                         "schema": {
@@ -505,16 +552,65 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{userId}/sleep/metrics": {
+        "/users/{userId}/goals/history": {
             "get": {
-                "description": "Compute per-sleep and per-day sleep metrics over a configurable window.",
+                "description": "List every version of the user's sleep goals, oldest first, with the period each was in force.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "sleep-insights"
+                    "goals"
                 ],
-                "summary": "Get sleep metrics",
+                "summary": "List sleep goal history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Goal history",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepGoalHistoryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid UUID format",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userId}/sleep-logs": {
+            "get": {
+                "description": "Fetch paginated sleep history. Filter by date range. Results sorted by start_at descending (newest first).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sleep-logs"
+                ],
+                "summary": "List sleep logs",
                 "parameters": [
                     {
                         "type": "string",
@@ -526,20 +622,49 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "maximum": 365,
+                        "type": "string",
+                        "format": "date-time",
+                        "example": "2024-01-01T00:00:00Z",
+                        "description": "Start of date range (RFC3339, UTC recommended for consistent filtering)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "example": "2024-01-31T23:59:59Z",
+                        "description": "End of date range (RFC3339, UTC recommended for consistent filtering)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
                         "minimum": 1,
                         "type": "integer",
-                        "default": 30,
-                        "description": "Number of days to analyze",
-                        "name": "window_days",
+                        "default": 20,
+                        "description": "Results per page (1-100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from previous response's next_cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "caffeine",
+                        "description": "Only logs with a factor of this tag",
+                        "name": "tag",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Sleep metrics",
+                        "description": "Sleep logs with pagination",
                         "schema": {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.MetricsResponse"
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepLogListResponse"
                         }
                     },
                     "400": {
@@ -561,360 +686,3677 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "github_com_blaisecz_sleep-tracker_internal_domain.ChronotypeResult": {
-            "description": "Chronotype analysis result.",
-            "type": "object",
-            "properties": {
-                "chronotype": {
-                    "description": "Chronotype classification",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.ChronotypeType"
+            },
+            "post": {
+                "description": "Log a sleep session. Use client_request_id for safe retries (idempotency). Returns 200 if duplicate request, 201 if new. Optional stages describe the hypnogram as contiguous AWAKE/LIGHT/DEEP/REM segments within the session.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sleep-logs"
+                ],
+                "summary": "Record sleep",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Sleep session data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.CreateSleepLogRequest"
                         }
-                    ],
-                    "example": "intermediate"
-                },
-                "mid_sleep_local_time": {
-                    "description": "Mid-sleep time in local timezone (HH:MM format)",
-                    "type": "string",
-                    "example": "03:45"
-                },
-                "mid_sleep_minutes_after_midnight": {
-                    "description": "Minutes after midnight for mid-sleep",
-                    "type": "integer",
-                    "example": 225
-                },
-                "sleeps_used": {
-                    "description": "Number of sleep logs used in calculation",
-                    "type": "integer",
-                    "example": 28
-                },
-                "window_days": {
-                    "description": "Number of days in the analysis window",
-                    "type": "integer",
-                    "example": 30
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Existing log returned (idempotent duplicate)",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepLogResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "New sleep log created",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepLogResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Sleep period overlaps with existing log, or client_request_id belongs to a deleted log",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid fields, stages that are not contiguous or leave the sleep period, or unknown factor tags",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
                 }
             }
         },
-        "github_com_blaisecz_sleep-tracker_internal_domain.ChronotypeType": {
-            "description": "Chronotype classification based on mid-sleep time.",
-            "type": "string",
+        "/users/{userId}/sleep-logs/export": {
+            "get": {
+                "description": "Download every sleep log of the user, oldest first, with UTC and local times. Unlike the list endpoint the export is not paginated; it is streamed as CSV, NDJSON (one log per line) or a JSON array.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/json"
+                ],
+                "tags": [
+                    "sleep-logs"
+                ],
+                "summary": "Export sleep history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson",
+                            "json"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "Export format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "example": "2024-01-01T00:00:00Z",
+                        "description": "Start of date range (RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "example": "2024-12-31T23:59:59Z",
+                        "description": "End of date range (RFC3339)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Sleep logs",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepLogResponse"
+                            }
+                        },
+                        "headers": {
+                            "Content-Disposition": {
+                                "type": "string",
+                                "description": "attachment; filename=sleep-logs-{userId}.{format}"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userId}/sleep-logs/import": {
+            "post": {
+                "description": "Upload a sleep export from Apple Health (export.xml), Fitbit (sleep JSON) or Google Fit / Health Connect (sleep session JSON) as the raw request body. Stage records are merged into CORE or NAP sessions with a quality derived from sleep efficiency. Sessions get stable client_request_ids, so re-uploading the same export does not create duplicates. Sessions are stored best-effort and reported per item.",
+                "consumes": [
+                    "application/xml",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sleep-logs"
+                ],
+                "summary": "Import wearable export",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "apple_health",
+                            "fitbit",
+                            "google_fit"
+                        ],
+                        "type": "string",
+                        "description": "Export format",
+                        "name": "source",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "207": {
+                        "description": "Per-session results",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api_handler.BatchCreateSleepLogsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Export could not be parsed or contains no sleep data",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Export larger than 256 MiB",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Missing or unsupported source",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userId}/sleep-logs/{logId}": {
+            "get": {
+                "description": "Fetch a single sleep session. The ETag header carries the log version for use with If-Match.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sleep-logs"
+                ],
+                "summary": "Get sleep log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "660e8400-e29b-41d4-a716-446655440001",
+                        "description": "Sleep Log UUID",
+                        "name": "logId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Sleep log",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepLogResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Entity tag of the sleep log representation"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User or sleep log not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update an existing sleep session. All fields are optional - only provided fields will be updated. Sending stages replaces all stage segments; an empty array removes them. Send the ETag from a previous response in If-Match to avoid overwriting concurrent edits.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sleep-logs"
+                ],
+                "summary": "Update sleep log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "660e8400-e29b-41d4-a716-446655440001",
+                        "description": "Sleep Log UUID",
+                        "name": "logId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"3\"",
+                        "description": "ETag of the version being updated",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Fields to update",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.UpdateSleepLogRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated sleep log",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepLogResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Entity tag of the updated version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User or sleep log not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Sleep period overlaps with existing log",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Sleep log was modified since the given ETag",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid fields, stages that are not contiguous or leave the sleep period, or unknown factor tags",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Soft-delete a sleep session. Deleted logs are excluded from listings, metrics and overlap checks, and can be restored until they are purged by the retention job. Send the ETag from a previous response in If-Match to avoid deleting a concurrently edited log. Deleting bumps the version; the response carries the new ETag, which a restore can send in If-Match.",
+                "tags": [
+                    "sleep-logs"
+                ],
+                "summary": "Delete sleep log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "660e8400-e29b-41d4-a716-446655440001",
+                        "description": "Sleep Log UUID",
+                        "name": "logId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"3\"",
+                        "description": "ETag of the version being deleted",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Sleep log deleted",
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Entity tag of the deleted version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User or sleep log not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Sleep log was modified since the given ETag",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Apply an RFC 7396 JSON merge patch to a sleep session. Members set to null are removed: a null local_timezone resets it to the user's timezone and a null client_request_id clears it. The patched log goes through the same validation and overlap checks as a new one.",
+                "consumes": [
+                    "application/merge-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sleep-logs"
+                ],
+                "summary": "Patch sleep log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "660e8400-e29b-41d4-a716-446655440001",
+                        "description": "Sleep Log UUID",
+                        "name": "logId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"3\"",
+                        "description": "ETag of the version being patched",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Merge patch document (any subset of fields, null removes)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.CreateSleepLogRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Patched sleep log",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepLogResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Entity tag of the patched version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid patch document or parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User or sleep log not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Sleep period overlaps with existing log, or client_request_id already in use",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Sleep log was modified since the given ETag",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Merge patch document is larger than 1 MiB",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Content-Type is not application/merge-patch+json",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Patched sleep log contains invalid fields",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userId}/sleep-logs/{logId}/restore": {
+            "post": {
+                "description": "Restore a soft-deleted sleep session. Fails if another log now occupies the same period. Send the ETag returned by the delete in If-Match to avoid restoring a log that was deleted again meanwhile.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sleep-logs"
+                ],
+                "summary": "Restore sleep log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "660e8400-e29b-41d4-a716-446655440001",
+                        "description": "Sleep Log UUID",
+                        "name": "logId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"4\"",
+                        "description": "ETag returned when the log was deleted",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Restored sleep log",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepLogResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Entity tag of the restored version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User or deleted sleep log not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Sleep period overlaps with existing log",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Sleep log was modified since the given ETag",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userId}/sleep-logs:batch": {
+            "post": {
+                "description": "Log up to 500 sleep sessions at once. Items are checked for overlaps with stored logs and with earlier items of the batch, and client_request_id is honoured per item. In atomic mode (default) nothing is stored if any item fails; in best_effort mode every valid item is stored. Per-item outcomes are returned in a 207 Multi-Status body.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sleep-logs"
+                ],
+                "summary": "Record sleep in bulk",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Batch of sleep sessions",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.BatchCreateSleepLogsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "207": {
+                        "description": "Per-item results",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api_handler.BatchCreateSleepLogsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid mode or number of items",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userId}/sleep/anomalies": {
+            "get": {
+                "description": "Flag nights whose duration, bedtime, mid-sleep or quality deviate from the user's rolling 28-day baseline, using robust z-scores (median and MAD). Weekday and weekend nights are compared separately.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sleep-insights"
+                ],
+                "summary": "Get sleep anomalies",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 365,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 14,
+                        "description": "Number of days of nights to score",
+                        "name": "window_days",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Unusual nights",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.AnomaliesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userId}/sleep/chronotype": {
+            "get": {
+                "description": "Compute the user's chronotype based on their sleep patterns over a configurable window.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sleep-insights"
+                ],
+                "summary": "Get user chronotype",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 365,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 30,
+                        "description": "Number of days to analyze",
+                        "name": "window_days",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 7,
+                        "description": "Minimum sleep logs required",
+                        "name": "min_sleeps",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Chronotype analysis result",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.ChronotypeResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userId}/sleep/debt": {
+            "get": {
+                "description": "Per-day running balance of total sleep (core and naps) minus the target of the user's goal. Each day a share of the balance decays; extra sleep pays off debt but is not banked, and debt is capped. Days without sleep only decay the balance.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sleep-insights"
+                ],
+                "summary": "Get sleep debt",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 365,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 30,
+                        "description": "Number of days in the series",
+                        "name": "span",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 0.99,
+                        "minimum": 0,
+                        "type": "number",
+                        "default": 0.1,
+                        "description": "Share of the balance forgiven each day",
+                        "name": "decay",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "number",
+                        "default": 20,
+                        "description": "Largest debt in hours",
+                        "name": "cap_hours",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Sleep debt series",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepDebtResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userId}/sleep/factors/impact": {
+            "get": {
+                "description": "Compare sleep duration and quality on nights with each factor against nights without it. Effect sizes are Cohen's d; intervals come from bootstrap resampling.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sleep-insights"
+                ],
+                "summary": "Get factor impact analysis",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 365,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 90,
+                        "description": "Number of days to analyze",
+                        "name": "window_days",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Factor impact analysis",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.FactorImpactResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userId}/sleep/insights": {
+            "get": {
+                "description": "Generate comprehensive sleep insights using chronotype, metrics, and LLM analysis. When the LLM is unavailable or fails, fixed rules write the insights instead; generator names the one used. Insights are cached until the user's sleep data, the model or the prompts change; X-Insights-Cache tells whether the response came from the cache and Age how old cached insights are.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sleep-insights"
+                ],
+                "summary": "Get LLM-powered sleep insights",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Generate new insights even if cached ones are still valid",
+                        "name": "refresh",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Sleep insights with LLM analysis",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.InsightsResponse"
+                        },
+                        "headers": {
+                            "Age": {
+                                "type": "integer",
+                                "description": "Seconds since cached insights were generated"
+                            },
+                            "X-Insights-Cache": {
+                                "type": "string",
+                                "description": "HIT or MISS"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "LLM call failed and the fallback is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "LLM provider not configured or temporarily unavailable and the fallback is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userId}/sleep/insights/feedback": {
+            "post": {
+                "description": "Submit a user rating and optional comment for a previous insights response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sleep-insights"
+                ],
+                "summary": "Submit feedback on sleep insights",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Feedback request",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_api_handler.FeedbackRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Feedback submitted"
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userId}/sleep/metrics": {
+            "get": {
+                "description": "Compute per-sleep and per-day sleep metrics over a rolling window, an ISO week, a calendar month or a custom range of dates. Calendar periods run from midnight to midnight in the user's timezone. With compare=previous the response also holds the previous period and the deltas.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sleep-insights"
+                ],
+                "summary": "Get sleep metrics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "rolling",
+                            "week",
+                            "month",
+                            "custom"
+                        ],
+                        "type": "string",
+                        "default": "rolling",
+                        "description": "Period",
+                        "name": "period",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 365,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 30,
+                        "description": "Number of days of a rolling period",
+                        "name": "window_days",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-01-15",
+                        "description": "Local date (YYYY-MM-DD) in the week or month, or ending the rolling period; defaults to today",
+                        "name": "as_of",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-01-01",
+                        "description": "First local date of a custom period (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-01-31",
+                        "description": "Last local date of a custom period (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "previous"
+                        ],
+                        "type": "string",
+                        "description": "Add the previous period with deltas",
+                        "name": "compare",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Sleep metrics",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.MetricsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userId}/sleep/trends": {
+            "get": {
+                "description": "Per-day series of a sleep metric with a simple moving average over the requested window, a 30-day simple moving average and an exponentially weighted moving average. Days are local dates of the sleep end time; days without sleep are marked as missing.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sleep-insights"
+                ],
+                "summary": "Get sleep trends",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "duration",
+                            "quality",
+                            "bedtime",
+                            "total_daily"
+                        ],
+                        "type": "string",
+                        "default": "duration",
+                        "description": "Metric",
+                        "name": "metric",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 90,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 7,
+                        "description": "Moving average window in days",
+                        "name": "window",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 365,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 90,
+                        "description": "Number of days in the series",
+                        "name": "span",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Trend series",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.TrendsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userId}/tags": {
+            "get": {
+                "description": "List the tags that can be attached to sleep logs as factors: the predefined catalogue (caffeine, alcohol, exercise, illness, ...) followed by the user's own tags.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "List factor tags",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Available tags",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.TagListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid UUID format",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Define a custom tag for factors missing from the catalogue. The name must not be used by the catalogue or another of the user's tags.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Create factor tag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Tag data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.CreateTagRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Tag created",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.TagResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Tag name already exists",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid fields",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userId}/workdays": {
+            "put": {
+                "description": "Replace the days of the week the user works. The other days are free days, used to compare workday and free-day sleep in the chronotype analysis.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update workday calendar",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "User UUID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Workdays",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.UpdateWorkdaysRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated user",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.UserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "github_com_blaisecz_sleep-tracker_internal_domain.AnomaliesResponse": {
+            "description": "Nights in a window that deviate from the user's rolling baseline.",
+            "type": "object",
+            "properties": {
+                "anomalies": {
+                    "description": "Flagged nights, most recent first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepAnomaly"
+                    }
+                },
+                "baseline_days": {
+                    "description": "Length of the rolling baseline before each night",
+                    "type": "integer",
+                    "example": 28
+                },
+                "nights_scored": {
+                    "description": "Number of nights with enough baseline to be scored",
+                    "type": "integer",
+                    "example": 12
+                },
+                "threshold": {
+                    "description": "Absolute robust z-score from which a measure is flagged",
+                    "type": "number",
+                    "example": 3.5
+                },
+                "window": {
+                    "description": "Window of the scored nights",
+                    "type": "object",
+                    "properties": {
+                        "from": {
+                            "type": "string",
+                            "example": "2024-01-01T00:00:00Z"
+                        },
+                        "to": {
+                            "type": "string",
+                            "example": "2024-01-14T23:59:59Z"
+                        }
+                    }
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.AnomalyBaseline": {
+            "description": "Medians of the baseline nights, per measure.",
+            "type": "object",
+            "properties": {
+                "bedtime_minutes": {
+                    "description": "Minutes after local midnight",
+                    "type": "number",
+                    "example": 1380
+                },
+                "duration_hours": {
+                    "type": "number",
+                    "example": 7.5
+                },
+                "mid_sleep_minutes": {
+                    "description": "Minutes after local midnight",
+                    "type": "number",
+                    "example": 195
+                },
+                "quality": {
+                    "type": "number",
+                    "example": 7
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.AnomalyMeasure": {
+            "description": "Scored measure: duration, bedtime, mid_sleep or quality.",
+            "type": "string",
+            "enum": [
+                "duration",
+                "bedtime",
+                "mid_sleep",
+                "quality"
+            ],
+            "x-enum-varnames": [
+                "AnomalyMeasureDuration",
+                "AnomalyMeasureBedtime",
+                "AnomalyMeasureMidSleep",
+                "AnomalyMeasureQuality"
+            ]
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.AnomalySeverity": {
+            "description": "Anomaly severity: MILD, MODERATE or SEVERE.",
+            "type": "string",
+            "enum": [
+                "MILD",
+                "MODERATE",
+                "SEVERE"
+            ],
+            "x-enum-varnames": [
+                "AnomalySeverityMild",
+                "AnomalySeverityModerate",
+                "AnomalySeveritySevere"
+            ]
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.BatchCreateSleepLogsRequest": {
+            "description": "Request payload for importing many sleep sessions at once.",
+            "type": "object",
+            "required": [
+                "items"
+            ],
+            "properties": {
+                "items": {
+                    "description": "Sleep sessions to create (1-500)",
+                    "type": "array",
+                    "maxItems": 500,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.CreateSleepLogRequest"
+                    }
+                },
+                "mode": {
+                    "description": "Commit mode: atomic (default) or best_effort",
+                    "enum": [
+                        "atomic",
+                        "best_effort"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.BatchMode"
+                        }
+                    ],
+                    "example": "atomic"
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.BatchMode": {
+            "description": "atomic inserts all items or none; best_effort inserts every item that passes its checks.",
+            "type": "string",
+            "enum": [
+                "atomic",
+                "best_effort"
+            ],
+            "x-enum-varnames": [
+                "BatchModeAtomic",
+                "BatchModeBestEffort"
+            ]
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.ChronotypeResult": {
+            "description": "Chronotype analysis result.",
+            "type": "object",
+            "properties": {
+                "chronotype": {
+                    "description": "Chronotype classification, from MSFsc when social jetlag is available\nand from the median mid-sleep otherwise",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.ChronotypeType"
+                        }
+                    ],
+                    "example": "intermediate"
+                },
+                "mid_sleep_local_time": {
+                    "description": "Mid-sleep time in local timezone (HH:MM format)",
+                    "type": "string",
+                    "example": "03:45"
+                },
+                "mid_sleep_minutes_after_midnight": {
+                    "description": "Minutes after midnight for mid-sleep",
+                    "type": "integer",
+                    "example": 225
+                },
+                "sleeps_used": {
+                    "description": "Number of sleep logs used in calculation",
+                    "type": "integer",
+                    "example": 28
+                },
+                "social_jetlag": {
+                    "description": "Workday versus free-day timing, when both have enough nights",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SocialJetlag"
+                        }
+                    ]
+                },
+                "window_days": {
+                    "description": "Number of days in the analysis window",
+                    "type": "integer",
+                    "example": 30
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.ChronotypeType": {
+            "description": "Chronotype classification based on mid-sleep time.",
+            "type": "string",
+            "enum": [
+                "early_bird",
+                "intermediate",
+                "night_owl",
+                "unknown"
+            ],
+            "x-enum-varnames": [
+                "ChronotypeEarlyBird",
+                "ChronotypeIntermediate",
+                "ChronotypeNightOwl",
+                "ChronotypeUnknown"
+            ]
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.CohortDistribution": {
+            "description": "Mean, standard deviation and percentiles of a per-user value.",
+            "type": "object",
+            "properties": {
+                "avg": {
+                    "type": "number",
+                    "example": 7.2
+                },
+                "median": {
+                    "type": "number",
+                    "example": 7.2
+                },
+                "p10": {
+                    "description": "Percentiles, linearly interpolated",
+                    "type": "number",
+                    "example": 6.1
+                },
+                "p25": {
+                    "type": "number",
+                    "example": 6.7
+                },
+                "p75": {
+                    "type": "number",
+                    "example": 7.7
+                },
+                "p90": {
+                    "type": "number",
+                    "example": 8.3
+                },
+                "std": {
+                    "type": "number",
+                    "example": 0.8
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.CohortFilter": {
+            "description": "Users to include in a cohort.",
+            "type": "object",
+            "properties": {
+                "chronotype": {
+                    "description": "Chronotype over the window",
+                    "enum": [
+                        "early_bird",
+                        "intermediate",
+                        "night_owl"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.ChronotypeType"
+                        }
+                    ],
+                    "example": "night_owl"
+                },
+                "tag": {
+                    "description": "Tag recorded on at least one sleep log in the window",
+                    "type": "string",
+                    "example": "caffeine"
+                },
+                "timezone": {
+                    "description": "IANA timezone of the users",
+                    "type": "string",
+                    "example": "Europe/Prague"
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.CohortMetrics": {
+            "description": "Distributions of per-user metrics across a cohort.",
+            "type": "object",
+            "properties": {
+                "avg_duration_hours": {
+                    "description": "Average sleep duration in hours",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.CohortDistribution"
+                        }
+                    ]
+                },
+                "avg_quality": {
+                    "description": "Average quality (1-10 scale)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.CohortDistribution"
+                        }
+                    ]
+                },
+                "avg_total_daily_hours": {
+                    "description": "Average total daily sleep in hours (core + naps)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.CohortDistribution"
+                        }
+                    ]
+                },
+                "bedtime_std_minutes": {
+                    "description": "Circular bedtime standard deviation in minutes",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.CohortDistribution"
+                        }
+                    ]
+                },
+                "daily_sufficiency_score": {
+                    "description": "Percentage of days meeting the target",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.CohortDistribution"
+                        }
+                    ]
+                },
+                "overall_sleep_score": {
+                    "description": "Overall sleep score",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.CohortDistribution"
+                        }
+                    ]
+                },
+                "sleep_regularity_index": {
+                    "description": "Sleep Regularity Index",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.CohortDistribution"
+                        }
+                    ]
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.CohortMetricsResponse": {
+            "description": "Population baselines over a cohort of users.",
+            "type": "object",
+            "properties": {
+                "cohort_size": {
+                    "description": "Number of users matching the filter with sleep in the window",
+                    "type": "integer",
+                    "example": 128
+                },
+                "filter": {
+                    "description": "Filter of the cohort",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.CohortFilter"
+                        }
+                    ]
+                },
+                "metrics": {
+                    "description": "Distributions of the per-user metrics",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.CohortMetrics"
+                        }
+                    ]
+                },
+                "min_cohort_size": {
+                    "description": "Smallest cohort that is reported",
+                    "type": "integer",
+                    "example": 10
+                },
+                "window": {
+                    "type": "object",
+                    "properties": {
+                        "from": {
+                            "type": "string",
+                            "example": "2024-01-01T00:00:00Z"
+                        },
+                        "to": {
+                            "type": "string",
+                            "example": "2024-01-31T23:59:59Z"
+                        }
+                    }
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.CohortRanks": {
+            "description": "Percentile ranks (0-100) of a user's metrics within a cohort.",
+            "type": "object",
+            "properties": {
+                "avg_duration_hours": {
+                    "type": "number",
+                    "example": 62.5
+                },
+                "avg_quality": {
+                    "type": "number",
+                    "example": 48
+                },
+                "avg_total_daily_hours": {
+                    "type": "number",
+                    "example": 70.1
+                },
+                "bedtime_std_minutes": {
+                    "type": "number",
+                    "example": 35.2
+                },
+                "daily_sufficiency_score": {
+                    "type": "number",
+                    "example": 55
+                },
+                "overall_sleep_score": {
+                    "type": "number",
+                    "example": 66.4
+                },
+                "sleep_regularity_index": {
+                    "type": "number",
+                    "example": 81.3
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.CohortRanksResponse": {
+            "description": "Where a user sits within a cohort.",
+            "type": "object",
+            "properties": {
+                "cohort_size": {
+                    "description": "Number of users matching the filter with sleep in the window",
+                    "type": "integer",
+                    "example": 128
+                },
+                "filter": {
+                    "description": "Filter of the cohort",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.CohortFilter"
+                        }
+                    ]
+                },
+                "ranks": {
+                    "description": "Percentile ranks of the user",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.CohortRanks"
+                        }
+                    ]
+                },
+                "window": {
+                    "type": "object",
+                    "properties": {
+                        "from": {
+                            "type": "string",
+                            "example": "2024-01-01T00:00:00Z"
+                        },
+                        "to": {
+                            "type": "string",
+                            "example": "2024-01-31T23:59:59Z"
+                        }
+                    }
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.ConfidenceInterval": {
+            "description": "Lower and upper bound of a confidence interval.",
+            "type": "object",
+            "properties": {
+                "high": {
+                    "type": "number",
+                    "example": -0.3
+                },
+                "low": {
+                    "type": "number",
+                    "example": -1.2
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.CreateSleepLogRequest": {
+            "description": "Request payload for recording a sleep session.",
+            "type": "object",
+            "required": [
+                "end_at",
+                "quality",
+                "start_at",
+                "type"
+            ],
+            "properties": {
+                "client_request_id": {
+                    "description": "Optional client-generated ID for idempotent requests (max 255 chars)",
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "client-uuid-12345"
+                },
+                "end_at": {
+                    "description": "Sleep end time in RFC3339 format (must be after start_at)",
+                    "type": "string",
+                    "example": "2024-01-16T07:00:00Z"
+                },
+                "factors": {
+                    "description": "Optional contextual factors (caffeine, alcohol, exercise, ...), each tag at most once",
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.FactorRequest"
+                    }
+                },
+                "local_timezone": {
+                    "description": "Optional IANA timezone for local time display (defaults to user's timezone)",
+                    "type": "string",
+                    "example": "Europe/Prague"
+                },
+                "quality": {
+                    "description": "Sleep quality rating from 1 (poor) to 10 (excellent)",
+                    "type": "integer",
+                    "maximum": 10,
+                    "minimum": 1,
+                    "example": 7
+                },
+                "stages": {
+                    "description": "Optional sleep stage segments (contiguous, within start_at and end_at)",
+                    "type": "array",
+                    "maxItems": 500,
+                    "items": {
+                        "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepStageRequest"
+                    }
+                },
+                "start_at": {
+                    "description": "Sleep start time in RFC3339 format (UTC recommended)",
+                    "type": "string",
+                    "example": "2024-01-15T23:00:00Z"
+                },
+                "type": {
+                    "description": "Sleep type: CORE (main sleep) or NAP (daytime nap)",
+                    "enum": [
+                        "CORE",
+                        "NAP"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepType"
+                        }
+                    ],
+                    "example": "CORE"
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.CreateTagRequest": {
+            "description": "Request payload for a user-defined factor tag.",
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "category": {
+                    "description": "Category (defaults to OTHER)",
+                    "enum": [
+                        "SUBSTANCE",
+                        "ACTIVITY",
+                        "HEALTH",
+                        "ENVIRONMENT",
+                        "OTHER"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.TagCategory"
+                        }
+                    ],
+                    "example": "SUBSTANCE"
+                },
+                "label": {
+                    "description": "Display label (defaults to the name)",
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "Melatonin"
+                },
+                "name": {
+                    "description": "Unique name used to reference the tag (lowercase letters, digits and underscores)",
+                    "type": "string",
+                    "maxLength": 50,
+                    "example": "melatonin"
+                },
+                "unit": {
+                    "description": "Unit of the factor amount",
+                    "type": "string",
+                    "maxLength": 20,
+                    "example": "mg"
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.CreateUserRequest": {
+            "description": "Request payload for creating a new user account.",
+            "type": "object",
+            "required": [
+                "timezone"
+            ],
+            "properties": {
+                "timezone": {
+                    "description": "IANA timezone identifier (e.g., \"America/New_York\", \"Europe/London\", \"UTC\").\nSee: https://en.wikipedia.org/wiki/List_of_tz_database_time_zones",
+                    "type": "string",
+                    "example": "Europe/Prague"
+                },
+                "workdays": {
+                    "description": "Days of the week the user works (defaults to MON-FRI); the others are free days",
+                    "type": "array",
+                    "maxItems": 7,
+                    "uniqueItems": true,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "MON",
+                        "TUE",
+                        "WED",
+                        "THU",
+                        "FRI"
+                    ]
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.DailyOverallMetrics": {
+            "description": "Daily total sleep metrics (core + naps combined).",
+            "type": "object",
+            "properties": {
+                "daily_sufficiency_score": {
+                    "description": "Percentage of days meeting target (0-100)",
+                    "type": "number",
+                    "example": 73.3
+                },
+                "days_count": {
+                    "description": "Number of days with sleep data",
+                    "type": "integer",
+                    "example": 30
+                },
+                "days_meeting_target": {
+                    "description": "Number of days meeting the target in force on that day",
+                    "type": "integer",
+                    "example": 22
+                },
+                "days_over_nap_allowance": {
+                    "description": "Number of days with more naps than the goal allows, absent without a nap allowance",
+                    "type": "integer",
+                    "example": 3
+                },
+                "target_hours": {
+                    "description": "Target hours of the goal in force at the end of the window",
+                    "type": "number",
+                    "example": 7
+                },
+                "total_daily_hours": {
+                    "description": "Total daily sleep hours statistics",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.DescriptiveStats"
+                        }
+                    ]
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.DerivedScores": {
+            "description": "Derived scores based on sleep metrics.",
+            "type": "object",
+            "properties": {
+                "bedtime_goal_score": {
+                    "description": "Percentage of core sleeps starting within the goal's bedtime window, absent without one",
+                    "type": "number",
+                    "example": 71.4
+                },
+                "consistency_score": {
+                    "description": "Consistency score based on bedtime variability (0-100)",
+                    "type": "number",
+                    "example": 75
+                },
+                "overall_sleep_score": {
+                    "description": "Overall sleep score combining factors (0-100)",
+                    "type": "number",
+                    "example": 77.5
+                },
+                "regularity_source": {
+                    "description": "Measure used for the regularity component of the overall score",
+                    "enum": [
+                        "bedtime_std",
+                        "sri"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.RegularitySource"
+                        }
+                    ],
+                    "example": "bedtime_std"
+                },
+                "scoring_model": {
+                    "description": "Name of the scoring model behind the scores",
+                    "type": "string",
+                    "example": "default"
+                },
+                "scoring_model_version": {
+                    "description": "Version of the scoring model",
+                    "type": "string",
+                    "example": "1"
+                },
+                "sleep_regularity_index": {
+                    "description": "Sleep Regularity Index (-100 to 100), absent without enough consecutive days",
+                    "type": "number",
+                    "example": 82.4
+                },
+                "sufficiency_score": {
+                    "description": "Sufficiency score based on duration meeting targets (0-100)",
+                    "type": "number",
+                    "example": 80
+                },
+                "wake_goal_score": {
+                    "description": "Percentage of core sleeps ending within 30 minutes of the goal's wake time, absent without one",
+                    "type": "number",
+                    "example": 85.7
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.DescriptiveStats": {
+            "description": "Basic statistical measures for a metric.",
+            "type": "object",
+            "properties": {
+                "avg": {
+                    "type": "number",
+                    "example": 7.2
+                },
+                "max": {
+                    "type": "number",
+                    "example": 9
+                },
+                "median": {
+                    "type": "number",
+                    "example": 7.2
+                },
+                "min": {
+                    "type": "number",
+                    "example": 5.5
+                },
+                "p10": {
+                    "description": "Percentiles, linearly interpolated",
+                    "type": "number",
+                    "example": 6.1
+                },
+                "p25": {
+                    "type": "number",
+                    "example": 6.7
+                },
+                "p75": {
+                    "type": "number",
+                    "example": 7.7
+                },
+                "p90": {
+                    "type": "number",
+                    "example": 8.3
+                },
+                "resultant_length": {
+                    "description": "Mean resultant length (0-1) of clock times; 1 means the same time every night",
+                    "type": "number",
+                    "example": 0.97
+                },
+                "std": {
+                    "type": "number",
+                    "example": 0.8
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.FactorAnalysis": {
+            "description": "Duration and quality on nights with a factor compared with nights without it.",
+            "type": "object",
+            "properties": {
+                "category": {
+                    "description": "Tag category",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.TagCategory"
+                        }
+                    ],
+                    "example": "SUBSTANCE"
+                },
+                "duration": {
+                    "description": "Duration in hours",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.FactorEffect"
+                        }
+                    ]
+                },
+                "label": {
+                    "description": "Tag label",
+                    "type": "string",
+                    "example": "Alcohol"
+                },
+                "quality": {
+                    "description": "Quality (1-10 scale)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.FactorEffect"
+                        }
+                    ]
+                },
+                "tag": {
+                    "description": "Tag name",
+                    "type": "string",
+                    "example": "alcohol"
+                },
+                "tagged_count": {
+                    "description": "Number of nights with the factor",
+                    "type": "integer",
+                    "example": 8
+                },
+                "untagged_count": {
+                    "description": "Number of nights without the factor",
+                    "type": "integer",
+                    "example": 52
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.FactorEffect": {
+            "description": "Distributions of a measure with and without a factor, with the effect size and bootstrap confidence intervals.",
+            "type": "object",
+            "properties": {
+                "effect_size": {
+                    "description": "Standardised mean difference (Cohen's d with pooled standard deviation)",
+                    "type": "number",
+                    "example": -0.82
+                },
+                "effect_size_ci": {
+                    "description": "Bootstrap confidence interval of effect_size",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.ConfidenceInterval"
+                        }
+                    ]
+                },
+                "mean_diff": {
+                    "description": "Mean of tagged minus mean of untagged nights (absent if a group is too small)",
+                    "type": "number",
+                    "example": -0.75
+                },
+                "mean_diff_ci": {
+                    "description": "Bootstrap confidence interval of mean_diff",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.ConfidenceInterval"
+                        }
+                    ]
+                },
+                "tagged": {
+                    "description": "Statistics of tagged nights",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.DescriptiveStats"
+                        }
+                    ]
+                },
+                "untagged": {
+                    "description": "Statistics of untagged nights",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.DescriptiveStats"
+                        }
+                    ]
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.FactorGroupStats": {
+            "description": "Sleep statistics for logs with or without a factor.",
+            "type": "object",
+            "properties": {
+                "avg_duration_hours": {
+                    "description": "Average duration in hours",
+                    "type": "number",
+                    "example": 6.8
+                },
+                "avg_quality": {
+                    "description": "Average quality (1-10 scale)",
+                    "type": "number",
+                    "example": 6.2
+                },
+                "sleep_count": {
+                    "description": "Number of sleep logs",
+                    "type": "integer",
+                    "example": 9
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.FactorImpact": {
+            "description": "Average duration and quality of sleep logs with a factor versus those without it.",
+            "type": "object",
+            "properties": {
+                "category": {
+                    "description": "Tag category",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.TagCategory"
+                        }
+                    ],
+                    "example": "SUBSTANCE"
+                },
+                "duration_diff_hours": {
+                    "description": "Average duration with minus without, in hours (absent if either group is empty)",
+                    "type": "number",
+                    "example": -0.6
+                },
+                "label": {
+                    "description": "Tag label",
+                    "type": "string",
+                    "example": "Alcohol"
+                },
+                "quality_diff": {
+                    "description": "Average quality with minus without (absent if either group is empty)",
+                    "type": "number",
+                    "example": -1.1
+                },
+                "tag": {
+                    "description": "Tag name",
+                    "type": "string",
+                    "example": "alcohol"
+                },
+                "with": {
+                    "description": "Logs with the factor",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.FactorGroupStats"
+                        }
+                    ]
+                },
+                "without": {
+                    "description": "Logs without the factor",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.FactorGroupStats"
+                        }
+                    ]
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.FactorImpactResponse": {
+            "description": "Impact of each recorded factor on sleep duration and quality over a window.",
+            "type": "object",
+            "properties": {
+                "confidence_level": {
+                    "description": "Confidence level of the intervals",
+                    "type": "number",
+                    "example": 0.95
+                },
+                "factors": {
+                    "description": "One analysis per tag recorded in the window, ordered by tag name",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.FactorAnalysis"
+                    }
+                },
+                "min_group_size": {
+                    "description": "Minimum nights per group for effect sizes and intervals",
+                    "type": "integer",
+                    "example": 3
+                },
+                "resamples": {
+                    "description": "Number of bootstrap resamples",
+                    "type": "integer",
+                    "example": 1000
+                },
+                "window": {
+                    "description": "Analysis window",
+                    "type": "object",
+                    "properties": {
+                        "from": {
+                            "type": "string",
+                            "example": "2024-01-01T00:00:00Z"
+                        },
+                        "to": {
+                            "type": "string",
+                            "example": "2024-03-31T23:59:59Z"
+                        }
+                    }
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.FactorRequest": {
+            "description": "Contextual factor, referencing a catalogue or user-defined tag by name.",
+            "type": "object",
+            "required": [
+                "tag"
+            ],
+            "properties": {
+                "amount": {
+                    "description": "Optional amount in the tag's unit",
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 200
+                },
+                "at": {
+                    "description": "Optional time of the factor in RFC3339 format",
+                    "type": "string",
+                    "example": "2024-01-15T16:00:00Z"
+                },
+                "tag": {
+                    "description": "Tag name",
+                    "type": "string",
+                    "maxLength": 50,
+                    "example": "caffeine"
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.FactorResponse": {
+            "description": "Contextual factor of a sleep log.",
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 200
+                },
+                "at": {
+                    "type": "string",
+                    "example": "2024-01-15T16:00:00Z"
+                },
+                "category": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.TagCategory"
+                        }
+                    ],
+                    "example": "SUBSTANCE"
+                },
+                "label": {
+                    "type": "string",
+                    "example": "Caffeine"
+                },
+                "tag": {
+                    "type": "string",
+                    "example": "caffeine"
+                },
+                "unit": {
+                    "type": "string",
+                    "example": "mg"
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.InsightsGenerator": {
+            "type": "object",
+            "properties": {
+                "model": {
+                    "type": "string",
+                    "example": "gpt-4o-mini"
+                },
+                "prompt_version": {
+                    "type": "string",
+                    "example": "3f2a9c1b7e4d"
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "openai"
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.InsightsResponse": {
+            "description": "Complete sleep insights response.",
+            "type": "object",
+            "properties": {
+                "chronotype": {
+                    "description": "Chronotype analysis",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.ChronotypeResult"
+                        }
+                    ]
+                },
+                "generated_at": {
+                    "description": "When the insights were generated; earlier than the request when served from the cache",
+                    "type": "string",
+                    "example": "2024-01-15T08:30:00Z"
+                },
+                "generator": {
+                    "description": "Provider, model and prompt version that wrote the insights; provider\n\"rules\" when the fixed rules stood in for an unavailable LLM",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.InsightsGenerator"
+                        }
+                    ]
+                },
+                "insights": {
+                    "description": "LLM-generated insights",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.LLMInsightsOutput"
+                        }
+                    ]
+                },
+                "metrics": {
+                    "description": "Metrics for different time windows",
+                    "type": "object",
+                    "properties": {
+                        "history": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.WindowMetrics"
+                        },
+                        "last_night": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.WindowMetrics"
+                        },
+                        "recent": {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.WindowMetrics"
+                        }
+                    }
+                },
+                "trace_id": {
+                    "description": "Trace ID for feedback (optional, only present when Langfuse is enabled)",
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.LLMInsightsOutput": {
+            "description": "LLM-generated sleep insights.",
+            "type": "object",
+            "required": [
+                "guidance",
+                "observations",
+                "summary"
+            ],
+            "properties": {
+                "guidance": {
+                    "description": "Actionable guidance (3-5 items)",
+                    "type": "array",
+                    "maxItems": 5,
+                    "minItems": 3,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "[\"Try to maintain your current bedtime of around 11 PM\"]"
+                    ]
+                },
+                "observations": {
+                    "description": "Observations about patterns (3-6 items)",
+                    "type": "array",
+                    "maxItems": 6,
+                    "minItems": 3,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "[\"Average duration of 7.2 hours meets recommended guidelines\"]"
+                    ]
+                },
+                "summary": {
+                    "description": "Summary of sleep patterns (2-3 sentences)",
+                    "type": "string",
+                    "example": "Your sleep has been fairly consistent this week..."
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.MeasureAnomaly": {
+            "description": "Measure that deviates from the baseline, with its robust z-score.",
+            "type": "object",
+            "properties": {
+                "baseline_median": {
+                    "description": "Median of the baseline nights, in the same unit",
+                    "type": "number",
+                    "example": 7.5
+                },
+                "measure": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.AnomalyMeasure"
+                        }
+                    ],
+                    "example": "duration"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "Slept 4.5 h, 3 h less than the usual 7.5 h on weekday nights"
+                },
+                "robust_z": {
+                    "description": "Robust z-score based on the median absolute deviation",
+                    "type": "number",
+                    "example": -4.2
+                },
+                "severity": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.AnomalySeverity"
+                        }
+                    ],
+                    "example": "MILD"
+                },
+                "value": {
+                    "description": "Value of the night (hours, minutes after midnight or quality score)",
+                    "type": "number",
+                    "example": 4.5
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.MetricsComparison": {
+            "description": "Metrics of the previous period and the change since.",
+            "type": "object",
+            "properties": {
+                "deltas": {
+                    "description": "Current minus previous values",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.MetricsDeltas"
+                        }
+                    ]
+                },
+                "metrics": {
+                    "description": "Metrics of the previous period",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.WindowMetrics"
+                        }
+                    ]
+                },
+                "period": {
+                    "description": "Previous period",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.MetricsPeriod"
+                        }
+                    ]
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.MetricsDeltas": {
+            "description": "Current minus previous period values.",
+            "type": "object",
+            "properties": {
+                "avg_duration_hours": {
+                    "type": "number",
+                    "example": 0.35
+                },
+                "avg_quality": {
+                    "type": "number",
+                    "example": 0.5
+                },
+                "avg_total_daily_hours": {
+                    "type": "number",
+                    "example": 0.4
+                },
+                "bedtime_std_minutes": {
+                    "type": "number",
+                    "example": -12.4
+                },
+                "consistency_score": {
+                    "type": "number",
+                    "example": 10.3
+                },
+                "daily_sufficiency_score": {
+                    "type": "number",
+                    "example": 14.3
+                },
+                "overall_sleep_score": {
+                    "type": "number",
+                    "example": 6.1
+                },
+                "sleep_count": {
+                    "type": "integer",
+                    "example": -1
+                },
+                "sleep_regularity_index": {
+                    "type": "number",
+                    "example": 4.2
+                },
+                "sufficiency_score": {
+                    "type": "number",
+                    "example": 8.8
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.MetricsPeriod": {
+            "description": "Period of a metrics window in the user's timezone.",
+            "type": "object",
+            "properties": {
+                "end_date": {
+                    "description": "Last local date (inclusive)",
+                    "type": "string",
+                    "example": "2024-01-21"
+                },
+                "start_date": {
+                    "description": "First local date",
+                    "type": "string",
+                    "example": "2024-01-15"
+                },
+                "timezone": {
+                    "description": "Timezone of the dates",
+                    "type": "string",
+                    "example": "Europe/Prague"
+                },
+                "type": {
+                    "description": "Period type",
+                    "enum": [
+                        "rolling",
+                        "week",
+                        "month",
+                        "custom"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.MetricsPeriodType"
+                        }
+                    ],
+                    "example": "week"
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.MetricsPeriodType": {
+            "description": "Metrics period: rolling, week, month or custom.",
+            "type": "string",
             "enum": [
-                "early_bird",
-                "intermediate",
-                "night_owl",
-                "unknown"
+                "rolling",
+                "week",
+                "month",
+                "custom"
             ],
             "x-enum-varnames": [
-                "ChronotypeEarlyBird",
-                "ChronotypeIntermediate",
-                "ChronotypeNightOwl",
-                "ChronotypeUnknown"
+                "MetricsPeriodRolling",
+                "MetricsPeriodWeek",
+                "MetricsPeriodMonth",
+                "MetricsPeriodCustom"
+            ]
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.MetricsResponse": {
+            "description": "Sleep metrics response with window statistics.",
+            "type": "object",
+            "properties": {
+                "daily_overall": {
+                    "description": "Daily overall metrics",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.DailyOverallMetrics"
+                        }
+                    ]
+                },
+                "factors": {
+                    "description": "Comparison of sleep with and without each recorded factor",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.FactorImpact"
+                    }
+                },
+                "per_sleep": {
+                    "description": "Per-sleep metrics",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.PerSleepMetrics"
+                        }
+                    ]
+                },
+                "period": {
+                    "description": "Period of the window in the user's timezone",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.MetricsPeriod"
+                        }
+                    ]
+                },
+                "previous": {
+                    "description": "Previous period with the change since, present in comparison mode",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.MetricsComparison"
+                        }
+                    ]
+                },
+                "regularity": {
+                    "description": "Sleep Regularity Index details",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepRegularity"
+                        }
+                    ]
+                },
+                "scores": {
+                    "description": "Derived scores",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.DerivedScores"
+                        }
+                    ]
+                },
+                "window": {
+                    "description": "Analysis window",
+                    "type": "object",
+                    "properties": {
+                        "from": {
+                            "type": "string",
+                            "example": "2024-01-01T00:00:00Z"
+                        },
+                        "to": {
+                            "type": "string",
+                            "example": "2024-01-31T23:59:59Z"
+                        }
+                    }
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.PaginationResponse": {
+            "description": "Cursor-based pagination info.",
+            "type": "object",
+            "properties": {
+                "has_more": {
+                    "description": "True if more results are available",
+                    "type": "boolean",
+                    "example": true
+                },
+                "next_cursor": {
+                    "description": "Cursor for fetching the next page (empty if no more pages)",
+                    "type": "string",
+                    "example": "eyJpZCI6IjU1MGU4NDAwLWUyOWItNDFkNC1hNzE2LTQ0NjY1NTQ0MDAwMCJ9"
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.PerSleepMetrics": {
+            "description": "Per-sleep metrics aggregated over a time window.",
+            "type": "object",
+            "properties": {
+                "bedtime": {
+                    "description": "Bedtime statistics in minutes after midnight (circular)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.DescriptiveStats"
+                        }
+                    ]
+                },
+                "duration": {
+                    "description": "Duration statistics in hours",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.DescriptiveStats"
+                        }
+                    ]
+                },
+                "mid_sleep": {
+                    "description": "Mid-sleep statistics in minutes after midnight (circular)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.DescriptiveStats"
+                        }
+                    ]
+                },
+                "quality": {
+                    "description": "Quality statistics (1-10 scale)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.DescriptiveStats"
+                        }
+                    ]
+                },
+                "sleep_count": {
+                    "description": "Number of sleep logs in this window",
+                    "type": "integer",
+                    "example": 28
+                },
+                "stages": {
+                    "description": "Stage-based statistics, present when any log in the window has stages",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.StageMetrics"
+                        }
+                    ]
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.RegularitySource": {
+            "description": "Regularity measure of the overall score: bedtime_std or sri.",
+            "type": "string",
+            "enum": [
+                "bedtime_std",
+                "sri"
+            ],
+            "x-enum-varnames": [
+                "RegularitySourceBedtimeStd",
+                "RegularitySourceSRI"
             ]
         },
-        "github_com_blaisecz_sleep-tracker_internal_domain.CreateSleepLogRequest": {
-            "description": "Request payload for recording a sleep session.",
+        "github_com_blaisecz_sleep-tracker_internal_domain.ScoreComparisonRequest": {
+            "description": "Two scoring models to apply to the same window.",
+            "type": "object",
+            "properties": {
+                "baseline": {
+                    "description": "Model to compare against; the active model when omitted",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.ScoringModelConfig"
+                        }
+                    ]
+                },
+                "candidate": {
+                    "description": "Model to try",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.ScoringModelConfig"
+                        }
+                    ]
+                },
+                "window_days": {
+                    "description": "Number of days to score",
+                    "type": "integer",
+                    "maximum": 365,
+                    "minimum": 1,
+                    "example": 30
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.ScoreComparisonResponse": {
+            "description": "Derived scores of two models over the same window.",
+            "type": "object",
+            "properties": {
+                "baseline": {
+                    "description": "Scores of the baseline model",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.DerivedScores"
+                        }
+                    ]
+                },
+                "candidate": {
+                    "description": "Scores of the candidate model",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.DerivedScores"
+                        }
+                    ]
+                },
+                "overall_diff": {
+                    "description": "Candidate minus baseline overall sleep score",
+                    "type": "number",
+                    "example": -2.5
+                },
+                "window": {
+                    "type": "object",
+                    "properties": {
+                        "from": {
+                            "type": "string",
+                            "example": "2024-01-01T00:00:00Z"
+                        },
+                        "to": {
+                            "type": "string",
+                            "example": "2024-01-31T23:59:59Z"
+                        }
+                    }
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.ScoreCurve": {
+            "type": "object",
+            "properties": {
+                "best": {
+                    "type": "number"
+                },
+                "exponent": {
+                    "type": "number"
+                },
+                "worst": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.ScoringModelConfig": {
+            "description": "Weights, curves and bounds of a scoring model.",
+            "type": "object",
+            "properties": {
+                "consistency": {
+                    "description": "Consistency score from the bedtime std in minutes",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.ScoreCurve"
+                        }
+                    ]
+                },
+                "name": {
+                    "description": "Model name",
+                    "type": "string",
+                    "example": "default"
+                },
+                "overall_max": {
+                    "type": "number",
+                    "example": 100
+                },
+                "overall_min": {
+                    "description": "Bounds of the overall score",
+                    "type": "number",
+                    "example": 0
+                },
+                "regularity_source": {
+                    "description": "Measure behind the regularity component",
+                    "enum": [
+                        "bedtime_std",
+                        "sri"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.RegularitySource"
+                        }
+                    ],
+                    "example": "bedtime_std"
+                },
+                "sufficiency": {
+                    "description": "Sufficiency score from the average core sleep duration in hours relative to the daily target",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.ScoreCurve"
+                        }
+                    ]
+                },
+                "version": {
+                    "description": "Model version",
+                    "type": "string",
+                    "example": "1"
+                },
+                "weights": {
+                    "description": "Weights of the overall score; they must add up to 1",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.ScoringWeights"
+                        }
+                    ]
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.ScoringWeights": {
+            "type": "object",
+            "properties": {
+                "daily_sufficiency": {
+                    "type": "number",
+                    "example": 0.3
+                },
+                "regularity": {
+                    "type": "number",
+                    "example": 0.4
+                },
+                "sufficiency": {
+                    "type": "number",
+                    "example": 0.3
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.SleepAnomaly": {
+            "description": "Unusual night with the measures that triggered it.",
+            "type": "object",
+            "properties": {
+                "baseline_medians": {
+                    "description": "Medians of those baseline nights",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.AnomalyBaseline"
+                        }
+                    ]
+                },
+                "baseline_nights": {
+                    "description": "Number of baseline nights the night was compared with",
+                    "type": "integer",
+                    "example": 14
+                },
+                "date": {
+                    "description": "Local date of the sleep end time (YYYY-MM-DD)",
+                    "type": "string",
+                    "example": "2024-01-15"
+                },
+                "end_at": {
+                    "type": "string",
+                    "example": "2024-01-15T07:00:00Z"
+                },
+                "measures": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.MeasureAnomaly"
+                    }
+                },
+                "reason": {
+                    "description": "Reasons of all measures",
+                    "type": "string",
+                    "example": "Slept 4.5 h, 3 h less than the usual 7.5 h on weekday nights"
+                },
+                "seasonal": {
+                    "description": "False if there were too few nights of the same kind and all nights were used",
+                    "type": "boolean",
+                    "example": true
+                },
+                "severity": {
+                    "description": "Highest severity of the measures",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.AnomalySeverity"
+                        }
+                    ],
+                    "example": "MODERATE"
+                },
+                "sleep_log_id": {
+                    "type": "string",
+                    "example": "660e8400-e29b-41d4-a716-446655440001"
+                },
+                "start_at": {
+                    "type": "string",
+                    "example": "2024-01-15T02:30:00Z"
+                },
+                "weekend": {
+                    "description": "True for nights before a free day in the user's workday calendar",
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.SleepDebtPoint": {
+            "description": "Daily total sleep against the target with the running balance.",
+            "type": "object",
+            "properties": {
+                "balance_hours": {
+                    "description": "Running balance in hours after the day; negative values are debt",
+                    "type": "number",
+                    "example": -3.25
+                },
+                "date": {
+                    "description": "Local date (YYYY-MM-DD), by sleep end time",
+                    "type": "string",
+                    "example": "2024-01-15"
+                },
+                "missing": {
+                    "description": "True if no sleep counts towards the day",
+                    "type": "boolean",
+                    "example": false
+                },
+                "target_hours": {
+                    "description": "Target of the goal in force on the day",
+                    "type": "number",
+                    "example": 7.5
+                },
+                "total_hours": {
+                    "description": "Total sleep of the day (core + naps) in hours, null when missing",
+                    "type": "number",
+                    "example": 6.5
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.SleepDebtResponse": {
+            "description": "Daily running balance of total sleep minus the target.",
+            "type": "object",
+            "properties": {
+                "cap_hours": {
+                    "description": "Largest debt in hours the balance can reach",
+                    "type": "number",
+                    "example": 20
+                },
+                "current_debt_hours": {
+                    "description": "Sleep owed at the end of the series in hours",
+                    "type": "number",
+                    "example": 3.25
+                },
+                "decay": {
+                    "description": "Share of the balance forgiven each day (0-1)",
+                    "type": "number",
+                    "example": 0.1
+                },
+                "from": {
+                    "description": "First and last local date of the series",
+                    "type": "string",
+                    "example": "2023-12-17"
+                },
+                "points": {
+                    "description": "One point per day, oldest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepDebtPoint"
+                    }
+                },
+                "span_days": {
+                    "description": "Number of days in the series",
+                    "type": "integer",
+                    "example": 30
+                },
+                "timezone": {
+                    "description": "Timezone used for \"today\"",
+                    "type": "string",
+                    "example": "Europe/Prague"
+                },
+                "to": {
+                    "type": "string",
+                    "example": "2024-01-15"
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.SleepGoalHistoryResponse": {
+            "description": "All versions of the user's sleep goals, oldest first.",
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepGoalResponse"
+                    }
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.SleepGoalRequest": {
+            "description": "Sleep goals. Omitted optional goals are not tracked.",
             "type": "object",
             "required": [
-                "end_at",
-                "quality",
-                "start_at",
-                "type"
+                "target_hours"
             ],
+            "properties": {
+                "bedtime_window_end": {
+                    "description": "Latest targeted bedtime in local time (HH:MM); may be after midnight",
+                    "type": "string",
+                    "example": "23:30"
+                },
+                "bedtime_window_start": {
+                    "description": "Earliest targeted bedtime in local time (HH:MM), together with bedtime_window_end",
+                    "type": "string",
+                    "example": "22:30"
+                },
+                "nap_allowance_minutes": {
+                    "description": "Naps per day in minutes that fit the goal",
+                    "type": "integer",
+                    "maximum": 240,
+                    "minimum": 0,
+                    "example": 30
+                },
+                "target_hours": {
+                    "description": "Target total sleep per day in hours",
+                    "type": "number",
+                    "maximum": 12,
+                    "minimum": 4,
+                    "example": 8
+                },
+                "wake_time": {
+                    "description": "Targeted wake time in local time (HH:MM)",
+                    "type": "string",
+                    "example": "07:00"
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.SleepGoalResponse": {
+            "description": "A version of the user's sleep goals.",
+            "type": "object",
+            "properties": {
+                "bedtime_window_end": {
+                    "description": "Latest targeted bedtime in local time",
+                    "type": "string",
+                    "example": "23:30"
+                },
+                "bedtime_window_start": {
+                    "description": "Earliest targeted bedtime in local time",
+                    "type": "string",
+                    "example": "22:30"
+                },
+                "effective_from": {
+                    "description": "When the goal came into force (RFC3339)",
+                    "type": "string",
+                    "example": "2024-01-15T10:30:00Z"
+                },
+                "effective_to": {
+                    "description": "When the goal was replaced or removed, absent while in force",
+                    "type": "string",
+                    "example": "2024-02-01T08:00:00Z"
+                },
+                "nap_allowance_minutes": {
+                    "description": "Naps per day in minutes that fit the goal",
+                    "type": "integer",
+                    "example": 30
+                },
+                "target_hours": {
+                    "description": "Target total sleep per day in hours",
+                    "type": "number",
+                    "example": 8
+                },
+                "wake_time": {
+                    "description": "Targeted wake time in local time",
+                    "type": "string",
+                    "example": "07:00"
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.SleepLogListResponse": {
+            "description": "Paginated list of sleep logs.",
+            "type": "object",
+            "properties": {
+                "data": {
+                    "description": "Array of sleep log records",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepLogResponse"
+                    }
+                },
+                "pagination": {
+                    "description": "Pagination metadata",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.PaginationResponse"
+                        }
+                    ]
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.SleepLogResponse": {
+            "description": "Sleep session record with UTC and local times.",
+            "type": "object",
             "properties": {
                 "client_request_id": {
-                    "description": "Optional client-generated ID for idempotent requests (max 255 chars)",
+                    "description": "Client-provided request ID (if any)",
                     "type": "string",
-                    "maxLength": 255,
                     "example": "client-uuid-12345"
                 },
+                "created_at": {
+                    "description": "Record creation timestamp",
+                    "type": "string",
+                    "example": "2024-01-16T07:05:00Z"
+                },
                 "end_at": {
-                    "description": "Sleep end time in RFC3339 format (must be after start_at)",
+                    "description": "Sleep end time (UTC)",
                     "type": "string",
                     "example": "2024-01-16T07:00:00Z"
                 },
+                "factors": {
+                    "description": "Contextual factors, if recorded",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.FactorResponse"
+                    }
+                },
+                "id": {
+                    "description": "Unique sleep log identifier",
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "local_end_at": {
+                    "description": "Sleep end in local timezone",
+                    "type": "string",
+                    "example": "2024-01-16T08:00:00+01:00"
+                },
+                "local_start_at": {
+                    "description": "Sleep start in local timezone",
+                    "type": "string",
+                    "example": "2024-01-16T00:00:00+01:00"
+                },
                 "local_timezone": {
-                    "description": "Optional IANA timezone for local time display (defaults to user's timezone)",
+                    "description": "Timezone used for local times",
                     "type": "string",
                     "example": "Europe/Prague"
                 },
                 "quality": {
-                    "description": "Sleep quality rating from 1 (poor) to 10 (excellent)",
+                    "description": "Sleep quality (1-10)",
                     "type": "integer",
-                    "maximum": 10,
-                    "minimum": 1,
                     "example": 7
                 },
+                "stages": {
+                    "description": "Sleep stage segments, if recorded",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepStageResponse"
+                    }
+                },
                 "start_at": {
-                    "description": "Sleep start time in RFC3339 format (UTC recommended)",
+                    "description": "Sleep start time (UTC)",
                     "type": "string",
                     "example": "2024-01-15T23:00:00Z"
                 },
                 "type": {
-                    "description": "Sleep type: CORE (main sleep) or NAP (daytime nap)",
-                    "enum": [
-                        "CORE",
-                        "NAP"
-                    ],
+                    "description": "Sleep type",
                     "allOf": [
                         {
                             "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepType"
                         }
                     ],
                     "example": "CORE"
-                }
-            }
-        },
-        "github_com_blaisecz_sleep-tracker_internal_domain.CreateUserRequest": {
-            "description": "Request payload for creating a new user account.",
-            "type": "object",
-            "required": [
-                "timezone"
-            ],
-            "properties": {
-                "timezone": {
-                    "description": "IANA timezone identifier (e.g., \"America/New_York\", \"Europe/London\", \"UTC\").\nSee: https://en.wikipedia.org/wiki/List_of_tz_database_time_zones",
-                    "type": "string",
-                    "example": "Europe/Prague"
-                }
-            }
-        },
-        "github_com_blaisecz_sleep-tracker_internal_domain.DailyOverallMetrics": {
-            "description": "Daily total sleep metrics (core + naps combined).",
-            "type": "object",
-            "properties": {
-                "daily_sufficiency_score": {
-                    "description": "Percentage of days meeting target (0-100)",
-                    "type": "number",
-                    "example": 73.3
                 },
-                "days_count": {
-                    "description": "Number of days with sleep data",
-                    "type": "integer",
-                    "example": 30
+                "user_id": {
+                    "description": "Owner user ID",
+                    "type": "string",
+                    "example": "660e8400-e29b-41d4-a716-446655440001"
                 },
-                "days_meeting_target": {
-                    "description": "Number of days meeting the target",
+                "version": {
+                    "description": "Record version, incremented on every update (also returned as the ETag header)",
                     "type": "integer",
-                    "example": 22
-                },
-                "target_hours": {
-                    "description": "Target hours for sufficiency calculation",
-                    "type": "number",
-                    "example": 7
-                },
-                "total_daily_hours": {
-                    "description": "Total daily sleep hours statistics",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.DescriptiveStats"
-                        }
-                    ]
-                }
-            }
-        },
-        "github_com_blaisecz_sleep-tracker_internal_domain.DerivedScores": {
-            "description": "Derived scores based on sleep metrics.",
-            "type": "object",
-            "properties": {
-                "consistency_score": {
-                    "description": "Consistency score based on bedtime variability (0-100)",
-                    "type": "number",
-                    "example": 75
-                },
-                "overall_sleep_score": {
-                    "description": "Overall sleep score combining factors (0-100)",
-                    "type": "number",
-                    "example": 77.5
-                },
-                "sufficiency_score": {
-                    "description": "Sufficiency score based on duration meeting targets (0-100)",
-                    "type": "number",
-                    "example": 80
+                    "example": 1
                 }
             }
         },
-        "github_com_blaisecz_sleep-tracker_internal_domain.DescriptiveStats": {
-            "description": "Basic statistical measures for a metric.",
+        "github_com_blaisecz_sleep-tracker_internal_domain.SleepRegularity": {
+            "description": "Sleep Regularity Index: the chance of being in the same sleep/wake state 24 hours apart, scaled to -100..100.",
             "type": "object",
             "properties": {
-                "avg": {
-                    "type": "number",
-                    "example": 7.2
+                "day_pairs": {
+                    "description": "Number of consecutive recorded day pairs compared",
+                    "type": "integer",
+                    "example": 26
                 },
-                "max": {
-                    "type": "number",
-                    "example": 9
+                "days_recorded": {
+                    "description": "Number of days in the window with logs ending on them and on the next day",
+                    "type": "integer",
+                    "example": 28
                 },
-                "min": {
+                "sri": {
+                    "description": "Sleep Regularity Index (-100 to 100), absent without enough consecutive days",
                     "type": "number",
-                    "example": 5.5
+                    "example": 82.4
                 },
-                "std": {
-                    "type": "number",
-                    "example": 0.8
+                "timezone": {
+                    "description": "Timezone of the minute-level sleep/wake series",
+                    "type": "string",
+                    "example": "Europe/Prague"
                 }
             }
         },
-        "github_com_blaisecz_sleep-tracker_internal_domain.InsightsResponse": {
-            "description": "Complete sleep insights response.",
+        "github_com_blaisecz_sleep-tracker_internal_domain.SleepStageRequest": {
+            "description": "Sleep stage segment. Segments must be contiguous and lie within the sleep log.",
             "type": "object",
+            "required": [
+                "end_at",
+                "stage",
+                "start_at"
+            ],
             "properties": {
-                "chronotype": {
-                    "description": "Chronotype analysis",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.ChronotypeResult"
-                        }
-                    ]
+                "end_at": {
+                    "description": "Segment end time in RFC3339 format (must be after start_at)",
+                    "type": "string",
+                    "example": "2024-01-16T02:30:00Z"
                 },
-                "insights": {
-                    "description": "LLM-generated insights",
+                "stage": {
+                    "description": "Stage type",
+                    "enum": [
+                        "AWAKE",
+                        "LIGHT",
+                        "DEEP",
+                        "REM"
+                    ],
                     "allOf": [
                         {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.LLMInsightsOutput"
-                        }
-                    ]
-                },
-                "metrics": {
-                    "description": "Metrics for different time windows",
-                    "type": "object",
-                    "properties": {
-                        "history": {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.WindowMetrics"
-                        },
-                        "last_night": {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.WindowMetrics"
-                        },
-                        "recent": {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.WindowMetrics"
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepStageType"
                         }
-                    }
+                    ],
+                    "example": "DEEP"
                 },
-                "trace_id": {
-                    "description": "Trace ID for feedback (optional, only present when Langfuse is enabled)",
+                "start_at": {
+                    "description": "Segment start time in RFC3339 format",
                     "type": "string",
-                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                    "example": "2024-01-16T01:00:00Z"
                 }
             }
         },
-        "github_com_blaisecz_sleep-tracker_internal_domain.LLMInsightsOutput": {
-            "description": "LLM-generated sleep insights.",
+        "github_com_blaisecz_sleep-tracker_internal_domain.SleepStageResponse": {
+            "description": "Sleep stage segment (UTC).",
             "type": "object",
             "properties": {
-                "guidance": {
-                    "description": "Actionable guidance (3-5 items)",
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    },
-                    "example": [
-                        "[\"Try to maintain your current bedtime of around 11 PM\"]"
-                    ]
-                },
-                "observations": {
-                    "description": "Observations about patterns (3-6 items)",
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    },
-                    "example": [
-                        "[\"Average duration of 7.2 hours meets recommended guidelines\"]"
-                    ]
-                },
-                "summary": {
-                    "description": "Summary of sleep patterns (2-3 sentences)",
+                "end_at": {
                     "type": "string",
-                    "example": "Your sleep has been fairly consistent this week..."
-                }
-            }
-        },
-        "github_com_blaisecz_sleep-tracker_internal_domain.MetricsResponse": {
-            "description": "Sleep metrics response with window statistics.",
-            "type": "object",
-            "properties": {
-                "daily_overall": {
-                    "description": "Daily overall metrics",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.DailyOverallMetrics"
-                        }
-                    ]
-                },
-                "per_sleep": {
-                    "description": "Per-sleep metrics",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.PerSleepMetrics"
-                        }
-                    ]
+                    "example": "2024-01-16T02:30:00Z"
                 },
-                "scores": {
-                    "description": "Derived scores",
+                "stage": {
                     "allOf": [
                         {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.DerivedScores"
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepStageType"
                         }
-                    ]
+                    ],
+                    "example": "DEEP"
                 },
-                "window": {
-                    "description": "Analysis window",
-                    "type": "object",
-                    "properties": {
-                        "from": {
-                            "type": "string",
-                            "example": "2024-01-01T00:00:00Z"
-                        },
-                        "to": {
-                            "type": "string",
-                            "example": "2024-01-31T23:59:59Z"
-                        }
-                    }
+                "start_at": {
+                    "type": "string",
+                    "example": "2024-01-16T01:00:00Z"
                 }
             }
         },
-        "github_com_blaisecz_sleep-tracker_internal_domain.PaginationResponse": {
-            "description": "Cursor-based pagination info.",
+        "github_com_blaisecz_sleep-tracker_internal_domain.SleepStageType": {
+            "description": "Sleep stage: AWAKE, LIGHT, DEEP or REM.",
+            "type": "string",
+            "enum": [
+                "AWAKE",
+                "LIGHT",
+                "DEEP",
+                "REM"
+            ],
+            "x-enum-varnames": [
+                "SleepStageAwake",
+                "SleepStageLight",
+                "SleepStageDeep",
+                "SleepStageREM"
+            ]
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.SleepType": {
+            "description": "Type of sleep: CORE for main night sleep, NAP for daytime naps.",
+            "type": "string",
+            "enum": [
+                "CORE",
+                "NAP"
+            ],
+            "x-enum-varnames": [
+                "SleepTypeCore",
+                "SleepTypeNap"
+            ]
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.SocialJetlag": {
+            "description": "Workday and free-day mid-sleep, sleep-debt-corrected mid-sleep on free days (MSFsc) and social jetlag.",
             "type": "object",
             "properties": {
-                "has_more": {
-                    "description": "True if more results are available",
-                    "type": "boolean",
-                    "example": true
+                "chronotype": {
+                    "description": "Chronotype classified from MSFsc",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.ChronotypeType"
+                        }
+                    ],
+                    "example": "intermediate"
                 },
-                "next_cursor": {
-                    "description": "Cursor for fetching the next page (empty if no more pages)",
+                "free_day_mid_sleep_local_time": {
+                    "description": "Median mid-sleep before free days (MSF)",
                     "type": "string",
-                    "example": "eyJpZCI6IjU1MGU4NDAwLWUyOWItNDFkNC1hNzE2LTQ0NjY1NTQ0MDAwMCJ9"
+                    "example": "04:45"
+                },
+                "free_day_mid_sleep_minutes_after_midnight": {
+                    "type": "integer",
+                    "example": 285
+                },
+                "free_day_nights": {
+                    "type": "integer",
+                    "example": 8
+                },
+                "free_day_sleep_hours": {
+                    "type": "number",
+                    "example": 8.4
+                },
+                "msfsc_local_time": {
+                    "description": "MSF corrected for the sleep debt accumulated on workdays (MSFsc)",
+                    "type": "string",
+                    "example": "04:19"
+                },
+                "msfsc_minutes_after_midnight": {
+                    "type": "integer",
+                    "example": 259
+                },
+                "social_jetlag_minutes": {
+                    "description": "Absolute difference between MSF and MSW",
+                    "type": "integer",
+                    "example": 90
+                },
+                "workday_mid_sleep_local_time": {
+                    "description": "Median mid-sleep before workdays (MSW)",
+                    "type": "string",
+                    "example": "03:15"
+                },
+                "workday_mid_sleep_minutes_after_midnight": {
+                    "type": "integer",
+                    "example": 195
+                },
+                "workday_nights": {
+                    "description": "Number of nights before workdays and before free days",
+                    "type": "integer",
+                    "example": 20
+                },
+                "workday_sleep_hours": {
+                    "description": "Average sleep duration before workdays and before free days in hours",
+                    "type": "number",
+                    "example": 6.8
+                },
+                "workdays": {
+                    "description": "Workday calendar used to split the nights",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "MON",
+                        "TUE",
+                        "WED",
+                        "THU",
+                        "FRI"
+                    ]
                 }
             }
         },
-        "github_com_blaisecz_sleep-tracker_internal_domain.PerSleepMetrics": {
-            "description": "Per-sleep metrics aggregated over a time window.",
+        "github_com_blaisecz_sleep-tracker_internal_domain.StageMetrics": {
+            "description": "Per-sleep stage metrics over the logs that have stage data.",
             "type": "object",
             "properties": {
-                "bedtime": {
-                    "description": "Bedtime statistics in minutes after midnight",
+                "deep_percent": {
+                    "description": "Deep sleep as a percentage of total sleep time",
                     "allOf": [
                         {
                             "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.DescriptiveStats"
                         }
                     ]
                 },
-                "duration": {
-                    "description": "Duration statistics in hours",
+                "efficiency_percent": {
+                    "description": "Total sleep time as a percentage of time in bed",
                     "allOf": [
                         {
                             "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.DescriptiveStats"
                         }
                     ]
                 },
-                "quality": {
-                    "description": "Quality statistics (1-10 scale)",
+                "rem_percent": {
+                    "description": "REM sleep as a percentage of total sleep time",
                     "allOf": [
                         {
                             "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.DescriptiveStats"
@@ -922,110 +4364,187 @@ const docTemplate = `{
                     ]
                 },
                 "sleep_count": {
-                    "description": "Number of sleep logs in this window",
+                    "description": "Number of sleep logs with stage data",
                     "type": "integer",
-                    "example": 28
+                    "example": 21
+                },
+                "waso_minutes": {
+                    "description": "Wake after sleep onset in minutes",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.DescriptiveStats"
+                        }
+                    ]
                 }
             }
         },
-        "github_com_blaisecz_sleep-tracker_internal_domain.SleepLogListResponse": {
-            "description": "Paginated list of sleep logs.",
+        "github_com_blaisecz_sleep-tracker_internal_domain.TagCategory": {
+            "description": "Factor category: SUBSTANCE, ACTIVITY, HEALTH, ENVIRONMENT or OTHER.",
+            "type": "string",
+            "enum": [
+                "SUBSTANCE",
+                "ACTIVITY",
+                "HEALTH",
+                "ENVIRONMENT",
+                "OTHER"
+            ],
+            "x-enum-varnames": [
+                "TagCategorySubstance",
+                "TagCategoryActivity",
+                "TagCategoryHealth",
+                "TagCategoryEnvironment",
+                "TagCategoryOther"
+            ]
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.TagListResponse": {
+            "description": "Catalogue tags followed by the user's own tags.",
             "type": "object",
             "properties": {
                 "data": {
-                    "description": "Array of sleep log records",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepLogResponse"
+                        "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.TagResponse"
                     }
-                },
-                "pagination": {
-                    "description": "Pagination metadata",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.PaginationResponse"
-                        }
-                    ]
                 }
             }
         },
-        "github_com_blaisecz_sleep-tracker_internal_domain.SleepLogResponse": {
-            "description": "Sleep session record with UTC and local times.",
+        "github_com_blaisecz_sleep-tracker_internal_domain.TagResponse": {
+            "description": "Factor tag from the catalogue or defined by the user.",
             "type": "object",
             "properties": {
-                "client_request_id": {
-                    "description": "Client-provided request ID (if any)",
-                    "type": "string",
-                    "example": "client-uuid-12345"
+                "category": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.TagCategory"
+                        }
+                    ],
+                    "example": "SUBSTANCE"
                 },
-                "created_at": {
-                    "description": "Record creation timestamp",
+                "id": {
                     "type": "string",
-                    "example": "2024-01-16T07:05:00Z"
+                    "example": "770e8400-e29b-41d4-a716-446655440002"
                 },
-                "end_at": {
-                    "description": "Sleep end time (UTC)",
+                "label": {
                     "type": "string",
-                    "example": "2024-01-16T07:00:00Z"
+                    "example": "Caffeine"
                 },
-                "id": {
-                    "description": "Unique sleep log identifier",
+                "name": {
                     "type": "string",
-                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                    "example": "caffeine"
                 },
-                "local_end_at": {
-                    "description": "Sleep end in local timezone",
-                    "type": "string",
-                    "example": "2024-01-16T08:00:00+01:00"
+                "predefined": {
+                    "description": "True for catalogue tags shared by all users",
+                    "type": "boolean",
+                    "example": true
                 },
-                "local_start_at": {
-                    "description": "Sleep start in local timezone",
+                "unit": {
                     "type": "string",
-                    "example": "2024-01-16T00:00:00+01:00"
-                },
-                "local_timezone": {
-                    "description": "Timezone used for local times",
+                    "example": "mg"
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.TrendMetric": {
+            "description": "Trend measure: duration, quality, bedtime or total_daily.",
+            "type": "string",
+            "enum": [
+                "duration",
+                "quality",
+                "bedtime",
+                "total_daily"
+            ],
+            "x-enum-varnames": [
+                "TrendMetricDuration",
+                "TrendMetricQuality",
+                "TrendMetricBedtime",
+                "TrendMetricTotalDaily"
+            ]
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.TrendPoint": {
+            "description": "Daily value with moving averages. Averages skip missing days and are null until a value is available.",
+            "type": "object",
+            "properties": {
+                "date": {
+                    "description": "Local date (YYYY-MM-DD), by sleep end time",
                     "type": "string",
-                    "example": "Europe/Prague"
+                    "example": "2024-01-15"
                 },
-                "quality": {
-                    "description": "Sleep quality (1-10)",
-                    "type": "integer",
-                    "example": 7
+                "ewma": {
+                    "description": "Exponentially weighted moving average with the span of the requested window",
+                    "type": "number",
+                    "example": 7.35
                 },
-                "start_at": {
-                    "description": "Sleep start time (UTC)",
+                "missing": {
+                    "description": "True if no sleep counts towards the day",
+                    "type": "boolean",
+                    "example": false
+                },
+                "sma": {
+                    "description": "Simple moving average over the requested window",
+                    "type": "number",
+                    "example": 7.3
+                },
+                "sma_30": {
+                    "description": "Simple moving average over 30 days",
+                    "type": "number",
+                    "example": 7.1
+                },
+                "value": {
+                    "description": "Value of the day, null when missing",
+                    "type": "number",
+                    "example": 7.5
+                }
+            }
+        },
+        "github_com_blaisecz_sleep-tracker_internal_domain.TrendsResponse": {
+            "description": "Per-day time series of a sleep measure with moving averages.",
+            "type": "object",
+            "properties": {
+                "from": {
+                    "description": "First and last local date of the series",
                     "type": "string",
-                    "example": "2024-01-15T23:00:00Z"
+                    "example": "2023-10-18"
                 },
-                "type": {
-                    "description": "Sleep type",
+                "metric": {
                     "allOf": [
                         {
-                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepType"
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.TrendMetric"
                         }
                     ],
-                    "example": "CORE"
+                    "example": "duration"
                 },
-                "user_id": {
-                    "description": "Owner user ID",
+                "points": {
+                    "description": "One point per day, oldest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.TrendPoint"
+                    }
+                },
+                "span_days": {
+                    "description": "Number of days in the series",
+                    "type": "integer",
+                    "example": 90
+                },
+                "timezone": {
+                    "description": "Timezone used for \"today\"",
                     "type": "string",
-                    "example": "660e8400-e29b-41d4-a716-446655440001"
+                    "example": "Europe/Prague"
+                },
+                "to": {
+                    "type": "string",
+                    "example": "2024-01-15"
+                },
+                "unit": {
+                    "description": "Unit of the values",
+                    "type": "string",
+                    "example": "hours"
+                },
+                "window": {
+                    "description": "Short moving average window in days",
+                    "type": "integer",
+                    "example": 7
                 }
             }
         },
-        "github_com_blaisecz_sleep-tracker_internal_domain.SleepType": {
-            "description": "Type of sleep: CORE for main night sleep, NAP for daytime naps.",
-            "type": "string",
-            "enum": [
-                "CORE",
-                "NAP"
-            ],
-            "x-enum-varnames": [
-                "SleepTypeCore",
-                "SleepTypeNap"
-            ]
-        },
         "github_com_blaisecz_sleep-tracker_internal_domain.UpdateSleepLogRequest": {
             "description": "Request payload for updating a sleep session. All fields are optional.",
             "type": "object",
@@ -1035,6 +4554,14 @@ const docTemplate = `{
                     "type": "string",
                     "example": "2024-01-16T07:00:00Z"
                 },
+                "factors": {
+                    "description": "Contextual factors; replaces all stored factors when present, [] removes them",
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.FactorRequest"
+                    }
+                },
                 "local_timezone": {
                     "description": "Optional IANA timezone for local time display",
                     "type": "string",
@@ -1047,6 +4574,14 @@ const docTemplate = `{
                     "minimum": 1,
                     "example": 7
                 },
+                "stages": {
+                    "description": "Sleep stage segments; replaces all stored stages when present, [] removes them",
+                    "type": "array",
+                    "maxItems": 500,
+                    "items": {
+                        "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepStageRequest"
+                    }
+                },
                 "start_at": {
                     "description": "Sleep start time in RFC3339 format (UTC recommended)",
                     "type": "string",
@@ -1067,6 +4602,27 @@ const docTemplate = `{
                 }
             }
         },
+        "github_com_blaisecz_sleep-tracker_internal_domain.UpdateWorkdaysRequest": {
+            "description": "Days of the week the user works. An empty list makes every day free.",
+            "type": "object",
+            "properties": {
+                "workdays": {
+                    "type": "array",
+                    "maxItems": 7,
+                    "uniqueItems": true,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "MON",
+                        "TUE",
+                        "WED",
+                        "THU",
+                        "FRI"
+                    ]
+                }
+            }
+        },
         "github_com_blaisecz_sleep-tracker_internal_domain.UserResponse": {
             "description": "User account details.",
             "type": "object",
@@ -1085,6 +4641,20 @@ const docTemplate = `{
                     "description": "User's preferred IANA timezone",
                     "type": "string",
                     "example": "Europe/Prague"
+                },
+                "workdays": {
+                    "description": "Days of the week the user works",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "MON",
+                        "TUE",
+                        "WED",
+                        "THU",
+                        "FRI"
+                    ]
                 }
             }
         },
@@ -1100,6 +4670,13 @@ const docTemplate = `{
                         }
                     ]
                 },
+                "factors": {
+                    "description": "Comparison of sleep with and without each recorded factor",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.FactorImpact"
+                    }
+                },
                 "from": {
                     "description": "Window start date",
                     "type": "string",
@@ -1113,6 +4690,14 @@ const docTemplate = `{
                         }
                     ]
                 },
+                "regularity": {
+                    "description": "Sleep Regularity Index details",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepRegularity"
+                        }
+                    ]
+                },
                 "scores": {
                     "description": "Derived scores",
                     "allOf": [
@@ -1162,6 +4747,75 @@ const docTemplate = `{
                 }
             }
         },
+        "internal_api_handler.BatchCreateSleepLogsResponse": {
+            "description": "Per-item results of a batch request, in request order.",
+            "type": "object",
+            "properties": {
+                "created": {
+                    "description": "Number of sleep logs created",
+                    "type": "integer",
+                    "example": 2
+                },
+                "existing": {
+                    "description": "Number of items answered with an existing log (idempotent duplicates)",
+                    "type": "integer",
+                    "example": 0
+                },
+                "failed": {
+                    "description": "Number of failed items",
+                    "type": "integer",
+                    "example": 1
+                },
+                "mode": {
+                    "description": "Commit mode that was applied",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.BatchMode"
+                        }
+                    ],
+                    "example": "atomic"
+                },
+                "results": {
+                    "description": "One result per request item",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_api_handler.BatchItemResponse"
+                    }
+                }
+            }
+        },
+        "internal_api_handler.BatchItemResponse": {
+            "description": "Per-item result: the created (or existing) sleep log, or a problem describing why the item failed.",
+            "type": "object",
+            "properties": {
+                "data": {
+                    "description": "Sleep log, for successful items",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_internal_domain.SleepLogResponse"
+                        }
+                    ]
+                },
+                "error": {
+                    "description": "Problem details, for failed items",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_blaisecz_sleep-tracker_pkg_problem.Problem"
+                        }
+                    ]
+                },
+                "index": {
+                    "description": "Position of the item in the request",
+                    "type": "integer",
+                    "example": 0
+                },
+                "status": {
+                    "description": "HTTP status the item would have received as a single request",
+                    "type": "integer",
+                    "example": 201
+                }
+            }
+        },
         "internal_api_handler.FeedbackRequest": {
             "description": "Request body for submitting feedback on insights.",
             "type": "object",
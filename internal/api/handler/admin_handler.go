@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/blaisecz/sleep-tracker/internal/api/validation"
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/service"
+	"github.com/blaisecz/sleep-tracker/pkg/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// AdminHandler handles admin endpoints.
+type AdminHandler struct {
+	metricsService service.MetricsService
+}
+
+// NewAdminHandler creates a new AdminHandler.
+func NewAdminHandler(metricsService service.MetricsService) *AdminHandler {
+	return &AdminHandler{metricsService: metricsService}
+}
+
+// CompareScoringModels handles POST /v1/admin/users/{userId}/sleep/scores/compare
+// @Summary Compare scoring models
+// @Description Score the same window of a user's sleep with two scoring models side by side. The baseline defaults to the active model. Requires the admin API key in the X-Admin-Key header.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param request body domain.ScoreComparisonRequest true "Models to compare"
+// @Success 200 {object} domain.ScoreComparisonResponse "Scores of both models"
+// @Failure 400 {object} problem.Problem "Invalid request"
+// @Failure 401 {object} problem.Problem "Missing or invalid admin API key"
+// @Failure 403 {object} problem.Problem "Admin API disabled"
+// @Failure 404 {object} problem.Problem "User not found"
+// @Failure 422 {object} problem.Problem "Validation error"
+// @Failure 500 {object} problem.Problem "Server error"
+// @Router /admin/users/{userId}/sleep/scores/compare [post]
+func (h *AdminHandler) CompareScoringModels(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		problem.BadRequest("Invalid user ID format").Write(w)
+		return
+	}
+
+	var req domain.ScoreComparisonRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		problem.BadRequest("Invalid JSON body").Write(w)
+		return
+	}
+
+	if fieldErrors := validation.Validate(req); fieldErrors != nil {
+		problem.ValidationError("Request body contains invalid fields", fieldErrors).Write(w)
+		return
+	}
+
+	var baseline service.ScoringModel
+	if req.Baseline != nil {
+		baseline, err = service.NewScoringModel(*req.Baseline)
+		if err != nil {
+			fieldProblem("baseline", err).Write(w)
+			return
+		}
+	}
+	candidate, err := service.NewScoringModel(req.Candidate)
+	if err != nil {
+		fieldProblem("candidate", err).Write(w)
+		return
+	}
+
+	result, err := h.metricsService.CompareScores(r.Context(), userID, req.WindowDays, baseline, candidate)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			problem.NotFound("User not found").Write(w)
+			return
+		}
+		problem.InternalError("Failed to compare scoring models").Write(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
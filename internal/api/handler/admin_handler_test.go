@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/blaisecz/sleep-tracker/internal/api/middleware"
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// mockCompareMetricsService records the models of CompareScores.
+type mockCompareMetricsService struct {
+	mockMetricsService
+	baseline, candidate service.ScoringModel
+	err                 error
+}
+
+func (m *mockCompareMetricsService) CompareScores(ctx context.Context, userID uuid.UUID, windowDays int, baseline, candidate service.ScoringModel) (*domain.ScoreComparisonResponse, error) {
+	m.baseline, m.candidate = baseline, candidate
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &domain.ScoreComparisonResponse{}, nil
+}
+
+func TestAdminHandler_CompareScoringModels(t *testing.T) {
+	userID := uuid.New()
+	candidate := `"candidate": {"name": "candidate", "version": "2", "regularity_source": "sri",
+		"weights": {"regularity": 0.5, "sufficiency": 0.25, "daily_sufficiency": 0.25},
+		"consistency": {"worst": 90, "best": 0, "exponent": 1},
+		"sufficiency": {"worst": -2, "best": 2, "exponent": 1},
+		"overall_min": 0, "overall_max": 100}`
+
+	tests := []struct {
+		name           string
+		apiKey         string
+		header         string
+		body           string
+		serviceErr     error
+		wantStatusCode int
+		wantBaseline   bool
+	}{
+		{
+			name:           "active model as baseline",
+			apiKey:         "secret",
+			header:         "secret",
+			body:           `{` + candidate + `}`,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:   "explicit baseline",
+			apiKey: "secret",
+			header: "secret",
+			body: `{"window_days": 14, ` + candidate + `, "baseline": {"name": "old", "version": "1", "regularity_source": "bedtime_std",
+				"weights": {"regularity": 0.4, "sufficiency": 0.3, "daily_sufficiency": 0.3},
+				"consistency": {"worst": 120, "best": 0, "exponent": 1},
+				"sufficiency": {"worst": -2, "best": 2, "exponent": 1},
+				"overall_min": 0, "overall_max": 100}}`,
+			wantStatusCode: http.StatusOK,
+			wantBaseline:   true,
+		},
+		{
+			name:           "invalid candidate",
+			apiKey:         "secret",
+			header:         "secret",
+			body:           `{"candidate": {"name": "empty", "version": "1"}}`,
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "window out of range",
+			apiKey:         "secret",
+			header:         "secret",
+			body:           `{"window_days": 400, ` + candidate + `}`,
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "user not found",
+			apiKey:         "secret",
+			header:         "secret",
+			body:           `{` + candidate + `}`,
+			serviceErr:     domain.ErrNotFound,
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "wrong key",
+			apiKey:         "secret",
+			header:         "guess",
+			body:           `{` + candidate + `}`,
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "admin API disabled",
+			body:           `{` + candidate + `}`,
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := &mockCompareMetricsService{err: tt.serviceErr}
+			handler := NewAdminHandler(metrics)
+
+			r := chi.NewRouter()
+			r.With(middleware.AdminKey(tt.apiKey)).Post("/admin/users/{userId}/sleep/scores/compare", handler.CompareScoringModels)
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/users/"+userID.String()+"/sleep/scores/compare", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.header != "" {
+				req.Header.Set(middleware.AdminKeyHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatusCode, w.Code, w.Body.String())
+			}
+			if tt.wantStatusCode != http.StatusOK {
+				return
+			}
+			if metrics.candidate == nil || metrics.candidate.Name() != "candidate" {
+				t.Errorf("unexpected candidate: %v", metrics.candidate)
+			}
+			if (metrics.baseline != nil) != tt.wantBaseline {
+				t.Errorf("baseline = %v, want set: %v", metrics.baseline, tt.wantBaseline)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/service"
+	"github.com/blaisecz/sleep-tracker/pkg/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// CohortHandler handles cohort analytics endpoints.
+type CohortHandler struct {
+	cohortService service.CohortService
+}
+
+// NewCohortHandler creates a new CohortHandler.
+func NewCohortHandler(cohortService service.CohortService) *CohortHandler {
+	return &CohortHandler{cohortService: cohortService}
+}
+
+// Metrics handles GET /v1/admin/cohorts/metrics
+// @Summary Get cohort metrics
+// @Description Distributions (percentiles) of per-user metrics over the users matching the filter that have sleep in the window. Cohorts below the minimum size are not reported. Cohorts are built from a bounded number of users and cached for a while, so the window is the one of the cached cohort. Requires the admin API key in the X-Admin-Key header.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param timezone query string false "IANA timezone of the users" example(Europe/Prague)
+// @Param chronotype query string false "Chronotype over the window" Enums(early_bird, intermediate, night_owl)
+// @Param tag query string false "Tag recorded in the window" example(caffeine)
+// @Param window_days query integer false "Number of days to analyze" default(30) minimum(1) maximum(365)
+// @Success 200 {object} domain.CohortMetricsResponse "Cohort distributions"
+// @Failure 400 {object} problem.Problem "Invalid query parameters"
+// @Failure 401 {object} problem.Problem "Missing or invalid admin API key"
+// @Failure 403 {object} problem.Problem "Admin API disabled"
+// @Failure 422 {object} problem.Problem "Cohort too small"
+// @Failure 500 {object} problem.Problem "Server error"
+// @Router /admin/cohorts/metrics [get]
+func (h *CohortHandler) Metrics(w http.ResponseWriter, r *http.Request) {
+	filter, windowDays, err := parseCohortParams(r)
+	if err != nil {
+		problem.BadRequest(err.Error()).Write(w)
+		return
+	}
+
+	result, err := h.cohortService.Metrics(r.Context(), filter, windowDays)
+	if err != nil {
+		if errors.Is(err, domain.ErrCohortTooSmall) {
+			cohortTooSmall(err).Write(w)
+			return
+		}
+		problem.InternalError("Failed to compute cohort metrics").Write(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// Ranks handles GET /v1/admin/users/{userId}/sleep/cohort-ranks
+// @Summary Get cohort percentile ranks
+// @Description Percentile ranks of the user's metrics within the cohort matching the filter: the percentage of the cohort with a lower value, counting ties as half. Cohorts below the minimum size are not reported. The cohort is shared with the metrics endpoint and cached for a while. Requires the admin API key in the X-Admin-Key header, since building a cohort computes the metrics of every user in it.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
+// @Param timezone query string false "IANA timezone of the users" example(Europe/Prague)
+// @Param chronotype query string false "Chronotype over the window" Enums(early_bird, intermediate, night_owl)
+// @Param tag query string false "Tag recorded in the window" example(caffeine)
+// @Param window_days query integer false "Number of days to analyze" default(30) minimum(1) maximum(365)
+// @Success 200 {object} domain.CohortRanksResponse "Percentile ranks"
+// @Failure 400 {object} problem.Problem "Invalid query parameters"
+// @Failure 401 {object} problem.Problem "Missing or invalid admin API key"
+// @Failure 403 {object} problem.Problem "Admin API disabled"
+// @Failure 404 {object} problem.Problem "User not found"
+// @Failure 422 {object} problem.Problem "Cohort too small"
+// @Failure 500 {object} problem.Problem "Server error"
+// @Router /admin/users/{userId}/sleep/cohort-ranks [get]
+func (h *CohortHandler) Ranks(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		problem.BadRequest("Invalid user ID format").Write(w)
+		return
+	}
+
+	filter, windowDays, err := parseCohortParams(r)
+	if err != nil {
+		problem.BadRequest(err.Error()).Write(w)
+		return
+	}
+
+	result, err := h.cohortService.Ranks(r.Context(), userID, filter, windowDays)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			problem.NotFound("User not found").Write(w)
+			return
+		}
+		if errors.Is(err, domain.ErrCohortTooSmall) {
+			cohortTooSmall(err).Write(w)
+			return
+		}
+		problem.InternalError("Failed to compute cohort ranks").Write(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// parseCohortParams parses and validates the cohort filter and window.
+func parseCohortParams(r *http.Request) (domain.CohortFilter, int, error) {
+	query := r.URL.Query()
+	filter := domain.CohortFilter{
+		Timezone:   query.Get("timezone"),
+		Chronotype: domain.ChronotypeType(query.Get("chronotype")),
+		Tag:        query.Get("tag"),
+	}
+
+	if filter.Timezone != "" {
+		if _, err := time.LoadLocation(filter.Timezone); err != nil {
+			return filter, 0, errors.New("timezone must be a valid IANA timezone")
+		}
+	}
+	switch filter.Chronotype {
+	case "", domain.ChronotypeEarlyBird, domain.ChronotypeIntermediate, domain.ChronotypeNightOwl:
+	default:
+		return filter, 0, errors.New("chronotype must be one of early_bird, intermediate, night_owl")
+	}
+
+	windowDays, err := parseIntParam(r, "window_days", service.DefaultCohortWindowDays)
+	if err != nil {
+		return filter, 0, err
+	}
+	if windowDays < 1 || windowDays > 365 {
+		return filter, 0, errors.New("window_days must be between 1 and 365")
+	}
+	return filter, windowDays, nil
+}
+
+func cohortTooSmall(err error) *problem.Problem {
+	return problem.New(http.StatusUnprocessableEntity, "cohort-too-small", "Cohort Too Small", err.Error())
+}
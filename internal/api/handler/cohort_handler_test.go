@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/blaisecz/sleep-tracker/internal/api/middleware"
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type mockCohortService struct {
+	filter     domain.CohortFilter
+	windowDays int
+	err        error
+}
+
+func (m *mockCohortService) Metrics(ctx context.Context, filter domain.CohortFilter, windowDays int) (*domain.CohortMetricsResponse, error) {
+	m.filter, m.windowDays = filter, windowDays
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &domain.CohortMetricsResponse{Filter: filter}, nil
+}
+
+func (m *mockCohortService) Ranks(ctx context.Context, userID uuid.UUID, filter domain.CohortFilter, windowDays int) (*domain.CohortRanksResponse, error) {
+	m.filter, m.windowDays = filter, windowDays
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &domain.CohortRanksResponse{Filter: filter}, nil
+}
+
+func TestCohortHandler_Metrics(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		apiKey         string
+		serviceErr     error
+		wantStatusCode int
+		wantFilter     domain.CohortFilter
+		wantWindowDays int
+	}{
+		{
+			name:           "defaults",
+			apiKey:         "secret",
+			wantStatusCode: http.StatusOK,
+			wantWindowDays: 30,
+		},
+		{
+			name:           "all filters",
+			query:          "?timezone=Europe/Prague&chronotype=night_owl&tag=caffeine&window_days=60",
+			apiKey:         "secret",
+			wantStatusCode: http.StatusOK,
+			wantFilter:     domain.CohortFilter{Timezone: "Europe/Prague", Chronotype: domain.ChronotypeNightOwl, Tag: "caffeine"},
+			wantWindowDays: 60,
+		},
+		{
+			name:           "invalid timezone",
+			query:          "?timezone=Mars/Olympus",
+			apiKey:         "secret",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid chronotype",
+			query:          "?chronotype=unknown",
+			apiKey:         "secret",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "window out of range",
+			query:          "?window_days=400",
+			apiKey:         "secret",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "cohort too small",
+			apiKey:         "secret",
+			serviceErr:     fmt.Errorf("%w: at least 10 users", domain.ErrCohortTooSmall),
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "admin API disabled",
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cohorts := &mockCohortService{err: tt.serviceErr}
+			handler := NewCohortHandler(cohorts)
+
+			r := chi.NewRouter()
+			r.With(middleware.AdminKey(tt.apiKey)).Get("/admin/cohorts/metrics", handler.Metrics)
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/cohorts/metrics"+tt.query, nil)
+			req.Header.Set(middleware.AdminKeyHeader, "secret")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatusCode, w.Code, w.Body.String())
+			}
+			if tt.wantStatusCode != http.StatusOK {
+				return
+			}
+			if cohorts.filter != tt.wantFilter || cohorts.windowDays != tt.wantWindowDays {
+				t.Errorf("got filter %+v over %d days, want %+v over %d days", cohorts.filter, cohorts.windowDays, tt.wantFilter, tt.wantWindowDays)
+			}
+		})
+	}
+}
+
+func TestCohortHandler_Ranks(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         string
+		query          string
+		serviceErr     error
+		wantStatusCode int
+	}{
+		{
+			name:           "success",
+			userID:         uuid.New().String(),
+			query:          "?tag=caffeine",
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "invalid user ID",
+			userID:         "invalid",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "user not found",
+			userID:         uuid.New().String(),
+			serviceErr:     domain.ErrNotFound,
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "cohort too small",
+			userID:         uuid.New().String(),
+			serviceErr:     domain.ErrCohortTooSmall,
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewCohortHandler(&mockCohortService{err: tt.serviceErr})
+
+			r := chi.NewRouter()
+			r.Get("/admin/users/{userId}/sleep/cohort-ranks", handler.Ranks)
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/users/"+tt.userID+"/sleep/cohort-ranks"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatusCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var errInvalidIfMatch = errors.New("If-Match must be \"*\" or a single strong entity tag")
+
+// versionETag formats a resource version as a strong entity tag.
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseIfMatch extracts the expected version from the If-Match header.
+// It returns 0 when the header is absent or "*", meaning no precondition.
+// Weak tags never match, since If-Match requires strong comparison.
+func parseIfMatch(r *http.Request) (int, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+	if strings.HasPrefix(value, "W/") {
+		return -1, nil
+	}
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return 0, errInvalidIfMatch
+	}
+
+	version, err := strconv.Atoi(value[1 : len(value)-1])
+	if err != nil || version < 1 {
+		return 0, errInvalidIfMatch
+	}
+	return version, nil
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/importer"
+	"github.com/blaisecz/sleep-tracker/internal/service"
+	"github.com/blaisecz/sleep-tracker/pkg/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// maxImportBodyBytes bounds the size of an uploaded export file.
+const maxImportBodyBytes = 256 << 20
+
+type ImportHandler struct {
+	service service.ImportService
+}
+
+func NewImportHandler(service service.ImportService) *ImportHandler {
+	return &ImportHandler{service: service}
+}
+
+// Import handles POST /v1/users/{userId}/sleep-logs/import
+// @Summary Import wearable export
+// @Description Upload a sleep export from Apple Health (export.xml), Fitbit (sleep JSON) or Google Fit / Health Connect (sleep session JSON) as the raw request body. Stage records are merged into CORE or NAP sessions with a quality derived from sleep efficiency. Sessions get stable client_request_ids, so re-uploading the same export does not create duplicates. Sessions are stored best-effort and reported per item.
+// @Tags sleep-logs
+// @Accept application/xml
+// @Accept json
+// @Produce json
+// @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
+// @Param source query string true "Export format" Enums(apple_health, fitbit, google_fit)
+// @Success 207 {object} BatchCreateSleepLogsResponse "Per-session results"
+// @Failure 400 {object} problem.Problem "Export could not be parsed or contains no sleep data"
+// @Failure 404 {object} problem.Problem "User not found"
+// @Failure 413 {object} problem.Problem "Export larger than 256 MiB"
+// @Failure 422 {object} problem.Problem "Missing or unsupported source"
+// @Failure 500 {object} problem.Problem "Server error"
+// @Router /users/{userId}/sleep-logs/import [post]
+func (h *ImportHandler) Import(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		problem.BadRequest("Invalid user ID format").Write(w)
+		return
+	}
+
+	source, err := importer.ParseSource(r.URL.Query().Get("source"))
+	if err != nil {
+		problem.ValidationError("Invalid query parameters", []problem.FieldError{{
+			Field:   "source",
+			Message: "must be one of: apple_health fitbit google_fit",
+		}}).Write(w)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportBodyBytes)
+	results, err := h.service.Import(r.Context(), userID, source, body)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			problem.NotFound("User not found").Write(w)
+			return
+		}
+		// The parsers report the truncated body as invalid input
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			problem.PayloadTooLarge("Export must not exceed 256 MiB").Write(w)
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidInput) {
+			problem.BadRequest("Could not import export: " + err.Error()).Write(w)
+			return
+		}
+		problem.InternalError("Failed to import sleep logs").Write(w)
+		return
+	}
+
+	response := BatchCreateSleepLogsResponse{
+		Mode:    domain.BatchModeBestEffort,
+		Results: make([]BatchItemResponse, len(results)),
+	}
+	for i, result := range results {
+		response.Results[i].Index = i
+		setBatchItemResult(&response.Results[i], result)
+	}
+
+	writeBatchResponse(w, response)
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/importer"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func TestImportHandler_Import(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name           string
+		query          string
+		importFunc     func(ctx context.Context, userID uuid.UUID, source importer.Source, r io.Reader) ([]domain.BatchItemResult, error)
+		wantStatusCode int
+		wantStatuses   []int
+	}{
+		{
+			name:  "per-session results",
+			query: "?source=apple_health",
+			importFunc: func(ctx context.Context, userID uuid.UUID, source importer.Source, r io.Reader) ([]domain.BatchItemResult, error) {
+				if source != importer.SourceAppleHealth {
+					return nil, fmt.Errorf("unexpected source %q", source)
+				}
+				return []domain.BatchItemResult{
+					{Log: &domain.SleepLog{ID: uuid.New()}},
+					{Log: &domain.SleepLog{ID: uuid.New()}, Existing: true},
+					{Err: domain.ErrOverlappingSleep},
+				}, nil
+			},
+			wantStatusCode: http.StatusMultiStatus,
+			wantStatuses:   []int{http.StatusCreated, http.StatusOK, http.StatusConflict},
+		},
+		{
+			name:           "missing source",
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "unsupported source",
+			query:          "?source=garmin",
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:  "unparseable export",
+			query: "?source=fitbit",
+			importFunc: func(ctx context.Context, userID uuid.UUID, source importer.Source, r io.Reader) ([]domain.BatchItemResult, error) {
+				return nil, fmt.Errorf("%w: %w", domain.ErrInvalidInput, importer.ErrNoSleepData)
+			},
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:  "export too large",
+			query: "?source=apple_health",
+			importFunc: func(ctx context.Context, userID uuid.UUID, source importer.Source, r io.Reader) ([]domain.BatchItemResult, error) {
+				err := fmt.Errorf("apple_health: invalid XML: %w", &http.MaxBytesError{Limit: maxImportBodyBytes})
+				return nil, fmt.Errorf("%w: %w", domain.ErrInvalidInput, err)
+			},
+			wantStatusCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:  "user not found",
+			query: "?source=fitbit",
+			importFunc: func(ctx context.Context, userID uuid.UUID, source importer.Source, r io.Reader) ([]domain.BatchItemResult, error) {
+				return nil, domain.ErrNotFound
+			},
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewImportHandler(&MockImportService{importFunc: tt.importFunc})
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/users/"+userID.String()+"/sleep-logs/import"+tt.query, strings.NewReader("<HealthData/>"))
+			rec := httptest.NewRecorder()
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("userId", userID.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			handler.Import(rec, req)
+
+			if rec.Code != tt.wantStatusCode {
+				t.Fatalf("Import() status = %d, want %d, body: %s", rec.Code, tt.wantStatusCode, rec.Body.String())
+			}
+			if tt.wantStatuses == nil {
+				return
+			}
+
+			var response BatchCreateSleepLogsResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(response.Results) != len(tt.wantStatuses) {
+				t.Fatalf("got %d results, want %d", len(response.Results), len(tt.wantStatuses))
+			}
+			for i, result := range response.Results {
+				if result.Index != i || result.Status != tt.wantStatuses[i] {
+					t.Errorf("results[%d] = index %d status %d, want status %d", i, result.Index, result.Status, tt.wantStatuses[i])
+				}
+			}
+			if response.Created != 1 || response.Existing != 1 || response.Failed != 1 {
+				t.Errorf("counters = %d/%d/%d, want 1/1/1", response.Created, response.Existing, response.Failed)
+			}
+		})
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"slices"
 	"strconv"
+	"time"
 
 	"github.com/blaisecz/sleep-tracker/internal/domain"
 	"github.com/blaisecz/sleep-tracker/internal/langfuse"
@@ -21,6 +24,9 @@ import (
 type InsightsHandler struct {
 	chronotypeService service.ChronotypeService
 	metricsService    service.MetricsService
+	factorService     service.FactorImpactService
+	anomalyService    service.AnomalyService
+	debtService       service.SleepDebtService
 	insightsService   service.InsightsService
 	langfuseClient    langfuse.Client
 }
@@ -29,12 +35,18 @@ type InsightsHandler struct {
 func NewInsightsHandler(
 	chronotypeService service.ChronotypeService,
 	metricsService service.MetricsService,
+	factorService service.FactorImpactService,
+	anomalyService service.AnomalyService,
+	debtService service.SleepDebtService,
 	insightsService service.InsightsService,
 	langfuseClient langfuse.Client,
 ) *InsightsHandler {
 	return &InsightsHandler{
 		chronotypeService: chronotypeService,
 		metricsService:    metricsService,
+		factorService:     factorService,
+		anomalyService:    anomalyService,
+		debtService:       debtService,
 		insightsService:   insightsService,
 		langfuseClient:    langfuseClient,
 	}
@@ -98,11 +110,16 @@ func (h *InsightsHandler) GetChronotype(w http.ResponseWriter, r *http.Request)
 
 // GetMetrics handles GET /v1/users/{userId}/sleep/metrics
 // @Summary Get sleep metrics
-// @Description Compute per-sleep and per-day sleep metrics over a configurable window.
+// @Description Compute per-sleep and per-day sleep metrics over a rolling window, an ISO week, a calendar month or a custom range of dates. Calendar periods run from midnight to midnight in the user's timezone. With compare=previous the response also holds the previous period and the deltas.
 // @Tags sleep-insights
 // @Produce json
 // @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
-// @Param window_days query integer false "Number of days to analyze" default(30) minimum(1) maximum(365)
+// @Param period query string false "Period" Enums(rolling, week, month, custom) default(rolling)
+// @Param window_days query integer false "Number of days of a rolling period" default(30) minimum(1) maximum(365)
+// @Param as_of query string false "Local date (YYYY-MM-DD) in the week or month, or ending the rolling period; defaults to today" example(2024-01-15)
+// @Param from query string false "First local date of a custom period (YYYY-MM-DD)" example(2024-01-01)
+// @Param to query string false "Last local date of a custom period (YYYY-MM-DD)" example(2024-01-31)
+// @Param compare query string false "Add the previous period with deltas" Enums(previous)
 // @Success 200 {object} domain.MetricsResponse "Sleep metrics"
 // @Failure 400 {object} problem.Problem "Invalid query parameters"
 // @Failure 404 {object} problem.Problem "User not found"
@@ -116,19 +133,71 @@ func (h *InsightsHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse query parameters
-	windowDays, err := parseIntParam(r, "window_days", service.DefaultMetricsWindowDays)
+	req := domain.MetricsRequest{Period: domain.MetricsPeriodRolling}
+	if val := r.URL.Query().Get("period"); val != "" {
+		req.Period = domain.MetricsPeriodType(val)
+	}
+	req.WindowDays, err = parseIntParam(r, "window_days", service.DefaultMetricsWindowDays)
+	if err != nil {
+		problem.BadRequest(err.Error()).Write(w)
+		return
+	}
+	req.AsOf, err = parseDateParam(r, "as_of")
+	if err != nil {
+		problem.BadRequest(err.Error()).Write(w)
+		return
+	}
+	req.From, err = parseDateParam(r, "from")
+	if err != nil {
+		problem.BadRequest(err.Error()).Write(w)
+		return
+	}
+	req.To, err = parseDateParam(r, "to")
 	if err != nil {
 		problem.BadRequest(err.Error()).Write(w)
 		return
 	}
+	switch compare := r.URL.Query().Get("compare"); compare {
+	case "":
+	case "previous":
+		req.Compare = true
+	default:
+		problem.BadRequest("compare must be previous").Write(w)
+		return
+	}
 
 	// Validate parameters
-	if windowDays < 1 || windowDays > 365 {
+	if !slices.Contains(domain.MetricsPeriodTypes, req.Period) {
+		problem.BadRequest("period must be one of rolling, week, month, custom").Write(w)
+		return
+	}
+	if req.WindowDays < 1 || req.WindowDays > 365 {
 		problem.BadRequest("window_days must be between 1 and 365").Write(w)
 		return
 	}
+	if req.Period == domain.MetricsPeriodCustom {
+		if req.From == nil || req.To == nil {
+			problem.BadRequest("from and to are required for period=custom").Write(w)
+			return
+		}
+		if req.To.Before(*req.From) {
+			problem.BadRequest("to must not be before from").Write(w)
+			return
+		}
+		if req.To.Sub(*req.From) >= 366*24*time.Hour {
+			problem.BadRequest("custom periods can span at most 366 days").Write(w)
+			return
+		}
+		if req.AsOf != nil {
+			problem.BadRequest("as_of cannot be combined with period=custom").Write(w)
+			return
+		}
+	} else if req.From != nil || req.To != nil {
+		problem.BadRequest("from and to require period=custom").Write(w)
+		return
+	}
 
-	result, err := h.metricsService.Compute(r.Context(), userID, windowDays)
+	result, err := h.metricsService.Compute(r.Context(), userID, req)
 	if err != nil {
 		if errors.Is(err, domain.ErrNotFound) {
 			problem.NotFound("User not found").Write(w)
@@ -142,16 +211,244 @@ func (h *InsightsHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(result)
 }
 
+// GetTrends handles GET /v1/users/{userId}/sleep/trends
+// @Summary Get sleep trends
+// @Description Per-day series of a sleep metric with a simple moving average over the requested window, a 30-day simple moving average and an exponentially weighted moving average. Days are local dates of the sleep end time; days without sleep are marked as missing.
+// @Tags sleep-insights
+// @Produce json
+// @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
+// @Param metric query string false "Metric" Enums(duration, quality, bedtime, total_daily) default(duration)
+// @Param window query integer false "Moving average window in days" default(7) minimum(1) maximum(90)
+// @Param span query integer false "Number of days in the series" default(90) minimum(1) maximum(365)
+// @Success 200 {object} domain.TrendsResponse "Trend series"
+// @Failure 400 {object} problem.Problem "Invalid query parameters"
+// @Failure 404 {object} problem.Problem "User not found"
+// @Failure 500 {object} problem.Problem "Server error"
+// @Router /users/{userId}/sleep/trends [get]
+func (h *InsightsHandler) GetTrends(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		problem.BadRequest("Invalid user ID format").Write(w)
+		return
+	}
+
+	// Parse query parameters
+	metric := domain.TrendMetricDuration
+	if val := r.URL.Query().Get("metric"); val != "" {
+		metric = domain.TrendMetric(val)
+	}
+	window, err := parseIntParam(r, "window", service.DefaultTrendWindow)
+	if err != nil {
+		problem.BadRequest(err.Error()).Write(w)
+		return
+	}
+	spanDays, err := parseIntParam(r, "span", service.DefaultTrendSpanDays)
+	if err != nil {
+		problem.BadRequest(err.Error()).Write(w)
+		return
+	}
+
+	// Validate parameters
+	if !slices.Contains(domain.TrendMetrics, metric) {
+		problem.BadRequest("metric must be one of duration, quality, bedtime, total_daily").Write(w)
+		return
+	}
+	if window < 1 || window > 90 {
+		problem.BadRequest("window must be between 1 and 90").Write(w)
+		return
+	}
+	if spanDays < 1 || spanDays > 365 {
+		problem.BadRequest("span must be between 1 and 365").Write(w)
+		return
+	}
+
+	result, err := h.metricsService.Trends(r.Context(), userID, metric, window, spanDays)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			problem.NotFound("User not found").Write(w)
+			return
+		}
+		problem.InternalError("Failed to compute trends").Write(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetFactorImpact handles GET /v1/users/{userId}/sleep/factors/impact
+// @Summary Get factor impact analysis
+// @Description Compare sleep duration and quality on nights with each factor against nights without it. Effect sizes are Cohen's d; intervals come from bootstrap resampling.
+// @Tags sleep-insights
+// @Produce json
+// @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
+// @Param window_days query integer false "Number of days to analyze" default(90) minimum(1) maximum(365)
+// @Success 200 {object} domain.FactorImpactResponse "Factor impact analysis"
+// @Failure 400 {object} problem.Problem "Invalid query parameters"
+// @Failure 404 {object} problem.Problem "User not found"
+// @Failure 500 {object} problem.Problem "Server error"
+// @Router /users/{userId}/sleep/factors/impact [get]
+func (h *InsightsHandler) GetFactorImpact(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		problem.BadRequest("Invalid user ID format").Write(w)
+		return
+	}
+
+	// Parse query parameters
+	windowDays, err := parseIntParam(r, "window_days", service.DefaultFactorImpactWindowDays)
+	if err != nil {
+		problem.BadRequest(err.Error()).Write(w)
+		return
+	}
+
+	// Validate parameters
+	if windowDays < 1 || windowDays > 365 {
+		problem.BadRequest("window_days must be between 1 and 365").Write(w)
+		return
+	}
+
+	result, err := h.factorService.Compute(r.Context(), userID, windowDays)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			problem.NotFound("User not found").Write(w)
+			return
+		}
+		problem.InternalError("Failed to compute factor impact").Write(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetAnomalies handles GET /v1/users/{userId}/sleep/anomalies
+// @Summary Get sleep anomalies
+// @Description Flag nights whose duration, bedtime, mid-sleep or quality deviate from the user's rolling 28-day baseline, using robust z-scores (median and MAD). Weekday and weekend nights are compared separately.
+// @Tags sleep-insights
+// @Produce json
+// @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
+// @Param window_days query integer false "Number of days of nights to score" default(14) minimum(1) maximum(365)
+// @Success 200 {object} domain.AnomaliesResponse "Unusual nights"
+// @Failure 400 {object} problem.Problem "Invalid query parameters"
+// @Failure 404 {object} problem.Problem "User not found"
+// @Failure 500 {object} problem.Problem "Server error"
+// @Router /users/{userId}/sleep/anomalies [get]
+func (h *InsightsHandler) GetAnomalies(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		problem.BadRequest("Invalid user ID format").Write(w)
+		return
+	}
+
+	// Parse query parameters
+	windowDays, err := parseIntParam(r, "window_days", service.DefaultAnomalyWindowDays)
+	if err != nil {
+		problem.BadRequest(err.Error()).Write(w)
+		return
+	}
+
+	// Validate parameters
+	if windowDays < 1 || windowDays > 365 {
+		problem.BadRequest("window_days must be between 1 and 365").Write(w)
+		return
+	}
+
+	result, err := h.anomalyService.Detect(r.Context(), userID, windowDays)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			problem.NotFound("User not found").Write(w)
+			return
+		}
+		problem.InternalError("Failed to detect anomalies").Write(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetSleepDebt handles GET /v1/users/{userId}/sleep/debt
+// @Summary Get sleep debt
+// @Description Per-day running balance of total sleep (core and naps) minus the target of the user's goal. Each day a share of the balance decays; extra sleep pays off debt but is not banked, and debt is capped. Days without sleep only decay the balance.
+// @Tags sleep-insights
+// @Produce json
+// @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
+// @Param span query integer false "Number of days in the series" default(30) minimum(1) maximum(365)
+// @Param decay query number false "Share of the balance forgiven each day" default(0.1) minimum(0) maximum(0.99)
+// @Param cap_hours query number false "Largest debt in hours" default(20) minimum(1) maximum(100)
+// @Success 200 {object} domain.SleepDebtResponse "Sleep debt series"
+// @Failure 400 {object} problem.Problem "Invalid query parameters"
+// @Failure 404 {object} problem.Problem "User not found"
+// @Failure 500 {object} problem.Problem "Server error"
+// @Router /users/{userId}/sleep/debt [get]
+func (h *InsightsHandler) GetSleepDebt(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		problem.BadRequest("Invalid user ID format").Write(w)
+		return
+	}
+
+	// Parse query parameters
+	spanDays, err := parseIntParam(r, "span", service.DefaultSleepDebtSpanDays)
+	if err != nil {
+		problem.BadRequest(err.Error()).Write(w)
+		return
+	}
+	decay, err := parseFloatParam(r, "decay", service.DefaultSleepDebtDecay)
+	if err != nil {
+		problem.BadRequest(err.Error()).Write(w)
+		return
+	}
+	capHours, err := parseFloatParam(r, "cap_hours", service.DefaultSleepDebtCapHours)
+	if err != nil {
+		problem.BadRequest(err.Error()).Write(w)
+		return
+	}
+
+	// Validate parameters
+	if spanDays < 1 || spanDays > 365 {
+		problem.BadRequest("span must be between 1 and 365").Write(w)
+		return
+	}
+	if decay < 0 || decay > 0.99 {
+		problem.BadRequest("decay must be between 0 and 0.99").Write(w)
+		return
+	}
+	if capHours < 1 || capHours > 100 {
+		problem.BadRequest("cap_hours must be between 1 and 100").Write(w)
+		return
+	}
+
+	result, err := h.debtService.Compute(r.Context(), userID, spanDays, decay, capHours)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			problem.NotFound("User not found").Write(w)
+			return
+		}
+		problem.InternalError("Failed to compute sleep debt").Write(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
 // GetInsights handles GET /v1/users/{userId}/sleep/insights
 // @Summary Get LLM-powered sleep insights
-// @Description Generate comprehensive sleep insights using chronotype, metrics, and LLM analysis.
+// @Description Generate comprehensive sleep insights using chronotype, metrics, and LLM analysis. When the LLM is unavailable or fails, fixed rules write the insights instead; generator names the one used. Insights are cached until the user's sleep data, the model or the prompts change; X-Insights-Cache tells whether the response came from the cache and Age how old cached insights are.
 // @Tags sleep-insights
 // @Produce json
 // @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
+// @Param refresh query boolean false "Generate new insights even if cached ones are still valid" default(false)
 // @Success 200 {object} domain.InsightsResponse "Sleep insights with LLM analysis"
+// @Header 200 {string} X-Insights-Cache "HIT or MISS"
+// @Header 200 {integer} Age "Seconds since cached insights were generated"
+// @Failure 400 {object} problem.Problem "Invalid query parameters"
 // @Failure 404 {object} problem.Problem "User not found"
 // @Failure 500 {object} problem.Problem "Server error"
-// @Failure 503 {object} problem.Problem "LLM service unavailable"
+// @Failure 502 {object} problem.Problem "LLM call failed and the fallback is disabled"
+// @Failure 503 {object} problem.Problem "LLM provider not configured or temporarily unavailable and the fallback is disabled"
 // @Router /users/{userId}/sleep/insights [get]
 func (h *InsightsHandler) GetInsights(w http.ResponseWriter, r *http.Request) {
 	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
@@ -160,17 +457,30 @@ func (h *InsightsHandler) GetInsights(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.insightsService.Generate(r.Context(), userID)
+	refresh := false
+	if val := r.URL.Query().Get("refresh"); val != "" {
+		refresh, err = strconv.ParseBool(val)
+		if err != nil {
+			problem.BadRequest("refresh must be true or false").Write(w)
+			return
+		}
+	}
+
+	result, err := h.insightsService.Generate(r.Context(), userID, refresh)
 	if err != nil {
 		if errors.Is(err, domain.ErrNotFound) {
 			problem.NotFound("User not found").Write(w)
 			return
 		}
-		if errors.Is(err, llm.ErrOpenAIUnavailable) {
-			problem.New(http.StatusServiceUnavailable, "service-unavailable", "Service Unavailable", "OpenAI service is not configured").Write(w)
+		if errors.Is(err, llm.ErrCircuitOpen) {
+			problem.New(http.StatusServiceUnavailable, "service-unavailable", "Service Unavailable", "LLM provider is temporarily unavailable, try again later").Write(w)
 			return
 		}
-		if errors.Is(err, llm.ErrOpenAIRequest) || errors.Is(err, llm.ErrOpenAIResponse) {
+		if errors.Is(err, llm.ErrLLMUnavailable) {
+			problem.New(http.StatusServiceUnavailable, "service-unavailable", "Service Unavailable", "LLM provider is not configured").Write(w)
+			return
+		}
+		if errors.Is(err, llm.ErrLLMRequest) || errors.Is(err, llm.ErrLLMResponse) {
 			problem.New(http.StatusBadGateway, "llm-error", "LLM Error", "Failed to generate insights from LLM").Write(w)
 			return
 		}
@@ -178,12 +488,22 @@ func (h *InsightsHandler) GetInsights(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Attach OTEL trace ID (if present) to response for feedback linking
+	// Attach OTEL trace ID (if present) to response for feedback linking;
+	// cached insights keep the trace they were generated in
 	span := trace.SpanFromContext(r.Context())
-	if span.SpanContext().IsValid() {
+	if result.TraceID == "" && span.SpanContext().IsValid() {
 		result.TraceID = span.SpanContext().TraceID().String()
 	}
 
+	if result.Cached {
+		w.Header().Set("X-Insights-Cache", "HIT")
+		if !result.GeneratedAt.IsZero() {
+			age := max(time.Since(result.GeneratedAt), 0)
+			w.Header().Set("Age", strconv.Itoa(int(age.Seconds())))
+		}
+	} else {
+		w.Header().Set("X-Insights-Cache", "MISS")
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
@@ -268,3 +588,30 @@ func parseIntParam(r *http.Request, name string, defaultValue int) (int, error)
 	}
 	return parsed, nil
 }
+
+// parseFloatParam parses a number query parameter with a default value.
+// It returns an error if the value is present but not a valid number.
+func parseFloatParam(r *http.Request, name string, defaultValue float64) (float64, error) {
+	val := r.URL.Query().Get(name)
+	if val == "" {
+		return defaultValue, nil
+	}
+	parsed, err := strconv.ParseFloat(val, 64)
+	if err != nil || math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+		return 0, fmt.Errorf("%s must be a valid number", name)
+	}
+	return parsed, nil
+}
+
+// parseDateParam parses an optional date query parameter (YYYY-MM-DD).
+func parseDateParam(r *http.Request, name string) (*time.Time, error) {
+	val := r.URL.Query().Get(name)
+	if val == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse("2006-01-02", val)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a date (YYYY-MM-DD)", name)
+	}
+	return &parsed, nil
+}
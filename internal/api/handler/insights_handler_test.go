@@ -11,6 +11,7 @@ import (
 
 	"github.com/blaisecz/sleep-tracker/internal/domain"
 	"github.com/blaisecz/sleep-tracker/internal/langfuse"
+	"github.com/blaisecz/sleep-tracker/internal/service"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel/trace"
@@ -30,9 +31,12 @@ func (m *mockChronotypeService) Compute(ctx context.Context, userID uuid.UUID, w
 	}, nil
 }
 
-type mockMetricsService struct{}
+type mockMetricsService struct {
+	req domain.MetricsRequest
+}
 
-func (m *mockMetricsService) Compute(ctx context.Context, userID uuid.UUID, windowDays int) (*domain.MetricsResponse, error) {
+func (m *mockMetricsService) Compute(ctx context.Context, userID uuid.UUID, req domain.MetricsRequest) (*domain.MetricsResponse, error) {
+	m.req = req
 	return &domain.MetricsResponse{}, nil
 }
 
@@ -40,10 +44,53 @@ func (m *mockMetricsService) ComputeWindow(ctx context.Context, userID uuid.UUID
 	return &domain.WindowMetrics{}, nil
 }
 
-type mockInsightsService struct{}
+func (m *mockMetricsService) Trends(ctx context.Context, userID uuid.UUID, metric domain.TrendMetric, window, spanDays int) (*domain.TrendsResponse, error) {
+	return &domain.TrendsResponse{Metric: metric, Window: window, SpanDays: spanDays}, nil
+}
+
+func (m *mockMetricsService) CompareScores(ctx context.Context, userID uuid.UUID, windowDays int, baseline, candidate service.ScoringModel) (*domain.ScoreComparisonResponse, error) {
+	return &domain.ScoreComparisonResponse{}, nil
+}
+
+type mockFactorImpactService struct {
+	windowDays int
+	err        error
+}
+
+func (m *mockFactorImpactService) Compute(ctx context.Context, userID uuid.UUID, windowDays int) (*domain.FactorImpactResponse, error) {
+	m.windowDays = windowDays
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &domain.FactorImpactResponse{Factors: []domain.FactorAnalysis{}}, nil
+}
+
+type mockAnomalyService struct{}
 
-func (m *mockInsightsService) Generate(ctx context.Context, userID uuid.UUID) (*domain.InsightsResponse, error) {
+func (m *mockAnomalyService) Detect(ctx context.Context, userID uuid.UUID, windowDays int) (*domain.AnomaliesResponse, error) {
+	return &domain.AnomaliesResponse{Anomalies: []domain.SleepAnomaly{}}, nil
+}
+
+type mockSleepDebtService struct {
+	capHours float64
+}
+
+func (m *mockSleepDebtService) Compute(ctx context.Context, userID uuid.UUID, spanDays int, decay, capHours float64) (*domain.SleepDebtResponse, error) {
+	m.capHours = capHours
+	return &domain.SleepDebtResponse{SpanDays: spanDays, Decay: decay, CapHours: capHours}, nil
+}
+
+type mockInsightsService struct {
+	// cachedAt, if set, makes responses come from the cache
+	cachedAt time.Time
+	refresh  bool
+}
+
+func (m *mockInsightsService) Generate(ctx context.Context, userID uuid.UUID, refresh bool) (*domain.InsightsResponse, error) {
+	m.refresh = refresh
 	return &domain.InsightsResponse{
+		Cached:      !m.cachedAt.IsZero(),
+		GeneratedAt: m.cachedAt,
 		Chronotype: domain.ChronotypeResult{
 			Chronotype: domain.ChronotypeIntermediate,
 		},
@@ -82,6 +129,9 @@ func TestGetInsights_IncludesTraceID(t *testing.T) {
 	handler := NewInsightsHandler(
 		&mockChronotypeService{},
 		&mockMetricsService{},
+		&mockFactorImpactService{},
+		&mockAnomalyService{},
+		&mockSleepDebtService{},
 		&mockInsightsService{},
 		mockLangfuse,
 	)
@@ -128,6 +178,9 @@ func TestGetInsights_NoTraceIDWhenDisabled(t *testing.T) {
 	handler := NewInsightsHandler(
 		&mockChronotypeService{},
 		&mockMetricsService{},
+		&mockFactorImpactService{},
+		&mockAnomalyService{},
+		&mockSleepDebtService{},
 		&mockInsightsService{},
 		mockLangfuse,
 	)
@@ -151,6 +204,58 @@ func TestGetInsights_NoTraceIDWhenDisabled(t *testing.T) {
 	}
 }
 
+func TestGetInsights_Cache(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		cachedAt    time.Time
+		wantStatus  int
+		wantRefresh bool
+		wantCache   string
+		wantAge     string
+	}{
+		{"generated", "", time.Time{}, http.StatusOK, false, "MISS", ""},
+		{"cached", "", time.Now().Add(-90 * time.Second), http.StatusOK, false, "HIT", "90"},
+		{"refresh", "?refresh=true", time.Time{}, http.StatusOK, true, "MISS", ""},
+		{"invalid refresh", "?refresh=always", time.Time{}, http.StatusBadRequest, false, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			insightsService := &mockInsightsService{cachedAt: tt.cachedAt}
+			handler := NewInsightsHandler(
+				&mockChronotypeService{},
+				&mockMetricsService{},
+				&mockFactorImpactService{},
+				&mockAnomalyService{},
+				&mockSleepDebtService{},
+				insightsService,
+				&mockLangfuseClient{},
+			)
+
+			r := chi.NewRouter()
+			r.Get("/users/{userId}/sleep/insights", handler.GetInsights)
+
+			req := httptest.NewRequest(http.MethodGet, "/users/"+uuid.New().String()+"/sleep/insights"+tt.query, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if insightsService.refresh != tt.wantRefresh {
+				t.Errorf("refresh = %v, want %v", insightsService.refresh, tt.wantRefresh)
+			}
+			if got := w.Header().Get("X-Insights-Cache"); got != tt.wantCache {
+				t.Errorf("X-Insights-Cache = %q, want %q", got, tt.wantCache)
+			}
+			if got := w.Header().Get("Age"); got != tt.wantAge {
+				t.Errorf("Age = %q, want %q", got, tt.wantAge)
+			}
+		})
+	}
+}
+
 func TestPostFeedback_Success(t *testing.T) {
 	userID := uuid.New()
 
@@ -159,6 +264,9 @@ func TestPostFeedback_Success(t *testing.T) {
 	handler := NewInsightsHandler(
 		&mockChronotypeService{},
 		&mockMetricsService{},
+		&mockFactorImpactService{},
+		&mockAnomalyService{},
+		&mockSleepDebtService{},
 		&mockInsightsService{},
 		mockLangfuse,
 	)
@@ -188,6 +296,9 @@ func TestPostFeedback_ValidationErrors(t *testing.T) {
 	handler := NewInsightsHandler(
 		&mockChronotypeService{},
 		&mockMetricsService{},
+		&mockFactorImpactService{},
+		&mockAnomalyService{},
+		&mockSleepDebtService{},
 		&mockInsightsService{},
 		&mockLangfuseClient{enabled: true},
 	)
@@ -225,6 +336,9 @@ func TestGetChronotype_InvalidQueryParams(t *testing.T) {
 	handler := NewInsightsHandler(
 		&mockChronotypeService{},
 		&mockMetricsService{},
+		&mockFactorImpactService{},
+		&mockAnomalyService{},
+		&mockSleepDebtService{},
 		&mockInsightsService{},
 		&mockLangfuseClient{enabled: false},
 	)
@@ -248,6 +362,9 @@ func TestGetMetrics_InvalidWindowDays(t *testing.T) {
 	handler := NewInsightsHandler(
 		&mockChronotypeService{},
 		&mockMetricsService{},
+		&mockFactorImpactService{},
+		&mockAnomalyService{},
+		&mockSleepDebtService{},
 		&mockInsightsService{},
 		&mockLangfuseClient{enabled: false},
 	)
@@ -264,3 +381,262 @@ func TestGetMetrics_InvalidWindowDays(t *testing.T) {
 		t.Fatalf("expected status 400, got %d", w.Code)
 	}
 }
+
+func TestGetMetrics_Periods(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedPeriod domain.MetricsPeriodType
+		expectCompare  bool
+	}{
+		{"defaults", "", http.StatusOK, domain.MetricsPeriodRolling, false},
+		{"week with comparison", "?period=week&as_of=2024-01-17&compare=previous", http.StatusOK, domain.MetricsPeriodWeek, true},
+		{"month", "?period=month", http.StatusOK, domain.MetricsPeriodMonth, false},
+		{"custom", "?period=custom&from=2024-01-01&to=2024-01-31", http.StatusOK, domain.MetricsPeriodCustom, false},
+		{"unknown period", "?period=year", http.StatusBadRequest, "", false},
+		{"invalid date", "?period=week&as_of=17.01.2024", http.StatusBadRequest, "", false},
+		{"custom without to", "?period=custom&from=2024-01-01", http.StatusBadRequest, "", false},
+		{"custom reversed", "?period=custom&from=2024-02-01&to=2024-01-01", http.StatusBadRequest, "", false},
+		{"custom too long", "?period=custom&from=2023-01-01&to=2024-06-01", http.StatusBadRequest, "", false},
+		{"custom with as_of", "?period=custom&from=2024-01-01&to=2024-01-31&as_of=2024-01-15", http.StatusBadRequest, "", false},
+		{"from without custom", "?period=week&from=2024-01-01", http.StatusBadRequest, "", false},
+		{"unknown comparison", "?compare=year", http.StatusBadRequest, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metricsService := &mockMetricsService{}
+			handler := NewInsightsHandler(
+				&mockChronotypeService{},
+				metricsService,
+				&mockFactorImpactService{},
+				&mockAnomalyService{},
+				&mockSleepDebtService{},
+				&mockInsightsService{},
+				&mockLangfuseClient{enabled: false},
+			)
+
+			r := chi.NewRouter()
+			r.Get("/users/{userId}/sleep/metrics", handler.GetMetrics)
+
+			req := httptest.NewRequest(http.MethodGet, "/users/"+userID.String()+"/sleep/metrics"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			if metricsService.req.Period != tt.expectedPeriod || metricsService.req.Compare != tt.expectCompare {
+				t.Errorf("unexpected request: %+v", metricsService.req)
+			}
+		})
+	}
+}
+
+func TestGetFactorImpact(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name           string
+		query          string
+		serviceErr     error
+		expectedStatus int
+		expectedWindow int
+	}{
+		{
+			name:           "default window",
+			expectedStatus: http.StatusOK,
+			expectedWindow: 90,
+		},
+		{
+			name:           "custom window",
+			query:          "?window_days=30",
+			expectedStatus: http.StatusOK,
+			expectedWindow: 30,
+		},
+		{
+			name:           "invalid window",
+			query:          "?window_days=abc",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "window out of range",
+			query:          "?window_days=400",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "user not found",
+			serviceErr:     domain.ErrNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedWindow: 90,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			factorService := &mockFactorImpactService{err: tt.serviceErr}
+			handler := NewInsightsHandler(
+				&mockChronotypeService{},
+				&mockMetricsService{},
+				factorService,
+				&mockAnomalyService{},
+				&mockSleepDebtService{},
+				&mockInsightsService{},
+				&mockLangfuseClient{enabled: false},
+			)
+
+			r := chi.NewRouter()
+			r.Get("/users/{userId}/sleep/factors/impact", handler.GetFactorImpact)
+
+			req := httptest.NewRequest(http.MethodGet, "/users/"+userID.String()+"/sleep/factors/impact"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if factorService.windowDays != tt.expectedWindow {
+				t.Errorf("expected window_days %d, got %d", tt.expectedWindow, factorService.windowDays)
+			}
+		})
+	}
+}
+
+func TestGetTrends_InvalidQueryParams(t *testing.T) {
+	userID := uuid.New()
+
+	handler := NewInsightsHandler(
+		&mockChronotypeService{},
+		&mockMetricsService{},
+		&mockFactorImpactService{},
+		&mockAnomalyService{},
+		&mockSleepDebtService{},
+		&mockInsightsService{},
+		&mockLangfuseClient{enabled: false},
+	)
+
+	r := chi.NewRouter()
+	r.Get("/users/{userId}/sleep/trends", handler.GetTrends)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+	}{
+		{"defaults", "", http.StatusOK},
+		{"all params", "?metric=bedtime&window=14&span=30", http.StatusOK},
+		{"unknown metric", "?metric=steps", http.StatusBadRequest},
+		{"invalid window", "?window=week", http.StatusBadRequest},
+		{"window out of range", "?window=0", http.StatusBadRequest},
+		{"span out of range", "?span=366", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/users/"+userID.String()+"/sleep/trends"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestGetAnomalies_InvalidWindowDays(t *testing.T) {
+	userID := uuid.New()
+
+	handler := NewInsightsHandler(
+		&mockChronotypeService{},
+		&mockMetricsService{},
+		&mockFactorImpactService{},
+		&mockAnomalyService{},
+		&mockSleepDebtService{},
+		&mockInsightsService{},
+		&mockLangfuseClient{enabled: false},
+	)
+
+	r := chi.NewRouter()
+	r.Get("/users/{userId}/sleep/anomalies", handler.GetAnomalies)
+
+	tests := []struct {
+		query          string
+		expectedStatus int
+	}{
+		{"", http.StatusOK},
+		{"?window_days=30", http.StatusOK},
+		{"?window_days=abc", http.StatusBadRequest},
+		{"?window_days=0", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/users/"+userID.String()+"/sleep/anomalies"+tt.query, nil)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.expectedStatus {
+			t.Errorf("%q: expected status %d, got %d", tt.query, tt.expectedStatus, w.Code)
+		}
+	}
+}
+
+func TestGetSleepDebt_InvalidQueryParams(t *testing.T) {
+	userID := uuid.New()
+
+	debtService := &mockSleepDebtService{}
+	handler := NewInsightsHandler(
+		&mockChronotypeService{},
+		&mockMetricsService{},
+		&mockFactorImpactService{},
+		&mockAnomalyService{},
+		debtService,
+		&mockInsightsService{},
+		&mockLangfuseClient{enabled: false},
+	)
+
+	r := chi.NewRouter()
+	r.Get("/users/{userId}/sleep/debt", handler.GetSleepDebt)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+	}{
+		{"defaults", "", http.StatusOK},
+		{"all params", "?span=14&decay=0&cap_hours=12.5", http.StatusOK},
+		{"invalid decay", "?decay=high", http.StatusBadRequest},
+		{"decay out of range", "?decay=1", http.StatusBadRequest},
+		{"negative decay", "?decay=-0.1", http.StatusBadRequest},
+		{"cap out of range", "?cap_hours=0", http.StatusBadRequest},
+		{"not a number", "?cap_hours=NaN", http.StatusBadRequest},
+		{"span out of range", "?span=0", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/users/"+userID.String()+"/sleep/debt"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+
+	if debtService.capHours != 12.5 {
+		t.Errorf("expected cap_hours 12.5 to reach the service, got %v", debtService.capHours)
+	}
+}
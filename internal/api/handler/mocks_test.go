@@ -2,17 +2,29 @@ package handler
 
 import (
 	"context"
+	"io"
 	"time"
 
 	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/importer"
 	"github.com/google/uuid"
 )
 
 // MockSleepLogService is a mock implementation of SleepLogService
 type MockSleepLogService struct {
-	createFunc func(ctx context.Context, userID uuid.UUID, req *domain.CreateSleepLogRequest) (*domain.SleepLog, bool, error)
-	updateFunc func(ctx context.Context, userID uuid.UUID, logID uuid.UUID, req *domain.UpdateSleepLogRequest) (*domain.SleepLog, error)
-	listFunc   func(ctx context.Context, userID uuid.UUID, filter domain.SleepLogFilter) (*domain.SleepLogListResponse, error)
+	createFunc  func(ctx context.Context, userID uuid.UUID, req *domain.CreateSleepLogRequest) (*domain.SleepLog, bool, error)
+	getFunc     func(ctx context.Context, userID uuid.UUID, logID uuid.UUID) (*domain.SleepLog, error)
+	updateFunc  func(ctx context.Context, userID uuid.UUID, logID uuid.UUID, req *domain.UpdateSleepLogRequest) (*domain.SleepLog, error)
+	replaceFunc func(ctx context.Context, userID uuid.UUID, logID uuid.UUID, req *domain.CreateSleepLogRequest, ifMatch int) (*domain.SleepLog, error)
+	patchFunc   func(ctx context.Context, userID uuid.UUID, logID uuid.UUID, patch []byte, ifMatch int) (*domain.SleepLog, error)
+	listFunc    func(ctx context.Context, userID uuid.UUID, filter domain.SleepLogFilter) (*domain.SleepLogListResponse, error)
+	deleteFunc  func(ctx context.Context, userID uuid.UUID, logID uuid.UUID, ifMatch int) (*domain.SleepLog, error)
+	restoreFunc func(ctx context.Context, userID uuid.UUID, logID uuid.UUID, ifMatch int) (*domain.SleepLog, error)
+	batchFunc   func(ctx context.Context, userID uuid.UUID, items []domain.CreateSleepLogRequest, mode domain.BatchMode) ([]domain.BatchItemResult, error)
+	exportFunc  func(ctx context.Context, userID uuid.UUID, filter domain.SleepLogFilter, fn func(*domain.SleepLog) error) error
+
+	// lastIfMatch records the expected version passed to the last Update call
+	lastIfMatch int
 }
 
 func (m *MockSleepLogService) Create(ctx context.Context, userID uuid.UUID, req *domain.CreateSleepLogRequest) (*domain.SleepLog, bool, error) {
@@ -31,7 +43,24 @@ func (m *MockSleepLogService) Create(ctx context.Context, userID uuid.UUID, req
 	}, false, nil
 }
 
-func (m *MockSleepLogService) Update(ctx context.Context, userID uuid.UUID, logID uuid.UUID, req *domain.UpdateSleepLogRequest) (*domain.SleepLog, error) {
+func (m *MockSleepLogService) Get(ctx context.Context, userID uuid.UUID, logID uuid.UUID) (*domain.SleepLog, error) {
+	if m.getFunc != nil {
+		return m.getFunc(ctx, userID, logID)
+	}
+	return &domain.SleepLog{
+		ID:            logID,
+		UserID:        userID,
+		StartAt:       time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC),
+		EndAt:         time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC),
+		Quality:       8,
+		Type:          domain.SleepTypeCore,
+		LocalTimezone: "UTC",
+		CreatedAt:     time.Now(),
+	}, nil
+}
+
+func (m *MockSleepLogService) Update(ctx context.Context, userID uuid.UUID, logID uuid.UUID, req *domain.UpdateSleepLogRequest, ifMatch int) (*domain.SleepLog, error) {
+	m.lastIfMatch = ifMatch
 	if m.updateFunc != nil {
 		return m.updateFunc(ctx, userID, logID, req)
 	}
@@ -47,6 +76,44 @@ func (m *MockSleepLogService) Update(ctx context.Context, userID uuid.UUID, logI
 	}, nil
 }
 
+func (m *MockSleepLogService) Replace(ctx context.Context, userID uuid.UUID, logID uuid.UUID, req *domain.CreateSleepLogRequest, ifMatch int) (*domain.SleepLog, error) {
+	if m.replaceFunc != nil {
+		return m.replaceFunc(ctx, userID, logID, req, ifMatch)
+	}
+	localTZ := "UTC"
+	if req.LocalTimezone != nil {
+		localTZ = *req.LocalTimezone
+	}
+	return &domain.SleepLog{
+		ID:              logID,
+		UserID:          userID,
+		StartAt:         req.StartAt,
+		EndAt:           req.EndAt,
+		Quality:         req.Quality,
+		Type:            req.Type,
+		LocalTimezone:   localTZ,
+		ClientRequestID: req.ClientRequestID,
+		Version:         ifMatch + 1,
+		CreatedAt:       time.Now(),
+	}, nil
+}
+
+func (m *MockSleepLogService) Patch(ctx context.Context, userID uuid.UUID, logID uuid.UUID, patch []byte, ifMatch int) (*domain.SleepLog, error) {
+	if m.patchFunc != nil {
+		return m.patchFunc(ctx, userID, logID, patch, ifMatch)
+	}
+	return &domain.SleepLog{
+		ID:            logID,
+		UserID:        userID,
+		StartAt:       time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC),
+		EndAt:         time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC),
+		Quality:       7,
+		Type:          domain.SleepTypeCore,
+		LocalTimezone: "UTC",
+		Version:       ifMatch + 1,
+	}, nil
+}
+
 func (m *MockSleepLogService) List(ctx context.Context, userID uuid.UUID, filter domain.SleepLogFilter) (*domain.SleepLogListResponse, error) {
 	if m.listFunc != nil {
 		return m.listFunc(ctx, userID, filter)
@@ -56,3 +123,66 @@ func (m *MockSleepLogService) List(ctx context.Context, userID uuid.UUID, filter
 		Pagination: domain.PaginationResponse{HasMore: false},
 	}, nil
 }
+
+func (m *MockSleepLogService) Delete(ctx context.Context, userID uuid.UUID, logID uuid.UUID, ifMatch int) (*domain.SleepLog, error) {
+	if m.deleteFunc != nil {
+		return m.deleteFunc(ctx, userID, logID, ifMatch)
+	}
+	return &domain.SleepLog{ID: logID, UserID: userID, Version: 2}, nil
+}
+
+func (m *MockSleepLogService) Restore(ctx context.Context, userID uuid.UUID, logID uuid.UUID, ifMatch int) (*domain.SleepLog, error) {
+	if m.restoreFunc != nil {
+		return m.restoreFunc(ctx, userID, logID, ifMatch)
+	}
+	return &domain.SleepLog{
+		ID:            logID,
+		UserID:        userID,
+		StartAt:       time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC),
+		EndAt:         time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC),
+		Quality:       8,
+		Type:          domain.SleepTypeCore,
+		LocalTimezone: "UTC",
+		CreatedAt:     time.Now(),
+	}, nil
+}
+
+func (m *MockSleepLogService) CreateBatch(ctx context.Context, userID uuid.UUID, items []domain.CreateSleepLogRequest, mode domain.BatchMode) ([]domain.BatchItemResult, error) {
+	if m.batchFunc != nil {
+		return m.batchFunc(ctx, userID, items, mode)
+	}
+	results := make([]domain.BatchItemResult, len(items))
+	for i, req := range items {
+		results[i].Log = &domain.SleepLog{
+			ID:            uuid.New(),
+			UserID:        userID,
+			StartAt:       req.StartAt,
+			EndAt:         req.EndAt,
+			Quality:       req.Quality,
+			Type:          req.Type,
+			LocalTimezone: "UTC",
+			Version:       1,
+			CreatedAt:     time.Now(),
+		}
+	}
+	return results, nil
+}
+
+func (m *MockSleepLogService) Export(ctx context.Context, userID uuid.UUID, filter domain.SleepLogFilter, fn func(*domain.SleepLog) error) error {
+	if m.exportFunc != nil {
+		return m.exportFunc(ctx, userID, filter, fn)
+	}
+	return nil
+}
+
+// MockImportService is a mock implementation of ImportService
+type MockImportService struct {
+	importFunc func(ctx context.Context, userID uuid.UUID, source importer.Source, r io.Reader) ([]domain.BatchItemResult, error)
+}
+
+func (m *MockImportService) Import(ctx context.Context, userID uuid.UUID, source importer.Source, r io.Reader) ([]domain.BatchItemResult, error) {
+	if m.importFunc != nil {
+		return m.importFunc(ctx, userID, source, r)
+	}
+	return nil, nil
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/blaisecz/sleep-tracker/internal/api/validation"
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/service"
+	"github.com/blaisecz/sleep-tracker/pkg/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type SleepGoalHandler struct {
+	service service.SleepGoalService
+}
+
+func NewSleepGoalHandler(service service.SleepGoalService) *SleepGoalHandler {
+	return &SleepGoalHandler{service: service}
+}
+
+// Get handles GET /v1/users/{userId}/goals
+// @Summary Get sleep goals
+// @Description Get the sleep goals in force. Without goals, metrics use a 7-hour target.
+// @Tags goals
+// @Produce json
+// @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
+// @Success 200 {object} domain.SleepGoalResponse "Sleep goals in force"
+// @Failure 400 {object} problem.Problem "Invalid UUID format"
+// @Failure 404 {object} problem.Problem "User not found or no goals set"
+// @Failure 500 {object} problem.Problem "Server error"
+// @Router /users/{userId}/goals [get]
+func (h *SleepGoalHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		problem.BadRequest("Invalid user ID format").Write(w)
+		return
+	}
+
+	goal, err := h.service.Get(r.Context(), userID)
+	if err != nil {
+		writeGoalError(w, err, "Failed to get sleep goals")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(goal.ToResponse())
+}
+
+// Put handles PUT /v1/users/{userId}/goals
+// @Summary Set sleep goals
+// @Description Replace the sleep goals from now on. The previous goals are kept in the history, so past windows are still scored against the goals in force at the time.
+// @Tags goals
+// @Accept json
+// @Produce json
+// @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
+// @Param request body domain.SleepGoalRequest true "Sleep goals"
+// @Success 200 {object} domain.SleepGoalResponse "Sleep goals in force"
+// @Failure 400 {object} problem.Problem "Invalid request"
+// @Failure 404 {object} problem.Problem "User not found"
+// @Failure 422 {object} problem.Problem "Validation error"
+// @Failure 500 {object} problem.Problem "Server error"
+// @Router /users/{userId}/goals [put]
+func (h *SleepGoalHandler) Put(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		problem.BadRequest("Invalid user ID format").Write(w)
+		return
+	}
+
+	var req domain.SleepGoalRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		problem.BadRequest("Invalid JSON body").Write(w)
+		return
+	}
+
+	if fieldErrors := validation.Validate(req); fieldErrors != nil {
+		problem.ValidationError("Request body contains invalid fields", fieldErrors).Write(w)
+		return
+	}
+
+	goal, err := h.service.Set(r.Context(), userID, &req)
+	if err != nil {
+		writeGoalError(w, err, "Failed to set sleep goals")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(goal.ToResponse())
+}
+
+// Delete handles DELETE /v1/users/{userId}/goals
+// @Summary Remove sleep goals
+// @Description End the sleep goals in force, so the defaults apply from now on. The goals stay in the history.
+// @Tags goals
+// @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
+// @Success 204 "Sleep goals removed"
+// @Failure 400 {object} problem.Problem "Invalid UUID format"
+// @Failure 404 {object} problem.Problem "User not found or no goals set"
+// @Failure 500 {object} problem.Problem "Server error"
+// @Router /users/{userId}/goals [delete]
+func (h *SleepGoalHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		problem.BadRequest("Invalid user ID format").Write(w)
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), userID); err != nil {
+		writeGoalError(w, err, "Failed to remove sleep goals")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// History handles GET /v1/users/{userId}/goals/history
+// @Summary List sleep goal history
+// @Description List every version of the user's sleep goals, oldest first, with the period each was in force.
+// @Tags goals
+// @Produce json
+// @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
+// @Success 200 {object} domain.SleepGoalHistoryResponse "Goal history"
+// @Failure 400 {object} problem.Problem "Invalid UUID format"
+// @Failure 404 {object} problem.Problem "User not found"
+// @Failure 500 {object} problem.Problem "Server error"
+// @Router /users/{userId}/goals/history [get]
+func (h *SleepGoalHandler) History(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		problem.BadRequest("Invalid user ID format").Write(w)
+		return
+	}
+
+	goals, err := h.service.History(r.Context(), userID)
+	if err != nil {
+		writeGoalError(w, err, "Failed to list sleep goal history")
+		return
+	}
+
+	response := domain.SleepGoalHistoryResponse{Data: make([]domain.SleepGoalResponse, len(goals))}
+	for i := range goals {
+		response.Data[i] = goals[i].ToResponse()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// writeGoalError maps sleep goal service errors to problems.
+func writeGoalError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		problem.NotFound("User not found").Write(w)
+	case errors.Is(err, domain.ErrGoalNotSet):
+		problem.NotFound("No sleep goals set").Write(w)
+	default:
+		problem.InternalError(fallback).Write(w)
+	}
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// MockSleepGoalService is a mock implementation of SleepGoalService
+type MockSleepGoalService struct {
+	getFunc    func(ctx context.Context, userID uuid.UUID) (*domain.SleepGoal, error)
+	deleteFunc func(ctx context.Context, userID uuid.UUID) error
+}
+
+func (m *MockSleepGoalService) Get(ctx context.Context, userID uuid.UUID) (*domain.SleepGoal, error) {
+	if m.getFunc != nil {
+		return m.getFunc(ctx, userID)
+	}
+	return &domain.SleepGoal{UserID: userID, TargetHours: 8}, nil
+}
+
+func (m *MockSleepGoalService) Set(ctx context.Context, userID uuid.UUID, req *domain.SleepGoalRequest) (*domain.SleepGoal, error) {
+	return &domain.SleepGoal{
+		UserID:              userID,
+		TargetHours:         req.TargetHours,
+		BedtimeWindowStart:  req.BedtimeWindowStart,
+		BedtimeWindowEnd:    req.BedtimeWindowEnd,
+		WakeTime:            req.WakeTime,
+		NapAllowanceMinutes: req.NapAllowanceMinutes,
+		EffectiveFrom:       time.Now().UTC(),
+	}, nil
+}
+
+func (m *MockSleepGoalService) Delete(ctx context.Context, userID uuid.UUID) error {
+	if m.deleteFunc != nil {
+		return m.deleteFunc(ctx, userID)
+	}
+	return nil
+}
+
+func (m *MockSleepGoalService) History(ctx context.Context, userID uuid.UUID) ([]domain.SleepGoal, error) {
+	return nil, nil
+}
+
+// goalRequest builds a request with the userId URL parameter.
+func goalRequest(method, userID, body string) *http.Request {
+	req := httptest.NewRequest(method, "/v1/users/"+userID+"/goals", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("userId", userID)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestSleepGoalHandler_Put(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name           string
+		body           string
+		wantStatusCode int
+		wantField      string
+	}{
+		{
+			name:           "all goals",
+			body:           `{"target_hours": 8, "bedtime_window_start": "23:30", "bedtime_window_end": "00:30", "wake_time": "07:30", "nap_allowance_minutes": 20}`,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "target only",
+			body:           `{"target_hours": 7.5}`,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "missing target",
+			body:           `{"wake_time": "07:00"}`,
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantField:      "target_hours",
+		},
+		{
+			name:           "invalid clock time",
+			body:           `{"target_hours": 8, "wake_time": "7am"}`,
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantField:      "wake_time",
+		},
+		{
+			name:           "bedtime window without end",
+			body:           `{"target_hours": 8, "bedtime_window_start": "22:30"}`,
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantField:      "bedtime_window_end",
+		},
+		{
+			name:           "unknown field",
+			body:           `{"target_hours": 8, "naps": 2}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewSleepGoalHandler(&MockSleepGoalService{})
+			rec := httptest.NewRecorder()
+
+			handler.Put(rec, goalRequest(http.MethodPut, userID.String(), tt.body))
+
+			if rec.Code != tt.wantStatusCode {
+				t.Fatalf("Put() status = %d, want %d, body: %s", rec.Code, tt.wantStatusCode, rec.Body.String())
+			}
+			if tt.wantField == "" {
+				return
+			}
+			var body struct {
+				Errors []struct {
+					Field string `json:"field"`
+				} `json:"errors"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(body.Errors) != 1 || body.Errors[0].Field != tt.wantField {
+				t.Errorf("errors = %+v, want one for %s", body.Errors, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestSleepGoalHandler_NotSet(t *testing.T) {
+	service := &MockSleepGoalService{
+		getFunc: func(ctx context.Context, userID uuid.UUID) (*domain.SleepGoal, error) {
+			return nil, domain.ErrGoalNotSet
+		},
+		deleteFunc: func(ctx context.Context, userID uuid.UUID) error {
+			return domain.ErrGoalNotSet
+		},
+	}
+	handler := NewSleepGoalHandler(service)
+	userID := uuid.New().String()
+
+	rec := httptest.NewRecorder()
+	handler.Get(rec, goalRequest(http.MethodGet, userID, ""))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Get() status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.Delete(rec, goalRequest(http.MethodDelete, userID, ""))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Delete() status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
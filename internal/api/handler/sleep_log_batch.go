@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/blaisecz/sleep-tracker/internal/api/validation"
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/pkg/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// BatchItemResponse is the outcome of one item of a batch request.
+// @Description Per-item result: the created (or existing) sleep log, or a problem describing why the item failed.
+type BatchItemResponse struct {
+	// Position of the item in the request
+	Index int `json:"index" example:"0"`
+	// HTTP status the item would have received as a single request
+	Status int `json:"status" example:"201"`
+	// Sleep log, for successful items
+	Data *domain.SleepLogResponse `json:"data,omitempty"`
+	// Problem details, for failed items
+	Error *problem.Problem `json:"error,omitempty"`
+}
+
+// BatchCreateSleepLogsResponse is the multi-status response body of a batch request.
+// @Description Per-item results of a batch request, in request order.
+type BatchCreateSleepLogsResponse struct {
+	// Commit mode that was applied
+	Mode domain.BatchMode `json:"mode" example:"atomic"`
+	// Number of sleep logs created
+	Created int `json:"created" example:"2"`
+	// Number of items answered with an existing log (idempotent duplicates)
+	Existing int `json:"existing" example:"0"`
+	// Number of failed items
+	Failed int `json:"failed" example:"1"`
+	// One result per request item
+	Results []BatchItemResponse `json:"results"`
+}
+
+// CreateBatch handles POST /v1/users/{userId}/sleep-logs:batch
+// @Summary Record sleep in bulk
+// @Description Log up to 500 sleep sessions at once. Items are checked for overlaps with stored logs and with earlier items of the batch, and client_request_id is honoured per item. In atomic mode (default) nothing is stored if any item fails; in best_effort mode every valid item is stored. Per-item outcomes are returned in a 207 Multi-Status body.
+// @Tags sleep-logs
+// @Accept json
+// @Produce json
+// @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
+// @Param request body domain.BatchCreateSleepLogsRequest true "Batch of sleep sessions"
+// @Success 207 {object} BatchCreateSleepLogsResponse "Per-item results"
+// @Failure 400 {object} problem.Problem "Invalid request body or parameters"
+// @Failure 404 {object} problem.Problem "User not found"
+// @Failure 422 {object} problem.Problem "Invalid mode or number of items"
+// @Failure 500 {object} problem.Problem "Server error"
+// @Router /users/{userId}/sleep-logs:batch [post]
+func (h *SleepLogHandler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		problem.BadRequest("Invalid user ID format").Write(w)
+		return
+	}
+
+	var req domain.BatchCreateSleepLogsRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		problem.BadRequest("Invalid JSON body").Write(w)
+		return
+	}
+
+	if fieldErrors := validation.Validate(req); fieldErrors != nil {
+		problem.ValidationError("Request body contains invalid fields", fieldErrors).Write(w)
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = domain.BatchModeAtomic
+	}
+
+	response := BatchCreateSleepLogsResponse{
+		Mode:    req.Mode,
+		Results: make([]BatchItemResponse, len(req.Items)),
+	}
+
+	// Validate items individually so each failure is reported at its index
+	var valid []domain.CreateSleepLogRequest
+	var validIdx []int
+	for i, item := range req.Items {
+		response.Results[i].Index = i
+		if fieldErrors := validation.Validate(item); fieldErrors != nil {
+			response.Results[i].Error = problem.ValidationError("Item contains invalid fields", fieldErrors)
+			continue
+		}
+		valid = append(valid, item)
+		validIdx = append(validIdx, i)
+	}
+
+	if req.Mode == domain.BatchModeAtomic && len(valid) < len(req.Items) {
+		for _, i := range validIdx {
+			response.Results[i].Error = batchItemProblem(domain.ErrBatchAborted)
+		}
+		writeBatchResponse(w, response)
+		return
+	}
+
+	results, err := h.service.CreateBatch(r.Context(), userID, valid, req.Mode)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			problem.NotFound("User not found").Write(w)
+			return
+		}
+		problem.InternalError("Failed to create sleep logs").Write(w)
+		return
+	}
+
+	for n, result := range results {
+		setBatchItemResult(&response.Results[validIdx[n]], result)
+	}
+
+	writeBatchResponse(w, response)
+}
+
+// setBatchItemResult records the service outcome of an item.
+func setBatchItemResult(item *BatchItemResponse, result domain.BatchItemResult) {
+	switch {
+	case result.Err != nil:
+		item.Error = batchItemProblem(result.Err)
+	case result.Existing:
+		item.Status = http.StatusOK
+		data := result.Log.ToResponse()
+		item.Data = &data
+	default:
+		item.Status = http.StatusCreated
+		data := result.Log.ToResponse()
+		item.Data = &data
+	}
+}
+
+// batchItemProblem maps a batch item error to the problem a single create would return.
+func batchItemProblem(err error) *problem.Problem {
+	switch {
+	case errors.Is(err, domain.ErrOverlappingSleep):
+		return problem.Conflict("Overlapping sleep period detected")
+	case errors.Is(err, domain.ErrDuplicateRequest):
+		return problem.Conflict("client_request_id is repeated in the batch or belongs to a deleted sleep log")
+	case errors.Is(err, domain.ErrInvalidInput):
+		return problem.BadRequest("End time must be after start time")
+	case errors.Is(err, domain.ErrInvalidStages):
+		return fieldProblem("stages", err)
+	case errors.Is(err, domain.ErrInvalidFactors):
+		return fieldProblem("factors", err)
+	case errors.Is(err, domain.ErrBatchAborted):
+		return problem.FailedDependency("Not stored because other items of the atomic batch failed")
+	default:
+		return problem.InternalError("Failed to create sleep log")
+	}
+}
+
+// writeBatchResponse fills in the item statuses and counters and writes a 207 response.
+func writeBatchResponse(w http.ResponseWriter, response BatchCreateSleepLogsResponse) {
+	for i := range response.Results {
+		item := &response.Results[i]
+		switch {
+		case item.Error != nil:
+			item.Status = item.Error.Status
+			response.Failed++
+		case item.Status == http.StatusOK:
+			response.Existing++
+		default:
+			response.Created++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusMultiStatus)
+	json.NewEncoder(w).Encode(response)
+}
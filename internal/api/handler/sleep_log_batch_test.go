@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func TestSleepLogHandler_CreateBatch(t *testing.T) {
+	userID := uuid.New()
+	validItem := `{"start_at": "2024-01-15T23:00:00Z", "end_at": "2024-01-16T07:00:00Z", "quality": 7, "type": "CORE"}`
+	invalidItem := `{"start_at": "2024-01-16T23:00:00Z", "end_at": "2024-01-17T07:00:00Z", "quality": 11, "type": "CORE"}`
+
+	tests := []struct {
+		name           string
+		body           string
+		batchFunc      func(ctx context.Context, userID uuid.UUID, items []domain.CreateSleepLogRequest, mode domain.BatchMode) ([]domain.BatchItemResult, error)
+		wantStatusCode int
+		wantStatuses   []int
+	}{
+		{
+			name:           "all created",
+			body:           `{"items": [` + validItem + `, ` + validItem + `]}`,
+			wantStatusCode: http.StatusMultiStatus,
+			wantStatuses:   []int{http.StatusCreated, http.StatusCreated},
+		},
+		{
+			name: "per-item service failures",
+			body: `{"mode": "best_effort", "items": [` + validItem + `, ` + validItem + `, ` + validItem + `]}`,
+			batchFunc: func(ctx context.Context, userID uuid.UUID, items []domain.CreateSleepLogRequest, mode domain.BatchMode) ([]domain.BatchItemResult, error) {
+				return []domain.BatchItemResult{
+					{Log: &domain.SleepLog{ID: uuid.New()}},
+					{Log: &domain.SleepLog{ID: uuid.New()}, Existing: true},
+					{Err: domain.ErrOverlappingSleep},
+				}, nil
+			},
+			wantStatusCode: http.StatusMultiStatus,
+			wantStatuses:   []int{http.StatusCreated, http.StatusOK, http.StatusConflict},
+		},
+		{
+			name:           "invalid item aborts atomic batch",
+			body:           `{"items": [` + validItem + `, ` + invalidItem + `]}`,
+			wantStatusCode: http.StatusMultiStatus,
+			wantStatuses:   []int{http.StatusFailedDependency, http.StatusUnprocessableEntity},
+		},
+		{
+			name:           "invalid item skipped in best effort mode",
+			body:           `{"mode": "best_effort", "items": [` + invalidItem + `, ` + validItem + `]}`,
+			wantStatusCode: http.StatusMultiStatus,
+			wantStatuses:   []int{http.StatusUnprocessableEntity, http.StatusCreated},
+		},
+		{
+			name:           "unknown mode",
+			body:           `{"mode": "eventually", "items": [` + validItem + `]}`,
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "empty batch",
+			body:           `{"items": []}`,
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "too many items",
+			body:           `{"items": [` + strings.Repeat(validItem+`,`, domain.MaxBatchItems) + validItem + `]}`,
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "invalid JSON",
+			body:           `{"items": [}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "user not found",
+			body: `{"items": [` + validItem + `]}`,
+			batchFunc: func(ctx context.Context, userID uuid.UUID, items []domain.CreateSleepLogRequest, mode domain.BatchMode) ([]domain.BatchItemResult, error) {
+				return nil, domain.ErrNotFound
+			},
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockSleepLogService{batchFunc: tt.batchFunc}
+			handler := NewSleepLogHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/users/"+userID.String()+"/sleep-logs:batch", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("userId", userID.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			handler.CreateBatch(rec, req)
+
+			if rec.Code != tt.wantStatusCode {
+				t.Fatalf("CreateBatch() status = %d, want %d, body: %s", rec.Code, tt.wantStatusCode, rec.Body.String())
+			}
+			if tt.wantStatuses == nil {
+				return
+			}
+
+			var response BatchCreateSleepLogsResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(response.Results) != len(tt.wantStatuses) {
+				t.Fatalf("got %d results, want %d", len(response.Results), len(tt.wantStatuses))
+			}
+			failed := 0
+			for i, result := range response.Results {
+				if result.Index != i {
+					t.Errorf("results[%d].Index = %d", i, result.Index)
+				}
+				if result.Status != tt.wantStatuses[i] {
+					t.Errorf("results[%d].Status = %d, want %d", i, result.Status, tt.wantStatuses[i])
+				}
+				if result.Status >= 400 {
+					failed++
+					if result.Error == nil || result.Data != nil {
+						t.Errorf("results[%d] should carry only an error", i)
+					}
+				} else if result.Data == nil || result.Error != nil {
+					t.Errorf("results[%d] should carry only data", i)
+				}
+			}
+			if response.Failed != failed {
+				t.Errorf("Failed = %d, want %d", response.Failed, failed)
+			}
+		})
+	}
+}
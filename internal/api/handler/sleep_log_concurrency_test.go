@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    int
+		wantErr bool
+	}{
+		{name: "absent", header: "", want: 0},
+		{name: "wildcard", header: "*", want: 0},
+		{name: "strong tag", header: `"7"`, want: 7},
+		{name: "weak tag", header: `W/"7"`, want: -1},
+		{name: "unquoted", header: "7", wantErr: true},
+		{name: "not a version", header: `"abc"`, wantErr: true},
+		{name: "list", header: `"1", "2"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("If-Match", tt.header)
+			}
+
+			got, err := parseIfMatch(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseIfMatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Fatalf("parseIfMatch() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSleepLogHandler_Update_IfMatch(t *testing.T) {
+	userID := uuid.New()
+	logID := uuid.New()
+
+	tests := []struct {
+		name           string
+		ifMatch        string
+		updateErr      error
+		wantStatusCode int
+		wantIfMatch    int
+		wantETag       string
+	}{
+		{name: "matching version", ifMatch: `"3"`, wantStatusCode: http.StatusOK, wantIfMatch: 3, wantETag: `"4"`},
+		{name: "no header", ifMatch: "", wantStatusCode: http.StatusOK, wantIfMatch: 0, wantETag: `"4"`},
+		{name: "stale version", ifMatch: `"2"`, updateErr: domain.ErrPreconditionFailed, wantStatusCode: http.StatusPreconditionFailed, wantIfMatch: 2},
+		{name: "malformed header", ifMatch: "3", wantStatusCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockSleepLogService{
+				updateFunc: func(ctx context.Context, uid uuid.UUID, lid uuid.UUID, req *domain.UpdateSleepLogRequest) (*domain.SleepLog, error) {
+					if tt.updateErr != nil {
+						return nil, tt.updateErr
+					}
+					return &domain.SleepLog{
+						ID:            lid,
+						UserID:        uid,
+						StartAt:       time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC),
+						EndAt:         time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC),
+						Quality:       9,
+						Type:          domain.SleepTypeCore,
+						LocalTimezone: "UTC",
+						Version:       4,
+					}, nil
+				},
+			}
+			handler := NewSleepLogHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodPut, "/v1/users/"+userID.String()+"/sleep-logs/"+logID.String(), bytes.NewBufferString(`{"quality": 9}`))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("userId", userID.String())
+			rctx.URLParams.Add("logId", logID.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			handler.Update(rec, req)
+
+			if rec.Code != tt.wantStatusCode {
+				t.Fatalf("Update() status = %d, want %d, body: %s", rec.Code, tt.wantStatusCode, rec.Body.String())
+			}
+			if mockService.lastIfMatch != tt.wantIfMatch {
+				t.Errorf("service received ifMatch = %d, want %d", mockService.lastIfMatch, tt.wantIfMatch)
+			}
+			if got := rec.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("ETag = %q, want %q", got, tt.wantETag)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func TestSleepLogHandler_Delete(t *testing.T) {
+	userID := uuid.New()
+	logID := uuid.New()
+
+	tests := []struct {
+		name           string
+		userID         string
+		logID          string
+		ifMatch        string
+		mockService    *MockSleepLogService
+		wantStatusCode int
+	}{
+		{
+			name:           "deleted",
+			userID:         userID.String(),
+			logID:          logID.String(),
+			mockService:    &MockSleepLogService{},
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:           "invalid user ID",
+			userID:         "not-a-uuid",
+			logID:          logID.String(),
+			mockService:    &MockSleepLogService{},
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid log ID",
+			userID:         userID.String(),
+			logID:          "not-a-uuid",
+			mockService:    &MockSleepLogService{},
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:   "log not found",
+			userID: userID.String(),
+			logID:  logID.String(),
+			mockService: &MockSleepLogService{
+				deleteFunc: func(ctx context.Context, uid uuid.UUID, lid uuid.UUID, ifMatch int) (*domain.SleepLog, error) {
+					return nil, domain.ErrNotFound
+				},
+			},
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:    "stale If-Match",
+			userID:  userID.String(),
+			logID:   logID.String(),
+			ifMatch: `"2"`,
+			mockService: &MockSleepLogService{
+				deleteFunc: func(ctx context.Context, uid uuid.UUID, lid uuid.UUID, ifMatch int) (*domain.SleepLog, error) {
+					if ifMatch != 2 {
+						t.Errorf("Delete() ifMatch = %d, want 2", ifMatch)
+					}
+					return nil, domain.ErrPreconditionFailed
+				},
+			},
+			wantStatusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:           "malformed If-Match",
+			userID:         userID.String(),
+			logID:          logID.String(),
+			ifMatch:        "abc",
+			mockService:    &MockSleepLogService{},
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewSleepLogHandler(tt.mockService)
+
+			req := httptest.NewRequest(http.MethodDelete, "/v1/users/"+tt.userID+"/sleep-logs/"+tt.logID, nil)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("userId", tt.userID)
+			rctx.URLParams.Add("logId", tt.logID)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			handler.Delete(rec, req)
+
+			if rec.Code != tt.wantStatusCode {
+				t.Errorf("Delete() status = %d, want %d, body: %s", rec.Code, tt.wantStatusCode, rec.Body.String())
+			}
+			// The new version is returned so the log can be restored
+			if rec.Code == http.StatusNoContent && rec.Header().Get("ETag") != `"2"` {
+				t.Errorf("Delete() ETag = %q, want \"2\"", rec.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestSleepLogHandler_Restore(t *testing.T) {
+	userID := uuid.New()
+	logID := uuid.New()
+
+	tests := []struct {
+		name           string
+		mockService    *MockSleepLogService
+		wantStatusCode int
+	}{
+		{
+			name:           "restored",
+			mockService:    &MockSleepLogService{},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name: "deleted log not found",
+			mockService: &MockSleepLogService{
+				restoreFunc: func(ctx context.Context, uid uuid.UUID, lid uuid.UUID, ifMatch int) (*domain.SleepLog, error) {
+					return nil, domain.ErrNotFound
+				},
+			},
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "period taken by another log",
+			mockService: &MockSleepLogService{
+				restoreFunc: func(ctx context.Context, uid uuid.UUID, lid uuid.UUID, ifMatch int) (*domain.SleepLog, error) {
+					return nil, domain.ErrOverlappingSleep
+				},
+			},
+			wantStatusCode: http.StatusConflict,
+		},
+		{
+			name: "stale If-Match",
+			mockService: &MockSleepLogService{
+				restoreFunc: func(ctx context.Context, uid uuid.UUID, lid uuid.UUID, ifMatch int) (*domain.SleepLog, error) {
+					return nil, domain.ErrPreconditionFailed
+				},
+			},
+			wantStatusCode: http.StatusPreconditionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewSleepLogHandler(tt.mockService)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/users/"+userID.String()+"/sleep-logs/"+logID.String()+"/restore", nil)
+			req.Header.Set("If-Match", `"4"`)
+			rec := httptest.NewRecorder()
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("userId", userID.String())
+			rctx.URLParams.Add("logId", logID.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			handler.Restore(rec, req)
+
+			if rec.Code != tt.wantStatusCode {
+				t.Errorf("Restore() status = %d, want %d, body: %s", rec.Code, tt.wantStatusCode, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/api/validation"
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/pkg/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// Supported export formats.
+const (
+	exportFormatCSV    = "csv"
+	exportFormatNDJSON = "ndjson"
+	exportFormatJSON   = "json"
+)
+
+// exportWriter encodes sleep logs one at a time in an export format.
+type exportWriter interface {
+	Write(log domain.SleepLogResponse) error
+	Close() error
+}
+
+// Export handles GET /v1/users/{userId}/sleep-logs/export
+// @Summary Export sleep history
+// @Description Download every sleep log of the user, oldest first, with UTC and local times. Unlike the list endpoint the export is not paginated; it is streamed as CSV, NDJSON (one log per line) or a JSON array.
+// @Tags sleep-logs
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce json
+// @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
+// @Param format query string false "Export format" Enums(csv, ndjson, json) default(json)
+// @Param from query string false "Start of date range (RFC3339)" format(date-time) example(2024-01-01T00:00:00Z)
+// @Param to query string false "End of date range (RFC3339)" format(date-time) example(2024-12-31T23:59:59Z)
+// @Success 200 {array} domain.SleepLogResponse "Sleep logs"
+// @Header 200 {string} Content-Disposition "attachment; filename=sleep-logs-{userId}.{format}"
+// @Failure 400 {object} problem.Problem "Invalid parameters"
+// @Failure 404 {object} problem.Problem "User not found"
+// @Failure 422 {object} problem.Problem "Invalid query parameters"
+// @Failure 500 {object} problem.Problem "Server error"
+// @Router /users/{userId}/sleep-logs/export [get]
+func (h *SleepLogHandler) Export(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		problem.BadRequest("Invalid user ID format").Write(w)
+		return
+	}
+
+	filter, fieldErrors := validation.ParseTimeRange(r)
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = exportFormatJSON
+	}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		fieldErrors = append(fieldErrors, problem.FieldError{
+			Field:   "format",
+			Message: "must be one of: csv ndjson json",
+		})
+	}
+	if fieldErrors != nil {
+		problem.ValidationError("Invalid query parameters", fieldErrors).Write(w)
+		return
+	}
+
+	// Headers are sent with the first log (or at the end for an empty
+	// export), so errors before that can still be reported as problems.
+	var out exportWriter
+	begin := func() {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="sleep-logs-%s.%s"`, userID, format))
+		w.WriteHeader(http.StatusOK)
+		out = newExportWriter(format, w)
+	}
+
+	err = h.service.Export(r.Context(), userID, filter, func(log *domain.SleepLog) error {
+		if out == nil {
+			begin()
+		}
+		return out.Write(log.ToResponse())
+	})
+	if err != nil {
+		if out != nil {
+			// The status line is already sent; all we can do is cut the body short.
+			log.Printf("Export: failed to stream sleep logs for user %s: %v", userID, err)
+			return
+		}
+		if errors.Is(err, domain.ErrNotFound) {
+			problem.NotFound("User not found").Write(w)
+			return
+		}
+		problem.InternalError("Failed to export sleep logs").Write(w)
+		return
+	}
+
+	if out == nil {
+		begin()
+	}
+	if err := out.Close(); err != nil {
+		log.Printf("Export: failed to finish export for user %s: %v", userID, err)
+	}
+}
+
+var exportContentTypes = map[string]string{
+	exportFormatCSV:    "text/csv; charset=utf-8",
+	exportFormatNDJSON: "application/x-ndjson",
+	exportFormatJSON:   "application/json",
+}
+
+func newExportWriter(format string, w io.Writer) exportWriter {
+	switch format {
+	case exportFormatCSV:
+		return newCSVExportWriter(w)
+	case exportFormatNDJSON:
+		return &ndjsonExportWriter{enc: json.NewEncoder(w)}
+	default:
+		return &jsonExportWriter{w: w, enc: json.NewEncoder(w)}
+	}
+}
+
+// csvExportHeader lists the CSV columns in order.
+var csvExportHeader = []string{
+	"id", "start_at", "end_at", "local_timezone", "local_start_at", "local_end_at",
+	"duration_minutes", "quality", "type", "client_request_id", "created_at", "version",
+}
+
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func newCSVExportWriter(w io.Writer) *csvExportWriter {
+	cw := csv.NewWriter(w)
+	cw.Write(csvExportHeader)
+	return &csvExportWriter{w: cw}
+}
+
+func (c *csvExportWriter) Write(log domain.SleepLogResponse) error {
+	clientRequestID := ""
+	if log.ClientRequestID != nil {
+		clientRequestID = *log.ClientRequestID
+	}
+	return c.w.Write(csvEscapeRow([]string{
+		log.ID.String(),
+		log.StartAt.Format(time.RFC3339),
+		log.EndAt.Format(time.RFC3339),
+		log.LocalTimezone,
+		log.LocalStartAt.Format(time.RFC3339),
+		log.LocalEndAt.Format(time.RFC3339),
+		strconv.Itoa(int(log.EndAt.Sub(log.StartAt).Minutes())),
+		strconv.Itoa(log.Quality),
+		string(log.Type),
+		clientRequestID,
+		log.CreatedAt.UTC().Format(time.RFC3339),
+		strconv.Itoa(log.Version),
+	}))
+}
+
+// csvEscapeRow prefixes cells that a spreadsheet would evaluate as a formula
+// with a single quote, since some columns such as client_request_id are
+// user-controlled. Leading tabs and carriage returns are escaped as well,
+// since spreadsheets skip them before looking for a formula.
+func csvEscapeRow(row []string) []string {
+	for i, cell := range row {
+		if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+			row[i] = "'" + cell
+		}
+	}
+	return row
+}
+
+func (c *csvExportWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonExportWriter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonExportWriter) Write(log domain.SleepLogResponse) error {
+	return n.enc.Encode(log)
+}
+
+func (n *ndjsonExportWriter) Close() error {
+	return nil
+}
+
+// jsonExportWriter writes a single JSON array element by element.
+type jsonExportWriter struct {
+	w       io.Writer
+	enc     *json.Encoder
+	started bool
+}
+
+func (j *jsonExportWriter) Write(log domain.SleepLogResponse) error {
+	sep := ","
+	if !j.started {
+		sep = "["
+		j.started = true
+	}
+	if _, err := io.WriteString(j.w, sep); err != nil {
+		return err
+	}
+	return j.enc.Encode(log)
+}
+
+func (j *jsonExportWriter) Close() error {
+	if !j.started {
+		_, err := io.WriteString(j.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(j.w, "]\n")
+	return err
+}
@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func TestSleepLogHandler_Export(t *testing.T) {
+	userID := uuid.New()
+	clientID := "client-1"
+	logs := []domain.SleepLog{
+		{
+			ID:              uuid.New(),
+			UserID:          userID,
+			StartAt:         time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC),
+			EndAt:           time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC),
+			Quality:         7,
+			Type:            domain.SleepTypeCore,
+			LocalTimezone:   "Europe/Prague",
+			ClientRequestID: &clientID,
+			Version:         1,
+		},
+		{
+			ID:            uuid.New(),
+			UserID:        userID,
+			StartAt:       time.Date(2024, 1, 16, 13, 0, 0, 0, time.UTC),
+			EndAt:         time.Date(2024, 1, 16, 13, 30, 0, 0, time.UTC),
+			Quality:       6,
+			Type:          domain.SleepTypeNap,
+			LocalTimezone: "UTC",
+			Version:       2,
+		},
+	}
+	streamLogs := func(ctx context.Context, uid uuid.UUID, filter domain.SleepLogFilter, fn func(*domain.SleepLog) error) error {
+		for i := range logs {
+			if err := fn(&logs[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	streamClientID := func(id string) func(ctx context.Context, uid uuid.UUID, filter domain.SleepLogFilter, fn func(*domain.SleepLog) error) error {
+		return func(ctx context.Context, uid uuid.UUID, filter domain.SleepLogFilter, fn func(*domain.SleepLog) error) error {
+			log := logs[0]
+			log.ClientRequestID = &id
+			return fn(&log)
+		}
+	}
+	formulaID := "=HYPERLINK(\"http://evil\")"
+	tabID := "\t=1+2"
+	streamNothing := func(ctx context.Context, uid uuid.UUID, filter domain.SleepLogFilter, fn func(*domain.SleepLog) error) error {
+		return nil
+	}
+
+	tests := []struct {
+		name            string
+		query           string
+		exportFunc      func(ctx context.Context, uid uuid.UUID, filter domain.SleepLogFilter, fn func(*domain.SleepLog) error) error
+		wantStatusCode  int
+		wantContentType string
+		validate        func(*testing.T, string)
+	}{
+		{
+			name:            "csv",
+			query:           "?format=csv",
+			exportFunc:      streamLogs,
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+			validate: func(t *testing.T, body string) {
+				records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+				if err != nil {
+					t.Fatalf("invalid CSV: %v", err)
+				}
+				if len(records) != 3 {
+					t.Fatalf("got %d rows, want header + 2", len(records))
+				}
+				if records[0][0] != "id" {
+					t.Errorf("missing header row: %v", records[0])
+				}
+				row := records[1]
+				if row[4] != "2024-01-16T00:00:00+01:00" {
+					t.Errorf("local_start_at = %s, want 2024-01-16T00:00:00+01:00", row[4])
+				}
+				if row[6] != "480" {
+					t.Errorf("duration_minutes = %s, want 480", row[6])
+				}
+				if row[9] != clientID || records[2][9] != "" {
+					t.Errorf("client_request_id columns = %q, %q", row[9], records[2][9])
+				}
+			},
+		},
+		{
+			name:            "csv escapes formula cells",
+			query:           "?format=csv",
+			exportFunc:      streamClientID(formulaID),
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+			validate: func(t *testing.T, body string) {
+				records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+				if err != nil {
+					t.Fatalf("invalid CSV: %v", err)
+				}
+				if got := records[1][9]; got != "'"+formulaID {
+					t.Errorf("client_request_id = %q, want %q", got, "'"+formulaID)
+				}
+				if got := records[1][4]; got != "2024-01-16T00:00:00+01:00" {
+					t.Errorf("local_start_at = %q, want it unchanged", got)
+				}
+			},
+		},
+		{
+			name:            "csv escapes cells starting with a tab",
+			query:           "?format=csv",
+			exportFunc:      streamClientID(tabID),
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+			validate: func(t *testing.T, body string) {
+				records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+				if err != nil {
+					t.Fatalf("invalid CSV: %v", err)
+				}
+				if got := records[1][9]; got != "'"+tabID {
+					t.Errorf("client_request_id = %q, want %q", got, "'"+tabID)
+				}
+			},
+		},
+		{
+			name:            "ndjson",
+			query:           "?format=ndjson",
+			exportFunc:      streamLogs,
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "application/x-ndjson",
+			validate: func(t *testing.T, body string) {
+				scanner := bufio.NewScanner(strings.NewReader(body))
+				lines := 0
+				for scanner.Scan() {
+					var log domain.SleepLogResponse
+					if err := json.Unmarshal(scanner.Bytes(), &log); err != nil {
+						t.Fatalf("line %d is not JSON: %v", lines, err)
+					}
+					lines++
+				}
+				if lines != 2 {
+					t.Errorf("got %d lines, want 2", lines)
+				}
+			},
+		},
+		{
+			name:            "json is the default",
+			exportFunc:      streamLogs,
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "application/json",
+			validate: func(t *testing.T, body string) {
+				var got []domain.SleepLogResponse
+				if err := json.Unmarshal([]byte(body), &got); err != nil {
+					t.Fatalf("invalid JSON array: %v", err)
+				}
+				if len(got) != 2 || got[1].Type != domain.SleepTypeNap {
+					t.Errorf("unexpected logs: %+v", got)
+				}
+			},
+		},
+		{
+			name:            "empty json export",
+			query:           "?format=json",
+			exportFunc:      streamNothing,
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "application/json",
+			validate: func(t *testing.T, body string) {
+				if strings.TrimSpace(body) != "[]" {
+					t.Errorf("body = %q, want []", body)
+				}
+			},
+		},
+		{
+			name:           "unknown format",
+			query:          "?format=xml",
+			exportFunc:     streamLogs,
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "invalid range",
+			query:          "?from=2024-02-01T00:00:00Z&to=2024-01-01T00:00:00Z",
+			exportFunc:     streamLogs,
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:  "user not found",
+			query: "?format=csv",
+			exportFunc: func(ctx context.Context, uid uuid.UUID, filter domain.SleepLogFilter, fn func(*domain.SleepLog) error) error {
+				return domain.ErrNotFound
+			},
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:  "failure before first log",
+			query: "?format=csv",
+			exportFunc: func(ctx context.Context, uid uuid.UUID, filter domain.SleepLogFilter, fn func(*domain.SleepLog) error) error {
+				return errors.New("connection reset")
+			},
+			wantStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockSleepLogService{exportFunc: tt.exportFunc}
+			handler := NewSleepLogHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/users/"+userID.String()+"/sleep-logs/export"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("userId", userID.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			handler.Export(rec, req)
+
+			if rec.Code != tt.wantStatusCode {
+				t.Fatalf("Export() status = %d, want %d, body: %s", rec.Code, tt.wantStatusCode, rec.Body.String())
+			}
+			if tt.wantContentType != "" {
+				if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+					t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+				}
+				if got := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(got, "attachment;") {
+					t.Errorf("Content-Disposition = %q, want attachment", got)
+				}
+			}
+			if tt.validate != nil {
+				tt.validate(t, rec.Body.String())
+			}
+		})
+	}
+}
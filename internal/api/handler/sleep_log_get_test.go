@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func TestSleepLogHandler_Get(t *testing.T) {
+	userID := uuid.New()
+	logID := uuid.New()
+
+	tests := []struct {
+		name           string
+		userID         string
+		logID          string
+		mockService    *MockSleepLogService
+		wantStatusCode int
+	}{
+		{
+			name:           "found",
+			userID:         userID.String(),
+			logID:          logID.String(),
+			mockService:    &MockSleepLogService{},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "invalid user ID",
+			userID:         "not-a-uuid",
+			logID:          logID.String(),
+			mockService:    &MockSleepLogService{},
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid log ID",
+			userID:         userID.String(),
+			logID:          "not-a-uuid",
+			mockService:    &MockSleepLogService{},
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:   "not found or owned by another user",
+			userID: userID.String(),
+			logID:  logID.String(),
+			mockService: &MockSleepLogService{
+				getFunc: func(ctx context.Context, uid uuid.UUID, lid uuid.UUID) (*domain.SleepLog, error) {
+					return nil, domain.ErrNotFound
+				},
+			},
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewSleepLogHandler(tt.mockService)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/users/"+tt.userID+"/sleep-logs/"+tt.logID, nil)
+			rec := httptest.NewRecorder()
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("userId", tt.userID)
+			rctx.URLParams.Add("logId", tt.logID)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			handler.Get(rec, req)
+
+			if rec.Code != tt.wantStatusCode {
+				t.Fatalf("Get() status = %d, want %d, body: %s", rec.Code, tt.wantStatusCode, rec.Body.String())
+			}
+			if tt.wantStatusCode != http.StatusOK {
+				return
+			}
+
+			if rec.Header().Get("ETag") == "" {
+				t.Error("expected ETag header")
+			}
+			var response domain.SleepLogResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.ID != logID {
+				t.Errorf("ID = %v, want %v", response.ID, logID)
+			}
+		})
+	}
+}
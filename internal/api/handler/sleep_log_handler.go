@@ -3,11 +3,14 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"io"
+	"mime"
 	"net/http"
 
 	"github.com/blaisecz/sleep-tracker/internal/api/validation"
 	"github.com/blaisecz/sleep-tracker/internal/domain"
 	"github.com/blaisecz/sleep-tracker/internal/service"
+	"github.com/blaisecz/sleep-tracker/pkg/mergepatch"
 	"github.com/blaisecz/sleep-tracker/pkg/problem"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -23,7 +26,7 @@ func NewSleepLogHandler(service service.SleepLogService) *SleepLogHandler {
 
 // Create handles POST /v1/users/{userId}/sleep-logs
 // @Summary Record sleep
-// @Description Log a sleep session. Use client_request_id for safe retries (idempotency). Returns 200 if duplicate request, 201 if new.
+// @Description Log a sleep session. Use client_request_id for safe retries (idempotency). Returns 200 if duplicate request, 201 if new. Optional stages describe the hypnogram as contiguous AWAKE/LIGHT/DEEP/REM segments within the session.
 // @Tags sleep-logs
 // @Accept json
 // @Produce json
@@ -33,7 +36,8 @@ func NewSleepLogHandler(service service.SleepLogService) *SleepLogHandler {
 // @Success 200 {object} domain.SleepLogResponse "Existing log returned (idempotent duplicate)"
 // @Failure 400 {object} problem.Problem "Invalid request body or parameters"
 // @Failure 404 {object} problem.Problem "User not found"
-// @Failure 409 {object} problem.Problem "Sleep period overlaps with existing log"
+// @Failure 409 {object} problem.Problem "Sleep period overlaps with existing log, or client_request_id belongs to a deleted log"
+// @Failure 422 {object} problem.Problem "Invalid fields, stages that are not contiguous or leave the sleep period, or unknown factor tags"
 // @Failure 500 {object} problem.Problem "Server error"
 // @Router /users/{userId}/sleep-logs [post]
 func (h *SleepLogHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -66,17 +70,27 @@ func (h *SleepLogHandler) Create(w http.ResponseWriter, r *http.Request) {
 			problem.Conflict("Overlapping sleep period detected").Write(w)
 			return
 		}
+		if errors.Is(err, domain.ErrDuplicateRequest) {
+			problem.Conflict("client_request_id belongs to a deleted sleep log; restore it instead").Write(w)
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidStages) {
+			fieldProblem("stages", err).Write(w)
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidFactors) {
+			fieldProblem("factors", err).Write(w)
+			return
+		}
 		problem.InternalError("Failed to create sleep log").Write(w)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
 	if isExisting {
-		w.WriteHeader(http.StatusOK) // Return 200 for idempotent duplicate
+		writeSleepLog(w, http.StatusOK, log) // Return 200 for idempotent duplicate
 	} else {
-		w.WriteHeader(http.StatusCreated)
+		writeSleepLog(w, http.StatusCreated, log)
 	}
-	json.NewEncoder(w).Encode(log.ToResponse())
 }
 
 // List handles GET /v1/users/{userId}/sleep-logs
@@ -89,6 +103,7 @@ func (h *SleepLogHandler) Create(w http.ResponseWriter, r *http.Request) {
 // @Param to query string false "End of date range (RFC3339, UTC recommended for consistent filtering)" format(date-time) example(2024-01-31T23:59:59Z)
 // @Param limit query integer false "Results per page (1-100)" default(20) minimum(1) maximum(100)
 // @Param cursor query string false "Cursor from previous response's next_cursor"
+// @Param tag query string false "Only logs with a factor of this tag" example(caffeine)
 // @Success 200 {object} domain.SleepLogListResponse "Sleep logs with pagination"
 // @Failure 400 {object} problem.Problem "Invalid query parameters"
 // @Failure 404 {object} problem.Problem "User not found"
@@ -121,19 +136,62 @@ func (h *SleepLogHandler) List(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// Get handles GET /v1/users/{userId}/sleep-logs/{logId}
+// @Summary Get sleep log
+// @Description Fetch a single sleep session. The ETag header carries the log version for use with If-Match.
+// @Tags sleep-logs
+// @Produce json
+// @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
+// @Param logId path string true "Sleep Log UUID" format(uuid) example(660e8400-e29b-41d4-a716-446655440001)
+// @Success 200 {object} domain.SleepLogResponse "Sleep log"
+// @Header 200 {string} ETag "Entity tag of the sleep log representation"
+// @Failure 400 {object} problem.Problem "Invalid parameters"
+// @Failure 404 {object} problem.Problem "User or sleep log not found"
+// @Failure 500 {object} problem.Problem "Server error"
+// @Router /users/{userId}/sleep-logs/{logId} [get]
+func (h *SleepLogHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		problem.BadRequest("Invalid user ID format").Write(w)
+		return
+	}
+
+	logID, err := uuid.Parse(chi.URLParam(r, "logId"))
+	if err != nil {
+		problem.BadRequest("Invalid sleep log ID format").Write(w)
+		return
+	}
+
+	log, err := h.service.Get(r.Context(), userID, logID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			problem.NotFound("Sleep log not found").Write(w)
+			return
+		}
+		problem.InternalError("Failed to get sleep log").Write(w)
+		return
+	}
+
+	writeSleepLog(w, http.StatusOK, log)
+}
+
 // Update handles PUT /v1/users/{userId}/sleep-logs/{logId}
 // @Summary Update sleep log
-// @Description Update an existing sleep session. All fields are optional - only provided fields will be updated.
+// @Description Update an existing sleep session. All fields are optional - only provided fields will be updated. Sending stages replaces all stage segments; an empty array removes them. Send the ETag from a previous response in If-Match to avoid overwriting concurrent edits.
 // @Tags sleep-logs
 // @Accept json
 // @Produce json
 // @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
 // @Param logId path string true "Sleep Log UUID" format(uuid) example(660e8400-e29b-41d4-a716-446655440001)
+// @Param If-Match header string false "ETag of the version being updated" example("3")
 // @Param request body domain.UpdateSleepLogRequest true "Fields to update"
 // @Success 200 {object} domain.SleepLogResponse "Updated sleep log"
+// @Header 200 {string} ETag "Entity tag of the updated version"
 // @Failure 400 {object} problem.Problem "Invalid request body or parameters"
 // @Failure 404 {object} problem.Problem "User or sleep log not found"
 // @Failure 409 {object} problem.Problem "Sleep period overlaps with existing log"
+// @Failure 412 {object} problem.Problem "Sleep log was modified since the given ETag"
+// @Failure 422 {object} problem.Problem "Invalid fields, stages that are not contiguous or leave the sleep period, or unknown factor tags"
 // @Failure 500 {object} problem.Problem "Server error"
 // @Router /users/{userId}/sleep-logs/{logId} [put]
 func (h *SleepLogHandler) Update(w http.ResponseWriter, r *http.Request) {
@@ -149,6 +207,12 @@ func (h *SleepLogHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ifMatch, err := parseIfMatch(r)
+	if err != nil {
+		problem.BadRequest(err.Error()).Write(w)
+		return
+	}
+
 	var req domain.UpdateSleepLogRequest
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields()
@@ -162,12 +226,16 @@ func (h *SleepLogHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log, err := h.service.Update(r.Context(), userID, logID, &req)
+	log, err := h.service.Update(r.Context(), userID, logID, &req, ifMatch)
 	if err != nil {
 		if errors.Is(err, domain.ErrNotFound) {
 			problem.NotFound("Sleep log not found").Write(w)
 			return
 		}
+		if errors.Is(err, domain.ErrPreconditionFailed) {
+			problem.PreconditionFailed("Sleep log has been modified; fetch the latest version and retry").Write(w)
+			return
+		}
 		if errors.Is(err, domain.ErrOverlappingSleep) {
 			problem.Conflict("Overlapping sleep period detected").Write(w)
 			return
@@ -176,10 +244,245 @@ func (h *SleepLogHandler) Update(w http.ResponseWriter, r *http.Request) {
 			problem.BadRequest("End time must be after start time").Write(w)
 			return
 		}
+		if errors.Is(err, domain.ErrInvalidStages) {
+			fieldProblem("stages", err).Write(w)
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidFactors) {
+			fieldProblem("factors", err).Write(w)
+			return
+		}
 		problem.InternalError("Failed to update sleep log").Write(w)
 		return
 	}
 
+	writeSleepLog(w, http.StatusOK, log)
+}
+
+// Delete handles DELETE /v1/users/{userId}/sleep-logs/{logId}
+// @Summary Delete sleep log
+// @Description Soft-delete a sleep session. Deleted logs are excluded from listings, metrics and overlap checks, and can be restored until they are purged by the retention job. Send the ETag from a previous response in If-Match to avoid deleting a concurrently edited log. Deleting bumps the version; the response carries the new ETag, which a restore can send in If-Match.
+// @Tags sleep-logs
+// @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
+// @Param logId path string true "Sleep Log UUID" format(uuid) example(660e8400-e29b-41d4-a716-446655440001)
+// @Param If-Match header string false "ETag of the version being deleted" example("3")
+// @Success 204 "Sleep log deleted"
+// @Header 204 {string} ETag "Entity tag of the deleted version"
+// @Failure 400 {object} problem.Problem "Invalid parameters"
+// @Failure 404 {object} problem.Problem "User or sleep log not found"
+// @Failure 412 {object} problem.Problem "Sleep log was modified since the given ETag"
+// @Failure 500 {object} problem.Problem "Server error"
+// @Router /users/{userId}/sleep-logs/{logId} [delete]
+func (h *SleepLogHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		problem.BadRequest("Invalid user ID format").Write(w)
+		return
+	}
+
+	logID, err := uuid.Parse(chi.URLParam(r, "logId"))
+	if err != nil {
+		problem.BadRequest("Invalid sleep log ID format").Write(w)
+		return
+	}
+
+	ifMatch, err := parseIfMatch(r)
+	if err != nil {
+		problem.BadRequest(err.Error()).Write(w)
+		return
+	}
+
+	log, err := h.service.Delete(r.Context(), userID, logID, ifMatch)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			problem.NotFound("Sleep log not found").Write(w)
+			return
+		}
+		if errors.Is(err, domain.ErrPreconditionFailed) {
+			problem.PreconditionFailed("Sleep log has been modified; fetch the latest version and retry").Write(w)
+			return
+		}
+		problem.InternalError("Failed to delete sleep log").Write(w)
+		return
+	}
+
+	w.Header().Set("ETag", versionETag(log.Version))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Restore handles POST /v1/users/{userId}/sleep-logs/{logId}/restore
+// @Summary Restore sleep log
+// @Description Restore a soft-deleted sleep session. Fails if another log now occupies the same period. Send the ETag returned by the delete in If-Match to avoid restoring a log that was deleted again meanwhile.
+// @Tags sleep-logs
+// @Produce json
+// @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
+// @Param logId path string true "Sleep Log UUID" format(uuid) example(660e8400-e29b-41d4-a716-446655440001)
+// @Param If-Match header string false "ETag returned when the log was deleted" example("4")
+// @Success 200 {object} domain.SleepLogResponse "Restored sleep log"
+// @Header 200 {string} ETag "Entity tag of the restored version"
+// @Failure 400 {object} problem.Problem "Invalid parameters"
+// @Failure 404 {object} problem.Problem "User or deleted sleep log not found"
+// @Failure 409 {object} problem.Problem "Sleep period overlaps with existing log"
+// @Failure 412 {object} problem.Problem "Sleep log was modified since the given ETag"
+// @Failure 500 {object} problem.Problem "Server error"
+// @Router /users/{userId}/sleep-logs/{logId}/restore [post]
+func (h *SleepLogHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		problem.BadRequest("Invalid user ID format").Write(w)
+		return
+	}
+
+	logID, err := uuid.Parse(chi.URLParam(r, "logId"))
+	if err != nil {
+		problem.BadRequest("Invalid sleep log ID format").Write(w)
+		return
+	}
+
+	ifMatch, err := parseIfMatch(r)
+	if err != nil {
+		problem.BadRequest(err.Error()).Write(w)
+		return
+	}
+
+	log, err := h.service.Restore(r.Context(), userID, logID, ifMatch)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			problem.NotFound("Deleted sleep log not found").Write(w)
+			return
+		}
+		if errors.Is(err, domain.ErrPreconditionFailed) {
+			problem.PreconditionFailed("Sleep log has been modified; fetch the latest version and retry").Write(w)
+			return
+		}
+		if errors.Is(err, domain.ErrOverlappingSleep) {
+			problem.Conflict("Overlapping sleep period detected").Write(w)
+			return
+		}
+		problem.InternalError("Failed to restore sleep log").Write(w)
+		return
+	}
+
+	writeSleepLog(w, http.StatusOK, log)
+}
+
+// maxPatchBodyBytes bounds the size of a merge patch document.
+const maxPatchBodyBytes = 1 << 20
+
+// Patch handles PATCH /v1/users/{userId}/sleep-logs/{logId}
+// @Summary Patch sleep log
+// @Description Apply an RFC 7396 JSON merge patch to a sleep session. Members set to null are removed: a null local_timezone resets it to the user's timezone and a null client_request_id clears it. The patched log goes through the same validation and overlap checks as a new one.
+// @Tags sleep-logs
+// @Accept application/merge-patch+json
+// @Produce json
+// @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
+// @Param logId path string true "Sleep Log UUID" format(uuid) example(660e8400-e29b-41d4-a716-446655440001)
+// @Param If-Match header string false "ETag of the version being patched" example("3")
+// @Param request body domain.CreateSleepLogRequest true "Merge patch document (any subset of fields, null removes)"
+// @Success 200 {object} domain.SleepLogResponse "Patched sleep log"
+// @Header 200 {string} ETag "Entity tag of the patched version"
+// @Failure 400 {object} problem.Problem "Invalid patch document or parameters"
+// @Failure 404 {object} problem.Problem "User or sleep log not found"
+// @Failure 409 {object} problem.Problem "Sleep period overlaps with existing log, or client_request_id already in use"
+// @Failure 412 {object} problem.Problem "Sleep log was modified since the given ETag"
+// @Failure 413 {object} problem.Problem "Merge patch document is larger than 1 MiB"
+// @Failure 415 {object} problem.Problem "Content-Type is not application/merge-patch+json"
+// @Failure 422 {object} problem.Problem "Patched sleep log contains invalid fields"
+// @Failure 500 {object} problem.Problem "Server error"
+// @Router /users/{userId}/sleep-logs/{logId} [patch]
+func (h *SleepLogHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		problem.BadRequest("Invalid user ID format").Write(w)
+		return
+	}
+
+	logID, err := uuid.Parse(chi.URLParam(r, "logId"))
+	if err != nil {
+		problem.BadRequest("Invalid sleep log ID format").Write(w)
+		return
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != mergepatch.ContentType {
+		problem.UnsupportedMediaType("Content-Type must be " + mergepatch.ContentType).Write(w)
+		return
+	}
+
+	ifMatch, err := parseIfMatch(r)
+	if err != nil {
+		problem.BadRequest(err.Error()).Write(w)
+		return
+	}
+
+	patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchBodyBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			problem.PayloadTooLarge("Merge patch document must not exceed 1 MiB").Write(w)
+			return
+		}
+		problem.BadRequest("Failed to read request body").Write(w)
+		return
+	}
+
+	log, err := h.service.Patch(r.Context(), userID, logID, patch, ifMatch)
+	if err != nil {
+		var validationErr *service.ValidationError
+		if errors.As(err, &validationErr) {
+			problem.ValidationError("Patched sleep log contains invalid fields", validation.FieldErrors(validationErr.Fields)).Write(w)
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidPatch) {
+			problem.BadRequest("Invalid JSON merge patch").Write(w)
+			return
+		}
+		if errors.Is(err, domain.ErrNotFound) {
+			problem.NotFound("Sleep log not found").Write(w)
+			return
+		}
+		if errors.Is(err, domain.ErrPreconditionFailed) {
+			problem.PreconditionFailed("Sleep log has been modified; fetch the latest version and retry").Write(w)
+			return
+		}
+		if errors.Is(err, domain.ErrOverlappingSleep) {
+			problem.Conflict("Overlapping sleep period detected").Write(w)
+			return
+		}
+		if errors.Is(err, domain.ErrDuplicateRequest) {
+			problem.Conflict("client_request_id is already used by another sleep log").Write(w)
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidInput) {
+			problem.BadRequest("End time must be after start time").Write(w)
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidStages) {
+			fieldProblem("stages", err).Write(w)
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidFactors) {
+			fieldProblem("factors", err).Write(w)
+			return
+		}
+		problem.InternalError("Failed to patch sleep log").Write(w)
+		return
+	}
+
+	writeSleepLog(w, http.StatusOK, log)
+}
+
+// fieldProblem reports a domain validation error as a validation error on a single field.
+func fieldProblem(field string, err error) *problem.Problem {
+	return problem.ValidationError("Request body contains invalid fields", []problem.FieldError{{
+		Field:   field,
+		Message: err.Error(),
+	}})
+}
+
+// writeSleepLog writes a sleep log representation with its version as the ETag.
+func writeSleepLog(w http.ResponseWriter, status int, log *domain.SleepLog) {
+	w.Header().Set("ETag", versionETag(log.Version))
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(log.ToResponse())
 }
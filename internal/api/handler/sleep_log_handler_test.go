@@ -108,6 +108,42 @@ func TestSleepLogHandler_Create(t *testing.T) {
 			},
 			wantStatusCode: http.StatusNotFound,
 		},
+		{
+			name:           "invalid stage type",
+			userID:         userID.String(),
+			body:           `{"start_at": "2024-01-15T23:00:00Z", "end_at": "2024-01-16T07:00:00Z", "quality": 8, "type": "CORE", "stages": [{"stage": "DOZING", "start_at": "2024-01-15T23:00:00Z", "end_at": "2024-01-16T01:00:00Z"}]}`,
+			mockService:    &MockSleepLogService{},
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "stages outside the sleep period",
+			userID: userID.String(),
+			body:   `{"start_at": "2024-01-15T23:00:00Z", "end_at": "2024-01-16T07:00:00Z", "quality": 8, "type": "CORE", "stages": [{"stage": "DEEP", "start_at": "2024-01-16T06:00:00Z", "end_at": "2024-01-16T08:00:00Z"}]}`,
+			mockService: &MockSleepLogService{
+				createFunc: func(ctx context.Context, uid uuid.UUID, req *domain.CreateSleepLogRequest) (*domain.SleepLog, bool, error) {
+					return nil, false, domain.ErrInvalidStages
+				},
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "unknown factor tag",
+			userID: userID.String(),
+			body:   `{"start_at": "2024-01-15T23:00:00Z", "end_at": "2024-01-16T07:00:00Z", "quality": 8, "type": "CORE", "factors": [{"tag": "chamomile"}]}`,
+			mockService: &MockSleepLogService{
+				createFunc: func(ctx context.Context, uid uuid.UUID, req *domain.CreateSleepLogRequest) (*domain.SleepLog, bool, error) {
+					return nil, false, domain.ErrInvalidFactors
+				},
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "negative factor amount",
+			userID:         userID.String(),
+			body:           `{"start_at": "2024-01-15T23:00:00Z", "end_at": "2024-01-16T07:00:00Z", "quality": 8, "type": "CORE", "factors": [{"tag": "caffeine", "amount": -1}]}`,
+			mockService:    &MockSleepLogService{},
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
 		{
 			name:   "overlapping sleep",
 			userID: userID.String(),
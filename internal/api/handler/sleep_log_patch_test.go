@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/service"
+	"github.com/blaisecz/sleep-tracker/internal/validation"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func TestSleepLogHandler_Patch(t *testing.T) {
+	userID := uuid.New()
+	logID := uuid.New()
+
+	tests := []struct {
+		name           string
+		contentType    string
+		ifMatch        string
+		body           string
+		patchErr       error
+		wantStatusCode int
+		wantIfMatch    int
+	}{
+		{
+			name:           "patched",
+			contentType:    "application/merge-patch+json",
+			ifMatch:        `"3"`,
+			body:           `{"quality": 9}`,
+			wantStatusCode: http.StatusOK,
+			wantIfMatch:    3,
+		},
+		{
+			name:           "content type with charset",
+			contentType:    "application/merge-patch+json; charset=utf-8",
+			body:           `{"client_request_id": null}`,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "plain JSON content type",
+			contentType:    "application/json",
+			body:           `{"quality": 9}`,
+			wantStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:           "malformed If-Match",
+			contentType:    "application/merge-patch+json",
+			ifMatch:        "abc",
+			body:           `{"quality": 9}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "body too large",
+			contentType:    "application/merge-patch+json",
+			body:           `{"client_request_id": "` + strings.Repeat("a", maxPatchBodyBytes) + `"}`,
+			wantStatusCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "invalid patch",
+			contentType:    "application/merge-patch+json",
+			body:           `{invalid}`,
+			patchErr:       domain.ErrInvalidPatch,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "patched log fails validation",
+			contentType:    "application/merge-patch+json",
+			body:           `{"quality": null}`,
+			patchErr:       &service.ValidationError{Fields: []validation.FieldError{{Field: "quality", Message: "is required"}}},
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "log not found",
+			contentType:    "application/merge-patch+json",
+			body:           `{"quality": 9}`,
+			patchErr:       domain.ErrNotFound,
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "stale If-Match",
+			contentType:    "application/merge-patch+json",
+			ifMatch:        `"2"`,
+			body:           `{"quality": 9}`,
+			patchErr:       domain.ErrPreconditionFailed,
+			wantStatusCode: http.StatusPreconditionFailed,
+			wantIfMatch:    2,
+		},
+		{
+			name:           "overlap",
+			contentType:    "application/merge-patch+json",
+			body:           `{"end_at": "2024-01-16T09:00:00Z"}`,
+			patchErr:       domain.ErrOverlappingSleep,
+			wantStatusCode: http.StatusConflict,
+		},
+		{
+			name:           "stages outside the period",
+			contentType:    "application/merge-patch+json",
+			body:           `{"end_at": "2024-01-16T05:00:00Z"}`,
+			patchErr:       domain.ErrInvalidStages,
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPatch []byte
+			gotIfMatch := -1
+			mockService := &MockSleepLogService{
+				patchFunc: func(ctx context.Context, uid uuid.UUID, lid uuid.UUID, patch []byte, ifMatch int) (*domain.SleepLog, error) {
+					gotPatch, gotIfMatch = patch, ifMatch
+					if tt.patchErr != nil {
+						return nil, tt.patchErr
+					}
+					return &domain.SleepLog{ID: lid, UserID: uid, StartAt: time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC), EndAt: time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC), Quality: 9, Type: domain.SleepTypeCore, LocalTimezone: "UTC", Version: 4}, nil
+				},
+			}
+			handler := NewSleepLogHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodPatch, "/v1/users/"+userID.String()+"/sleep-logs/"+logID.String(), bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("userId", userID.String())
+			rctx.URLParams.Add("logId", logID.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			handler.Patch(rec, req)
+
+			if rec.Code != tt.wantStatusCode {
+				t.Fatalf("Patch() status = %d, want %d, body: %s", rec.Code, tt.wantStatusCode, rec.Body.String())
+			}
+			if gotIfMatch >= 0 {
+				if string(gotPatch) != tt.body {
+					t.Errorf("patch = %s, want %s", gotPatch, tt.body)
+				}
+				if gotIfMatch != tt.wantIfMatch {
+					t.Errorf("ifMatch = %d, want %d", gotIfMatch, tt.wantIfMatch)
+				}
+			}
+			if rec.Code == http.StatusOK {
+				var response domain.SleepLogResponse
+				if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if got := rec.Header().Get("ETag"); got != `"4"` {
+					t.Errorf("ETag = %q, want \"4\"", got)
+				}
+			}
+		})
+	}
+}
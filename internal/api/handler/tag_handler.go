@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/blaisecz/sleep-tracker/internal/api/validation"
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/service"
+	"github.com/blaisecz/sleep-tracker/pkg/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type TagHandler struct {
+	service service.TagService
+}
+
+func NewTagHandler(service service.TagService) *TagHandler {
+	return &TagHandler{service: service}
+}
+
+// List handles GET /v1/users/{userId}/tags
+// @Summary List factor tags
+// @Description List the tags that can be attached to sleep logs as factors: the predefined catalogue (caffeine, alcohol, exercise, illness, ...) followed by the user's own tags.
+// @Tags tags
+// @Produce json
+// @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
+// @Success 200 {object} domain.TagListResponse "Available tags"
+// @Failure 400 {object} problem.Problem "Invalid UUID format"
+// @Failure 404 {object} problem.Problem "User not found"
+// @Failure 500 {object} problem.Problem "Server error"
+// @Router /users/{userId}/tags [get]
+func (h *TagHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		problem.BadRequest("Invalid user ID format").Write(w)
+		return
+	}
+
+	tags, err := h.service.List(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			problem.NotFound("User not found").Write(w)
+			return
+		}
+		problem.InternalError("Failed to list tags").Write(w)
+		return
+	}
+
+	response := domain.TagListResponse{Data: make([]domain.TagResponse, len(tags))}
+	for i := range tags {
+		response.Data[i] = tags[i].ToResponse()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Create handles POST /v1/users/{userId}/tags
+// @Summary Create factor tag
+// @Description Define a custom tag for factors missing from the catalogue. The name must not be used by the catalogue or another of the user's tags.
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
+// @Param request body domain.CreateTagRequest true "Tag data"
+// @Success 201 {object} domain.TagResponse "Tag created"
+// @Failure 400 {object} problem.Problem "Invalid request body or parameters"
+// @Failure 404 {object} problem.Problem "User not found"
+// @Failure 409 {object} problem.Problem "Tag name already exists"
+// @Failure 422 {object} problem.Problem "Invalid fields"
+// @Failure 500 {object} problem.Problem "Server error"
+// @Router /users/{userId}/tags [post]
+func (h *TagHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		problem.BadRequest("Invalid user ID format").Write(w)
+		return
+	}
+
+	var req domain.CreateTagRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		problem.BadRequest("Invalid JSON body").Write(w)
+		return
+	}
+
+	if fieldErrors := validation.Validate(req); fieldErrors != nil {
+		problem.ValidationError("Request body contains invalid fields", fieldErrors).Write(w)
+		return
+	}
+
+	tag, err := h.service.Create(r.Context(), userID, &req)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			problem.NotFound("User not found").Write(w)
+			return
+		}
+		if errors.Is(err, domain.ErrConflict) {
+			problem.Conflict("A tag with this name already exists").Write(w)
+			return
+		}
+		problem.InternalError("Failed to create tag").Write(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tag.ToResponse())
+}
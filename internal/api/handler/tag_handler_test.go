@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// MockTagService is a mock implementation of TagService
+type MockTagService struct {
+	listFunc   func(ctx context.Context, userID uuid.UUID) ([]domain.Tag, error)
+	createFunc func(ctx context.Context, userID uuid.UUID, req *domain.CreateTagRequest) (*domain.Tag, error)
+}
+
+func (m *MockTagService) List(ctx context.Context, userID uuid.UUID) ([]domain.Tag, error) {
+	if m.listFunc != nil {
+		return m.listFunc(ctx, userID)
+	}
+	return domain.CatalogueTags(), nil
+}
+
+func (m *MockTagService) Create(ctx context.Context, userID uuid.UUID, req *domain.CreateTagRequest) (*domain.Tag, error) {
+	if m.createFunc != nil {
+		return m.createFunc(ctx, userID, req)
+	}
+	return &domain.Tag{ID: uuid.New(), UserID: &userID, Name: req.Name, Label: req.Label, Category: req.Category}, nil
+}
+
+func TestTagHandler_List(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name           string
+		userID         string
+		mockService    *MockTagService
+		wantStatusCode int
+		wantCount      int
+	}{
+		{
+			name:           "catalogue and user tags",
+			userID:         userID.String(),
+			mockService:    &MockTagService{},
+			wantStatusCode: http.StatusOK,
+			wantCount:      len(domain.CatalogueTags()),
+		},
+		{
+			name:   "user not found",
+			userID: userID.String(),
+			mockService: &MockTagService{
+				listFunc: func(ctx context.Context, userID uuid.UUID) ([]domain.Tag, error) {
+					return nil, domain.ErrNotFound
+				},
+			},
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "invalid UUID",
+			userID:         "not-a-uuid",
+			mockService:    &MockTagService{},
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewTagHandler(tt.mockService)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/users/"+tt.userID+"/tags", nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("userId", tt.userID)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			rec := httptest.NewRecorder()
+
+			handler.List(rec, req)
+
+			if rec.Code != tt.wantStatusCode {
+				t.Fatalf("List() status = %d, want %d, body: %s", rec.Code, tt.wantStatusCode, rec.Body.String())
+			}
+			if tt.wantStatusCode != http.StatusOK {
+				return
+			}
+			var response domain.TagListResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(response.Data) != tt.wantCount {
+				t.Errorf("List() returned %d tags, want %d", len(response.Data), tt.wantCount)
+			}
+			if !response.Data[0].Predefined {
+				t.Error("catalogue tags should be marked predefined")
+			}
+		})
+	}
+}
+
+func TestTagHandler_Create(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name           string
+		body           string
+		mockService    *MockTagService
+		wantStatusCode int
+	}{
+		{
+			name:           "valid tag",
+			body:           `{"name": "melatonin", "label": "Melatonin", "category": "SUBSTANCE", "unit": "mg"}`,
+			mockService:    &MockTagService{},
+			wantStatusCode: http.StatusCreated,
+		},
+		{
+			name:           "name only",
+			body:           `{"name": "cold_room"}`,
+			mockService:    &MockTagService{},
+			wantStatusCode: http.StatusCreated,
+		},
+		{
+			name:           "name is not a slug",
+			body:           `{"name": "Late Coffee"}`,
+			mockService:    &MockTagService{},
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "invalid category",
+			body:           `{"name": "sauna", "category": "SPA"}`,
+			mockService:    &MockTagService{},
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "unknown field",
+			body:           `{"name": "sauna", "color": "red"}`,
+			mockService:    &MockTagService{},
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "name already used",
+			body: `{"name": "caffeine"}`,
+			mockService: &MockTagService{
+				createFunc: func(ctx context.Context, userID uuid.UUID, req *domain.CreateTagRequest) (*domain.Tag, error) {
+					return nil, domain.ErrConflict
+				},
+			},
+			wantStatusCode: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewTagHandler(tt.mockService)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/users/"+userID.String()+"/tags", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("userId", userID.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			rec := httptest.NewRecorder()
+
+			handler.Create(rec, req)
+
+			if rec.Code != tt.wantStatusCode {
+				t.Errorf("Create() status = %d, want %d, body: %s", rec.Code, tt.wantStatusCode, rec.Body.String())
+			}
+		})
+	}
+}
@@ -88,3 +88,57 @@ func (h *UserHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(user.ToResponse())
 }
+
+// UpdateWorkdays handles PUT /v1/users/{userId}/workdays
+// @Summary Update workday calendar
+// @Description Replace the days of the week the user works. The other days are free days, used to compare workday and free-day sleep in the chronotype analysis.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param userId path string true "User UUID" format(uuid) example(550e8400-e29b-41d4-a716-446655440000)
+// @Param request body domain.UpdateWorkdaysRequest true "Workdays" example({"workdays": ["MON", "TUE", "WED", "THU"]})
+// @Success 200 {object} domain.UserResponse "Updated user"
+// @Failure 400 {object} problem.Problem "Invalid request"
+// @Failure 404 {object} problem.Problem "User not found"
+// @Failure 422 {object} problem.Problem "Validation error"
+// @Failure 500 {object} problem.Problem "Server error"
+// @Router /users/{userId}/workdays [put]
+func (h *UserHandler) UpdateWorkdays(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		problem.BadRequest("Invalid user ID format").Write(w)
+		return
+	}
+
+	var req domain.UpdateWorkdaysRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		problem.BadRequest("Invalid JSON body").Write(w)
+		return
+	}
+	if req.Workdays == nil {
+		problem.ValidationError("Request body contains invalid fields", []problem.FieldError{
+			{Field: "workdays", Message: "is required"},
+		}).Write(w)
+		return
+	}
+
+	if fieldErrors := validation.Validate(req); fieldErrors != nil {
+		problem.ValidationError("Request body contains invalid fields", fieldErrors).Write(w)
+		return
+	}
+
+	user, err := h.service.UpdateWorkdays(r.Context(), userID, &req)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			problem.NotFound("User not found").Write(w)
+			return
+		}
+		problem.InternalError("Failed to update workdays").Write(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user.ToResponse())
+}
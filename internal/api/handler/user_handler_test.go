@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/blaisecz/sleep-tracker/internal/domain"
@@ -33,6 +34,15 @@ func (m *MockUserService) GetByID(ctx context.Context, id uuid.UUID) (*domain.Us
 	return nil, domain.ErrNotFound
 }
 
+func (m *MockUserService) UpdateWorkdays(ctx context.Context, id uuid.UUID, req *domain.UpdateWorkdaysRequest) (*domain.User, error) {
+	user, err := m.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	user.SetWorkdays(req.Workdays)
+	return user, nil
+}
+
 func TestUserHandler_Create(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -163,3 +173,90 @@ func TestUserHandler_GetByID(t *testing.T) {
 		})
 	}
 }
+
+func TestUserHandler_UpdateWorkdays(t *testing.T) {
+	userID := uuid.New()
+	mockService := &MockUserService{
+		getByIDFunc: func(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+			if id == userID {
+				return &domain.User{ID: userID, Timezone: "UTC"}, nil
+			}
+			return nil, domain.ErrNotFound
+		},
+	}
+
+	tests := []struct {
+		name           string
+		userID         string
+		body           string
+		wantStatusCode int
+		wantWorkdays   []string
+	}{
+		{
+			name:           "four-day week",
+			userID:         userID.String(),
+			body:           `{"workdays": ["THU", "MON", "TUE", "WED"]}`,
+			wantStatusCode: http.StatusOK,
+			wantWorkdays:   []string{"MON", "TUE", "WED", "THU"},
+		},
+		{
+			name:           "no workdays",
+			userID:         userID.String(),
+			body:           `{"workdays": []}`,
+			wantStatusCode: http.StatusOK,
+			wantWorkdays:   []string{},
+		},
+		{
+			name:           "missing workdays",
+			userID:         userID.String(),
+			body:           `{}`,
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "unknown day",
+			userID:         userID.String(),
+			body:           `{"workdays": ["MONDAY"]}`,
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "repeated day",
+			userID:         userID.String(),
+			body:           `{"workdays": ["MON", "MON"]}`,
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "non-existing user",
+			userID:         uuid.New().String(),
+			body:           `{"workdays": ["MON"]}`,
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewUserHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodPut, "/v1/users/"+tt.userID+"/workdays", bytes.NewBufferString(tt.body))
+			rec := httptest.NewRecorder()
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("userId", tt.userID)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			handler.UpdateWorkdays(rec, req)
+
+			if rec.Code != tt.wantStatusCode {
+				t.Fatalf("UpdateWorkdays() status = %d, want %d, body: %s", rec.Code, tt.wantStatusCode, rec.Body.String())
+			}
+			if tt.wantWorkdays != nil {
+				var response domain.UserResponse
+				if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if strings.Join(response.Workdays, ",") != strings.Join(tt.wantWorkdays, ",") {
+					t.Errorf("workdays = %v, want %v", response.Workdays, tt.wantWorkdays)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/blaisecz/sleep-tracker/pkg/problem"
+)
+
+// AdminKeyHeader is the request header carrying the admin API key.
+const AdminKeyHeader = "X-Admin-Key"
+
+// AdminKey only lets through requests carrying apiKey in the X-Admin-Key
+// header. With an empty apiKey the admin API is disabled.
+func AdminKey(apiKey string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if apiKey == "" {
+				problem.Forbidden("Admin API is disabled").Write(w)
+				return
+			}
+			if subtle.ConstantTimeCompare([]byte(r.Header.Get(AdminKeyHeader)), []byte(apiKey)) != 1 {
+				problem.Unauthorized("Missing or invalid admin API key").Write(w)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -15,13 +15,25 @@ type Router struct {
 	userHandler     *handler.UserHandler
 	sleepLogHandler *handler.SleepLogHandler
 	insightsHandler *handler.InsightsHandler
+	importHandler   *handler.ImportHandler
+	tagHandler      *handler.TagHandler
+	goalHandler     *handler.SleepGoalHandler
+	adminHandler    *handler.AdminHandler
+	cohortHandler   *handler.CohortHandler
+	adminAPIKey     string
 }
 
-func NewRouter(userHandler *handler.UserHandler, sleepLogHandler *handler.SleepLogHandler, insightsHandler *handler.InsightsHandler) *Router {
+func NewRouter(userHandler *handler.UserHandler, sleepLogHandler *handler.SleepLogHandler, insightsHandler *handler.InsightsHandler, importHandler *handler.ImportHandler, tagHandler *handler.TagHandler, goalHandler *handler.SleepGoalHandler, adminHandler *handler.AdminHandler, cohortHandler *handler.CohortHandler, adminAPIKey string) *Router {
 	return &Router{
 		userHandler:     userHandler,
 		sleepLogHandler: sleepLogHandler,
 		insightsHandler: insightsHandler,
+		importHandler:   importHandler,
+		tagHandler:      tagHandler,
+		goalHandler:     goalHandler,
+		adminHandler:    adminHandler,
+		cohortHandler:   cohortHandler,
+		adminAPIKey:     adminAPIKey,
 	}
 }
 
@@ -53,22 +65,48 @@ func (rt *Router) Setup() http.Handler {
 		r.Route("/users", func(r chi.Router) {
 			r.Post("/", rt.userHandler.Create)
 			r.Get("/{userId}", rt.userHandler.GetByID)
+			r.Put("/{userId}/workdays", rt.userHandler.UpdateWorkdays)
+			r.Post("/{userId}/sleep-logs:batch", rt.sleepLogHandler.CreateBatch)
+			r.Get("/{userId}/tags", rt.tagHandler.List)
+			r.Post("/{userId}/tags", rt.tagHandler.Create)
+			r.Get("/{userId}/goals", rt.goalHandler.Get)
+			r.Put("/{userId}/goals", rt.goalHandler.Put)
+			r.Delete("/{userId}/goals", rt.goalHandler.Delete)
+			r.Get("/{userId}/goals/history", rt.goalHandler.History)
 
 			// Sleep logs (nested under users)
 			r.Route("/{userId}/sleep-logs", func(r chi.Router) {
 				r.Post("/", rt.sleepLogHandler.Create)
 				r.Get("/", rt.sleepLogHandler.List)
+				r.Get("/export", rt.sleepLogHandler.Export)
+				r.Post("/import", rt.importHandler.Import)
+				r.Get("/{logId}", rt.sleepLogHandler.Get)
 				r.Put("/{logId}", rt.sleepLogHandler.Update)
+				r.Patch("/{logId}", rt.sleepLogHandler.Patch)
+				r.Delete("/{logId}", rt.sleepLogHandler.Delete)
+				r.Post("/{logId}/restore", rt.sleepLogHandler.Restore)
 			})
 
 			// Sleep insights (nested under users)
 			r.Route("/{userId}/sleep", func(r chi.Router) {
 				r.Get("/chronotype", rt.insightsHandler.GetChronotype)
 				r.Get("/metrics", rt.insightsHandler.GetMetrics)
+				r.Get("/trends", rt.insightsHandler.GetTrends)
+				r.Get("/factors/impact", rt.insightsHandler.GetFactorImpact)
+				r.Get("/anomalies", rt.insightsHandler.GetAnomalies)
+				r.Get("/debt", rt.insightsHandler.GetSleepDebt)
 				r.Get("/insights", rt.insightsHandler.GetInsights)
 				r.Post("/insights/feedback", rt.insightsHandler.PostFeedback)
 			})
 		})
+
+		// Admin (requires the admin API key)
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(middleware.AdminKey(rt.adminAPIKey))
+			r.Post("/users/{userId}/sleep/scores/compare", rt.adminHandler.CompareScoringModels)
+			r.Get("/users/{userId}/sleep/cohort-ranks", rt.cohortHandler.Ranks)
+			r.Get("/cohorts/metrics", rt.cohortHandler.Metrics)
+		})
 	})
 
 	return r
@@ -6,75 +6,59 @@ import (
 	"time"
 
 	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/validation"
 	"github.com/blaisecz/sleep-tracker/pkg/problem"
-	"github.com/go-playground/validator/v10"
 )
 
-var validate *validator.Validate
-
-func init() {
-	validate = validator.New()
-
-	// Register custom timezone validator
-	validate.RegisterValidation("timezone", func(fl validator.FieldLevel) bool {
-		tz := fl.Field().String()
-		_, err := time.LoadLocation(tz)
-		return err == nil
-	})
-}
-
 // Validate validates a struct and returns field errors
 func Validate(s interface{}) []problem.FieldError {
-	err := validate.Struct(s)
-	if err == nil {
+	return FieldErrors(validation.Validate(s))
+}
+
+// FieldErrors converts validation field errors to their problem+json form.
+func FieldErrors(errs []validation.FieldError) []problem.FieldError {
+	if errs == nil {
 		return nil
 	}
-
-	var fieldErrors []problem.FieldError
-	for _, err := range err.(validator.ValidationErrors) {
-		fieldErrors = append(fieldErrors, problem.FieldError{
-			Field:   toSnakeCase(err.Field()),
-			Message: getValidationMessage(err),
-		})
+	fieldErrors := make([]problem.FieldError, len(errs))
+	for i, err := range errs {
+		fieldErrors[i] = problem.FieldError{Field: err.Field, Message: err.Message}
 	}
 	return fieldErrors
 }
 
-func getValidationMessage(err validator.FieldError) string {
-	switch err.Tag() {
-	case "required":
-		return "is required"
-	case "min":
-		return "must be at least " + err.Param()
-	case "max":
-		return "must be at most " + err.Param()
-	case "oneof":
-		return "must be one of: " + err.Param()
-	case "gtfield":
-		return "must be greater than " + toSnakeCase(err.Param())
-	case "timezone":
-		return "must be a valid IANA timezone"
-	default:
-		return "is invalid"
-	}
-}
+func ParseSleepLogFilter(r *http.Request) (domain.SleepLogFilter, []problem.FieldError) {
+	filter, fieldErrors := ParseTimeRange(r)
 
-func toSnakeCase(s string) string {
-	var result []byte
-	for i, c := range s {
-		if c >= 'A' && c <= 'Z' {
-			if i > 0 {
-				result = append(result, '_')
-			}
-			result = append(result, byte(c+'a'-'A'))
+	// Parse 'limit' parameter
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			fieldErrors = append(fieldErrors, problem.FieldError{
+				Field:   "limit",
+				Message: "must be a positive integer",
+			})
 		} else {
-			result = append(result, byte(c))
+			filter.Limit = limit
 		}
 	}
-	return string(result)
+
+	// Parse 'cursor' parameter
+	filter.Cursor = r.URL.Query().Get("cursor")
+
+	// Parse 'tag' parameter
+	filter.Tag = r.URL.Query().Get("tag")
+
+	if len(fieldErrors) > 0 {
+		return filter, fieldErrors
+	}
+
+	return filter, nil
 }
 
-func ParseSleepLogFilter(r *http.Request) (domain.SleepLogFilter, []problem.FieldError) {
+// ParseTimeRange parses the optional 'from' and 'to' RFC3339 query parameters
+// into a filter without pagination.
+func ParseTimeRange(r *http.Request) (domain.SleepLogFilter, []problem.FieldError) {
 	var filter domain.SleepLogFilter
 	var fieldErrors []problem.FieldError
 
@@ -111,25 +95,5 @@ func ParseSleepLogFilter(r *http.Request) (domain.SleepLogFilter, []problem.Fiel
 		})
 	}
 
-	// Parse 'limit' parameter
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		limit, err := strconv.Atoi(limitStr)
-		if err != nil || limit < 1 {
-			fieldErrors = append(fieldErrors, problem.FieldError{
-				Field:   "limit",
-				Message: "must be a positive integer",
-			})
-		} else {
-			filter.Limit = limit
-		}
-	}
-
-	// Parse 'cursor' parameter
-	filter.Cursor = r.URL.Query().Get("cursor")
-
-	if len(fieldErrors) > 0 {
-		return filter, fieldErrors
-	}
-
-	return filter, nil
+	return filter, fieldErrors
 }
@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,9 +14,63 @@ type Config struct {
 	LogLevel    string
 	Seed        bool
 
+	// Retention of soft-deleted sleep logs
+	SleepLogRetentionDays int
+	SleepLogPurgeInterval time.Duration
+
+	// Regularity component of the overall sleep score: bedtime_std or sri
+	OverallScoreRegularity string
+
+	// Scoring model file (YAML or JSON); the built-in model when empty
+	ScoringModelFile string
+
+	// Key for the admin API; the admin API is disabled when empty
+	AdminAPIKey string
+
+	// Smallest cohort reported by cohort analytics
+	CohortMinSize int
+
+	// Number of users a cohort is built from, and how long a built cohort
+	// is reused
+	CohortMaxUsers    int
+	CohortSnapshotTTL time.Duration
+
+	// LLM provider for insights: openai, openai_compatible, anthropic,
+	// azure_openai or rules
+	LLMProvider string
+
+	// Write insights with the fixed rules when the LLM fails
+	InsightsFallback bool
+
+	// Resilience of LLM calls: per-attempt timeout, retries with backoff and
+	// a circuit breaker (threshold 0 disables it)
+	LLMTimeout          time.Duration
+	LLMMaxRetries       int
+	LLMRetryBaseDelay   time.Duration
+	LLMRetryMaxDelay    time.Duration
+	LLMBreakerThreshold int
+	LLMBreakerCooldown  time.Duration
+
 	// OpenAI configuration
 	OpenAIAPIKey             string
 	OpenAISleepInsightsModel string
+	OpenAIBaseURL            string
+
+	// OpenAI-compatible server configuration (Ollama, vLLM, llama.cpp server)
+	OpenAICompatibleBaseURL string
+	OpenAICompatibleAPIKey  string
+	OpenAICompatibleModel   string
+
+	// Anthropic configuration
+	AnthropicAPIKey  string
+	AnthropicModel   string
+	AnthropicBaseURL string
+
+	// Azure OpenAI configuration
+	AzureOpenAIEndpoint   string
+	AzureOpenAIAPIKey     string
+	AzureOpenAIDeployment string
+	AzureOpenAIAPIVersion string
 
 	// Langfuse configuration
 	LangfuseBaseURL        string
@@ -36,8 +92,44 @@ func Load() *Config {
 		LogLevel:    getEnv("LOG_LEVEL", "info"),
 		Seed:        getEnv("SEED", "false") == "true",
 
+		SleepLogRetentionDays: getEnvInt("SLEEP_LOG_RETENTION_DAYS", 30),
+		SleepLogPurgeInterval: getEnvDuration("SLEEP_LOG_PURGE_INTERVAL", 24*time.Hour),
+
+		OverallScoreRegularity: getEnv("OVERALL_SCORE_REGULARITY", "bedtime_std"),
+		ScoringModelFile:       getEnv("SCORING_MODEL_FILE", ""),
+
+		AdminAPIKey: getEnv("ADMIN_API_KEY", ""),
+
+		CohortMinSize:     getEnvInt("COHORT_MIN_SIZE", 10),
+		CohortMaxUsers:    getEnvInt("COHORT_MAX_USERS", 1000),
+		CohortSnapshotTTL: getEnvDuration("COHORT_SNAPSHOT_TTL", time.Hour),
+
+		LLMProvider:      getEnv("LLM_PROVIDER", "openai"),
+		InsightsFallback: getEnv("INSIGHTS_FALLBACK", "true") == "true",
+
+		LLMTimeout:          getEnvDuration("LLM_TIMEOUT", 30*time.Second),
+		LLMMaxRetries:       getEnvInt("LLM_MAX_RETRIES", 2),
+		LLMRetryBaseDelay:   getEnvDuration("LLM_RETRY_BASE_DELAY", 500*time.Millisecond),
+		LLMRetryMaxDelay:    getEnvDuration("LLM_RETRY_MAX_DELAY", 10*time.Second),
+		LLMBreakerThreshold: getEnvInt("LLM_BREAKER_THRESHOLD", 5),
+		LLMBreakerCooldown:  getEnvDuration("LLM_BREAKER_COOLDOWN", 30*time.Second),
+
 		OpenAIAPIKey:             getEnv("OPENAI_API_KEY", ""),
 		OpenAISleepInsightsModel: getEnv("OPENAI_SLEEP_INSIGHTS_MODEL", "gpt-4o-mini"),
+		OpenAIBaseURL:            getEnv("OPENAI_BASE_URL", ""),
+
+		OpenAICompatibleBaseURL: getEnv("OPENAI_COMPATIBLE_BASE_URL", ""),
+		OpenAICompatibleAPIKey:  getEnv("OPENAI_COMPATIBLE_API_KEY", ""),
+		OpenAICompatibleModel:   getEnv("OPENAI_COMPATIBLE_MODEL", ""),
+
+		AnthropicAPIKey:  getEnv("ANTHROPIC_API_KEY", ""),
+		AnthropicModel:   getEnv("ANTHROPIC_MODEL", "claude-3-5-haiku-latest"),
+		AnthropicBaseURL: getEnv("ANTHROPIC_BASE_URL", ""),
+
+		AzureOpenAIEndpoint:   getEnv("AZURE_OPENAI_ENDPOINT", ""),
+		AzureOpenAIAPIKey:     getEnv("AZURE_OPENAI_API_KEY", ""),
+		AzureOpenAIDeployment: getEnv("AZURE_OPENAI_DEPLOYMENT", ""),
+		AzureOpenAIAPIVersion: getEnv("AZURE_OPENAI_API_VERSION", "2024-10-21"),
 
 		LangfuseBaseURL:        getEnv("LANGFUSE_BASE_URL", ""),
 		LangfusePublicKey:      getEnv("LANGFUSE_PUBLIC_KEY", ""),
@@ -55,3 +147,21 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvInt returns the integer value of key, or defaultValue if unset or invalid.
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(getEnv(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvDuration returns the duration value of key (e.g. "24h"), or defaultValue if unset or invalid.
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(getEnv(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
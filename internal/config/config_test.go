@@ -1,6 +1,9 @@
 package config
 
-import "testing"
+import (
+    "testing"
+    "time"
+)
 
 func TestGetEnv(t *testing.T) {
     t.Setenv("CFG_VALUE", "custom")
@@ -48,3 +51,23 @@ func TestLoad(t *testing.T) {
         t.Fatalf("openai env overrides missing: %+v", cfg)
     }
 }
+
+func TestGetEnvIntAndDuration(t *testing.T) {
+    t.Setenv("CFG_INT", "14")
+    if got := getEnvInt("CFG_INT", 30); got != 14 {
+        t.Fatalf("getEnvInt returned %d, want 14", got)
+    }
+    t.Setenv("CFG_INT", "not-a-number")
+    if got := getEnvInt("CFG_INT", 30); got != 30 {
+        t.Fatalf("getEnvInt returned %d, want fallback 30", got)
+    }
+
+    t.Setenv("CFG_DURATION", "90m")
+    if got := getEnvDuration("CFG_DURATION", time.Hour); got != 90*time.Minute {
+        t.Fatalf("getEnvDuration returned %v, want 90m", got)
+    }
+    t.Setenv("CFG_DURATION", "")
+    if got := getEnvDuration("CFG_DURATION", time.Hour); got != time.Hour {
+        t.Fatalf("getEnvDuration returned %v, want fallback 1h", got)
+    }
+}
@@ -0,0 +1,95 @@
+package domain
+
+import "time"
+
+// CohortFilter selects the users of a cohort. Empty fields match everyone.
+// @Description Users to include in a cohort.
+type CohortFilter struct {
+	// IANA timezone of the users
+	Timezone string `json:"timezone,omitempty" example:"Europe/Prague"`
+	// Chronotype over the window
+	Chronotype ChronotypeType `json:"chronotype,omitempty" example:"night_owl" enums:"early_bird,intermediate,night_owl"`
+	// Tag recorded on at least one sleep log in the window
+	Tag string `json:"tag,omitempty" example:"caffeine"`
+}
+
+// CohortDistribution summarizes a per-user value across a cohort. It has no
+// minimum or maximum, since those are the exact values of single users.
+// @Description Mean, standard deviation and percentiles of a per-user value.
+type CohortDistribution struct {
+	Avg float64 `json:"avg" example:"7.2"`
+	Std float64 `json:"std" example:"0.8"`
+	// Percentiles, linearly interpolated
+	P10    float64 `json:"p10" example:"6.1"`
+	P25    float64 `json:"p25" example:"6.7"`
+	Median float64 `json:"median" example:"7.2"`
+	P75    float64 `json:"p75" example:"7.7"`
+	P90    float64 `json:"p90" example:"8.3"`
+}
+
+// CohortMetrics are distributions of per-user window metrics across a
+// cohort. A distribution is absent when fewer users than the minimum cohort
+// size have the value.
+// @Description Distributions of per-user metrics across a cohort.
+type CohortMetrics struct {
+	// Average sleep duration in hours
+	AvgDurationHours *CohortDistribution `json:"avg_duration_hours,omitempty"`
+	// Average quality (1-10 scale)
+	AvgQuality *CohortDistribution `json:"avg_quality,omitempty"`
+	// Circular bedtime standard deviation in minutes
+	BedtimeStdMinutes *CohortDistribution `json:"bedtime_std_minutes,omitempty"`
+	// Average total daily sleep in hours (core + naps)
+	AvgTotalDailyHours *CohortDistribution `json:"avg_total_daily_hours,omitempty"`
+	// Percentage of days meeting the target
+	DailySufficiencyScore *CohortDistribution `json:"daily_sufficiency_score,omitempty"`
+	// Overall sleep score
+	OverallSleepScore *CohortDistribution `json:"overall_sleep_score,omitempty"`
+	// Sleep Regularity Index
+	SleepRegularityIndex *CohortDistribution `json:"sleep_regularity_index,omitempty"`
+}
+
+// CohortMetricsResponse is the response for the cohort metrics endpoint.
+// @Description Population baselines over a cohort of users.
+type CohortMetricsResponse struct {
+	Window struct {
+		From time.Time `json:"from" example:"2024-01-01T00:00:00Z"`
+		To   time.Time `json:"to" example:"2024-01-31T23:59:59Z"`
+	} `json:"window"`
+	// Filter of the cohort
+	Filter CohortFilter `json:"filter"`
+	// Number of users matching the filter with sleep in the window
+	CohortSize int `json:"cohort_size" example:"128"`
+	// Smallest cohort that is reported
+	MinCohortSize int `json:"min_cohort_size" example:"10"`
+	// Distributions of the per-user metrics
+	Metrics CohortMetrics `json:"metrics"`
+}
+
+// CohortRanks are a user's percentile ranks within a cohort: the percentage
+// of the cohort with a lower value, counting ties as half. A rank is absent
+// when the user or too many of the cohort lack the value.
+// @Description Percentile ranks (0-100) of a user's metrics within a cohort.
+type CohortRanks struct {
+	AvgDurationHours      *float64 `json:"avg_duration_hours,omitempty" example:"62.5"`
+	AvgQuality            *float64 `json:"avg_quality,omitempty" example:"48"`
+	BedtimeStdMinutes     *float64 `json:"bedtime_std_minutes,omitempty" example:"35.2"`
+	AvgTotalDailyHours    *float64 `json:"avg_total_daily_hours,omitempty" example:"70.1"`
+	DailySufficiencyScore *float64 `json:"daily_sufficiency_score,omitempty" example:"55"`
+	OverallSleepScore     *float64 `json:"overall_sleep_score,omitempty" example:"66.4"`
+	SleepRegularityIndex  *float64 `json:"sleep_regularity_index,omitempty" example:"81.3"`
+}
+
+// CohortRanksResponse is the response for the cohort ranks endpoint.
+// @Description Where a user sits within a cohort.
+type CohortRanksResponse struct {
+	Window struct {
+		From time.Time `json:"from" example:"2024-01-01T00:00:00Z"`
+		To   time.Time `json:"to" example:"2024-01-31T23:59:59Z"`
+	} `json:"window"`
+	// Filter of the cohort
+	Filter CohortFilter `json:"filter"`
+	// Number of users matching the filter with sleep in the window
+	CohortSize int `json:"cohort_size" example:"128"`
+	// Percentile ranks of the user
+	Ranks CohortRanks `json:"ranks"`
+}
@@ -3,9 +3,17 @@ package domain
 import "errors"
 
 var (
-	ErrNotFound           = errors.New("resource not found")
-	ErrConflict           = errors.New("resource conflict")
-	ErrOverlappingSleep   = errors.New("overlapping sleep period detected")
-	ErrDuplicateRequest   = errors.New("duplicate client request")
-	ErrInvalidInput       = errors.New("invalid input")
+	ErrNotFound            = errors.New("resource not found")
+	ErrConflict            = errors.New("resource conflict")
+	ErrOverlappingSleep    = errors.New("overlapping sleep period detected")
+	ErrDuplicateRequest    = errors.New("duplicate client request")
+	ErrInvalidInput        = errors.New("invalid input")
+	ErrPreconditionFailed  = errors.New("resource version precondition failed")
+	ErrInvalidPatch        = errors.New("invalid merge patch")
+	ErrBatchAborted        = errors.New("batch aborted due to failed items")
+	ErrInvalidStages       = errors.New("invalid sleep stages")
+	ErrInvalidFactors      = errors.New("invalid sleep factors")
+	ErrGoalNotSet          = errors.New("no sleep goal set")
+	ErrInvalidScoringModel = errors.New("invalid scoring model")
+	ErrCohortTooSmall      = errors.New("cohort too small")
 )
@@ -1,6 +1,10 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // ChronotypeType represents the user's sleep chronotype classification.
 // @Description Chronotype classification based on mid-sleep time.
@@ -16,7 +20,8 @@ const (
 // ChronotypeResult contains the computed chronotype and supporting data.
 // @Description Chronotype analysis result.
 type ChronotypeResult struct {
-	// Chronotype classification
+	// Chronotype classification, from MSFsc when social jetlag is available
+	// and from the median mid-sleep otherwise
 	Chronotype ChronotypeType `json:"chronotype" example:"intermediate"`
 	// Mid-sleep time in local timezone (HH:MM format)
 	MidSleepLocalTime string `json:"mid_sleep_local_time" example:"03:45"`
@@ -26,6 +31,35 @@ type ChronotypeResult struct {
 	WindowDays int `json:"window_days" example:"30"`
 	// Number of sleep logs used in calculation
 	SleepsUsed int `json:"sleeps_used" example:"28"`
+	// Workday versus free-day timing, when both have enough nights
+	SocialJetlag *SocialJetlag `json:"social_jetlag,omitempty"`
+}
+
+// SocialJetlag compares sleep timing before workdays and before free days,
+// following the Munich ChronoType Questionnaire (MCTQ).
+// @Description Workday and free-day mid-sleep, sleep-debt-corrected mid-sleep on free days (MSFsc) and social jetlag.
+type SocialJetlag struct {
+	// Workday calendar used to split the nights
+	Workdays []string `json:"workdays" example:"MON,TUE,WED,THU,FRI"`
+	// Number of nights before workdays and before free days
+	WorkdayNights int `json:"workday_nights" example:"20"`
+	FreeDayNights int `json:"free_day_nights" example:"8"`
+	// Median mid-sleep before workdays (MSW)
+	WorkdayMidSleepLocalTime            string `json:"workday_mid_sleep_local_time" example:"03:15"`
+	WorkdayMidSleepMinutesAfterMidnight int    `json:"workday_mid_sleep_minutes_after_midnight" example:"195"`
+	// Median mid-sleep before free days (MSF)
+	FreeDayMidSleepLocalTime            string `json:"free_day_mid_sleep_local_time" example:"04:45"`
+	FreeDayMidSleepMinutesAfterMidnight int    `json:"free_day_mid_sleep_minutes_after_midnight" example:"285"`
+	// Average sleep duration before workdays and before free days in hours
+	WorkdaySleepHours float64 `json:"workday_sleep_hours" example:"6.8"`
+	FreeDaySleepHours float64 `json:"free_day_sleep_hours" example:"8.4"`
+	// MSF corrected for the sleep debt accumulated on workdays (MSFsc)
+	MSFscLocalTime            string `json:"msfsc_local_time" example:"04:19"`
+	MSFscMinutesAfterMidnight int    `json:"msfsc_minutes_after_midnight" example:"259"`
+	// Absolute difference between MSF and MSW
+	SocialJetlagMinutes int `json:"social_jetlag_minutes" example:"90"`
+	// Chronotype classified from MSFsc
+	Chronotype ChronotypeType `json:"chronotype" example:"intermediate"`
 }
 
 // ChronotypeRequest contains query parameters for chronotype endpoint.
@@ -34,13 +68,24 @@ type ChronotypeRequest struct {
 	MinSleeps  int `json:"min_sleeps" validate:"omitempty,min=1,max=100"`
 }
 
-// DescriptiveStats holds basic statistical measures.
+// DescriptiveStats holds basic statistical measures. For clock times Avg is
+// the circular mean and Std the circular standard deviation; Min, Max and the
+// percentiles are ordered around the mean, so Min is later on the clock than
+// Max when the times cross midnight.
 // @Description Basic statistical measures for a metric.
 type DescriptiveStats struct {
 	Avg float64 `json:"avg" example:"7.2"`
 	Std float64 `json:"std" example:"0.8"`
 	Min float64 `json:"min" example:"5.5"`
 	Max float64 `json:"max" example:"9.0"`
+	// Percentiles, linearly interpolated
+	P10    float64 `json:"p10" example:"6.1"`
+	P25    float64 `json:"p25" example:"6.7"`
+	Median float64 `json:"median" example:"7.2"`
+	P75    float64 `json:"p75" example:"7.7"`
+	P90    float64 `json:"p90" example:"8.3"`
+	// Mean resultant length (0-1) of clock times; 1 means the same time every night
+	ResultantLength *float64 `json:"resultant_length,omitempty" example:"0.97"`
 }
 
 // PerSleepMetrics contains per-sleep statistics for a window.
@@ -50,10 +95,29 @@ type PerSleepMetrics struct {
 	Duration DescriptiveStats `json:"duration"`
 	// Quality statistics (1-10 scale)
 	Quality DescriptiveStats `json:"quality"`
-	// Bedtime statistics in minutes after midnight
+	// Bedtime statistics in minutes after midnight (circular)
 	Bedtime DescriptiveStats `json:"bedtime"`
+	// Mid-sleep statistics in minutes after midnight (circular)
+	MidSleep DescriptiveStats `json:"mid_sleep"`
 	// Number of sleep logs in this window
 	SleepCount int `json:"sleep_count" example:"28"`
+	// Stage-based statistics, present when any log in the window has stages
+	Stages *StageMetrics `json:"stages,omitempty"`
+}
+
+// StageMetrics contains statistics derived from sleep stage segments.
+// @Description Per-sleep stage metrics over the logs that have stage data.
+type StageMetrics struct {
+	// Number of sleep logs with stage data
+	SleepCount int `json:"sleep_count" example:"21"`
+	// Deep sleep as a percentage of total sleep time
+	DeepPercent DescriptiveStats `json:"deep_percent"`
+	// REM sleep as a percentage of total sleep time
+	REMPercent DescriptiveStats `json:"rem_percent"`
+	// Wake after sleep onset in minutes
+	WASOMinutes DescriptiveStats `json:"waso_minutes"`
+	// Total sleep time as a percentage of time in bed
+	EfficiencyPercent DescriptiveStats `json:"efficiency_percent"`
 }
 
 // DailyOverallMetrics contains per-day total sleep statistics.
@@ -63,14 +127,28 @@ type DailyOverallMetrics struct {
 	DaysCount int `json:"days_count" example:"30"`
 	// Total daily sleep hours statistics
 	TotalDailyHours DescriptiveStats `json:"total_daily_hours"`
-	// Target hours for sufficiency calculation
+	// Target hours of the goal in force at the end of the window
 	TargetHours float64 `json:"target_hours" example:"7.0"`
-	// Number of days meeting the target
+	// Number of days meeting the target in force on that day
 	DaysMeetingTarget int `json:"days_meeting_target" example:"22"`
+	// Number of days with more naps than the goal allows, absent without a nap allowance
+	DaysOverNapAllowance *int `json:"days_over_nap_allowance,omitempty" example:"3"`
 	// Percentage of days meeting target (0-100)
 	DailySufficiencyScore float64 `json:"daily_sufficiency_score" example:"73.3"`
 }
 
+// RegularitySource selects the measure behind the regularity component of
+// the overall sleep score.
+// @Description Regularity measure of the overall score: bedtime_std or sri.
+type RegularitySource string
+
+const (
+	// RegularitySourceBedtimeStd uses the consistency score.
+	RegularitySourceBedtimeStd RegularitySource = "bedtime_std"
+	// RegularitySourceSRI uses the Sleep Regularity Index.
+	RegularitySourceSRI RegularitySource = "sri"
+)
+
 // DerivedScores contains computed 0-100 scores.
 // @Description Derived scores based on sleep metrics.
 type DerivedScores struct {
@@ -78,8 +156,124 @@ type DerivedScores struct {
 	ConsistencyScore float64 `json:"consistency_score" example:"75.0"`
 	// Sufficiency score based on duration meeting targets (0-100)
 	SufficiencyScore float64 `json:"sufficiency_score" example:"80.0"`
+	// Percentage of core sleeps starting within the goal's bedtime window, absent without one
+	BedtimeGoalScore *float64 `json:"bedtime_goal_score,omitempty" example:"71.4"`
+	// Percentage of core sleeps ending within 30 minutes of the goal's wake time, absent without one
+	WakeGoalScore *float64 `json:"wake_goal_score,omitempty" example:"85.7"`
+	// Sleep Regularity Index (-100 to 100), absent without enough consecutive days
+	SleepRegularityIndex *float64 `json:"sleep_regularity_index,omitempty" example:"82.4"`
 	// Overall sleep score combining factors (0-100)
 	OverallSleepScore float64 `json:"overall_sleep_score" example:"77.5"`
+	// Measure used for the regularity component of the overall score
+	RegularitySource RegularitySource `json:"regularity_source" example:"bedtime_std" enums:"bedtime_std,sri"`
+	// Name of the scoring model behind the scores
+	ScoringModel string `json:"scoring_model" example:"default"`
+	// Version of the scoring model
+	ScoringModelVersion string `json:"scoring_model_version" example:"1"`
+}
+
+// SleepRegularity describes the Sleep Regularity Index calculation.
+// @Description Sleep Regularity Index: the chance of being in the same sleep/wake state 24 hours apart, scaled to -100..100.
+type SleepRegularity struct {
+	// Sleep Regularity Index (-100 to 100), absent without enough consecutive days
+	SRI *float64 `json:"sri,omitempty" example:"82.4"`
+	// Timezone of the minute-level sleep/wake series
+	Timezone string `json:"timezone" example:"Europe/Prague"`
+	// Number of days in the window with logs ending on them and on the next day
+	DaysRecorded int `json:"days_recorded" example:"28"`
+	// Number of consecutive recorded day pairs compared
+	DayPairs int `json:"day_pairs" example:"26"`
+}
+
+// FactorGroupStats summarises the sleep logs of one side of a factor comparison.
+// @Description Sleep statistics for logs with or without a factor.
+type FactorGroupStats struct {
+	// Number of sleep logs
+	SleepCount int `json:"sleep_count" example:"9"`
+	// Average duration in hours
+	AvgDurationHours float64 `json:"avg_duration_hours" example:"6.8"`
+	// Average quality (1-10 scale)
+	AvgQuality float64 `json:"avg_quality" example:"6.2"`
+}
+
+// FactorImpact compares sleep with and without a factor.
+// @Description Average duration and quality of sleep logs with a factor versus those without it.
+type FactorImpact struct {
+	// Tag name
+	Tag string `json:"tag" example:"alcohol"`
+	// Tag label
+	Label string `json:"label" example:"Alcohol"`
+	// Tag category
+	Category TagCategory `json:"category" example:"SUBSTANCE"`
+	// Logs with the factor
+	With FactorGroupStats `json:"with"`
+	// Logs without the factor
+	Without FactorGroupStats `json:"without"`
+	// Average duration with minus without, in hours (absent if either group is empty)
+	DurationDiffHours *float64 `json:"duration_diff_hours,omitempty" example:"-0.6"`
+	// Average quality with minus without (absent if either group is empty)
+	QualityDiff *float64 `json:"quality_diff,omitempty" example:"-1.1"`
+}
+
+// ConfidenceInterval is a two-sided interval estimate.
+// @Description Lower and upper bound of a confidence interval.
+type ConfidenceInterval struct {
+	Low  float64 `json:"low" example:"-1.2"`
+	High float64 `json:"high" example:"-0.3"`
+}
+
+// FactorEffect compares one measure on tagged and untagged nights.
+// @Description Distributions of a measure with and without a factor, with the effect size and bootstrap confidence intervals.
+type FactorEffect struct {
+	// Statistics of tagged nights
+	Tagged DescriptiveStats `json:"tagged"`
+	// Statistics of untagged nights
+	Untagged DescriptiveStats `json:"untagged"`
+	// Mean of tagged minus mean of untagged nights (absent if a group is too small)
+	MeanDiff *float64 `json:"mean_diff,omitempty" example:"-0.75"`
+	// Bootstrap confidence interval of mean_diff
+	MeanDiffCI *ConfidenceInterval `json:"mean_diff_ci,omitempty"`
+	// Standardised mean difference (Cohen's d with pooled standard deviation)
+	EffectSize *float64 `json:"effect_size,omitempty" example:"-0.82"`
+	// Bootstrap confidence interval of effect_size
+	EffectSizeCI *ConfidenceInterval `json:"effect_size_ci,omitempty"`
+}
+
+// FactorAnalysis is the impact analysis of one tag.
+// @Description Duration and quality on nights with a factor compared with nights without it.
+type FactorAnalysis struct {
+	// Tag name
+	Tag string `json:"tag" example:"alcohol"`
+	// Tag label
+	Label string `json:"label" example:"Alcohol"`
+	// Tag category
+	Category TagCategory `json:"category" example:"SUBSTANCE"`
+	// Number of nights with the factor
+	TaggedCount int `json:"tagged_count" example:"8"`
+	// Number of nights without the factor
+	UntaggedCount int `json:"untagged_count" example:"52"`
+	// Duration in hours
+	Duration FactorEffect `json:"duration"`
+	// Quality (1-10 scale)
+	Quality FactorEffect `json:"quality"`
+}
+
+// FactorImpactResponse is the response for the factor impact endpoint.
+// @Description Impact of each recorded factor on sleep duration and quality over a window.
+type FactorImpactResponse struct {
+	// Analysis window
+	Window struct {
+		From time.Time `json:"from" example:"2024-01-01T00:00:00Z"`
+		To   time.Time `json:"to" example:"2024-03-31T23:59:59Z"`
+	} `json:"window"`
+	// Confidence level of the intervals
+	ConfidenceLevel float64 `json:"confidence_level" example:"0.95"`
+	// Number of bootstrap resamples
+	Resamples int `json:"resamples" example:"1000"`
+	// Minimum nights per group for effect sizes and intervals
+	MinGroupSize int `json:"min_group_size" example:"3"`
+	// One analysis per tag recorded in the window, ordered by tag name
+	Factors []FactorAnalysis `json:"factors"`
 }
 
 // WindowMetrics contains all metrics for a single time window.
@@ -95,6 +289,10 @@ type WindowMetrics struct {
 	DailyOverall DailyOverallMetrics `json:"daily_overall"`
 	// Derived scores
 	Scores DerivedScores `json:"scores"`
+	// Sleep Regularity Index details
+	Regularity SleepRegularity `json:"regularity"`
+	// Comparison of sleep with and without each recorded factor
+	Factors []FactorImpact `json:"factors,omitempty"`
 }
 
 // MetricsResponse is the response for the metrics endpoint.
@@ -111,22 +309,274 @@ type MetricsResponse struct {
 	DailyOverall DailyOverallMetrics `json:"daily_overall"`
 	// Derived scores
 	Scores DerivedScores `json:"scores"`
+	// Sleep Regularity Index details
+	Regularity SleepRegularity `json:"regularity"`
+	// Comparison of sleep with and without each recorded factor
+	Factors []FactorImpact `json:"factors,omitempty"`
+	// Period of the window in the user's timezone
+	Period MetricsPeriod `json:"period"`
+	// Previous period with the change since, present in comparison mode
+	Previous *MetricsComparison `json:"previous,omitempty"`
 }
 
-// MetricsRequest contains query parameters for metrics endpoint.
+// MetricsPeriodType selects how the metrics window is chosen.
+// @Description Metrics period: rolling, week, month or custom.
+type MetricsPeriodType string
+
+const (
+	// MetricsPeriodRolling is the last window_days days.
+	MetricsPeriodRolling MetricsPeriodType = "rolling"
+	// MetricsPeriodWeek is the ISO week (Monday to Sunday).
+	MetricsPeriodWeek MetricsPeriodType = "week"
+	// MetricsPeriodMonth is the calendar month.
+	MetricsPeriodMonth MetricsPeriodType = "month"
+	// MetricsPeriodCustom runs from one local date to another.
+	MetricsPeriodCustom MetricsPeriodType = "custom"
+)
+
+// MetricsPeriodTypes lists the supported metrics periods.
+var MetricsPeriodTypes = []MetricsPeriodType{MetricsPeriodRolling, MetricsPeriodWeek, MetricsPeriodMonth, MetricsPeriodCustom}
+
+// MetricsRequest contains query parameters for metrics endpoint. Dates are
+// local calendar dates; only their year, month and day are used.
 type MetricsRequest struct {
+	// Days of a rolling period
 	WindowDays int `json:"window_days" validate:"omitempty,min=1,max=365"`
+	// Period type, rolling when empty
+	Period MetricsPeriodType `json:"period"`
+	// First and last day of a custom period
+	From *time.Time `json:"from"`
+	To   *time.Time `json:"to"`
+	// Day in the week or month, or the last day of a rolling period; today when nil
+	AsOf *time.Time `json:"as_of"`
+	// Also compute the previous period and the deltas
+	Compare bool `json:"compare"`
+}
+
+// MetricsPeriod describes the calendar days of a metrics window.
+// @Description Period of a metrics window in the user's timezone.
+type MetricsPeriod struct {
+	// Period type
+	Type MetricsPeriodType `json:"type" example:"week" enums:"rolling,week,month,custom"`
+	// Timezone of the dates
+	Timezone string `json:"timezone" example:"Europe/Prague"`
+	// First local date
+	StartDate string `json:"start_date" example:"2024-01-15"`
+	// Last local date (inclusive)
+	EndDate string `json:"end_date" example:"2024-01-21"`
+}
+
+// MetricsComparison holds the metrics of the previous period.
+// @Description Metrics of the previous period and the change since.
+type MetricsComparison struct {
+	// Previous period
+	Period MetricsPeriod `json:"period"`
+	// Metrics of the previous period
+	Metrics WindowMetrics `json:"metrics"`
+	// Current minus previous values
+	Deltas MetricsDeltas `json:"deltas"`
+}
+
+// MetricsDeltas are the changes from the previous period to the current one.
+// A delta is null when either period lacks the value.
+// @Description Current minus previous period values.
+type MetricsDeltas struct {
+	SleepCount            int      `json:"sleep_count" example:"-1"`
+	AvgDurationHours      *float64 `json:"avg_duration_hours" example:"0.35"`
+	AvgQuality            *float64 `json:"avg_quality" example:"0.5"`
+	BedtimeStdMinutes     *float64 `json:"bedtime_std_minutes" example:"-12.4"`
+	AvgTotalDailyHours    *float64 `json:"avg_total_daily_hours" example:"0.4"`
+	DailySufficiencyScore *float64 `json:"daily_sufficiency_score" example:"14.3"`
+	ConsistencyScore      *float64 `json:"consistency_score" example:"10.3"`
+	SufficiencyScore      *float64 `json:"sufficiency_score" example:"8.8"`
+	OverallSleepScore     *float64 `json:"overall_sleep_score" example:"6.1"`
+	SleepRegularityIndex  *float64 `json:"sleep_regularity_index" example:"4.2"`
+}
+
+// TrendMetric is the measure of a trend series.
+// @Description Trend measure: duration, quality, bedtime or total_daily.
+type TrendMetric string
+
+const (
+	TrendMetricDuration   TrendMetric = "duration"
+	TrendMetricQuality    TrendMetric = "quality"
+	TrendMetricBedtime    TrendMetric = "bedtime"
+	TrendMetricTotalDaily TrendMetric = "total_daily"
+)
+
+// TrendMetrics lists the supported trend measures.
+var TrendMetrics = []TrendMetric{TrendMetricDuration, TrendMetricQuality, TrendMetricBedtime, TrendMetricTotalDaily}
+
+// TrendPoint is the value of a trend series for one local day.
+// @Description Daily value with moving averages. Averages skip missing days and are null until a value is available.
+type TrendPoint struct {
+	// Local date (YYYY-MM-DD), by sleep end time
+	Date string `json:"date" example:"2024-01-15"`
+	// Value of the day, null when missing
+	Value *float64 `json:"value" example:"7.5"`
+	// True if no sleep counts towards the day
+	Missing bool `json:"missing" example:"false"`
+	// Simple moving average over the requested window
+	SMA *float64 `json:"sma" example:"7.3"`
+	// Simple moving average over 30 days
+	SMA30 *float64 `json:"sma_30" example:"7.1"`
+	// Exponentially weighted moving average with the span of the requested window
+	EWMA *float64 `json:"ewma" example:"7.35"`
+}
+
+// TrendsResponse is the response for the trends endpoint.
+// @Description Per-day time series of a sleep measure with moving averages.
+type TrendsResponse struct {
+	Metric TrendMetric `json:"metric" example:"duration"`
+	// Unit of the values
+	Unit string `json:"unit" example:"hours"`
+	// Short moving average window in days
+	Window int `json:"window" example:"7"`
+	// Number of days in the series
+	SpanDays int `json:"span_days" example:"90"`
+	// Timezone used for "today"
+	Timezone string `json:"timezone" example:"Europe/Prague"`
+	// First and last local date of the series
+	From string `json:"from" example:"2023-10-18"`
+	To   string `json:"to" example:"2024-01-15"`
+	// One point per day, oldest first
+	Points []TrendPoint `json:"points"`
+}
+
+// SleepDebtPoint is the sleep debt balance at the end of one local day.
+// @Description Daily total sleep against the target with the running balance.
+type SleepDebtPoint struct {
+	// Local date (YYYY-MM-DD), by sleep end time
+	Date string `json:"date" example:"2024-01-15"`
+	// Total sleep of the day (core + naps) in hours, null when missing
+	TotalHours *float64 `json:"total_hours" example:"6.5"`
+	// Target of the goal in force on the day
+	TargetHours float64 `json:"target_hours" example:"7.5"`
+	// True if no sleep counts towards the day
+	Missing bool `json:"missing" example:"false"`
+	// Running balance in hours after the day; negative values are debt
+	BalanceHours float64 `json:"balance_hours" example:"-3.25"`
+}
+
+// SleepDebtResponse is the response for the sleep debt endpoint.
+// @Description Daily running balance of total sleep minus the target.
+type SleepDebtResponse struct {
+	// Share of the balance forgiven each day (0-1)
+	Decay float64 `json:"decay" example:"0.1"`
+	// Largest debt in hours the balance can reach
+	CapHours float64 `json:"cap_hours" example:"20"`
+	// Number of days in the series
+	SpanDays int `json:"span_days" example:"30"`
+	// Timezone used for "today"
+	Timezone string `json:"timezone" example:"Europe/Prague"`
+	// First and last local date of the series
+	From string `json:"from" example:"2023-12-17"`
+	To   string `json:"to" example:"2024-01-15"`
+	// Sleep owed at the end of the series in hours
+	CurrentDebtHours float64 `json:"current_debt_hours" example:"3.25"`
+	// One point per day, oldest first
+	Points []SleepDebtPoint `json:"points"`
+}
+
+// AnomalySeverity grades how far a night deviates from the user's baseline.
+// @Description Anomaly severity: MILD, MODERATE or SEVERE.
+type AnomalySeverity string
+
+const (
+	AnomalySeverityMild     AnomalySeverity = "MILD"
+	AnomalySeverityModerate AnomalySeverity = "MODERATE"
+	AnomalySeveritySevere   AnomalySeverity = "SEVERE"
+)
+
+// AnomalyMeasure is a measure scored by anomaly detection.
+// @Description Scored measure: duration, bedtime, mid_sleep or quality.
+type AnomalyMeasure string
+
+const (
+	AnomalyMeasureDuration AnomalyMeasure = "duration"
+	AnomalyMeasureBedtime  AnomalyMeasure = "bedtime"
+	AnomalyMeasureMidSleep AnomalyMeasure = "mid_sleep"
+	AnomalyMeasureQuality  AnomalyMeasure = "quality"
+)
+
+// MeasureAnomaly is an unusual value of one measure.
+// @Description Measure that deviates from the baseline, with its robust z-score.
+type MeasureAnomaly struct {
+	Measure AnomalyMeasure `json:"measure" example:"duration"`
+	// Value of the night (hours, minutes after midnight or quality score)
+	Value float64 `json:"value" example:"4.5"`
+	// Median of the baseline nights, in the same unit
+	BaselineMedian float64 `json:"baseline_median" example:"7.5"`
+	// Robust z-score based on the median absolute deviation
+	RobustZ  float64         `json:"robust_z" example:"-4.2"`
+	Severity AnomalySeverity `json:"severity" example:"MILD"`
+	Reason   string          `json:"reason" example:"Slept 4.5 h, 3 h less than the usual 7.5 h on weekday nights"`
+}
+
+// AnomalyBaseline holds the medians of the baseline nights a night was
+// scored against.
+// @Description Medians of the baseline nights, per measure.
+type AnomalyBaseline struct {
+	DurationHours float64 `json:"duration_hours" example:"7.5"`
+	// Minutes after local midnight
+	BedtimeMinutes float64 `json:"bedtime_minutes" example:"1380"`
+	// Minutes after local midnight
+	MidSleepMinutes float64 `json:"mid_sleep_minutes" example:"195"`
+	Quality         float64 `json:"quality" example:"7"`
+}
+
+// SleepAnomaly is a night that deviates from the user's baseline.
+// @Description Unusual night with the measures that triggered it.
+type SleepAnomaly struct {
+	SleepLogID uuid.UUID `json:"sleep_log_id" example:"660e8400-e29b-41d4-a716-446655440001"`
+	// Local date of the sleep end time (YYYY-MM-DD)
+	Date    string    `json:"date" example:"2024-01-15"`
+	StartAt time.Time `json:"start_at" example:"2024-01-15T02:30:00Z"`
+	EndAt   time.Time `json:"end_at" example:"2024-01-15T07:00:00Z"`
+	// True for nights before a free day in the user's workday calendar
+	Weekend bool `json:"weekend" example:"false"`
+	// Number of baseline nights the night was compared with
+	BaselineNights int `json:"baseline_nights" example:"14"`
+	// Medians of those baseline nights
+	BaselineMedians AnomalyBaseline `json:"baseline_medians"`
+	// False if there were too few nights of the same kind and all nights were used
+	Seasonal bool `json:"seasonal" example:"true"`
+	// Highest severity of the measures
+	Severity AnomalySeverity `json:"severity" example:"MODERATE"`
+	// Reasons of all measures
+	Reason   string           `json:"reason" example:"Slept 4.5 h, 3 h less than the usual 7.5 h on weekday nights"`
+	Measures []MeasureAnomaly `json:"measures"`
+}
+
+// AnomaliesResponse is the response for the anomalies endpoint.
+// @Description Nights in a window that deviate from the user's rolling baseline.
+type AnomaliesResponse struct {
+	// Window of the scored nights
+	Window struct {
+		From time.Time `json:"from" example:"2024-01-01T00:00:00Z"`
+		To   time.Time `json:"to" example:"2024-01-14T23:59:59Z"`
+	} `json:"window"`
+	// Length of the rolling baseline before each night
+	BaselineDays int `json:"baseline_days" example:"28"`
+	// Absolute robust z-score from which a measure is flagged
+	Threshold float64 `json:"threshold" example:"3.5"`
+	// Number of nights with enough baseline to be scored
+	NightsScored int `json:"nights_scored" example:"12"`
+	// Flagged nights, most recent first
+	Anomalies []SleepAnomaly `json:"anomalies"`
 }
 
-// LLMInsightsOutput contains the structured output from the LLM.
+// LLMInsightsOutput contains the structured output from the LLM. The LLM
+// clients derive the response JSON Schema from the json tags and check every
+// reply against the validate tags, which hold the rules of the system prompt.
 // @Description LLM-generated sleep insights.
 type LLMInsightsOutput struct {
 	// Summary of sleep patterns (2-3 sentences)
-	Summary string `json:"summary" example:"Your sleep has been fairly consistent this week..."`
+	Summary string `json:"summary" validate:"required" example:"Your sleep has been fairly consistent this week..."`
 	// Observations about patterns (3-6 items)
-	Observations []string `json:"observations" example:"[\"Average duration of 7.2 hours meets recommended guidelines\"]"`
+	Observations []string `json:"observations" validate:"min=3,max=6,dive,required" example:"[\"Average duration of 7.2 hours meets recommended guidelines\"]"`
 	// Actionable guidance (3-5 items)
-	Guidance []string `json:"guidance" example:"[\"Try to maintain your current bedtime of around 11 PM\"]"`
+	Guidance []string `json:"guidance" validate:"min=3,max=5,dive,required" example:"[\"Try to maintain your current bedtime of around 11 PM\"]"`
 }
 
 // InsightsContext is the context object sent to the LLM.
@@ -136,6 +586,12 @@ type InsightsContext struct {
 	History    WindowMetrics    `json:"history"`
 	Recent     WindowMetrics    `json:"recent"`
 	LastNight  WindowMetrics    `json:"last_night"`
+	// Unusual nights of the recent window, most recent first
+	Anomalies []SleepAnomaly `json:"anomalies,omitempty"`
+	// Sleep goals in force, absent when the user has not set any
+	Goal *SleepGoalResponse `json:"goal,omitempty"`
+	// Sleep owed at the end of today in hours
+	SleepDebtHours float64 `json:"sleep_debt_hours"`
 }
 
 // InsightsResponse is the response for the insights endpoint.
@@ -151,6 +607,13 @@ type InsightsResponse struct {
 	} `json:"metrics"`
 	// LLM-generated insights
 	Insights LLMInsightsOutput `json:"insights"`
+	// Provider, model and prompt version that wrote the insights; provider
+	// "rules" when the fixed rules stood in for an unavailable LLM
+	Generator *InsightsGenerator `json:"generator,omitempty"`
+	// When the insights were generated; earlier than the request when served from the cache
+	GeneratedAt time.Time `json:"generated_at" example:"2024-01-15T08:30:00Z"`
+	// Cached is set when the response was served from the insights cache
+	Cached bool `json:"-"`
 	// Trace ID for feedback (optional, only present when Langfuse is enabled)
 	TraceID string `json:"trace_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`
 }
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InsightsGenerator identifies what produces insights: a provider, its model
+// and the version of the prompts sent to it.
+type InsightsGenerator struct {
+	Provider      string `json:"provider" example:"openai"`
+	Model         string `json:"model" example:"gpt-4o-mini"`
+	PromptVersion string `json:"prompt_version" example:"3f2a9c1b7e4d"`
+}
+
+// InsightsCacheEntry is the last insights response generated for a user.
+// It is served again while the fingerprint of the insights context and
+// generator is unchanged, and dropped when the user's sleep logs change.
+type InsightsCacheEntry struct {
+	UserID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	// Fingerprint is the hex SHA-256 of the insights context and generator
+	Fingerprint   string `gorm:"type:varchar(64);not null"`
+	Provider      string `gorm:"not null"`
+	Model         string `gorm:"not null"`
+	PromptVersion string `gorm:"not null"`
+	// Response is the JSON-encoded InsightsResponse
+	Response    string    `gorm:"type:text;not null"`
+	GeneratedAt time.Time `gorm:"not null"`
+}
+
+func (InsightsCacheEntry) TableName() string {
+	return "insights_cache"
+}
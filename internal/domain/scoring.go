@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// ScoreCurve maps a measure onto a 0-100 score. Values at or past Worst
+// score 0 and values at or past Best score 100. In between the score is
+// 100·t^Exponent, where t is the linear position from Worst to Best, so
+// exponents above 1 are stricter and exponents below 1 more lenient.
+type ScoreCurve struct {
+	Worst    float64 `json:"worst" yaml:"worst"`
+	Best     float64 `json:"best" yaml:"best"`
+	Exponent float64 `json:"exponent" yaml:"exponent"`
+}
+
+// ScoringWeights are the shares of the components of the overall score.
+type ScoringWeights struct {
+	Regularity       float64 `json:"regularity" yaml:"regularity" example:"0.4"`
+	Sufficiency      float64 `json:"sufficiency" yaml:"sufficiency" example:"0.3"`
+	DailySufficiency float64 `json:"daily_sufficiency" yaml:"daily_sufficiency" example:"0.3"`
+}
+
+// ScoringModelConfig describes a config-driven scoring model. Name and
+// Version identify the model in DerivedScores, so a change of weights or
+// curves should come with a new version.
+// @Description Weights, curves and bounds of a scoring model.
+type ScoringModelConfig struct {
+	// Model name
+	Name string `json:"name" yaml:"name" example:"default"`
+	// Model version
+	Version string `json:"version" yaml:"version" example:"1"`
+	// Measure behind the regularity component
+	RegularitySource RegularitySource `json:"regularity_source" yaml:"regularity_source" example:"bedtime_std" enums:"bedtime_std,sri"`
+	// Weights of the overall score; they must add up to 1
+	Weights ScoringWeights `json:"weights" yaml:"weights"`
+	// Consistency score from the bedtime std in minutes
+	Consistency ScoreCurve `json:"consistency" yaml:"consistency"`
+	// Sufficiency score from the average core sleep duration in hours relative to the daily target
+	Sufficiency ScoreCurve `json:"sufficiency" yaml:"sufficiency"`
+	// Bounds of the overall score
+	OverallMin float64 `json:"overall_min" yaml:"overall_min" example:"0"`
+	OverallMax float64 `json:"overall_max" yaml:"overall_max" example:"100"`
+}
+
+// Validate checks that the model is complete and consistent. Errors wrap
+// ErrInvalidScoringModel.
+func (c *ScoringModelConfig) Validate() error {
+	if c.Name == "" || c.Version == "" {
+		return fmt.Errorf("%w: name and version are required", ErrInvalidScoringModel)
+	}
+	if c.RegularitySource != RegularitySourceBedtimeStd && c.RegularitySource != RegularitySourceSRI {
+		return fmt.Errorf("%w: regularity_source must be bedtime_std or sri", ErrInvalidScoringModel)
+	}
+	w := c.Weights
+	if w.Regularity < 0 || w.Sufficiency < 0 || w.DailySufficiency < 0 {
+		return fmt.Errorf("%w: weights must not be negative", ErrInvalidScoringModel)
+	}
+	if math.Abs(w.Regularity+w.Sufficiency+w.DailySufficiency-1) > 1e-6 {
+		return fmt.Errorf("%w: weights must add up to 1", ErrInvalidScoringModel)
+	}
+	for name, curve := range map[string]ScoreCurve{"consistency": c.Consistency, "sufficiency": c.Sufficiency} {
+		if curve.Worst == curve.Best {
+			return fmt.Errorf("%w: %s worst and best must differ", ErrInvalidScoringModel, name)
+		}
+		if curve.Exponent <= 0 {
+			return fmt.Errorf("%w: %s exponent must be positive", ErrInvalidScoringModel, name)
+		}
+	}
+	if c.OverallMin < 0 || c.OverallMax > 100 || c.OverallMin >= c.OverallMax {
+		return fmt.Errorf("%w: overall_min and overall_max must satisfy 0 <= min < max <= 100", ErrInvalidScoringModel)
+	}
+	return nil
+}
+
+// ScoreComparisonRequest is the request body for comparing scoring models.
+// @Description Two scoring models to apply to the same window.
+type ScoreComparisonRequest struct {
+	// Number of days to score
+	WindowDays int `json:"window_days,omitempty" validate:"omitempty,min=1,max=365" example:"30"`
+	// Model to compare against; the active model when omitted
+	Baseline *ScoringModelConfig `json:"baseline,omitempty"`
+	// Model to try
+	Candidate ScoringModelConfig `json:"candidate"`
+}
+
+// ScoreComparisonResponse is the response for comparing scoring models.
+// @Description Derived scores of two models over the same window.
+type ScoreComparisonResponse struct {
+	Window struct {
+		From time.Time `json:"from" example:"2024-01-01T00:00:00Z"`
+		To   time.Time `json:"to" example:"2024-01-31T23:59:59Z"`
+	} `json:"window"`
+	// Scores of the baseline model
+	Baseline DerivedScores `json:"baseline"`
+	// Scores of the candidate model
+	Candidate DerivedScores `json:"candidate"`
+	// Candidate minus baseline overall sleep score
+	OverallDiff float64 `json:"overall_diff" example:"-2.5"`
+}
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SleepGoal is a version of a user's sleep goals. Every change closes the
+// goal in force and starts a new one, so past windows can be scored against
+// the goal that applied at the time. Clock times are local "HH:MM" strings.
+type SleepGoal struct {
+	ID                  uuid.UUID  `gorm:"type:uuid;primaryKey" json:"-"`
+	UserID              uuid.UUID  `gorm:"type:uuid;not null;index:idx_sleep_goals_user_from" json:"-"`
+	TargetHours         float64    `gorm:"not null" json:"target_hours"`
+	BedtimeWindowStart  *string    `gorm:"type:varchar(5)" json:"bedtime_window_start,omitempty"`
+	BedtimeWindowEnd    *string    `gorm:"type:varchar(5)" json:"bedtime_window_end,omitempty"`
+	WakeTime            *string    `gorm:"type:varchar(5)" json:"wake_time,omitempty"`
+	NapAllowanceMinutes *int       `json:"nap_allowance_minutes,omitempty"`
+	EffectiveFrom       time.Time  `gorm:"not null;index:idx_sleep_goals_user_from" json:"effective_from"`
+	EffectiveTo         *time.Time `json:"effective_to,omitempty"`
+	CreatedAt           time.Time  `gorm:"autoCreateTime" json:"-"`
+}
+
+func (SleepGoal) TableName() string {
+	return "sleep_goals"
+}
+
+// BeforeCreate assigns a UUID, as for SleepLog.
+func (g *SleepGoal) BeforeCreate(tx *gorm.DB) error {
+	if g.ID == uuid.Nil {
+		g.ID = uuid.New()
+	}
+	return nil
+}
+
+// InForce reports whether the goal applied at t.
+func (g *SleepGoal) InForce(t time.Time) bool {
+	return !t.Before(g.EffectiveFrom) && (g.EffectiveTo == nil || t.Before(*g.EffectiveTo))
+}
+
+// SleepGoalRequest is the request body for setting the sleep goals.
+// @Description Sleep goals. Omitted optional goals are not tracked.
+type SleepGoalRequest struct {
+	// Target total sleep per day in hours
+	TargetHours float64 `json:"target_hours" validate:"required,min=4,max=12" example:"8"`
+	// Earliest targeted bedtime in local time (HH:MM), together with bedtime_window_end
+	BedtimeWindowStart *string `json:"bedtime_window_start,omitempty" validate:"required_with=BedtimeWindowEnd,omitempty,clock" example:"22:30"`
+	// Latest targeted bedtime in local time (HH:MM); may be after midnight
+	BedtimeWindowEnd *string `json:"bedtime_window_end,omitempty" validate:"required_with=BedtimeWindowStart,omitempty,clock" example:"23:30"`
+	// Targeted wake time in local time (HH:MM)
+	WakeTime *string `json:"wake_time,omitempty" validate:"omitempty,clock" example:"07:00"`
+	// Naps per day in minutes that fit the goal
+	NapAllowanceMinutes *int `json:"nap_allowance_minutes,omitempty" validate:"omitempty,min=0,max=240" example:"30"`
+}
+
+// SleepGoalResponse is the response body for sleep goal endpoints.
+// @Description A version of the user's sleep goals.
+type SleepGoalResponse struct {
+	// Target total sleep per day in hours
+	TargetHours float64 `json:"target_hours" example:"8"`
+	// Earliest targeted bedtime in local time
+	BedtimeWindowStart *string `json:"bedtime_window_start,omitempty" example:"22:30"`
+	// Latest targeted bedtime in local time
+	BedtimeWindowEnd *string `json:"bedtime_window_end,omitempty" example:"23:30"`
+	// Targeted wake time in local time
+	WakeTime *string `json:"wake_time,omitempty" example:"07:00"`
+	// Naps per day in minutes that fit the goal
+	NapAllowanceMinutes *int `json:"nap_allowance_minutes,omitempty" example:"30"`
+	// When the goal came into force (RFC3339)
+	EffectiveFrom time.Time `json:"effective_from" example:"2024-01-15T10:30:00Z"`
+	// When the goal was replaced or removed, absent while in force
+	EffectiveTo *time.Time `json:"effective_to,omitempty" example:"2024-02-01T08:00:00Z"`
+}
+
+// SleepGoalHistoryResponse is the response body for the goal history.
+// @Description All versions of the user's sleep goals, oldest first.
+type SleepGoalHistoryResponse struct {
+	Data []SleepGoalResponse `json:"data"`
+}
+
+func (g *SleepGoal) ToResponse() SleepGoalResponse {
+	return SleepGoalResponse{
+		TargetHours:         g.TargetHours,
+		BedtimeWindowStart:  g.BedtimeWindowStart,
+		BedtimeWindowEnd:    g.BedtimeWindowEnd,
+		WakeTime:            g.WakeTime,
+		NapAllowanceMinutes: g.NapAllowanceMinutes,
+		EffectiveFrom:       g.EffectiveFrom,
+		EffectiveTo:         g.EffectiveTo,
+	}
+}
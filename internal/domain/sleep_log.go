@@ -28,9 +28,16 @@ type SleepLog struct {
 	LocalTimezone   string    `gorm:"type:varchar(64);not null;default:'UTC'" json:"local_timezone"`
 	ClientRequestID *string   `gorm:"type:varchar(255);uniqueIndex:idx_user_client_request,priority:2,where:client_request_id IS NOT NULL" json:"client_request_id,omitempty"`
 	CreatedAt       time.Time `gorm:"autoCreateTime" json:"created_at"`
+	// Version is incremented on every update and exposed as the ETag.
+	Version int `gorm:"not null;default:1" json:"version"`
+	// DeletedAt marks a soft-deleted log. GORM excludes these rows from
+	// regular queries; the retention job purges them permanently.
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Associations
-	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	User    User             `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	Stages  []SleepStage     `gorm:"foreignKey:SleepLogID;constraint:OnDelete:CASCADE" json:"stages,omitempty"`
+	Factors []SleepLogFactor `gorm:"foreignKey:SleepLogID;constraint:OnDelete:CASCADE" json:"factors,omitempty"`
 }
 
 func (SleepLog) TableName() string {
@@ -61,6 +68,10 @@ type CreateSleepLogRequest struct {
 	ClientRequestID *string `json:"client_request_id,omitempty" validate:"omitempty,max=255" example:"client-uuid-12345"`
 	// Optional IANA timezone for local time display (defaults to user's timezone)
 	LocalTimezone *string `json:"local_timezone,omitempty" validate:"omitempty,timezone" example:"Europe/Prague"`
+	// Optional sleep stage segments (contiguous, within start_at and end_at)
+	Stages []SleepStageRequest `json:"stages,omitempty" validate:"omitempty,max=500,dive"`
+	// Optional contextual factors (caffeine, alcohol, exercise, ...), each tag at most once
+	Factors []FactorRequest `json:"factors,omitempty" validate:"omitempty,max=20,dive"`
 }
 
 // SleepLogResponse is the response body for sleep log endpoints.
@@ -82,12 +93,18 @@ type SleepLogResponse struct {
 	ClientRequestID *string `json:"client_request_id,omitempty" example:"client-uuid-12345"`
 	// Record creation timestamp
 	CreatedAt time.Time `json:"created_at" example:"2024-01-16T07:05:00Z"`
+	// Record version, incremented on every update (also returned as the ETag header)
+	Version int `json:"version" example:"1"`
 	// Timezone used for local times
 	LocalTimezone string `json:"local_timezone" example:"Europe/Prague"`
 	// Sleep start in local timezone
 	LocalStartAt time.Time `json:"local_start_at" example:"2024-01-16T00:00:00+01:00"`
 	// Sleep end in local timezone
 	LocalEndAt time.Time `json:"local_end_at" example:"2024-01-16T08:00:00+01:00"`
+	// Sleep stage segments, if recorded
+	Stages []SleepStageResponse `json:"stages,omitempty"`
+	// Contextual factors, if recorded
+	Factors []FactorResponse `json:"factors,omitempty"`
 }
 
 func (s *SleepLog) ToResponse() SleepLogResponse {
@@ -98,6 +115,16 @@ func (s *SleepLog) ToResponse() SleepLogResponse {
 		}
 	}
 
+	var stages []SleepStageResponse
+	for _, stage := range s.Stages {
+		stages = append(stages, SleepStageResponse{Stage: stage.Stage, StartAt: stage.StartAt, EndAt: stage.EndAt})
+	}
+
+	var factors []FactorResponse
+	for _, factor := range s.Factors {
+		factors = append(factors, factor.ToResponse())
+	}
+
 	return SleepLogResponse{
 		ID:              s.ID,
 		UserID:          s.UserID,
@@ -107,9 +134,28 @@ func (s *SleepLog) ToResponse() SleepLogResponse {
 		Type:            s.Type,
 		ClientRequestID: s.ClientRequestID,
 		CreatedAt:       s.CreatedAt,
+		Version:         s.Version,
 		LocalTimezone:   s.LocalTimezone,
 		LocalStartAt:    s.StartAt.In(loc),
 		LocalEndAt:      s.EndAt.In(loc),
+		Stages:          stages,
+		Factors:         factors,
+	}
+}
+
+// ToCreateRequest returns the request document that describes the log's
+// current state. It is the target document for JSON merge patches.
+func (s *SleepLog) ToCreateRequest() CreateSleepLogRequest {
+	localTZ := s.LocalTimezone
+	return CreateSleepLogRequest{
+		StartAt:         s.StartAt,
+		EndAt:           s.EndAt,
+		Quality:         s.Quality,
+		Type:            s.Type,
+		ClientRequestID: s.ClientRequestID,
+		LocalTimezone:   &localTZ,
+		Stages:          StageRequests(s.Stages),
+		Factors:         FactorRequests(s.Factors),
 	}
 }
 
@@ -137,6 +183,8 @@ type SleepLogFilter struct {
 	To     *time.Time
 	Limit  int
 	Cursor string
+	// Tag limits results to logs with a factor of this tag name
+	Tag string
 }
 
 // UpdateSleepLogRequest is the request body for updating a sleep log.
@@ -152,4 +200,8 @@ type UpdateSleepLogRequest struct {
 	Type *SleepType `json:"type,omitempty" validate:"omitempty,oneof=CORE NAP" example:"CORE" enums:"CORE,NAP"`
 	// Optional IANA timezone for local time display
 	LocalTimezone *string `json:"local_timezone,omitempty" validate:"omitempty,timezone" example:"Europe/Prague"`
+	// Sleep stage segments; replaces all stored stages when present, [] removes them
+	Stages []SleepStageRequest `json:"stages,omitempty" validate:"omitempty,max=500,dive"`
+	// Contextual factors; replaces all stored factors when present, [] removes them
+	Factors []FactorRequest `json:"factors,omitempty" validate:"omitempty,max=20,dive"`
 }
@@ -0,0 +1,33 @@
+package domain
+
+// BatchMode controls how a batch of sleep logs is committed.
+// @Description atomic inserts all items or none; best_effort inserts every item that passes its checks.
+type BatchMode string
+
+const (
+	// BatchModeAtomic rejects the whole batch if any item fails
+	BatchModeAtomic BatchMode = "atomic"
+	// BatchModeBestEffort inserts the valid items and reports the rest
+	BatchModeBestEffort BatchMode = "best_effort"
+)
+
+// MaxBatchItems is the maximum number of sleep logs accepted in one batch.
+const MaxBatchItems = 500
+
+// BatchCreateSleepLogsRequest is the request body for creating sleep logs in bulk.
+// @Description Request payload for importing many sleep sessions at once.
+type BatchCreateSleepLogsRequest struct {
+	// Commit mode: atomic (default) or best_effort
+	Mode BatchMode `json:"mode,omitempty" validate:"omitempty,oneof=atomic best_effort" example:"atomic" enums:"atomic,best_effort"`
+	// Sleep sessions to create (1-500)
+	Items []CreateSleepLogRequest `json:"items" validate:"required,min=1,max=500"`
+}
+
+// BatchItemResult is the outcome of a single batch item.
+// Exactly one of Log or Err is set.
+type BatchItemResult struct {
+	Log *SleepLog
+	// Existing is true if Log was returned due to client_request_id idempotency
+	Existing bool
+	Err      error
+}
@@ -0,0 +1,113 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SleepStageType is a phase of the sleep hypnogram.
+// @Description Sleep stage: AWAKE, LIGHT, DEEP or REM.
+type SleepStageType string
+
+const (
+	SleepStageAwake SleepStageType = "AWAKE"
+	SleepStageLight SleepStageType = "LIGHT"
+	SleepStageDeep  SleepStageType = "DEEP"
+	SleepStageREM   SleepStageType = "REM"
+)
+
+// MaxSleepStages is the maximum number of stage segments per sleep log.
+const MaxSleepStages = 500
+
+// SleepStage is one contiguous segment of a sleep log's hypnogram.
+type SleepStage struct {
+	ID         uuid.UUID      `gorm:"type:uuid;primaryKey" json:"-"`
+	SleepLogID uuid.UUID      `gorm:"type:uuid;not null;index:idx_sleep_stages_log_start" json:"-"`
+	Stage      SleepStageType `gorm:"type:varchar(10);not null" json:"stage"`
+	StartAt    time.Time      `gorm:"not null;index:idx_sleep_stages_log_start" json:"start_at"`
+	EndAt      time.Time      `gorm:"not null" json:"end_at"`
+}
+
+func (SleepStage) TableName() string {
+	return "sleep_stages"
+}
+
+// BeforeCreate assigns a UUID, as for SleepLog.
+func (s *SleepStage) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// SleepStageRequest describes a stage segment in create and update requests.
+// @Description Sleep stage segment. Segments must be contiguous and lie within the sleep log.
+type SleepStageRequest struct {
+	// Stage type
+	Stage SleepStageType `json:"stage" validate:"required,oneof=AWAKE LIGHT DEEP REM" example:"DEEP" enums:"AWAKE,LIGHT,DEEP,REM"`
+	// Segment start time in RFC3339 format
+	StartAt time.Time `json:"start_at" validate:"required" example:"2024-01-16T01:00:00Z"`
+	// Segment end time in RFC3339 format (must be after start_at)
+	EndAt time.Time `json:"end_at" validate:"required,gtfield=StartAt" example:"2024-01-16T02:30:00Z"`
+}
+
+// SleepStageResponse is a stage segment in sleep log responses.
+// @Description Sleep stage segment (UTC).
+type SleepStageResponse struct {
+	Stage   SleepStageType `json:"stage" example:"DEEP"`
+	StartAt time.Time      `json:"start_at" example:"2024-01-16T01:00:00Z"`
+	EndAt   time.Time      `json:"end_at" example:"2024-01-16T02:30:00Z"`
+}
+
+// NewSleepStages converts request segments to UTC stages ordered by start time.
+func NewSleepStages(reqs []SleepStageRequest) []SleepStage {
+	if reqs == nil {
+		return nil
+	}
+	stages := make([]SleepStage, len(reqs))
+	for i, req := range reqs {
+		stages[i] = SleepStage{
+			Stage:   req.Stage,
+			StartAt: req.StartAt.UTC(),
+			EndAt:   req.EndAt.UTC(),
+		}
+	}
+	sort.SliceStable(stages, func(i, j int) bool { return stages[i].StartAt.Before(stages[j].StartAt) })
+	return stages
+}
+
+// ValidateSleepStages checks that stages, ordered by start time, are
+// contiguous (each starts where the previous ends) and lie within
+// [startAt, endAt]. Errors wrap ErrInvalidStages.
+func ValidateSleepStages(startAt, endAt time.Time, stages []SleepStage) error {
+	for i, stage := range stages {
+		if !stage.EndAt.After(stage.StartAt) {
+			return fmt.Errorf("%w: stage %d must end after it starts", ErrInvalidStages, i)
+		}
+		if i > 0 && !stage.StartAt.Equal(stages[i-1].EndAt) {
+			return fmt.Errorf("%w: stage %d must start when stage %d ends", ErrInvalidStages, i, i-1)
+		}
+	}
+	if len(stages) > 0 {
+		if stages[0].StartAt.Before(startAt) || stages[len(stages)-1].EndAt.After(endAt) {
+			return fmt.Errorf("%w: stages must lie within start_at and end_at", ErrInvalidStages)
+		}
+	}
+	return nil
+}
+
+// StageRequests converts stages back to request segments.
+func StageRequests(stages []SleepStage) []SleepStageRequest {
+	if len(stages) == 0 {
+		return nil
+	}
+	reqs := make([]SleepStageRequest, len(stages))
+	for i, stage := range stages {
+		reqs[i] = SleepStageRequest{Stage: stage.Stage, StartAt: stage.StartAt, EndAt: stage.EndAt}
+	}
+	return reqs
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidateSleepStages(t *testing.T) {
+	start := time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return start.Add(time.Duration(h) * time.Hour) }
+
+	tests := []struct {
+		name    string
+		stages  []SleepStageRequest
+		wantErr bool
+	}{
+		{
+			name:   "no stages",
+			stages: nil,
+		},
+		{
+			name: "contiguous stages covering the period",
+			stages: []SleepStageRequest{
+				{Stage: SleepStageLight, StartAt: at(0), EndAt: at(2)},
+				{Stage: SleepStageDeep, StartAt: at(2), EndAt: at(4)},
+				{Stage: SleepStageREM, StartAt: at(4), EndAt: at(8)},
+			},
+		},
+		{
+			name: "unordered stages are sorted first",
+			stages: []SleepStageRequest{
+				{Stage: SleepStageREM, StartAt: at(3), EndAt: at(5)},
+				{Stage: SleepStageLight, StartAt: at(1), EndAt: at(3)},
+			},
+		},
+		{
+			name: "gap between stages",
+			stages: []SleepStageRequest{
+				{Stage: SleepStageLight, StartAt: at(0), EndAt: at(2)},
+				{Stage: SleepStageDeep, StartAt: at(3), EndAt: at(4)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "overlapping stages",
+			stages: []SleepStageRequest{
+				{Stage: SleepStageLight, StartAt: at(0), EndAt: at(3)},
+				{Stage: SleepStageDeep, StartAt: at(2), EndAt: at(4)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "stage before start_at",
+			stages: []SleepStageRequest{
+				{Stage: SleepStageAwake, StartAt: at(-1), EndAt: at(1)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "stage after end_at",
+			stages: []SleepStageRequest{
+				{Stage: SleepStageREM, StartAt: at(7), EndAt: at(9)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty stage",
+			stages: []SleepStageRequest{
+				{Stage: SleepStageREM, StartAt: at(2), EndAt: at(2)},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSleepStages(start, end, NewSleepStages(tt.stages))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidStages) {
+					t.Errorf("ValidateSleepStages() error = %v, want ErrInvalidStages", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("ValidateSleepStages() unexpected error = %v", err)
+			}
+		})
+	}
+}
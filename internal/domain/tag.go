@@ -0,0 +1,221 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TagCategory groups contextual factors by kind.
+// @Description Factor category: SUBSTANCE, ACTIVITY, HEALTH, ENVIRONMENT or OTHER.
+type TagCategory string
+
+const (
+	TagCategorySubstance   TagCategory = "SUBSTANCE"
+	TagCategoryActivity    TagCategory = "ACTIVITY"
+	TagCategoryHealth      TagCategory = "HEALTH"
+	TagCategoryEnvironment TagCategory = "ENVIRONMENT"
+	TagCategoryOther       TagCategory = "OTHER"
+)
+
+// MaxFactorsPerLog is the maximum number of factors attached to one sleep log.
+const MaxFactorsPerLog = 20
+
+// Tag is a contextual factor that can be attached to sleep logs. Catalogue
+// tags have no owner and are shared by all users; user-defined tags belong
+// to a single user. Names are unique across a user's tags and the catalogue.
+type Tag struct {
+	ID        uuid.UUID   `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    *uuid.UUID  `gorm:"type:uuid;uniqueIndex:idx_tags_user_name" json:"-"`
+	Name      string      `gorm:"type:varchar(50);not null;uniqueIndex:idx_tags_user_name" json:"name"`
+	Label     string      `gorm:"type:varchar(100);not null" json:"label"`
+	Category  TagCategory `gorm:"type:varchar(20);not null" json:"category"`
+	Unit      string      `gorm:"type:varchar(20)" json:"unit,omitempty"`
+	CreatedAt time.Time   `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (Tag) TableName() string {
+	return "tags"
+}
+
+// BeforeCreate assigns a UUID, as for SleepLog.
+func (t *Tag) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// catalogueNamespace derives stable IDs for catalogue tags.
+var catalogueNamespace = uuid.MustParse("6f1c2b8e-3d4a-4c5b-9e7f-0a1b2c3d4e5f")
+
+// CatalogueTags returns the predefined tags. IDs are derived from the names,
+// so the catalogue can be upserted on every start.
+func CatalogueTags() []Tag {
+	tags := []Tag{
+		{Name: "caffeine", Label: "Caffeine", Category: TagCategorySubstance, Unit: "mg"},
+		{Name: "alcohol", Label: "Alcohol", Category: TagCategorySubstance, Unit: "drinks"},
+		{Name: "nicotine", Label: "Nicotine", Category: TagCategorySubstance},
+		{Name: "exercise", Label: "Exercise", Category: TagCategoryActivity, Unit: "minutes"},
+		{Name: "screen_time", Label: "Screen time before bed", Category: TagCategoryActivity, Unit: "minutes"},
+		{Name: "late_meal", Label: "Late meal", Category: TagCategoryActivity},
+		{Name: "illness", Label: "Illness", Category: TagCategoryHealth},
+		{Name: "stress", Label: "Stress", Category: TagCategoryHealth},
+		{Name: "medication", Label: "Sleep medication", Category: TagCategoryHealth},
+		{Name: "travel", Label: "Travel", Category: TagCategoryEnvironment},
+		{Name: "noise", Label: "Noise", Category: TagCategoryEnvironment},
+	}
+	for i := range tags {
+		tags[i].ID = uuid.NewSHA1(catalogueNamespace, []byte(tags[i].Name))
+	}
+	return tags
+}
+
+// CreateTagRequest is the request body for creating a user-defined tag.
+// @Description Request payload for a user-defined factor tag.
+type CreateTagRequest struct {
+	// Unique name used to reference the tag (lowercase letters, digits and underscores)
+	Name string `json:"name" validate:"required,max=50,slug" example:"melatonin"`
+	// Display label (defaults to the name)
+	Label string `json:"label,omitempty" validate:"omitempty,max=100" example:"Melatonin"`
+	// Category (defaults to OTHER)
+	Category TagCategory `json:"category,omitempty" validate:"omitempty,oneof=SUBSTANCE ACTIVITY HEALTH ENVIRONMENT OTHER" example:"SUBSTANCE" enums:"SUBSTANCE,ACTIVITY,HEALTH,ENVIRONMENT,OTHER"`
+	// Unit of the factor amount
+	Unit string `json:"unit,omitempty" validate:"omitempty,max=20" example:"mg"`
+}
+
+// TagResponse is the response body for tag endpoints.
+// @Description Factor tag from the catalogue or defined by the user.
+type TagResponse struct {
+	ID       uuid.UUID   `json:"id" example:"770e8400-e29b-41d4-a716-446655440002"`
+	Name     string      `json:"name" example:"caffeine"`
+	Label    string      `json:"label" example:"Caffeine"`
+	Category TagCategory `json:"category" example:"SUBSTANCE"`
+	Unit     string      `json:"unit,omitempty" example:"mg"`
+	// True for catalogue tags shared by all users
+	Predefined bool `json:"predefined" example:"true"`
+}
+
+// TagListResponse is the response body for listing tags.
+// @Description Catalogue tags followed by the user's own tags.
+type TagListResponse struct {
+	Data []TagResponse `json:"data"`
+}
+
+func (t *Tag) ToResponse() TagResponse {
+	return TagResponse{
+		ID:         t.ID,
+		Name:       t.Name,
+		Label:      t.Label,
+		Category:   t.Category,
+		Unit:       t.Unit,
+		Predefined: t.UserID == nil,
+	}
+}
+
+// SleepLogFactor links a tag to a sleep log, with an optional amount and time.
+type SleepLogFactor struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"-"`
+	SleepLogID uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_sleep_log_factors_log_tag" json:"-"`
+	TagID      uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_sleep_log_factors_log_tag;index" json:"-"`
+	Tag        Tag        `gorm:"foreignKey:TagID;constraint:OnDelete:CASCADE" json:"-"`
+	Amount     *float64   `json:"amount,omitempty"`
+	At         *time.Time `json:"at,omitempty"`
+}
+
+func (SleepLogFactor) TableName() string {
+	return "sleep_log_factors"
+}
+
+// BeforeCreate assigns a UUID, as for SleepLog.
+func (f *SleepLogFactor) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == uuid.Nil {
+		f.ID = uuid.New()
+	}
+	return nil
+}
+
+// FactorRequest attaches a tag to a sleep log in create and update requests.
+// @Description Contextual factor, referencing a catalogue or user-defined tag by name.
+type FactorRequest struct {
+	// Tag name
+	Tag string `json:"tag" validate:"required,max=50" example:"caffeine"`
+	// Optional amount in the tag's unit
+	Amount *float64 `json:"amount,omitempty" validate:"omitempty,gte=0" example:"200"`
+	// Optional time of the factor in RFC3339 format
+	At *time.Time `json:"at,omitempty" example:"2024-01-15T16:00:00Z"`
+}
+
+// FactorResponse is a factor in sleep log responses.
+// @Description Contextual factor of a sleep log.
+type FactorResponse struct {
+	Tag      string      `json:"tag" example:"caffeine"`
+	Label    string      `json:"label" example:"Caffeine"`
+	Category TagCategory `json:"category" example:"SUBSTANCE"`
+	Unit     string      `json:"unit,omitempty" example:"mg"`
+	Amount   *float64    `json:"amount,omitempty" example:"200"`
+	At       *time.Time  `json:"at,omitempty" example:"2024-01-15T16:00:00Z"`
+}
+
+// FactorTagNames returns the tag names referenced by the requests.
+func FactorTagNames(reqs []FactorRequest) []string {
+	names := make([]string, 0, len(reqs))
+	for _, req := range reqs {
+		names = append(names, req.Tag)
+	}
+	return names
+}
+
+// NewSleepLogFactors resolves factor requests against the tags available to
+// the user, keyed by name. Unknown and repeated tags fail with an error
+// wrapping ErrInvalidFactors. Returns nil when reqs is nil.
+func NewSleepLogFactors(reqs []FactorRequest, tags map[string]Tag) ([]SleepLogFactor, error) {
+	if reqs == nil {
+		return nil, nil
+	}
+	factors := make([]SleepLogFactor, 0, len(reqs))
+	seen := make(map[string]bool, len(reqs))
+	for _, req := range reqs {
+		tag, ok := tags[req.Tag]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown tag %q", ErrInvalidFactors, req.Tag)
+		}
+		if seen[req.Tag] {
+			return nil, fmt.Errorf("%w: tag %q is repeated", ErrInvalidFactors, req.Tag)
+		}
+		seen[req.Tag] = true
+
+		factor := SleepLogFactor{TagID: tag.ID, Tag: tag, Amount: req.Amount}
+		if req.At != nil {
+			at := req.At.UTC()
+			factor.At = &at
+		}
+		factors = append(factors, factor)
+	}
+	return factors, nil
+}
+
+// FactorRequests converts factors back to requests.
+func FactorRequests(factors []SleepLogFactor) []FactorRequest {
+	if len(factors) == 0 {
+		return nil
+	}
+	reqs := make([]FactorRequest, len(factors))
+	for i, factor := range factors {
+		reqs[i] = FactorRequest{Tag: factor.Tag.Name, Amount: factor.Amount, At: factor.At}
+	}
+	return reqs
+}
+
+func (f *SleepLogFactor) ToResponse() FactorResponse {
+	return FactorResponse{
+		Tag:      f.Tag.Name,
+		Label:    f.Tag.Label,
+		Category: f.Tag.Category,
+		Unit:     f.Tag.Unit,
+		Amount:   f.Amount,
+		At:       f.At,
+	}
+}
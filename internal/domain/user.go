@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,6 +10,7 @@ import (
 type User struct {
 	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
 	Timezone  string    `gorm:"type:varchar(64);not null;default:'UTC'" json:"timezone"`
+	Workdays  *string   `gorm:"type:varchar(27)" json:"-"` // comma-separated codes, nil for DefaultWorkdays
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
 }
 
@@ -22,6 +24,14 @@ type CreateUserRequest struct {
 	// IANA timezone identifier (e.g., "America/New_York", "Europe/London", "UTC").
 	// See: https://en.wikipedia.org/wiki/List_of_tz_database_time_zones
 	Timezone string `json:"timezone" validate:"required,timezone" example:"Europe/Prague"`
+	// Days of the week the user works (defaults to MON-FRI); the others are free days
+	Workdays []string `json:"workdays,omitempty" validate:"omitempty,max=7,unique,dive,oneof=MON TUE WED THU FRI SAT SUN" example:"MON,TUE,WED,THU,FRI"`
+}
+
+// UpdateWorkdaysRequest is the request body for replacing the workday calendar.
+// @Description Days of the week the user works. An empty list makes every day free.
+type UpdateWorkdaysRequest struct {
+	Workdays []string `json:"workdays" validate:"max=7,unique,dive,oneof=MON TUE WED THU FRI SAT SUN" example:"MON,TUE,WED,THU,FRI"`
 }
 
 // UserResponse is the response body for user endpoints.
@@ -31,6 +41,8 @@ type UserResponse struct {
 	ID uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
 	// User's preferred IANA timezone
 	Timezone string `json:"timezone" example:"Europe/Prague"`
+	// Days of the week the user works
+	Workdays []string `json:"workdays" example:"MON,TUE,WED,THU,FRI"`
 	// Account creation timestamp (RFC3339)
 	CreatedAt time.Time `json:"created_at" example:"2024-01-15T10:30:00Z"`
 }
@@ -39,6 +51,50 @@ func (u *User) ToResponse() UserResponse {
 	return UserResponse{
 		ID:        u.ID,
 		Timezone:  u.Timezone,
+		Workdays:  u.WorkdayCodes(),
 		CreatedAt: u.CreatedAt,
 	}
 }
+
+// DefaultWorkdays is the workday calendar of users who have not set one.
+var DefaultWorkdays = []string{"MON", "TUE", "WED", "THU", "FRI"}
+
+// weekdayCodes lists the workday codes in calendar order, indexed by time.Weekday.
+var weekdayCodes = [7]string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+
+// SetWorkdays stores the workdays in week order, starting on Monday.
+func (u *User) SetWorkdays(codes []string) {
+	set := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		set[code] = true
+	}
+	ordered := make([]string, 0, len(codes))
+	for i := 1; i <= 7; i++ {
+		if code := weekdayCodes[i%7]; set[code] {
+			ordered = append(ordered, code)
+		}
+	}
+	joined := strings.Join(ordered, ",")
+	u.Workdays = &joined
+}
+
+// WorkdayCodes returns the user's workday codes in week order.
+func (u *User) WorkdayCodes() []string {
+	if u.Workdays == nil {
+		return append([]string(nil), DefaultWorkdays...)
+	}
+	if *u.Workdays == "" {
+		return []string{}
+	}
+	return strings.Split(*u.Workdays, ",")
+}
+
+// IsWorkday reports whether the user works on the weekday.
+func (u *User) IsWorkday(day time.Weekday) bool {
+	for _, code := range u.WorkdayCodes() {
+		if code == weekdayCodes[day] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUser_Workdays(t *testing.T) {
+	tests := []struct {
+		name     string
+		set      []string
+		want     string
+		workday  time.Weekday
+		isWorker bool
+	}{
+		{name: "default calendar", set: nil, want: "MON,TUE,WED,THU,FRI", workday: time.Friday, isWorker: true},
+		{name: "ordered by week", set: []string{"SUN", "SAT", "MON"}, want: "MON,SAT,SUN", workday: time.Sunday, isWorker: true},
+		{name: "no workdays", set: []string{}, want: "", workday: time.Monday, isWorker: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &User{}
+			if tt.set != nil {
+				user.SetWorkdays(tt.set)
+			}
+			if got := strings.Join(user.WorkdayCodes(), ","); got != tt.want {
+				t.Errorf("WorkdayCodes() = %q, want %q", got, tt.want)
+			}
+			if got := user.IsWorkday(tt.workday); got != tt.isWorker {
+				t.Errorf("IsWorkday(%s) = %v, want %v", tt.workday, got, tt.isWorker)
+			}
+		})
+	}
+}
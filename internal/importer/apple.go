@@ -0,0 +1,80 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// appleSleepType is the record type of sleep analysis samples in export.xml.
+const appleSleepType = "HKCategoryTypeIdentifierSleepAnalysis"
+
+// appleDateLayout is the timestamp format used throughout export.xml.
+const appleDateLayout = "2006-01-02 15:04:05 -0700"
+
+var appleStages = map[string]stage{
+	"HKCategoryValueSleepAnalysisInBed":             stageInBed,
+	"HKCategoryValueSleepAnalysisAwake":             stageAwake,
+	"HKCategoryValueSleepAnalysisAsleep":            stageAsleep,
+	"HKCategoryValueSleepAnalysisAsleepUnspecified": stageAsleep,
+	// Apple's "core" sleep is the light stage, not the CORE sleep type
+	"HKCategoryValueSleepAnalysisAsleepCore": stageLight,
+	"HKCategoryValueSleepAnalysisAsleepDeep": stageDeep,
+	"HKCategoryValueSleepAnalysisAsleepREM":  stageREM,
+}
+
+// parseAppleHealth streams export.xml and keeps only sleep analysis records,
+// since full exports routinely exceed hundreds of megabytes.
+func parseAppleHealth(r io.Reader) ([]segment, error) {
+	decoder := xml.NewDecoder(r)
+	var segments []segment
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML: %w", err)
+		}
+
+		elem, ok := token.(xml.StartElement)
+		if !ok || elem.Name.Local != "Record" {
+			continue
+		}
+
+		var recordType, value, start, end string
+		for _, attr := range elem.Attr {
+			switch attr.Name.Local {
+			case "type":
+				recordType = attr.Value
+			case "value":
+				value = attr.Value
+			case "startDate":
+				start = attr.Value
+			case "endDate":
+				end = attr.Value
+			}
+		}
+		if recordType != appleSleepType {
+			continue
+		}
+
+		st, ok := appleStages[value]
+		if !ok {
+			continue
+		}
+		startAt, err := time.Parse(appleDateLayout, start)
+		if err != nil {
+			return nil, fmt.Errorf("invalid startDate %q: %w", start, err)
+		}
+		endAt, err := time.Parse(appleDateLayout, end)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endDate %q: %w", end, err)
+		}
+		segments = append(segments, segment{Start: startAt, End: endAt, Stage: st})
+	}
+
+	return segments, nil
+}
@@ -0,0 +1,108 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// fitbitTimeLayout is Fitbit's local timestamp format; it carries no offset.
+const fitbitTimeLayout = "2006-01-02T15:04:05.000"
+
+var fitbitStages = map[string]stage{
+	// Stages logs
+	"wake":  stageAwake,
+	"light": stageLight,
+	"deep":  stageDeep,
+	"rem":   stageREM,
+	// Classic logs
+	"awake":    stageAwake,
+	"restless": stageAwake,
+	"asleep":   stageAsleep,
+}
+
+type fitbitLog struct {
+	StartTime string `json:"startTime"`
+	EndTime   string `json:"endTime"`
+	Levels    struct {
+		Data []struct {
+			DateTime string `json:"dateTime"`
+			Level    string `json:"level"`
+			Seconds  int    `json:"seconds"`
+		} `json:"data"`
+	} `json:"levels"`
+}
+
+// parseFitbit accepts either the Web API response ({"sleep": [...]}) or a
+// Takeout sleep-*.json file (a bare array of logs).
+func parseFitbit(r io.Reader, loc *time.Location) ([]segment, error) {
+	br := bufio.NewReader(r)
+	first, err := firstNonSpace(br)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var logs []fitbitLog
+	if first == '[' {
+		err = json.NewDecoder(br).Decode(&logs)
+	} else {
+		var doc struct {
+			Sleep []fitbitLog `json:"sleep"`
+		}
+		err = json.NewDecoder(br).Decode(&doc)
+		logs = doc.Sleep
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var segments []segment
+	for _, l := range logs {
+		// The log bounds cover the whole night, including time before the
+		// first and after the last stage record
+		start, err := time.ParseInLocation(fitbitTimeLayout, l.StartTime, loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid startTime %q: %w", l.StartTime, err)
+		}
+		end, err := time.ParseInLocation(fitbitTimeLayout, l.EndTime, loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endTime %q: %w", l.EndTime, err)
+		}
+		segments = append(segments, segment{Start: start, End: end, Stage: stageInBed})
+
+		for _, d := range l.Levels.Data {
+			st, ok := fitbitStages[d.Level]
+			if !ok {
+				continue
+			}
+			start, err := time.ParseInLocation(fitbitTimeLayout, d.DateTime, loc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid dateTime %q: %w", d.DateTime, err)
+			}
+			segments = append(segments, segment{
+				Start: start,
+				End:   start.Add(time.Duration(d.Seconds) * time.Second),
+				Stage: st,
+			})
+		}
+	}
+
+	return segments, nil
+}
+
+// firstNonSpace peeks at the first non-whitespace byte without consuming it.
+func firstNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, br.UnreadByte()
+	}
+}
@@ -0,0 +1,119 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// googleFitSleepActivity is the Google Fit activity type of sleep sessions.
+const googleFitSleepActivity = 72
+
+// googleStages maps Google Fit sleep segment values and Health Connect
+// SleepSessionRecord stage constants, which share the same numbering.
+var googleStages = map[int]stage{
+	1: stageAwake,
+	2: stageAsleep,
+	4: stageLight,
+	5: stageDeep,
+	6: stageREM,
+	7: stageAwake, // Health Connect: awake in bed
+}
+
+// googleInt64 decodes an int64 sent as a JSON number or string, as Google
+// APIs encode int64 fields as strings.
+type googleInt64 int64
+
+func (g *googleInt64) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		data = []byte(s)
+	}
+	v, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return err
+	}
+	*g = googleInt64(v)
+	return nil
+}
+
+type googleFitDocument struct {
+	// Google Fit sessions (users.sessions.list)
+	Session []struct {
+		StartTimeMillis googleInt64 `json:"startTimeMillis"`
+		EndTimeMillis   googleInt64 `json:"endTimeMillis"`
+		ActivityType    int         `json:"activityType"`
+	} `json:"session"`
+	// Google Fit com.google.sleep.segment data points
+	Point []struct {
+		StartTimeNanos googleInt64 `json:"startTimeNanos"`
+		EndTimeNanos   googleInt64 `json:"endTimeNanos"`
+		Value          []struct {
+			IntVal int `json:"intVal"`
+		} `json:"value"`
+	} `json:"point"`
+	// Health Connect sleep sessions
+	SleepSessions []struct {
+		StartTime time.Time `json:"startTime"`
+		EndTime   time.Time `json:"endTime"`
+		Stages    []struct {
+			StartTime time.Time `json:"startTime"`
+			EndTime   time.Time `json:"endTime"`
+			Stage     int       `json:"stage"`
+		} `json:"stages"`
+	} `json:"sleepSessions"`
+}
+
+// parseGoogleFit reads Google Fit sessions and sleep segments as well as
+// Health Connect sleep sessions; a document may contain any combination.
+func parseGoogleFit(r io.Reader) ([]segment, error) {
+	var doc googleFitDocument
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var segments []segment
+	for _, s := range doc.Session {
+		if s.ActivityType != googleFitSleepActivity {
+			continue
+		}
+		segments = append(segments, segment{
+			Start: time.UnixMilli(int64(s.StartTimeMillis)),
+			End:   time.UnixMilli(int64(s.EndTimeMillis)),
+			Stage: stageInBed,
+		})
+	}
+
+	for _, p := range doc.Point {
+		if len(p.Value) == 0 {
+			continue
+		}
+		st, ok := googleStages[p.Value[0].IntVal]
+		if !ok {
+			continue
+		}
+		segments = append(segments, segment{
+			Start: time.Unix(0, int64(p.StartTimeNanos)),
+			End:   time.Unix(0, int64(p.EndTimeNanos)),
+			Stage: st,
+		})
+	}
+
+	for _, s := range doc.SleepSessions {
+		segments = append(segments, segment{Start: s.StartTime, End: s.EndTime, Stage: stageInBed})
+		for _, st := range s.Stages {
+			mapped, ok := googleStages[st.Stage]
+			if !ok {
+				continue
+			}
+			segments = append(segments, segment{Start: st.StartTime, End: st.EndTime, Stage: mapped})
+		}
+	}
+
+	return segments, nil
+}
@@ -0,0 +1,298 @@
+// Package importer converts sleep data exported from wearable platforms into
+// sleep log requests.
+//
+// Vendor exports describe sleep as many small stage records (in bed, awake,
+// light, deep, REM). Each parser turns its format into segments; segments
+// separated by at most MaxGap are merged into one session, which becomes a
+// single CORE or NAP sleep log with a quality derived from sleep efficiency.
+// Sessions with full stage detail also carry their hypnogram as sleep stages.
+package importer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+)
+
+// Source identifies a vendor export format.
+type Source string
+
+const (
+	// SourceAppleHealth is the export.xml file of an Apple Health export
+	SourceAppleHealth Source = "apple_health"
+	// SourceFitbit is Fitbit sleep JSON (Web API response or Takeout sleep-*.json)
+	SourceFitbit Source = "fitbit"
+	// SourceGoogleFit is Google Fit sessions/sleep segments or Health Connect sleep sessions JSON
+	SourceGoogleFit Source = "google_fit"
+)
+
+// Sources lists all supported sources.
+var Sources = []Source{SourceAppleHealth, SourceFitbit, SourceGoogleFit}
+
+const (
+	// MaxGap is the longest break between segments that still belongs to the same session.
+	MaxGap = 30 * time.Minute
+
+	// MinCoreDuration is the shortest session classified as CORE; shorter ones are naps.
+	MinCoreDuration = 3 * time.Hour
+)
+
+var (
+	// ErrUnsupportedSource is returned for an unknown source name.
+	ErrUnsupportedSource = errors.New("unsupported import source")
+	// ErrNoSleepData is returned when an export contains no sleep records.
+	ErrNoSleepData = errors.New("export contains no sleep data")
+)
+
+// ParseSource validates a source name.
+func ParseSource(s string) (Source, error) {
+	for _, source := range Sources {
+		if Source(s) == source {
+			return source, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnsupportedSource, s)
+}
+
+// Parse reads a vendor export and returns one request per detected sleep
+// session, ordered by start time. loc is used for formats with local
+// timestamps that carry no offset (Fitbit).
+func Parse(source Source, r io.Reader, loc *time.Location) ([]domain.CreateSleepLogRequest, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	var segments []segment
+	var err error
+	switch source {
+	case SourceAppleHealth:
+		segments, err = parseAppleHealth(r)
+	case SourceFitbit:
+		segments, err = parseFitbit(r, loc)
+	case SourceGoogleFit:
+		segments, err = parseGoogleFit(r)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedSource, source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+
+	sessions := mergeSessions(segments)
+	if len(sessions) == 0 {
+		return nil, ErrNoSleepData
+	}
+	requests := make([]domain.CreateSleepLogRequest, 0, len(sessions))
+	for _, s := range sessions {
+		requests = append(requests, s.toRequest(source))
+	}
+	return requests, nil
+}
+
+// stage is the sleep stage of a segment, normalised across vendors.
+type stage int
+
+const (
+	// stageInBed covers a whole night without stage detail
+	stageInBed stage = iota
+	stageAwake
+	// stageAsleep is sleep of unknown stage
+	stageAsleep
+	stageLight
+	stageDeep
+	stageREM
+)
+
+func (s stage) isAsleep() bool {
+	return s == stageAsleep || s == stageLight || s == stageDeep || s == stageREM
+}
+
+// segment is a single stage record from an export.
+type segment struct {
+	Start time.Time
+	End   time.Time
+	Stage stage
+}
+
+// session is a run of segments separated by no more than MaxGap.
+type session struct {
+	Start    time.Time
+	End      time.Time
+	Segments []segment
+}
+
+// mergeSessions sorts segments and groups them into sessions. Segments that
+// overlap (e.g. an in-bed record and the stages within it, or duplicate
+// records from two devices) always join the same session.
+func mergeSessions(segments []segment) []session {
+	sorted := make([]segment, 0, len(segments))
+	for _, seg := range segments {
+		if seg.End.After(seg.Start) {
+			seg.Start, seg.End = seg.Start.UTC(), seg.End.UTC()
+			sorted = append(sorted, seg)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	var sessions []session
+	for _, seg := range sorted {
+		if n := len(sessions); n > 0 && !seg.Start.After(sessions[n-1].End.Add(MaxGap)) {
+			last := &sessions[n-1]
+			last.Segments = append(last.Segments, seg)
+			if seg.End.After(last.End) {
+				last.End = seg.End
+			}
+			continue
+		}
+		sessions = append(sessions, session{Start: seg.Start, End: seg.End, Segments: []segment{seg}})
+	}
+
+	// Sessions made only of awake records carry no sleep
+	result := sessions[:0]
+	for _, s := range sessions {
+		for _, seg := range s.Segments {
+			if seg.Stage != stageAwake {
+				result = append(result, s)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// asleepDuration returns the time spent asleep. Overlapping records are
+// counted once. Without any stage detail the whole session counts as sleep.
+func (s session) asleepDuration() time.Duration {
+	var asleep []segment
+	staged := false
+	for _, seg := range s.Segments {
+		if seg.Stage != stageInBed {
+			staged = true
+		}
+		if seg.Stage.isAsleep() {
+			asleep = append(asleep, seg)
+		}
+	}
+	if !staged {
+		return s.End.Sub(s.Start)
+	}
+
+	// Segments are sorted by start, so a single sweep gives the union
+	var total time.Duration
+	var curStart, curEnd time.Time
+	for i, seg := range asleep {
+		if i > 0 && !seg.Start.After(curEnd) {
+			if seg.End.After(curEnd) {
+				curEnd = seg.End
+			}
+			continue
+		}
+		total += curEnd.Sub(curStart)
+		curStart, curEnd = seg.Start, seg.End
+	}
+	return total + curEnd.Sub(curStart)
+}
+
+// efficiency is the share of the session spent asleep, in [0, 1].
+func (s session) efficiency() float64 {
+	span := s.End.Sub(s.Start)
+	if span <= 0 {
+		return 0
+	}
+	return math.Min(1, float64(s.asleepDuration())/float64(span))
+}
+
+// quality maps sleep efficiency onto the 1-10 quality scale.
+func (s session) quality() int {
+	q := int(math.Round(s.efficiency() * 10))
+	if q < 1 {
+		return 1
+	}
+	if q > 10 {
+		return 10
+	}
+	return q
+}
+
+func (s session) sleepType() domain.SleepType {
+	if s.End.Sub(s.Start) >= MinCoreDuration {
+		return domain.SleepTypeCore
+	}
+	return domain.SleepTypeNap
+}
+
+// clientRequestID derives an idempotency key from the source and the session
+// bounds, so re-importing the same export yields the same IDs.
+func (s session) clientRequestID(source Source) string {
+	sum := sha256.Sum256([]byte(string(source) + "|" + s.Start.Format(time.RFC3339) + "|" + s.End.Format(time.RFC3339)))
+	return "import:" + string(source) + ":" + hex.EncodeToString(sum[:16])
+}
+
+// domainStages maps the stages that have a sleep stage counterpart.
+var domainStages = map[stage]domain.SleepStageType{
+	stageAwake: domain.SleepStageAwake,
+	stageLight: domain.SleepStageLight,
+	stageDeep:  domain.SleepStageDeep,
+	stageREM:   domain.SleepStageREM,
+}
+
+// stages returns the session's hypnogram as contiguous stage segments.
+// Gaps between stage records are filled with AWAKE and consecutive records
+// of the same stage are joined. Sessions without light/deep/REM detail,
+// with asleep records of unknown stage or with overlapping stage records
+// (e.g. two devices) get no stages.
+func (s session) stages() []domain.SleepStageRequest {
+	var stages []domain.SleepStageRequest
+	detailed := false
+	for _, seg := range s.Segments {
+		if seg.Stage == stageAsleep {
+			return nil
+		}
+		st, ok := domainStages[seg.Stage]
+		if !ok {
+			continue
+		}
+		if st != domain.SleepStageAwake {
+			detailed = true
+		}
+
+		if n := len(stages); n > 0 {
+			last := &stages[n-1]
+			if seg.Start.Before(last.EndAt) {
+				return nil
+			}
+			if seg.Start.After(last.EndAt) {
+				stages = append(stages, domain.SleepStageRequest{Stage: domain.SleepStageAwake, StartAt: last.EndAt, EndAt: seg.Start})
+				last = &stages[len(stages)-1]
+			}
+			if last.Stage == st {
+				last.EndAt = seg.End
+				continue
+			}
+		}
+		stages = append(stages, domain.SleepStageRequest{Stage: st, StartAt: seg.Start, EndAt: seg.End})
+	}
+	if !detailed || len(stages) > domain.MaxSleepStages {
+		return nil
+	}
+	return stages
+}
+
+func (s session) toRequest(source Source) domain.CreateSleepLogRequest {
+	clientRequestID := s.clientRequestID(source)
+	return domain.CreateSleepLogRequest{
+		StartAt:         s.Start,
+		EndAt:           s.End,
+		Quality:         s.quality(),
+		Type:            s.sleepType(),
+		ClientRequestID: &clientRequestID,
+		Stages:          s.stages(),
+	}
+}
@@ -0,0 +1,302 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+)
+
+const appleExport = `<?xml version="1.0" encoding="UTF-8"?>
+<HealthData locale="en_US">
+ <Record type="HKQuantityTypeIdentifierStepCount" sourceName="iPhone" startDate="2024-01-15 10:00:00 +0100" endDate="2024-01-15 10:05:00 +0100" value="120"/>
+ <Record type="HKCategoryTypeIdentifierSleepAnalysis" sourceName="Watch" startDate="2024-01-15 23:00:00 +0100" endDate="2024-01-16 07:00:00 +0100" value="HKCategoryValueSleepAnalysisInBed"/>
+ <Record type="HKCategoryTypeIdentifierSleepAnalysis" sourceName="Watch" startDate="2024-01-15 23:15:00 +0100" endDate="2024-01-16 01:00:00 +0100" value="HKCategoryValueSleepAnalysisAsleepCore"/>
+ <Record type="HKCategoryTypeIdentifierSleepAnalysis" sourceName="Watch" startDate="2024-01-16 01:00:00 +0100" endDate="2024-01-16 02:30:00 +0100" value="HKCategoryValueSleepAnalysisAsleepDeep"/>
+ <Record type="HKCategoryTypeIdentifierSleepAnalysis" sourceName="Watch" startDate="2024-01-16 02:30:00 +0100" endDate="2024-01-16 03:00:00 +0100" value="HKCategoryValueSleepAnalysisAwake"/>
+ <Record type="HKCategoryTypeIdentifierSleepAnalysis" sourceName="Watch" startDate="2024-01-16 03:00:00 +0100" endDate="2024-01-16 06:45:00 +0100" value="HKCategoryValueSleepAnalysisAsleepREM"/>
+ <Record type="HKCategoryTypeIdentifierSleepAnalysis" sourceName="Watch" startDate="2024-01-16 14:00:00 +0100" endDate="2024-01-16 14:40:00 +0100" value="HKCategoryValueSleepAnalysisAsleepUnspecified"/>
+</HealthData>`
+
+const fitbitTakeout = `[
+  {
+    "logId": 1,
+    "startTime": "2024-01-15T23:00:00.000",
+    "endTime": "2024-01-16T03:00:00.000",
+    "mainSleep": true,
+    "levels": {"data": [
+      {"dateTime": "2024-01-15T23:00:00.000", "level": "wake", "seconds": 1800},
+      {"dateTime": "2024-01-15T23:30:00.000", "level": "light", "seconds": 5400},
+      {"dateTime": "2024-01-16T01:00:00.000", "level": "deep", "seconds": 7200}
+    ]}
+  },
+  {
+    "logId": 2,
+    "startTime": "2024-01-16T03:20:00.000",
+    "endTime": "2024-01-16T07:00:00.000",
+    "levels": {"data": [
+      {"dateTime": "2024-01-16T03:20:00.000", "level": "rem", "seconds": 13200}
+    ]}
+  }
+]`
+
+const fitbitAPI = `{"sleep": [{"startTime": "2024-01-16T13:00:00.000", "endTime": "2024-01-16T13:30:00.000", "levels": {"data": []}}]}`
+
+const googleFitExport = `{
+  "session": [
+    {"id": "s1", "startTimeMillis": "1705356000000", "endTimeMillis": "1705384800000", "activityType": 72},
+    {"id": "run", "startTimeMillis": "1705400000000", "endTimeMillis": "1705401000000", "activityType": 8}
+  ],
+  "point": [
+    {"startTimeNanos": "1705356000000000000", "endTimeNanos": "1705363200000000000", "value": [{"intVal": 4}]},
+    {"startTimeNanos": "1705363200000000000", "endTimeNanos": "1705366800000000000", "value": [{"intVal": 1}]},
+    {"startTimeNanos": "1705366800000000000", "endTimeNanos": "1705384800000000000", "value": [{"intVal": 5}]}
+  ]
+}`
+
+const healthConnectExport = `{
+  "sleepSessions": [
+    {
+      "startTime": "2024-01-15T22:00:00Z",
+      "endTime": "2024-01-16T06:00:00Z",
+      "stages": [
+        {"startTime": "2024-01-15T22:00:00Z", "endTime": "2024-01-16T02:00:00Z", "stage": 4},
+        {"startTime": "2024-01-16T02:00:00Z", "endTime": "2024-01-16T06:00:00Z", "stage": 6}
+      ]
+    }
+  ]
+}`
+
+func TestParse(t *testing.T) {
+	prague, _ := time.LoadLocation("Europe/Prague")
+
+	tests := []struct {
+		name   string
+		source Source
+		input  string
+		loc    *time.Location
+		want   []domain.CreateSleepLogRequest
+	}{
+		{
+			name:   "apple health stages merge into one night plus a nap",
+			source: SourceAppleHealth,
+			input:  appleExport,
+			want: []domain.CreateSleepLogRequest{
+				{
+					StartAt: time.Date(2024, 1, 15, 22, 0, 0, 0, time.UTC),
+					EndAt:   time.Date(2024, 1, 16, 6, 0, 0, 0, time.UTC),
+					Quality: 9, // 7h asleep of 8h in bed
+					Type:    domain.SleepTypeCore,
+				},
+				{
+					StartAt: time.Date(2024, 1, 16, 13, 0, 0, 0, time.UTC),
+					EndAt:   time.Date(2024, 1, 16, 13, 40, 0, 0, time.UTC),
+					Quality: 10,
+					Type:    domain.SleepTypeNap,
+				},
+			},
+		},
+		{
+			name:   "fitbit logs split by a short gap are merged",
+			source: SourceFitbit,
+			input:  fitbitTakeout,
+			loc:    prague,
+			want: []domain.CreateSleepLogRequest{
+				{
+					StartAt: time.Date(2024, 1, 15, 22, 0, 0, 0, time.UTC),
+					EndAt:   time.Date(2024, 1, 16, 6, 0, 0, 0, time.UTC),
+					Quality: 9, // 7h10m asleep of 8h
+					Type:    domain.SleepTypeCore,
+				},
+			},
+		},
+		{
+			name:   "fitbit API response without stages",
+			source: SourceFitbit,
+			input:  fitbitAPI,
+			want: []domain.CreateSleepLogRequest{
+				{
+					StartAt: time.Date(2024, 1, 16, 13, 0, 0, 0, time.UTC),
+					EndAt:   time.Date(2024, 1, 16, 13, 30, 0, 0, time.UTC),
+					Quality: 10,
+					Type:    domain.SleepTypeNap,
+				},
+			},
+		},
+		{
+			name:   "google fit session with sleep segments",
+			source: SourceGoogleFit,
+			input:  googleFitExport,
+			want: []domain.CreateSleepLogRequest{
+				{
+					StartAt: time.UnixMilli(1705356000000).UTC(),
+					EndAt:   time.UnixMilli(1705384800000).UTC(),
+					Quality: 9, // 7h asleep of 8h
+					Type:    domain.SleepTypeCore,
+				},
+			},
+		},
+		{
+			name:   "health connect sleep session",
+			source: SourceGoogleFit,
+			input:  healthConnectExport,
+			want: []domain.CreateSleepLogRequest{
+				{
+					StartAt: time.Date(2024, 1, 15, 22, 0, 0, 0, time.UTC),
+					EndAt:   time.Date(2024, 1, 16, 6, 0, 0, 0, time.UTC),
+					Quality: 10,
+					Type:    domain.SleepTypeCore,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.source, strings.NewReader(tt.input), tt.loc)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Parse() returned %d sessions, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, want := range tt.want {
+				if !got[i].StartAt.Equal(want.StartAt) || !got[i].EndAt.Equal(want.EndAt) {
+					t.Errorf("session %d = [%s, %s], want [%s, %s]", i, got[i].StartAt, got[i].EndAt, want.StartAt, want.EndAt)
+				}
+				if got[i].Quality != want.Quality {
+					t.Errorf("session %d Quality = %d, want %d", i, got[i].Quality, want.Quality)
+				}
+				if got[i].Type != want.Type {
+					t.Errorf("session %d Type = %s, want %s", i, got[i].Type, want.Type)
+				}
+				if got[i].ClientRequestID == nil || !strings.HasPrefix(*got[i].ClientRequestID, "import:"+string(tt.source)+":") {
+					t.Errorf("session %d ClientRequestID = %v", i, got[i].ClientRequestID)
+				}
+			}
+		})
+	}
+}
+
+func TestParse_Stages(t *testing.T) {
+	prague, _ := time.LoadLocation("Europe/Prague")
+	at := func(day, hour, min int) time.Time { return time.Date(2024, 1, day, hour, min, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name   string
+		source Source
+		input  string
+		loc    *time.Location
+		want   []domain.SleepStageRequest
+	}{
+		{
+			name:   "apple health stages",
+			source: SourceAppleHealth,
+			input:  appleExport,
+			want: []domain.SleepStageRequest{
+				{Stage: domain.SleepStageLight, StartAt: at(15, 22, 15), EndAt: at(16, 0, 0)},
+				{Stage: domain.SleepStageDeep, StartAt: at(16, 0, 0), EndAt: at(16, 1, 30)},
+				{Stage: domain.SleepStageAwake, StartAt: at(16, 1, 30), EndAt: at(16, 2, 0)},
+				{Stage: domain.SleepStageREM, StartAt: at(16, 2, 0), EndAt: at(16, 5, 45)},
+			},
+		},
+		{
+			name:   "gap between fitbit logs is filled with awake",
+			source: SourceFitbit,
+			input:  fitbitTakeout,
+			loc:    prague,
+			want: []domain.SleepStageRequest{
+				{Stage: domain.SleepStageAwake, StartAt: at(15, 22, 0), EndAt: at(15, 22, 30)},
+				{Stage: domain.SleepStageLight, StartAt: at(15, 22, 30), EndAt: at(16, 0, 0)},
+				{Stage: domain.SleepStageDeep, StartAt: at(16, 0, 0), EndAt: at(16, 2, 0)},
+				{Stage: domain.SleepStageAwake, StartAt: at(16, 2, 0), EndAt: at(16, 2, 20)},
+				{Stage: domain.SleepStageREM, StartAt: at(16, 2, 20), EndAt: at(16, 6, 0)},
+			},
+		},
+		{
+			name:   "session without stage detail",
+			source: SourceFitbit,
+			input:  fitbitAPI,
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.source, strings.NewReader(tt.input), tt.loc)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			stages := got[0].Stages
+			if len(stages) != len(tt.want) {
+				t.Fatalf("got %d stages, want %d: %+v", len(stages), len(tt.want), stages)
+			}
+			for i, want := range tt.want {
+				if stages[i].Stage != want.Stage || !stages[i].StartAt.Equal(want.StartAt) || !stages[i].EndAt.Equal(want.EndAt) {
+					t.Errorf("stage %d = %s [%s, %s], want %s [%s, %s]", i,
+						stages[i].Stage, stages[i].StartAt, stages[i].EndAt, want.Stage, want.StartAt, want.EndAt)
+				}
+			}
+			if err := domain.ValidateSleepStages(got[0].StartAt, got[0].EndAt, domain.NewSleepStages(stages)); err != nil {
+				t.Errorf("imported stages are invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestParse_StableClientRequestIDs(t *testing.T) {
+	first, err := Parse(SourceAppleHealth, strings.NewReader(appleExport), nil)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	second, err := Parse(SourceAppleHealth, strings.NewReader(appleExport), nil)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if *first[0].ClientRequestID != *second[0].ClientRequestID {
+		t.Errorf("client_request_id changed between imports: %s != %s", *first[0].ClientRequestID, *second[0].ClientRequestID)
+	}
+	if *first[0].ClientRequestID == *first[1].ClientRequestID {
+		t.Error("different sessions share a client_request_id")
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  Source
+		input   string
+		wantErr error
+	}{
+		{"unknown source", Source("garmin"), `{}`, ErrUnsupportedSource},
+		{"no sleep records", SourceAppleHealth, `<HealthData></HealthData>`, ErrNoSleepData},
+		{"only awake records", SourceGoogleFit, `{"point": [{"startTimeNanos": "0", "endTimeNanos": "60000000000", "value": [{"intVal": 1}]}]}`, ErrNoSleepData},
+		{"malformed XML", SourceAppleHealth, `<HealthData><Record`, nil},
+		{"malformed JSON", SourceFitbit, `{"sleep": [`, nil},
+		{"bad fitbit timestamp", SourceFitbit, `[{"startTime": "yesterday", "endTime": "today"}]`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.source, strings.NewReader(tt.input), nil)
+			if err == nil {
+				t.Fatal("Parse() expected error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSource(t *testing.T) {
+	if s, err := ParseSource("fitbit"); err != nil || s != SourceFitbit {
+		t.Errorf("ParseSource(fitbit) = %q, %v", s, err)
+	}
+	if _, err := ParseSource("garmin"); !errors.Is(err, ErrUnsupportedSource) {
+		t.Errorf("ParseSource(garmin) error = %v, want %v", err, ErrUnsupportedSource)
+	}
+}
@@ -0,0 +1,152 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+)
+
+const (
+	// DefaultAnthropicBaseURL is the Anthropic API used when no base URL is configured.
+	DefaultAnthropicBaseURL = "https://api.anthropic.com"
+	// DefaultAnthropicModel is the model used when none is configured.
+	DefaultAnthropicModel = "claude-3-5-haiku-latest"
+
+	// anthropicVersion is the Messages API version sent with every request.
+	anthropicVersion = "2023-06-01"
+	// anthropicMaxTokens bounds the reply, which the Messages API requires.
+	anthropicMaxTokens = 1024
+)
+
+// AnthropicClient implements InsightsLLM using the Anthropic Messages API.
+type AnthropicClient struct {
+	httpClient     *http.Client
+	baseURL        string
+	apiKey         string
+	model          string
+	promptProvider SystemPromptProvider
+}
+
+// NewAnthropicClient creates a new Anthropic client for generating insights.
+// Returns nil if apiKey is empty.
+func NewAnthropicClient(apiKey, model, baseURL string, provider SystemPromptProvider) *AnthropicClient {
+	if apiKey == "" {
+		return nil
+	}
+
+	if model == "" {
+		model = DefaultAnthropicModel
+	}
+
+	if baseURL == "" {
+		baseURL = DefaultAnthropicBaseURL
+	}
+
+	if provider == nil {
+		provider = StaticSystemPromptProvider(DefaultSystemPrompt)
+	}
+
+	return &AnthropicClient{
+		httpClient:     &http.Client{},
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
+		apiKey:         apiKey,
+		model:          model,
+		promptProvider: provider,
+	}
+}
+
+// GenerateInsights calls the Messages API to generate sleep insights.
+func (c *AnthropicClient) GenerateInsights(ctx context.Context, insightsCtx *domain.InsightsContext) (*domain.LLMInsightsOutput, error) {
+	if c == nil {
+		return nil, ErrLLMUnavailable
+	}
+
+	return generateInsights(ctx, "AnthropicClient.GenerateInsights", ProviderAnthropic, c.model, c.promptProvider, insightsCtx, c.complete)
+}
+
+// Describe returns the provider, model and prompt version of the client.
+func (c *AnthropicClient) Describe(ctx context.Context) (domain.InsightsGenerator, error) {
+	if c == nil {
+		return domain.InsightsGenerator{}, ErrLLMUnavailable
+	}
+
+	return describe(ctx, ProviderAnthropic, c.model, c.promptProvider)
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	System    string             `json:"system,omitempty"`
+	Messages  []anthropicMessage `json:"messages"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+func (c *AnthropicClient) complete(ctx context.Context, systemPrompt string, messages []chatMessage) (string, error) {
+	request := anthropicRequest{
+		Model:     c.model,
+		MaxTokens: anthropicMaxTokens,
+		System:    systemPrompt,
+	}
+	for _, message := range messages {
+		request.Messages = append(request.Messages, anthropicMessage{Role: message.Role, Content: message.Content})
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrLLMRequest, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrLLMRequest, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrLLMRequest, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("%w: %w", ErrLLMRequest, newAPIError(resp.StatusCode, resp.Header, strings.TrimSpace(string(respBody))))
+	}
+
+	var parsed anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrLLMResponse, err)
+	}
+
+	// Concatenate the text blocks of the reply
+	var text strings.Builder
+	for _, block := range parsed.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("%w: no text in response", ErrLLMResponse)
+	}
+
+	return text.String(), nil
+}
@@ -0,0 +1,187 @@
+// Package llmtest provides a fake LLM server for tests and air-gapped
+// deployments. It speaks the OpenAI chat completions API (including the
+// Azure OpenAI deployment routes) and the Anthropic Messages API, and
+// answers with fixed replies.
+package llmtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultReply is a valid insights reply in the shape the system prompt asks for.
+const DefaultReply = `{
+  "summary": "Your sleep has been steady over the last month, and last night was close to your recent average.",
+  "observations": [
+    "Average duration is close to your target on most days.",
+    "Bedtimes vary by less than an hour, which supports a regular rhythm.",
+    "The recent week looks much like your longer history."
+  ],
+  "guidance": [
+    "Keep your current bedtime within a 30-minute window.",
+    "Wind down without screens for the last half hour before bed.",
+    "Keep naps short and before mid-afternoon."
+  ]
+}`
+
+// Request is a request received by the Server.
+type Request struct {
+	// Path of the request, e.g. /v1/chat/completions
+	Path string
+	// Header of the request, to check authentication
+	Header http.Header
+	// Query parameters of the request
+	Query map[string][]string
+	// Model named in the request body
+	Model string
+	// System prompt of the request
+	System string
+	// Last user message of the request
+	User string
+	// Number of messages, without the OpenAI system message
+	Messages int
+	// ResponseFormat of an OpenAI request, if any
+	ResponseFormat json.RawMessage
+}
+
+// Server is a fake LLM API. Mount it with httptest.NewServer or any
+// http.Server.
+type Server struct {
+	mu       sync.Mutex
+	replies  []string
+	status   int
+	requests []Request
+}
+
+// NewServer creates a Server answering with reply, or DefaultReply if empty.
+func NewServer(reply string) *Server {
+	if reply == "" {
+		reply = DefaultReply
+	}
+	return &Server{replies: []string{reply}, status: http.StatusOK}
+}
+
+// SetReply changes the text of later replies.
+func (s *Server) SetReply(reply string) {
+	s.SetReplies(reply)
+}
+
+// SetReplies makes later requests get the replies in turn. The last reply
+// is repeated once the others are used up.
+func (s *Server) SetReplies(replies ...string) {
+	if len(replies) == 0 {
+		replies = []string{DefaultReply}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies = append([]string(nil), replies...)
+}
+
+// SetStatus makes later requests fail with status, or succeed again with
+// http.StatusOK.
+func (s *Server) SetStatus(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+type chatRequest struct {
+	Model          string          `json:"model"`
+	System         string          `json:"system"`
+	ResponseFormat json.RawMessage `json:"response_format"`
+	Messages       []struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"messages"`
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	isChat := strings.HasSuffix(r.URL.Path, "/chat/completions")
+	isMessages := strings.HasSuffix(r.URL.Path, "/v1/messages")
+	if r.Method != http.MethodPost || (!isChat && !isMessages) {
+		http.NotFound(w, r)
+		return
+	}
+
+	var body chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	received := Request{
+		Path:           r.URL.Path,
+		Header:         r.Header.Clone(),
+		Query:          r.URL.Query(),
+		Model:          body.Model,
+		System:         body.System,
+		ResponseFormat: body.ResponseFormat,
+	}
+	for _, message := range body.Messages {
+		switch message.Role {
+		case "system":
+			received.System = message.Content
+			continue
+		case "user":
+			received.User = message.Content
+		}
+		received.Messages++
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, received)
+	reply, status := s.replies[0], s.status
+	if len(s.replies) > 1 {
+		s.replies = s.replies[1:]
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]any{
+			"error": map[string]string{"type": "fake_error", "message": http.StatusText(status)},
+		})
+		return
+	}
+
+	if isMessages {
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":          "msg_fake",
+			"type":        "message",
+			"role":        "assistant",
+			"model":       body.Model,
+			"content":     []map[string]string{{"type": "text", "text": reply}},
+			"stop_reason": "end_turn",
+			"usage":       map[string]int{"input_tokens": len(received.User) / 4, "output_tokens": len(reply) / 4},
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"id":      "chatcmpl-fake",
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   body.Model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       map[string]string{"role": "assistant", "content": reply},
+			"finish_reason": "stop",
+		}},
+		"usage": map[string]int{
+			"prompt_tokens":     len(received.User) / 4,
+			"completion_tokens": len(reply) / 4,
+			"total_tokens":      (len(received.User) + len(reply)) / 4,
+		},
+	})
+}
@@ -2,7 +2,6 @@ package llm
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"sync"
@@ -11,18 +10,7 @@ import (
 	"github.com/blaisecz/sleep-tracker/internal/domain"
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/option"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/trace"
-)
-
-var (
-	// ErrOpenAIUnavailable indicates the OpenAI service is not configured or unavailable.
-	ErrOpenAIUnavailable = errors.New("OpenAI service unavailable")
-	// ErrOpenAIRequest indicates an error during the OpenAI API request.
-	ErrOpenAIRequest = errors.New("OpenAI request failed")
-	// ErrOpenAIResponse indicates an error parsing the OpenAI response.
-	ErrOpenAIResponse = errors.New("failed to parse OpenAI response")
+	"github.com/openai/openai-go/v3/shared"
 )
 
 const DefaultSystemPrompt = `You are a non-medical sleep tracking assistant.
@@ -33,6 +21,8 @@ Your goals:
 - Describe the user's recent sleep in clear, neutral language.
 - Highlight patterns in duration, quality, consistency, and total daily sleep (core + naps).
 - Compare last night to the user's recent period and longer history.
+- Point out unusual recent nights from "anomalies", if any.
+- Relate the numbers to the user's own "goal", if set.
 - Factor in the user's chronotype when it helps explain patterns.
 - Give practical, behavioral suggestions to improve sleep habits.
 
@@ -74,6 +64,12 @@ Use:
 - "recent" to see more short-term changes (about 7–10 nights/days),
 - "last_night" to judge how the most recent night compares to both.
 
+"anomalies", when present, lists recent nights that were unusual compared with the user's own baseline, each with a severity and a reason. Mention the most relevant ones.
+
+"goal", when present, holds the user's sleep goals: target hours per day, a bedtime window, a wake time and a nap allowance. "target_hours" in "daily_overall" and the bedtime and wake goal scores follow these goals; without a goal, the target is 7 hours.
+
+"sleep_debt_hours" is how much sleep the user owes: a running balance of total daily sleep minus the target, of which a tenth is forgiven each day. Extra sleep pays it off but is not banked. Mention it when it is above a few hours.
+
 JSON:
 
 %s
@@ -139,9 +135,15 @@ func CachedPromptProvider(provider SystemPromptProvider, ttl time.Duration) Syst
 	}
 }
 
-// OpenAIClient implements InsightsLLM using the OpenAI API.
+// DefaultOpenAIModel is the model used when none is configured.
+const DefaultOpenAIModel = "gpt-4o-mini"
+
+// OpenAIClient implements InsightsLLM using the OpenAI chat completions API.
+// The same client serves OpenAI-compatible servers and Azure OpenAI, which
+// differ only in base URL and authentication.
 type OpenAIClient struct {
 	client         openai.Client
+	provider       string
 	model          string
 	promptProvider SystemPromptProvider
 }
@@ -154,96 +156,85 @@ func NewOpenAIClient(apiKey, model string, provider SystemPromptProvider) *OpenA
 	}
 
 	if model == "" {
-		model = "gpt-4o-mini"
+		model = DefaultOpenAIModel
 	}
 
+	return newOpenAIClient(ProviderOpenAI, model, provider, option.WithAPIKey(apiKey))
+}
+
+func newOpenAIClient(name, model string, provider SystemPromptProvider, opts ...option.RequestOption) *OpenAIClient {
 	if provider == nil {
 		provider = StaticSystemPromptProvider(DefaultSystemPrompt)
 	}
 
-	client := openai.NewClient(option.WithAPIKey(apiKey))
+	// Retries are left to WithResilience
+	opts = append([]option.RequestOption{option.WithMaxRetries(0)}, opts...)
 
 	return &OpenAIClient{
-		client:         client,
+		client:         openai.NewClient(opts...),
+		provider:       name,
 		model:          model,
 		promptProvider: provider,
 	}
 }
 
-// GenerateInsights calls OpenAI to generate sleep insights.
+// GenerateInsights calls the chat completions API to generate sleep insights.
 func (c *OpenAIClient) GenerateInsights(ctx context.Context, insightsCtx *domain.InsightsContext) (*domain.LLMInsightsOutput, error) {
 	if c == nil {
 		return nil, ErrOpenAIUnavailable
 	}
 
-	tracer := otel.Tracer("sleep-tracker-api/llm")
-	ctx, span := tracer.Start(ctx, "OpenAIClient.GenerateInsights",
-		trace.WithAttributes(
-			attribute.String("langfuse.observation.type", "generation"),
-			attribute.String("llm.model", c.model),
-			attribute.String("model", c.model),
-			attribute.String("langfuse.observation.model.name", c.model),
-		),
-	)
-	defer span.End()
-
-	// Serialize context to JSON
-	contextJSON, err := json.MarshalIndent(insightsCtx, "", "  ")
-	if err != nil {
-		return nil, fmt.Errorf("%w: failed to serialize context: %v", ErrOpenAIRequest, err)
-	}
+	return generateInsights(ctx, "OpenAIClient.GenerateInsights", c.provider, c.model, c.promptProvider, insightsCtx, c.complete)
+}
 
-	systemPrompt, err := c.promptProvider(ctx)
-	if err != nil {
-		span.RecordError(err)
-		return nil, fmt.Errorf("%w: failed to load system prompt: %v", ErrOpenAIRequest, err)
+// Describe returns the provider, model and prompt version of the client.
+func (c *OpenAIClient) Describe(ctx context.Context) (domain.InsightsGenerator, error) {
+	if c == nil {
+		return domain.InsightsGenerator{}, ErrLLMUnavailable
 	}
 
-	userPrompt := fmt.Sprintf(userPromptTemplate, string(contextJSON))
+	return describe(ctx, c.provider, c.model, c.promptProvider)
+}
 
-	// Attach prompts and context as Langfuse observation input
-	inputPayload := map[string]any{
-		"system_prompt":    systemPrompt,
-		"user_prompt":      userPrompt,
-		"insights_context": insightsCtx,
-	}
-	if inputJSON, err := json.Marshal(inputPayload); err == nil {
-		span.SetAttributes(
-			attribute.String("langfuse.observation.input", string(inputJSON)),
-			attribute.String("gen_ai.prompt", userPrompt),
-		)
+func (c *OpenAIClient) complete(ctx context.Context, systemPrompt string, messages []chatMessage) (string, error) {
+	params := []openai.ChatCompletionMessageParamUnion{openai.SystemMessage(systemPrompt)}
+	for _, message := range messages {
+		if message.Role == "assistant" {
+			params = append(params, openai.AssistantMessage(message.Content))
+		} else {
+			params = append(params, openai.UserMessage(message.Content))
+		}
 	}
 
-	// Call OpenAI
 	resp, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Model: c.model,
-		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(systemPrompt),
-			openai.UserMessage(userPrompt),
+		Model:    c.model,
+		Messages: params,
+		// Strict structured outputs keep the reply to the shape of the
+		// schema. Endpoints without support reject the request, so the
+		// schema holds only keywords strict mode accepts; item counts and
+		// non-empty strings are checked when the reply is parsed.
+		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:        insightsSchemaName,
+					Description: openai.String("Sleep insights: summary, observations and guidance"),
+					Schema:      InsightsOutputSchema,
+					Strict:      openai.Bool(true),
+				},
+			},
 		},
 	})
 	if err != nil {
-		span.RecordError(err)
-		return nil, fmt.Errorf("%w: %v", ErrOpenAIRequest, err)
+		var apiErr *openai.Error
+		if errors.As(err, &apiErr) && apiErr.Response != nil {
+			return "", fmt.Errorf("%w: %w", ErrLLMRequest, newAPIError(apiErr.StatusCode, apiErr.Response.Header, apiErr.Message))
+		}
+		return "", fmt.Errorf("%w: %w", ErrLLMRequest, err)
 	}
 
 	if len(resp.Choices) == 0 {
-		return nil, fmt.Errorf("%w: no choices in response", ErrOpenAIResponse)
-	}
-
-	content := resp.Choices[0].Message.Content
-
-	// Parse the JSON response
-	var output domain.LLMInsightsOutput
-	if err := json.Unmarshal([]byte(content), &output); err != nil {
-		span.RecordError(err)
-		return nil, fmt.Errorf("%w: %v", ErrOpenAIResponse, err)
+		return "", fmt.Errorf("%w: no choices in response", ErrLLMResponse)
 	}
 
-	// Attach model output as Langfuse observation output
-	span.SetAttributes(
-		attribute.String("langfuse.observation.output", content),
-	)
-
-	return &output, nil
+	return resp.Choices[0].Message.Content, nil
 }
@@ -0,0 +1,317 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/openai/openai-go/v3/option"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// ErrLLMUnavailable indicates the LLM provider is not configured or unavailable.
+	ErrLLMUnavailable = errors.New("LLM service unavailable")
+	// ErrLLMRequest indicates an error during the LLM API request.
+	ErrLLMRequest = errors.New("LLM request failed")
+	// ErrLLMResponse indicates an error parsing the LLM response.
+	ErrLLMResponse = errors.New("failed to parse LLM response")
+	// ErrUnknownProvider indicates a provider name that is not registered.
+	ErrUnknownProvider = errors.New("unknown LLM provider")
+
+	// ErrOpenAIUnavailable is the former name of ErrLLMUnavailable.
+	ErrOpenAIUnavailable = ErrLLMUnavailable
+	// ErrOpenAIRequest is the former name of ErrLLMRequest.
+	ErrOpenAIRequest = ErrLLMRequest
+	// ErrOpenAIResponse is the former name of ErrLLMResponse.
+	ErrOpenAIResponse = ErrLLMResponse
+)
+
+// Built-in providers.
+const (
+	// ProviderOpenAI is the OpenAI API.
+	ProviderOpenAI = "openai"
+	// ProviderOpenAICompatible is any server implementing the OpenAI chat
+	// completions API, such as Ollama, vLLM or the llama.cpp server.
+	ProviderOpenAICompatible = "openai_compatible"
+	// ProviderAnthropic is the Anthropic Messages API.
+	ProviderAnthropic = "anthropic"
+	// ProviderAzureOpenAI is an Azure OpenAI deployment.
+	ProviderAzureOpenAI = "azure_openai"
+)
+
+// DefaultAzureOpenAIAPIVersion is the Azure OpenAI API version used when none
+// is configured.
+const DefaultAzureOpenAIAPIVersion = "2024-10-21"
+
+// ProviderConfig holds the settings of one provider. Fields a provider does
+// not use are ignored.
+type ProviderConfig struct {
+	// APIKey authenticates the requests. Optional for OpenAI-compatible servers.
+	APIKey string
+	// Model to use; the deployment name for Azure OpenAI.
+	Model string
+	// BaseURL of the API; the resource endpoint for Azure OpenAI.
+	BaseURL string
+	// APIVersion of the Azure OpenAI API.
+	APIVersion string
+}
+
+// ProviderFactory creates an InsightsLLM from its settings. It returns an
+// error wrapping ErrLLMUnavailable if required settings are missing.
+type ProviderFactory func(cfg ProviderConfig, prompts SystemPromptProvider) (InsightsLLM, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]ProviderFactory{
+		ProviderOpenAI:           newOpenAIProvider,
+		ProviderOpenAICompatible: newOpenAICompatibleProvider,
+		ProviderAnthropic:        newAnthropicProvider,
+		ProviderAzureOpenAI:      newAzureOpenAIProvider,
+		ProviderRules:            newRulesProvider,
+	}
+)
+
+// RegisterProvider makes a provider available under name, replacing any
+// provider registered under the same name.
+func RegisterProvider(name string, factory ProviderFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[name] = factory
+}
+
+// Providers returns the names of the registered providers, sorted.
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewProvider creates the InsightsLLM of the named provider.
+func NewProvider(name string, cfg ProviderConfig, prompts SystemPromptProvider) (InsightsLLM, error) {
+	providersMu.RLock()
+	factory, ok := providers[name]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q (available: %s)", ErrUnknownProvider, name, strings.Join(Providers(), ", "))
+	}
+	return factory(cfg, prompts)
+}
+
+func newOpenAIProvider(cfg ProviderConfig, prompts SystemPromptProvider) (InsightsLLM, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("%w: %s API key not configured", ErrLLMUnavailable, ProviderOpenAI)
+	}
+	model := cfg.Model
+	if model == "" {
+		model = DefaultOpenAIModel
+	}
+
+	opts := []option.RequestOption{option.WithAPIKey(cfg.APIKey)}
+	if cfg.BaseURL != "" {
+		opts = append(opts, option.WithBaseURL(cfg.BaseURL))
+	}
+	return newOpenAIClient(ProviderOpenAI, model, prompts, opts...), nil
+}
+
+func newOpenAICompatibleProvider(cfg ProviderConfig, prompts SystemPromptProvider) (InsightsLLM, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("%w: %s base URL not configured", ErrLLMUnavailable, ProviderOpenAICompatible)
+	}
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("%w: %s model not configured", ErrLLMUnavailable, ProviderOpenAICompatible)
+	}
+
+	// Local servers often run without authentication. The key is always set
+	// so that an OPENAI_API_KEY in the environment is never sent elsewhere.
+	opts := []option.RequestOption{option.WithAPIKey(cfg.APIKey), option.WithBaseURL(cfg.BaseURL)}
+	if cfg.APIKey == "" {
+		opts = append(opts, option.WithHeaderDel("Authorization"))
+	}
+	return newOpenAIClient(ProviderOpenAICompatible, cfg.Model, prompts, opts...), nil
+}
+
+func newAzureOpenAIProvider(cfg ProviderConfig, prompts SystemPromptProvider) (InsightsLLM, error) {
+	if cfg.BaseURL == "" || cfg.APIKey == "" || cfg.Model == "" {
+		return nil, fmt.Errorf("%w: %s endpoint, API key and deployment must all be configured", ErrLLMUnavailable, ProviderAzureOpenAI)
+	}
+	apiVersion := cfg.APIVersion
+	if apiVersion == "" {
+		apiVersion = DefaultAzureOpenAIAPIVersion
+	}
+
+	// Azure routes by deployment in the path and authenticates with an
+	// api-key header instead of a bearer token.
+	baseURL := strings.TrimSuffix(cfg.BaseURL, "/") + "/openai/deployments/" + url.PathEscape(cfg.Model) + "/"
+	return newOpenAIClient(ProviderAzureOpenAI, cfg.Model, prompts,
+		option.WithAPIKey(""),
+		option.WithHeaderDel("Authorization"),
+		option.WithBaseURL(baseURL),
+		option.WithHeader("api-key", cfg.APIKey),
+		option.WithQuery("api-version", apiVersion),
+	), nil
+}
+
+func newAnthropicProvider(cfg ProviderConfig, prompts SystemPromptProvider) (InsightsLLM, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("%w: %s API key not configured", ErrLLMUnavailable, ProviderAnthropic)
+	}
+	return NewAnthropicClient(cfg.APIKey, cfg.Model, cfg.BaseURL, prompts), nil
+}
+
+// Describer is implemented by InsightsLLMs that can tell which generator
+// their insights come from, so that the insights can be cached.
+type Describer interface {
+	// Describe returns the generator of insights requested now.
+	Describe(ctx context.Context) (domain.InsightsGenerator, error)
+}
+
+// describe returns the generator of a provider. The prompt version is a hash
+// of the system prompt, which may come from Langfuse, and of the prompts and
+// output schema built into this package.
+func describe(ctx context.Context, provider, model string, prompts SystemPromptProvider) (domain.InsightsGenerator, error) {
+	systemPrompt, err := prompts(ctx)
+	if err != nil {
+		return domain.InsightsGenerator{}, fmt.Errorf("%w: failed to load system prompt: %v", ErrLLMRequest, err)
+	}
+
+	hash := sha256.New()
+	for _, part := range []string{systemPrompt, userPromptTemplate, reaskPromptTemplate} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	json.NewEncoder(hash).Encode(InsightsOutputSchema)
+
+	return domain.InsightsGenerator{
+		Provider:      provider,
+		Model:         model,
+		PromptVersion: hex.EncodeToString(hash.Sum(nil))[:12],
+	}, nil
+}
+
+// Unavailable returns an InsightsLLM that fails every call with reason, which
+// should wrap ErrLLMUnavailable. It stands in for a provider that is not
+// configured.
+func Unavailable(reason error) InsightsLLM {
+	if reason == nil {
+		reason = ErrLLMUnavailable
+	}
+	return unavailableLLM{reason: reason}
+}
+
+type unavailableLLM struct {
+	reason error
+}
+
+func (u unavailableLLM) GenerateInsights(context.Context, *domain.InsightsContext) (*domain.LLMInsightsOutput, error) {
+	return nil, u.reason
+}
+
+// chatMessage is a user or assistant turn of a conversation with the model.
+type chatMessage struct {
+	Role    string
+	Content string
+}
+
+// completeFunc sends the conversation to a model and returns its text reply.
+type completeFunc func(ctx context.Context, systemPrompt string, messages []chatMessage) (string, error)
+
+// maxInsightsAttempts is the number of replies requested before giving up on
+// an invalid one: the first answer and one re-ask.
+const maxInsightsAttempts = 2
+
+const reaskPromptTemplate = `Your previous reply could not be used: %s.
+
+Respond again with only the JSON object in the required shape: a non-empty "summary", 3–6 "observations" and 3–5 "guidance" items. No extra fields. No backticks.`
+
+// generateInsights builds the prompts, calls complete inside a Langfuse
+// generation span and parses the reply. A reply that fails parsing or the
+// prompt rules is sent back once with the errors. It is shared by all
+// providers.
+func generateInsights(ctx context.Context, spanName, provider, model string, prompts SystemPromptProvider, insightsCtx *domain.InsightsContext, complete completeFunc) (*domain.LLMInsightsOutput, error) {
+	tracer := otel.Tracer("sleep-tracker-api/llm")
+	ctx, span := tracer.Start(ctx, spanName,
+		trace.WithAttributes(
+			attribute.String("langfuse.observation.type", "generation"),
+			attribute.String("llm.provider", provider),
+			attribute.String("llm.model", model),
+			attribute.String("model", model),
+			attribute.String("langfuse.observation.model.name", model),
+		),
+	)
+	defer span.End()
+
+	// Serialize context to JSON
+	contextJSON, err := json.MarshalIndent(insightsCtx, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to serialize context: %v", ErrLLMRequest, err)
+	}
+
+	systemPrompt, err := prompts(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("%w: failed to load system prompt: %v", ErrLLMRequest, err)
+	}
+
+	userPrompt := fmt.Sprintf(userPromptTemplate, string(contextJSON))
+
+	// Attach prompts and context as Langfuse observation input
+	inputPayload := map[string]any{
+		"system_prompt":    systemPrompt,
+		"user_prompt":      userPrompt,
+		"insights_context": insightsCtx,
+	}
+	if inputJSON, err := json.Marshal(inputPayload); err == nil {
+		span.SetAttributes(
+			attribute.String("langfuse.observation.input", string(inputJSON)),
+			attribute.String("gen_ai.prompt", userPrompt),
+		)
+	}
+
+	messages := []chatMessage{{Role: "user", Content: userPrompt}}
+	for attempt := 1; ; attempt++ {
+		content, err := complete(ctx, systemPrompt, messages)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+
+		output, err := parseInsightsOutput(content)
+		if err == nil {
+			// Attach model output as Langfuse observation output
+			span.SetAttributes(
+				attribute.String("langfuse.observation.output", content),
+				attribute.Int("llm.attempts", attempt),
+			)
+			return output, nil
+		}
+
+		span.AddEvent("llm.invalid_output", trace.WithAttributes(
+			attribute.Int("llm.attempt", attempt),
+			attribute.String("llm.validation_errors", err.Error()),
+		))
+		if attempt == maxInsightsAttempts {
+			span.RecordError(err)
+			return nil, fmt.Errorf("%w: %v", ErrLLMResponse, err)
+		}
+		messages = append(messages,
+			chatMessage{Role: "assistant", Content: content},
+			chatMessage{Role: "user", Content: fmt.Sprintf(reaskPromptTemplate, err)},
+		)
+	}
+}
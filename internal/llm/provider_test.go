@@ -0,0 +1,327 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/llm/llmtest"
+)
+
+func TestNewProvider_FakeServer(t *testing.T) {
+	tests := []struct {
+		name       string
+		provider   string
+		cfg        func(baseURL string) ProviderConfig
+		wantPath   string
+		wantModel  string
+		wantHeader map[string]string
+		wantQuery  map[string]string
+	}{
+		{
+			name:     "openai",
+			provider: ProviderOpenAI,
+			cfg: func(baseURL string) ProviderConfig {
+				return ProviderConfig{APIKey: "sk-test", BaseURL: baseURL + "/v1"}
+			},
+			wantPath:   "/v1/chat/completions",
+			wantModel:  DefaultOpenAIModel,
+			wantHeader: map[string]string{"Authorization": "Bearer sk-test"},
+		},
+		{
+			name:     "openai compatible without key",
+			provider: ProviderOpenAICompatible,
+			cfg: func(baseURL string) ProviderConfig {
+				return ProviderConfig{BaseURL: baseURL + "/v1", Model: "llama3.1:8b"}
+			},
+			wantPath:   "/v1/chat/completions",
+			wantModel:  "llama3.1:8b",
+			wantHeader: map[string]string{"Authorization": ""},
+		},
+		{
+			name:     "anthropic",
+			provider: ProviderAnthropic,
+			cfg: func(baseURL string) ProviderConfig {
+				return ProviderConfig{APIKey: "ant-test", BaseURL: baseURL}
+			},
+			wantPath:   "/v1/messages",
+			wantModel:  DefaultAnthropicModel,
+			wantHeader: map[string]string{"x-api-key": "ant-test", "anthropic-version": anthropicVersion},
+		},
+		{
+			name:     "azure openai",
+			provider: ProviderAzureOpenAI,
+			cfg: func(baseURL string) ProviderConfig {
+				return ProviderConfig{APIKey: "az-test", BaseURL: baseURL, Model: "insights"}
+			},
+			wantPath:   "/openai/deployments/insights/chat/completions",
+			wantModel:  "insights",
+			wantHeader: map[string]string{"api-key": "az-test", "Authorization": ""},
+			wantQuery:  map[string]string{"api-version": DefaultAzureOpenAIAPIVersion},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := llmtest.NewServer("")
+			srv := httptest.NewServer(fake)
+			defer srv.Close()
+
+			client, err := NewProvider(tt.provider, tt.cfg(srv.URL), StaticSystemPromptProvider("system prompt"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			output, err := client.GenerateInsights(context.Background(), &domain.InsightsContext{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output.Summary == "" || len(output.Observations) != 3 || len(output.Guidance) != 3 {
+				t.Errorf("unexpected output: %+v", output)
+			}
+
+			requests := fake.Requests()
+			if len(requests) != 1 {
+				t.Fatalf("got %d requests, want 1", len(requests))
+			}
+			got := requests[0]
+			if got.Path != tt.wantPath {
+				t.Errorf("path = %q, want %q", got.Path, tt.wantPath)
+			}
+			if got.Model != tt.wantModel {
+				t.Errorf("model = %q, want %q", got.Model, tt.wantModel)
+			}
+			if got.System != "system prompt" || !strings.Contains(got.User, `"chronotype"`) {
+				t.Errorf("unexpected prompts: %q / %q", got.System, got.User)
+			}
+			for key, want := range tt.wantHeader {
+				if value := got.Header.Get(key); value != want {
+					t.Errorf("header %s = %q, want %q", key, value, want)
+				}
+			}
+			for key, want := range tt.wantQuery {
+				if values := got.Query[key]; len(values) != 1 || values[0] != want {
+					t.Errorf("query %s = %v, want %q", key, values, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewProvider_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		cfg      ProviderConfig
+		wantErr  error
+	}{
+		{"unknown provider", "gemini", ProviderConfig{APIKey: "key"}, ErrUnknownProvider},
+		{"openai without key", ProviderOpenAI, ProviderConfig{}, ErrLLMUnavailable},
+		{"compatible without base URL", ProviderOpenAICompatible, ProviderConfig{Model: "llama3"}, ErrLLMUnavailable},
+		{"compatible without model", ProviderOpenAICompatible, ProviderConfig{BaseURL: "http://localhost:11434/v1"}, ErrLLMUnavailable},
+		{"anthropic without key", ProviderAnthropic, ProviderConfig{}, ErrLLMUnavailable},
+		{"azure without deployment", ProviderAzureOpenAI, ProviderConfig{APIKey: "key", BaseURL: "https://example.openai.azure.com"}, ErrLLMUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewProvider(tt.provider, tt.cfg, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRegisterProvider(t *testing.T) {
+	RegisterProvider("test", func(cfg ProviderConfig, prompts SystemPromptProvider) (InsightsLLM, error) {
+		return Unavailable(errors.New("test provider")), nil
+	})
+	defer func() {
+		providersMu.Lock()
+		delete(providers, "test")
+		providersMu.Unlock()
+	}()
+
+	client, err := NewProvider("test", ProviderConfig{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.GenerateInsights(context.Background(), nil); err == nil || err.Error() != "test provider" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestGenerateInsights_ProviderErrors(t *testing.T) {
+	fake := llmtest.NewServer("")
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	anthropic, err := NewProvider(ProviderAnthropic, ProviderConfig{APIKey: "key", BaseURL: srv.URL}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	compatible, err := NewProvider(ProviderOpenAICompatible, ProviderConfig{BaseURL: srv.URL, Model: "fake"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, client := range map[string]InsightsLLM{"anthropic": anthropic, "compatible": compatible} {
+		fake.SetStatus(http.StatusBadRequest)
+		if _, err := client.GenerateInsights(context.Background(), &domain.InsightsContext{}); !errors.Is(err, ErrLLMRequest) {
+			t.Errorf("%s: expected ErrLLMRequest, got %v", name, err)
+		}
+
+		fake.SetStatus(http.StatusOK)
+		fake.SetReply("not json")
+		if _, err := client.GenerateInsights(context.Background(), &domain.InsightsContext{}); !errors.Is(err, ErrLLMResponse) {
+			t.Errorf("%s: expected ErrLLMResponse, got %v", name, err)
+		}
+		fake.SetReply(llmtest.DefaultReply)
+	}
+}
+
+func TestGenerateInsights_Reask(t *testing.T) {
+	invalid := `{"summary": "Short.", "observations": ["only one"], "guidance": ["a", "b", "c"]}`
+
+	tests := []struct {
+		name         string
+		provider     string
+		replies      []string
+		wantErr      error
+		wantRequests int
+	}{
+		{
+			name:         "fenced reply needs no re-ask",
+			provider:     ProviderOpenAICompatible,
+			replies:      []string{"```json\n" + llmtest.DefaultReply + "\n```"},
+			wantRequests: 1,
+		},
+		{
+			name:         "valid after re-ask",
+			provider:     ProviderOpenAICompatible,
+			replies:      []string{invalid, llmtest.DefaultReply},
+			wantRequests: 2,
+		},
+		{
+			name:         "anthropic valid after re-ask",
+			provider:     ProviderAnthropic,
+			replies:      []string{"Sure!", llmtest.DefaultReply},
+			wantRequests: 2,
+		},
+		{
+			name:         "invalid twice",
+			provider:     ProviderOpenAICompatible,
+			replies:      []string{invalid},
+			wantErr:      ErrLLMResponse,
+			wantRequests: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := llmtest.NewServer("")
+			fake.SetReplies(tt.replies...)
+			srv := httptest.NewServer(fake)
+			defer srv.Close()
+
+			client, err := NewProvider(tt.provider, ProviderConfig{APIKey: "key", BaseURL: srv.URL, Model: "fake"}, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			output, err := client.GenerateInsights(context.Background(), &domain.InsightsContext{})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			} else if len(output.Observations) != 3 {
+				t.Errorf("unexpected output: %+v", output)
+			}
+
+			requests := fake.Requests()
+			if len(requests) != tt.wantRequests {
+				t.Fatalf("got %d requests, want %d", len(requests), tt.wantRequests)
+			}
+			// The re-ask carries the invalid reply and what was wrong with it
+			if tt.wantRequests == 2 {
+				reask := requests[1]
+				if reask.Messages != 3 || !strings.Contains(reask.User, "could not be used") {
+					t.Errorf("unexpected re-ask: %d messages, %q", reask.Messages, reask.User)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenAIClient_ResponseFormat(t *testing.T) {
+	fake := llmtest.NewServer("")
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	client, err := NewProvider(ProviderOpenAI, ProviderConfig{APIKey: "key", BaseURL: srv.URL}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.GenerateInsights(context.Background(), &domain.InsightsContext{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var format struct {
+		Type       string `json:"type"`
+		JSONSchema struct {
+			Name   string         `json:"name"`
+			Strict bool           `json:"strict"`
+			Schema map[string]any `json:"schema"`
+		} `json:"json_schema"`
+	}
+	if err := json.Unmarshal(fake.Requests()[0].ResponseFormat, &format); err != nil {
+		t.Fatalf("invalid response_format: %v", err)
+	}
+	if format.Type != "json_schema" || format.JSONSchema.Name != insightsSchemaName || !format.JSONSchema.Strict {
+		t.Errorf("unexpected response_format: %+v", format)
+	}
+	if format.JSONSchema.Schema["additionalProperties"] != false {
+		t.Errorf("unexpected schema: %v", format.JSONSchema.Schema)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	describe := func(t *testing.T, provider, prompt string) domain.InsightsGenerator {
+		t.Helper()
+		client, err := NewProvider(provider, ProviderConfig{APIKey: "key", BaseURL: "http://localhost", Model: "fake"}, StaticSystemPromptProvider(prompt))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		describer, ok := WithResilience(client, ResilienceConfig{}).(Describer)
+		if !ok {
+			t.Fatalf("%s client is not a Describer", provider)
+		}
+		generator, err := describer.Describe(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return generator
+	}
+
+	openai := describe(t, ProviderOpenAICompatible, "prompt A")
+	if openai.Provider != ProviderOpenAICompatible || openai.Model != "fake" || len(openai.PromptVersion) != 12 {
+		t.Errorf("unexpected generator: %+v", openai)
+	}
+	if again := describe(t, ProviderOpenAICompatible, "prompt A"); again != openai {
+		t.Errorf("same prompt gave %+v, then %+v", openai, again)
+	}
+	if other := describe(t, ProviderOpenAICompatible, "prompt B"); other.PromptVersion == openai.PromptVersion {
+		t.Error("another system prompt kept the prompt version")
+	}
+	if anthropic := describe(t, ProviderAnthropic, "prompt A"); anthropic.Provider != ProviderAnthropic || anthropic.PromptVersion != openai.PromptVersion {
+		t.Errorf("unexpected generator: %+v", anthropic)
+	}
+}
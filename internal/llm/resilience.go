@@ -0,0 +1,318 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrCircuitOpen indicates that calls are rejected because the provider
+// failed repeatedly.
+var ErrCircuitOpen = errors.New("LLM provider temporarily unavailable")
+
+// APIError is a provider response with an error status. Request errors wrap
+// it so that retries can tell transient failures from permanent ones.
+type APIError struct {
+	StatusCode int
+	// RetryAfter is the delay asked for by the Retry-After header, or zero.
+	RetryAfter time.Duration
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.StatusCode, e.Message)
+}
+
+// newAPIError creates an APIError, reading Retry-After from header.
+func newAPIError(statusCode int, header http.Header, message string) *APIError {
+	return &APIError{
+		StatusCode: statusCode,
+		RetryAfter: parseRetryAfter(header.Get("Retry-After"), time.Now()),
+		Message:    message,
+	}
+}
+
+// parseRetryAfter parses a Retry-After value in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// ResilienceConfig configures WithResilience.
+type ResilienceConfig struct {
+	// Timeout of each attempt; zero leaves the deadline to the caller.
+	Timeout time.Duration
+	// MaxRetries after the first attempt of a transient failure.
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry; it doubles with each
+	// further retry, up to MaxDelay. Retry-After replaces the backoff, and a
+	// Retry-After beyond MaxDelay ends the retries.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// BreakerThreshold is the number of failed calls in a row that opens the
+	// circuit breaker; zero disables the breaker.
+	BreakerThreshold int
+	// BreakerCooldown is how long the breaker stays open before a trial call.
+	BreakerCooldown time.Duration
+}
+
+// WithResilience wraps an InsightsLLM with a per-attempt timeout, retries of
+// transient failures (timeouts, network errors, 408, 429 and 5xx) with
+// exponential backoff and jitter, and a circuit breaker that fails fast with
+// ErrCircuitOpen while the provider keeps failing. Retries, timeouts and
+// breaker changes are recorded as events on the caller's span.
+func WithResilience(next InsightsLLM, cfg ResilienceConfig) InsightsLLM {
+	return &resilientLLM{
+		next:   next,
+		cfg:    cfg,
+		now:    time.Now,
+		sleep:  sleepContext,
+		jitter: rand.Float64,
+		state:  breakerClosed,
+	}
+}
+
+// breakerState is the state of the circuit breaker.
+type breakerState string
+
+const (
+	breakerClosed   breakerState = "closed"
+	breakerOpen     breakerState = "open"
+	breakerHalfOpen breakerState = "half_open"
+)
+
+type resilientLLM struct {
+	next InsightsLLM
+	cfg  ResilienceConfig
+
+	// Replaced in tests
+	now    func() time.Time
+	sleep  func(ctx context.Context, d time.Duration) error
+	jitter func() float64
+
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+func (r *resilientLLM) GenerateInsights(ctx context.Context, insightsCtx *domain.InsightsContext) (*domain.LLMInsightsOutput, error) {
+	span := trace.SpanFromContext(ctx)
+
+	if err := r.allow(span); err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		output, err := r.attempt(ctx, insightsCtx)
+		if err == nil {
+			r.record(span, true)
+			return output, nil
+		}
+
+		// Calls the caller gave up on and local failures say nothing about
+		// the provider's health, and other permanent failures mean it answered
+		if !isTransient(ctx, err) {
+			if ctx.Err() != nil || !providerAnswered(err) {
+				r.release()
+			} else {
+				r.record(span, true)
+			}
+			return nil, err
+		}
+
+		delay, retry := r.backoff(attempt, err)
+		if !retry {
+			r.record(span, false)
+			return nil, err
+		}
+
+		span.AddEvent("llm.retry", trace.WithAttributes(
+			attribute.Int("llm.retry.attempt", attempt+1),
+			attribute.String("llm.retry.delay", delay.String()),
+			attribute.String("llm.retry.error", err.Error()),
+		))
+		if err := r.sleep(ctx, delay); err != nil {
+			r.release()
+			return nil, fmt.Errorf("%w: %v", ErrLLMRequest, err)
+		}
+	}
+}
+
+// Describe returns the generator of the wrapped InsightsLLM, if it is a
+// Describer.
+func (r *resilientLLM) Describe(ctx context.Context) (domain.InsightsGenerator, error) {
+	describer, ok := r.next.(Describer)
+	if !ok {
+		return domain.InsightsGenerator{}, fmt.Errorf("%T does not describe its generator", r.next)
+	}
+	return describer.Describe(ctx)
+}
+
+// attempt makes one call with the per-attempt timeout.
+func (r *resilientLLM) attempt(ctx context.Context, insightsCtx *domain.InsightsContext) (*domain.LLMInsightsOutput, error) {
+	if r.cfg.Timeout <= 0 {
+		return r.next.GenerateInsights(ctx, insightsCtx)
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	output, err := r.next.GenerateInsights(attemptCtx, insightsCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+		trace.SpanFromContext(ctx).AddEvent("llm.timeout", trace.WithAttributes(
+			attribute.String("llm.timeout", r.cfg.Timeout.String()),
+		))
+		return nil, fmt.Errorf("%w: timed out after %s: %w", ErrLLMRequest, r.cfg.Timeout, context.DeadlineExceeded)
+	}
+	return output, err
+}
+
+// backoff returns the delay before retry attempt+1, or false if the retries
+// are used up or the provider asks for a longer wait than MaxDelay.
+func (r *resilientLLM) backoff(attempt int, err error) (time.Duration, bool) {
+	if attempt >= r.cfg.MaxRetries {
+		return 0, false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter, apiErr.RetryAfter <= r.cfg.MaxDelay
+	}
+
+	// Equal jitter: half the exponential delay, plus up to the other half
+	delay := r.cfg.BaseDelay << attempt
+	if delay > r.cfg.MaxDelay || delay <= 0 {
+		delay = r.cfg.MaxDelay
+	}
+	return delay/2 + time.Duration(r.jitter()*float64(delay/2)), true
+}
+
+// allow rejects the call while the breaker is open, and lets a single trial
+// call through once the cooldown has passed.
+func (r *resilientLLM) allow(span trace.Span) error {
+	if r.cfg.BreakerThreshold <= 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch r.state {
+	case breakerOpen:
+		if r.now().Before(r.openUntil) {
+			break
+		}
+		r.setState(span, breakerHalfOpen)
+		r.trial = true
+		return nil
+	case breakerHalfOpen:
+		if !r.trial {
+			r.trial = true
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	span.AddEvent("llm.circuit_rejected", trace.WithAttributes(
+		attribute.String("llm.circuit.state", string(r.state)),
+	))
+	return fmt.Errorf("%w: retry in %s", ErrCircuitOpen, max(r.openUntil.Sub(r.now()), 0).Round(time.Second))
+}
+
+// record updates the breaker with the outcome of a call.
+func (r *resilientLLM) record(span trace.Span, healthy bool) {
+	if r.cfg.BreakerThreshold <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.trial = false
+	if healthy {
+		r.failures = 0
+		if r.state != breakerClosed {
+			r.setState(span, breakerClosed)
+		}
+		return
+	}
+
+	r.failures++
+	if r.state == breakerHalfOpen || r.failures >= r.cfg.BreakerThreshold {
+		r.openUntil = r.now().Add(r.cfg.BreakerCooldown)
+		r.setState(span, breakerOpen)
+	}
+}
+
+// release ends a call without an outcome, letting another trial call through.
+func (r *resilientLLM) release() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.trial = false
+}
+
+func (r *resilientLLM) setState(span trace.Span, state breakerState) {
+	span.AddEvent("llm.circuit_state", trace.WithAttributes(
+		attribute.String("llm.circuit.from", string(r.state)),
+		attribute.String("llm.circuit.to", string(state)),
+		attribute.Int("llm.circuit.failures", r.failures),
+	))
+	r.state = state
+}
+
+// isTransient reports whether err is worth retrying: an attempt timeout, a
+// network error, or a 408, 429 or 5xx response. Local failures, such as a
+// system prompt that cannot be loaded, and failures after the caller's
+// context ended are not.
+func isTransient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || !errors.Is(err, ErrLLMRequest) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusRequestTimeout ||
+			apiErr.StatusCode == http.StatusTooManyRequests ||
+			apiErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// providerAnswered reports whether err is a response of the provider, as
+// opposed to a failure before the request was sent.
+func providerAnswered(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) || errors.Is(err, ErrLLMResponse)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
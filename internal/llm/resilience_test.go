@@ -0,0 +1,295 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/llm/llmtest"
+)
+
+// llmFunc adapts a function to InsightsLLM.
+type llmFunc func(ctx context.Context) (*domain.LLMInsightsOutput, error)
+
+func (f llmFunc) GenerateInsights(ctx context.Context, _ *domain.InsightsContext) (*domain.LLMInsightsOutput, error) {
+	return f(ctx)
+}
+
+// scriptedLLM returns the errors in turn, then succeeds.
+func scriptedLLM(calls *int, errs ...error) InsightsLLM {
+	return llmFunc(func(ctx context.Context) (*domain.LLMInsightsOutput, error) {
+		*calls++
+		if *calls <= len(errs) {
+			return nil, errs[*calls-1]
+		}
+		return &domain.LLMInsightsOutput{Summary: "ok"}, nil
+	})
+}
+
+func statusErr(status int, retryAfter time.Duration) error {
+	return fmt.Errorf("%w: %w", ErrLLMRequest, &APIError{StatusCode: status, RetryAfter: retryAfter})
+}
+
+// newTestResilience returns a resilientLLM with a fake clock whose sleeps
+// are recorded instead of waited.
+func newTestResilience(next InsightsLLM, cfg ResilienceConfig) (*resilientLLM, *time.Time, *[]time.Duration) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	var sleeps []time.Duration
+	r := WithResilience(next, cfg).(*resilientLLM)
+	r.now = func() time.Time { return now }
+	r.sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+	r.jitter = func() float64 { return 0 }
+	return r, &now, &sleeps
+}
+
+func TestWithResilience_Retries(t *testing.T) {
+	cfg := ResilienceConfig{MaxRetries: 2, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	tests := []struct {
+		name       string
+		errs       []error
+		wantErr    error
+		wantCalls  int
+		wantSleeps []time.Duration
+	}{
+		{
+			name:       "exponential backoff until success",
+			errs:       []error{statusErr(503, 0), statusErr(500, 0)},
+			wantCalls:  3,
+			wantSleeps: []time.Duration{500 * time.Millisecond, time.Second},
+		},
+		{
+			name:       "network errors are retried",
+			errs:       []error{fmt.Errorf("%w: %w", ErrLLMRequest, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")})},
+			wantCalls:  2,
+			wantSleeps: []time.Duration{500 * time.Millisecond},
+		},
+		{
+			name:      "local failures are not retried",
+			errs:      []error{fmt.Errorf("%w: failed to load system prompt: not found", ErrLLMRequest)},
+			wantErr:   ErrLLMRequest,
+			wantCalls: 1,
+		},
+		{
+			name:       "Retry-After replaces the backoff",
+			errs:       []error{statusErr(429, 3*time.Second)},
+			wantCalls:  2,
+			wantSleeps: []time.Duration{3 * time.Second},
+		},
+		{
+			name:      "Retry-After beyond the max delay",
+			errs:      []error{statusErr(429, time.Minute)},
+			wantErr:   ErrLLMRequest,
+			wantCalls: 1,
+		},
+		{
+			name:       "retries used up",
+			errs:       []error{statusErr(502, 0), statusErr(502, 0), statusErr(502, 0)},
+			wantErr:    ErrLLMRequest,
+			wantCalls:  3,
+			wantSleeps: []time.Duration{500 * time.Millisecond, time.Second},
+		},
+		{
+			name:      "client errors are not retried",
+			errs:      []error{statusErr(400, 0)},
+			wantErr:   ErrLLMRequest,
+			wantCalls: 1,
+		},
+		{
+			name:      "invalid output is not retried",
+			errs:      []error{fmt.Errorf("%w: no JSON object in reply", ErrLLMResponse)},
+			wantErr:   ErrLLMResponse,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			r, _, sleeps := newTestResilience(scriptedLLM(&calls, tt.errs...), cfg)
+
+			_, err := r.GenerateInsights(context.Background(), nil)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if fmt.Sprint(*sleeps) != fmt.Sprint(tt.wantSleeps) {
+				t.Errorf("sleeps = %v, want %v", *sleeps, tt.wantSleeps)
+			}
+		})
+	}
+}
+
+func TestWithResilience_Jitter(t *testing.T) {
+	calls := 0
+	r, _, sleeps := newTestResilience(scriptedLLM(&calls, statusErr(503, 0), statusErr(503, 0)),
+		ResilienceConfig{MaxRetries: 2, BaseDelay: time.Second, MaxDelay: 1500 * time.Millisecond})
+	r.jitter = func() float64 { return 1 }
+
+	if _, err := r.GenerateInsights(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Full jitter reaches the exponential delay, capped at the max delay
+	want := []time.Duration{time.Second, 1500 * time.Millisecond}
+	if fmt.Sprint(*sleeps) != fmt.Sprint(want) {
+		t.Errorf("sleeps = %v, want %v", *sleeps, want)
+	}
+}
+
+func TestWithResilience_Timeout(t *testing.T) {
+	calls := 0
+	slow := llmFunc(func(ctx context.Context) (*domain.LLMInsightsOutput, error) {
+		calls++
+		<-ctx.Done()
+		return nil, fmt.Errorf("%w: %v", ErrLLMRequest, ctx.Err())
+	})
+	r, _, _ := newTestResilience(slow, ResilienceConfig{Timeout: 10 * time.Millisecond, MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+
+	_, err := r.GenerateInsights(context.Background(), nil)
+	if !errors.Is(err, ErrLLMRequest) || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+}
+
+func TestWithResilience_CircuitBreaker(t *testing.T) {
+	healthy := false
+	calls := 0
+	next := llmFunc(func(ctx context.Context) (*domain.LLMInsightsOutput, error) {
+		calls++
+		if healthy {
+			return &domain.LLMInsightsOutput{Summary: "ok"}, nil
+		}
+		return nil, statusErr(503, 0)
+	})
+	r, now, _ := newTestResilience(next, ResilienceConfig{BreakerThreshold: 2, BreakerCooldown: 30 * time.Second})
+	generate := func() error {
+		_, err := r.GenerateInsights(context.Background(), nil)
+		return err
+	}
+
+	// Two failed calls in a row open the breaker
+	for i := 0; i < 2; i++ {
+		if err := generate(); !errors.Is(err, ErrLLMRequest) {
+			t.Fatalf("call %d: expected ErrLLMRequest, got %v", i+1, err)
+		}
+	}
+	if err := generate(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("open breaker called the provider: %d calls", calls)
+	}
+
+	// After the cooldown a failed trial call opens it again
+	*now = now.Add(31 * time.Second)
+	if err := generate(); !errors.Is(err, ErrLLMRequest) {
+		t.Fatalf("expected the trial call to fail, got %v", err)
+	}
+	if err := generate(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen after a failed trial, got %v", err)
+	}
+
+	// A successful trial call closes it
+	*now = now.Add(31 * time.Second)
+	healthy = true
+	for i := 0; i < 2; i++ {
+		if err := generate(); err != nil {
+			t.Fatalf("call %d after recovery: %v", i+1, err)
+		}
+	}
+	if r.state != breakerClosed || r.failures != 0 {
+		t.Errorf("breaker not reset: %s with %d failures", r.state, r.failures)
+	}
+}
+
+func TestWithResilience_ClientErrorsKeepBreakerClosed(t *testing.T) {
+	calls := 0
+	r, _, _ := newTestResilience(scriptedLLM(&calls, statusErr(401, 0), statusErr(401, 0), statusErr(401, 0)),
+		ResilienceConfig{BreakerThreshold: 2, BreakerCooldown: time.Minute})
+
+	for i := 0; i < 3; i++ {
+		if _, err := r.GenerateInsights(context.Background(), nil); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d: breaker opened on client errors", i+1)
+		}
+	}
+}
+
+func TestWithResilience_LocalFailuresLeaveBreaker(t *testing.T) {
+	calls := 0
+	local := fmt.Errorf("%w: failed to serialize context: unsupported value", ErrLLMRequest)
+	r, _, _ := newTestResilience(scriptedLLM(&calls, statusErr(503, 0), local, statusErr(503, 0)),
+		ResilienceConfig{BreakerThreshold: 2, BreakerCooldown: time.Minute})
+
+	// The local failure neither counts as a failure nor resets the count
+	for i := 0; i < 3; i++ {
+		r.GenerateInsights(context.Background(), nil)
+	}
+	if r.state != breakerOpen {
+		t.Errorf("breaker %s after two provider failures, want open", r.state)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"0", 0},
+		{"soon", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestProviders_APIError(t *testing.T) {
+	fake := llmtest.NewServer("")
+	fake.SetStatus(http.StatusTooManyRequests)
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	for _, provider := range []string{ProviderOpenAICompatible, ProviderAnthropic} {
+		client, err := NewProvider(provider, ProviderConfig{APIKey: "key", BaseURL: srv.URL, Model: "fake"}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		_, err = client.GenerateInsights(context.Background(), &domain.InsightsContext{})
+		var apiErr *APIError
+		if !errors.Is(err, ErrLLMRequest) || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("%s: expected a 429 APIError, got %v", provider, err)
+		}
+	}
+	// The SDK's own retries are disabled, so each provider was called once
+	if got := len(fake.Requests()); got != 2 {
+		t.Errorf("got %d requests, want 2", got)
+	}
+}
@@ -0,0 +1,398 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ProviderRules writes insights from fixed rules on the metrics, without a
+// model. It is also the fallback when the configured provider fails.
+const ProviderRules = "rules"
+
+// RulesVersion is the version of the rules, reported as their prompt version.
+const RulesVersion = "1"
+
+// Thresholds of the rules.
+const (
+	// rulesLowSufficiencyScore is the share of days meeting the target (0-100)
+	// below which sleep is too short.
+	rulesLowSufficiencyScore = 60.0
+	// rulesHighBedtimeStdMinutes is the bedtime standard deviation from which
+	// bedtimes are irregular.
+	rulesHighBedtimeStdMinutes = 60.0
+	// rulesMinVariabilitySleeps is the number of sleeps needed to judge bedtime
+	// variability.
+	rulesMinVariabilitySleeps = 3
+	// rulesDurationDeltaHours, rulesQualityDelta and rulesBedtimeShiftMinutes
+	// are the changes from history to the recent window worth mentioning.
+	rulesDurationDeltaHours  = 0.5
+	rulesQualityDelta        = 1.0
+	rulesBedtimeShiftMinutes = 30.0
+	// rulesSocialJetlagMinutes is the workday versus free-day shift of
+	// mid-sleep from which the schedule fights the chronotype.
+	rulesSocialJetlagMinutes = 60
+	// rulesLateBedtimeMinutes is how far past the goal's bedtime window the
+	// recent bedtime may fall before it counts as a mismatch.
+	rulesLateBedtimeMinutes = 60.0
+	// rulesSleepDebtHours is the sleep debt worth mentioning.
+	rulesSleepDebtHours = 5.0
+)
+
+// rulesGeneralGuidance fills the guidance up to the minimum, in this order.
+var rulesGeneralGuidance = []string{
+	"Keep your wake-up time within 30 minutes every day, including free days.",
+	"Give yourself a 30-minute wind-down without screens before bed.",
+	"Keep naps under 30 minutes and before mid-afternoon.",
+}
+
+// RulesClient implements InsightsLLM with fixed rules: low daily
+// sufficiency, irregular bedtimes, changes from history to the recent window,
+// a schedule at odds with the chronotype, sleep debt and anomalies. The same
+// context always gives the same insights.
+type RulesClient struct{}
+
+// NewRulesClient creates a new rule-based insights generator.
+func NewRulesClient() *RulesClient {
+	return &RulesClient{}
+}
+
+func newRulesProvider(ProviderConfig, SystemPromptProvider) (InsightsLLM, error) {
+	return NewRulesClient(), nil
+}
+
+// GenerateInsights applies the rules to the context.
+func (c *RulesClient) GenerateInsights(ctx context.Context, insightsCtx *domain.InsightsContext) (*domain.LLMInsightsOutput, error) {
+	tracer := otel.Tracer("sleep-tracker-api/llm")
+	_, span := tracer.Start(ctx, "RulesClient.GenerateInsights",
+		trace.WithAttributes(
+			attribute.String("llm.provider", ProviderRules),
+			attribute.String("llm.rules_version", RulesVersion),
+		),
+	)
+	defer span.End()
+
+	if insightsCtx == nil {
+		return nil, fmt.Errorf("%w: no insights context", ErrLLMRequest)
+	}
+
+	output := applyRules(insightsCtx)
+	if err := outputValidator.Struct(output); err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("%w: %v", ErrLLMResponse, err)
+	}
+	span.SetAttributes(
+		attribute.Int("llm.rules.observations", len(output.Observations)),
+		attribute.Int("llm.rules.guidance", len(output.Guidance)),
+	)
+	return output, nil
+}
+
+// Describe returns the rules provider and version.
+func (c *RulesClient) Describe(context.Context) (domain.InsightsGenerator, error) {
+	return domain.InsightsGenerator{Provider: ProviderRules, Model: ProviderRules, PromptVersion: RulesVersion}, nil
+}
+
+// rulesInsights collects the output of the rules, most important first.
+type rulesInsights struct {
+	observations []string
+	guidance     []string
+	// focus is the sentence of the summary about what to work on
+	focus string
+}
+
+func (r *rulesInsights) observe(format string, args ...any) {
+	r.observations = append(r.observations, fmt.Sprintf(format, args...))
+}
+
+func (r *rulesInsights) advise(format string, args ...any) {
+	r.guidance = append(r.guidance, fmt.Sprintf(format, args...))
+}
+
+func (r *rulesInsights) setFocus(focus string) {
+	if r.focus == "" {
+		r.focus = focus
+	}
+}
+
+func applyRules(c *domain.InsightsContext) *domain.LLMInsightsOutput {
+	if c.History.PerSleep.SleepCount == 0 {
+		return noDataInsights(c)
+	}
+
+	r := &rulesInsights{}
+	sufficiencyRule(r, c)
+	regularityRule(r, c)
+	trendRule(r, c)
+	chronotypeRule(r, c)
+	sleepDebtRule(r, c)
+	anomalyRule(r, c)
+
+	// Fill up with general observations and advice
+	history := c.History
+	r.observe("Your overall sleep score over the last %d days is %.0f out of 100.", history.DailyOverall.DaysCount, history.Scores.OverallSleepScore)
+	r.observe("Average sleep quality is %.1f out of 10 over %d sleeps.", history.PerSleep.Quality.Avg, history.PerSleep.SleepCount)
+	if c.Chronotype.Chronotype != domain.ChronotypeUnknown && c.Chronotype.Chronotype != "" {
+		r.observe("Your mid-sleep time of %s fits the %s chronotype.", c.Chronotype.MidSleepLocalTime, chronotypeName(c.Chronotype.Chronotype))
+	}
+	for _, tip := range rulesGeneralGuidance {
+		if len(r.guidance) >= 3 {
+			break
+		}
+		r.advise("%s", tip)
+	}
+
+	r.setFocus("Keep up your current routine, it is working well.")
+	return &domain.LLMInsightsOutput{
+		Summary:      rulesSummary(c, r.focus),
+		Observations: firstN(r.observations, 6),
+		Guidance:     firstN(r.guidance, 5),
+	}
+}
+
+// sufficiencyRule checks the share of days reaching the target, over the
+// recent window if it has data.
+func sufficiencyRule(r *rulesInsights, c *domain.InsightsContext) {
+	window, label := c.Recent, "last 7 days"
+	if window.DailyOverall.DaysCount == 0 {
+		window, label = c.History, "last 30 days"
+	}
+	daily := window.DailyOverall
+
+	if daily.DailySufficiencyScore < rulesLowSufficiencyScore {
+		r.observe("Only %d of %d days with sleep in the %s reached your target of %s of total sleep.",
+			daily.DaysMeetingTarget, daily.DaysCount, label, hours(daily.TargetHours))
+		r.advise("Plan for at least %s of sleep a day: move your bedtime 20–30 minutes earlier until most days reach it.", hours(daily.TargetHours))
+		r.setFocus("The main thing to work on is getting enough total sleep each day.")
+		return
+	}
+	r.observe("You reached your target of %s of total sleep on %d of %d days with sleep in the %s.",
+		hours(daily.TargetHours), daily.DaysMeetingTarget, daily.DaysCount, label)
+}
+
+// regularityRule checks the bedtime variability of the history window.
+func regularityRule(r *rulesInsights, c *domain.InsightsContext) {
+	perSleep := c.History.PerSleep
+	if perSleep.SleepCount < rulesMinVariabilitySleeps {
+		return
+	}
+
+	std := perSleep.Bedtime.Std
+	if std >= rulesHighBedtimeStdMinutes {
+		r.observe("Your bedtime varies by about %.0f minutes from night to night, which makes a steady rhythm harder.", std)
+		r.advise("Pick a 30-minute bedtime window around %s and keep to it, also before free days.", clockTime(perSleep.Bedtime.Median))
+		r.setFocus("The main thing to work on is a more regular bedtime.")
+		return
+	}
+	r.observe("Your bedtimes are fairly regular, varying by about %.0f minutes around %s.", std, clockTime(perSleep.Bedtime.Median))
+}
+
+// trendRule compares the recent window with the history window.
+func trendRule(r *rulesInsights, c *domain.InsightsContext) {
+	recent, history := c.Recent.PerSleep, c.History.PerSleep
+	if recent.SleepCount == 0 {
+		r.observe("No sleep was logged in the last 7 days, so recent changes cannot be judged.")
+		return
+	}
+
+	changed := false
+	if delta := recent.Duration.Avg - history.Duration.Avg; math.Abs(delta) >= rulesDurationDeltaHours {
+		changed = true
+		r.observe("In the last 7 days you slept %s per night, %s %s than your 30-day average of %s.",
+			hours(recent.Duration.Avg), hours(math.Abs(delta)), moreOrLess(delta), hours(history.Duration.Avg))
+		if delta < 0 {
+			r.advise("Your recent nights are shorter than usual; go to bed a little earlier for the next few nights to catch up.")
+			r.setFocus("Your recent nights have been shorter than usual.")
+		}
+	}
+	if delta := recent.Quality.Avg - history.Quality.Avg; math.Abs(delta) >= rulesQualityDelta {
+		changed = true
+		r.observe("Recent sleep quality averages %.1f out of 10, %s than your 30-day average of %.1f.",
+			recent.Quality.Avg, betterOrWorse(delta), history.Quality.Avg)
+	}
+	if shift := clockDiff(recent.Bedtime.Avg, history.Bedtime.Avg); math.Abs(shift) >= rulesBedtimeShiftMinutes {
+		changed = true
+		r.observe("Your bedtime moved about %.0f minutes %s in the last 7 days, to around %s.",
+			math.Abs(shift), laterOrEarlier(shift), clockTime(recent.Bedtime.Avg))
+		if shift > 0 {
+			r.advise("Bring your bedtime back towards %s gradually, by 15 minutes every few nights.", clockTime(history.Bedtime.Avg))
+		}
+	}
+	if !changed {
+		r.observe("The last 7 days look much like your 30-day history.")
+	}
+}
+
+// chronotypeRule checks for social jetlag and for bedtimes later than the
+// goal allows.
+func chronotypeRule(r *rulesInsights, c *domain.InsightsContext) {
+	if jetlag := c.Chronotype.SocialJetlag; jetlag != nil && jetlag.SocialJetlagMinutes >= rulesSocialJetlagMinutes {
+		r.observe("Your mid-sleep is %s before workdays but %s before free days, a social jetlag of %d minutes.",
+			jetlag.WorkdayMidSleepLocalTime, jetlag.FreeDayMidSleepLocalTime, jetlag.SocialJetlagMinutes)
+		r.advise("Narrow the gap between workdays and free days: keep free-day wake-ups within an hour of your workday alarm.")
+		r.setFocus("Your workday schedule is at odds with your natural rhythm.")
+	}
+
+	if c.Goal == nil || c.Goal.BedtimeWindowEnd == nil || c.Recent.PerSleep.SleepCount == 0 {
+		return
+	}
+	windowEnd, ok := parseClock(*c.Goal.BedtimeWindowEnd)
+	if !ok {
+		return
+	}
+	bedtime := c.Recent.PerSleep.Bedtime.Avg
+	if late := clockDiff(bedtime, windowEnd); late >= rulesLateBedtimeMinutes {
+		note := ""
+		if c.Chronotype.Chronotype == domain.ChronotypeNightOwl {
+			note = ", which is common for a night owl"
+		}
+		r.observe("Recent bedtimes average %s, about %.0f minutes after your goal window ends at %s%s.",
+			clockTime(bedtime), late, *c.Goal.BedtimeWindowEnd, note)
+		r.advise("Move towards your goal bedtime in 15-minute steps, and get daylight soon after waking to shift your rhythm earlier.")
+		r.setFocus("Your bedtimes run later than your goal.")
+	}
+}
+
+// sleepDebtRule mentions a large sleep debt.
+func sleepDebtRule(r *rulesInsights, c *domain.InsightsContext) {
+	if c.SleepDebtHours < rulesSleepDebtHours {
+		return
+	}
+	r.observe("You owe about %s of sleep against your target.", hours(c.SleepDebtHours))
+	r.advise("Pay off the sleep debt with 30–60 extra minutes a night over the coming week rather than one long lie-in.")
+}
+
+// anomalyRule mentions the unusual nights of the recent window. The
+// anomalies are ordered most recent first.
+func anomalyRule(r *rulesInsights, c *domain.InsightsContext) {
+	if len(c.Anomalies) == 0 {
+		return
+	}
+	latest := c.Anomalies[0]
+	if len(c.Anomalies) == 1 {
+		r.observe("One night in the last week stood out (%s): %s.", latest.Date, lowerFirst(latest.Reason))
+		return
+	}
+	r.observe("%d nights in the last week stood out; the latest (%s): %s.", len(c.Anomalies), latest.Date, lowerFirst(latest.Reason))
+}
+
+// rulesSummary describes the history and last night, then the focus.
+func rulesSummary(c *domain.InsightsContext, focus string) string {
+	sentences := []string{fmt.Sprintf("Over the last 30 days you slept %s per night on average, with an overall sleep score of %.0f.",
+		hours(c.History.PerSleep.Duration.Avg), c.History.Scores.OverallSleepScore)}
+
+	lastNight, recent := c.LastNight.DailyOverall, c.Recent.DailyOverall
+	if c.LastNight.PerSleep.SleepCount > 0 && recent.DaysCount > 0 {
+		last, avg := lastNight.TotalDailyHours.Avg, recent.TotalDailyHours.Avg
+		comparison := "about the same as"
+		if delta := last - avg; math.Abs(delta) >= rulesDurationDeltaHours {
+			comparison = moreOrLess(delta) + " than"
+		}
+		sentences = append(sentences, fmt.Sprintf("On your last night with data you slept %s in total, %s your recent average of %s.",
+			hours(last), comparison, hours(avg)))
+	}
+
+	return strings.Join(append(sentences, focus), " ")
+}
+
+// noDataInsights is the output for a user without sleeps in the history window.
+func noDataInsights(c *domain.InsightsContext) *domain.LLMInsightsOutput {
+	target := c.History.DailyOverall.TargetHours
+	if target == 0 {
+		target = 7
+	}
+	return &domain.LLMInsightsOutput{
+		Summary: "There is no sleep logged in the last 30 days, so there is nothing to analyse yet. Log your sleep for a week to get your first insights.",
+		Observations: []string{
+			"No sleeps were logged in the last 30 days.",
+			"Your chronotype cannot be estimated without logged sleep.",
+			"Trends need at least a few nights in the last 7 days.",
+		},
+		Guidance: []string{
+			"Log every sleep, including naps, for at least a week.",
+			fmt.Sprintf("Aim for %s of sleep a day while you build up your log.", hours(target)),
+			rulesGeneralGuidance[0],
+		},
+	}
+}
+
+func hours(h float64) string {
+	return fmt.Sprintf("%.1f h", h)
+}
+
+// clockTime formats minutes after midnight as HH:MM.
+func clockTime(minutes float64) string {
+	m := ((int(math.Round(minutes)) % 1440) + 1440) % 1440
+	return fmt.Sprintf("%02d:%02d", m/60, m%60)
+}
+
+// clockDiff returns a - b in minutes on the clock, in (-720, 720].
+func clockDiff(a, b float64) float64 {
+	d := math.Mod(a-b, 1440)
+	if d > 720 {
+		d -= 1440
+	} else if d <= -720 {
+		d += 1440
+	}
+	return d
+}
+
+// parseClock parses HH:MM into minutes after midnight.
+func parseClock(value string) (float64, bool) {
+	h, m, ok := strings.Cut(value, ":")
+	if !ok {
+		return 0, false
+	}
+	hh, err1 := strconv.Atoi(h)
+	mm, err2 := strconv.Atoi(m)
+	if err1 != nil || err2 != nil {
+		return 0, false
+	}
+	return float64(hh*60 + mm), true
+}
+
+func chronotypeName(chronotype domain.ChronotypeType) string {
+	return strings.ReplaceAll(string(chronotype), "_", " ")
+}
+
+func moreOrLess(delta float64) string {
+	if delta > 0 {
+		return "more"
+	}
+	return "less"
+}
+
+func betterOrWorse(delta float64) string {
+	if delta > 0 {
+		return "better"
+	}
+	return "worse"
+}
+
+func laterOrEarlier(delta float64) string {
+	if delta > 0 {
+		return "later"
+	}
+	return "earlier"
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func firstN(items []string, n int) []string {
+	if len(items) > n {
+		return items[:n]
+	}
+	return items
+}
@@ -0,0 +1,208 @@
+package llm
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+)
+
+// rulesContext returns a context of 30 steady nights meeting the target.
+func rulesContext() *domain.InsightsContext {
+	window := func(sleeps, days int) domain.WindowMetrics {
+		var w domain.WindowMetrics
+		w.PerSleep.SleepCount = sleeps
+		w.PerSleep.Duration = domain.DescriptiveStats{Avg: 7.5}
+		w.PerSleep.Quality = domain.DescriptiveStats{Avg: 7}
+		w.PerSleep.Bedtime = domain.DescriptiveStats{Avg: 1380, Median: 1380, Std: 20}
+		w.DailyOverall = domain.DailyOverallMetrics{
+			DaysCount:             days,
+			TotalDailyHours:       domain.DescriptiveStats{Avg: 7.5},
+			TargetHours:           7,
+			DaysMeetingTarget:     days,
+			DailySufficiencyScore: 100,
+		}
+		w.Scores.OverallSleepScore = 85
+		return w
+	}
+	return &domain.InsightsContext{
+		Chronotype: domain.ChronotypeResult{Chronotype: domain.ChronotypeIntermediate, MidSleepLocalTime: "03:00"},
+		History:    window(30, 30),
+		Recent:     window(7, 7),
+		LastNight:  window(1, 1),
+	}
+}
+
+func TestRulesClient_GenerateInsights(t *testing.T) {
+	clock := func(s string) *string { return &s }
+
+	tests := []struct {
+		name   string
+		modify func(c *domain.InsightsContext)
+		// Substrings expected in the observations, guidance and summary
+		wantObservation string
+		wantGuidance    string
+		wantSummary     string
+	}{
+		{
+			name:            "steady sleep",
+			modify:          func(c *domain.InsightsContext) {},
+			wantObservation: "much like your 30-day history",
+			wantGuidance:    "wake-up time",
+			wantSummary:     "Keep up your current routine",
+		},
+		{
+			name: "no data",
+			modify: func(c *domain.InsightsContext) {
+				*c = domain.InsightsContext{}
+			},
+			wantObservation: "No sleeps were logged",
+			wantGuidance:    "Log every sleep",
+			wantSummary:     "nothing to analyse yet",
+		},
+		{
+			name: "low daily sufficiency",
+			modify: func(c *domain.InsightsContext) {
+				c.Recent.DailyOverall.DaysMeetingTarget = 2
+				c.Recent.DailyOverall.DailySufficiencyScore = 28.6
+			},
+			wantObservation: "Only 2 of 7 days",
+			wantGuidance:    "at least 7.0 h of sleep a day",
+			wantSummary:     "getting enough total sleep",
+		},
+		{
+			name: "irregular bedtimes",
+			modify: func(c *domain.InsightsContext) {
+				c.History.PerSleep.Bedtime.Std = 95
+			},
+			wantObservation: "varies by about 95 minutes",
+			wantGuidance:    "bedtime window around 23:00",
+			wantSummary:     "more regular bedtime",
+		},
+		{
+			name: "shorter and later recent nights",
+			modify: func(c *domain.InsightsContext) {
+				c.Recent.PerSleep.Duration.Avg = 6.5
+				c.Recent.PerSleep.Bedtime.Avg = 15 // 00:15, 75 minutes after 23:00
+			},
+			wantObservation: "1.0 h less than your 30-day average",
+			wantGuidance:    "back towards 23:00",
+			wantSummary:     "shorter than usual",
+		},
+		{
+			name: "social jetlag",
+			modify: func(c *domain.InsightsContext) {
+				c.Chronotype.SocialJetlag = &domain.SocialJetlag{
+					WorkdayMidSleepLocalTime: "02:45",
+					FreeDayMidSleepLocalTime: "04:30",
+					SocialJetlagMinutes:      105,
+				}
+			},
+			wantObservation: "social jetlag of 105 minutes",
+			wantGuidance:    "free-day wake-ups",
+			wantSummary:     "at odds with your natural rhythm",
+		},
+		{
+			name: "bedtimes later than the goal",
+			modify: func(c *domain.InsightsContext) {
+				c.Chronotype.Chronotype = domain.ChronotypeNightOwl
+				c.Goal = &domain.SleepGoalResponse{TargetHours: 7, BedtimeWindowStart: clock("21:30"), BedtimeWindowEnd: clock("22:00")}
+			},
+			wantObservation: "60 minutes after your goal window ends at 22:00, which is common for a night owl",
+			wantGuidance:    "15-minute steps",
+			wantSummary:     "later than your goal",
+		},
+		{
+			name: "sleep debt and anomalies",
+			modify: func(c *domain.InsightsContext) {
+				c.SleepDebtHours = 6.2
+				c.Anomalies = []domain.SleepAnomaly{{Date: "2024-01-14", Reason: "Slept 4.5 h, 3 h less than usual"}}
+			},
+			wantObservation: "One night in the last week stood out (2024-01-14): slept 4.5 h",
+			wantGuidance:    "Pay off the sleep debt",
+		},
+		{
+			name: "several anomalies report the most recent",
+			modify: func(c *domain.InsightsContext) {
+				c.Anomalies = []domain.SleepAnomaly{
+					{Date: "2024-01-14", Reason: "Went to bed 3 h later than usual"},
+					{Date: "2024-01-11", Reason: "Slept 4.5 h, 3 h less than usual"},
+				}
+			},
+			wantObservation: "2 nights in the last week stood out; the latest (2024-01-14): went to bed 3 h later",
+			wantGuidance:    "wake-up time",
+			wantSummary:     "Keep up your current routine",
+		},
+	}
+
+	client := NewRulesClient()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			insightsCtx := rulesContext()
+			tt.modify(insightsCtx)
+
+			output, err := client.GenerateInsights(context.Background(), insightsCtx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := outputValidator.Struct(output); err != nil {
+				t.Errorf("output breaks the schema: %v", err)
+			}
+			if got := strings.Join(output.Observations, "\n"); !strings.Contains(got, tt.wantObservation) {
+				t.Errorf("observations lack %q:\n%s", tt.wantObservation, got)
+			}
+			if got := strings.Join(output.Guidance, "\n"); !strings.Contains(got, tt.wantGuidance) {
+				t.Errorf("guidance lacks %q:\n%s", tt.wantGuidance, got)
+			}
+			if !strings.Contains(output.Summary, tt.wantSummary) {
+				t.Errorf("summary lacks %q: %s", tt.wantSummary, output.Summary)
+			}
+
+			// The same context gives the same insights
+			again, _ := client.GenerateInsights(context.Background(), insightsCtx)
+			if !reflect.DeepEqual(output, again) {
+				t.Errorf("insights differ between runs")
+			}
+		})
+	}
+}
+
+func TestRulesClient_CapsItems(t *testing.T) {
+	insightsCtx := rulesContext()
+	insightsCtx.Recent.DailyOverall.DailySufficiencyScore = 10
+	insightsCtx.History.PerSleep.Bedtime.Std = 120
+	insightsCtx.Recent.PerSleep.Duration.Avg = 5
+	insightsCtx.Recent.PerSleep.Quality.Avg = 4
+	insightsCtx.Recent.PerSleep.Bedtime.Avg = 60
+	insightsCtx.Chronotype.SocialJetlag = &domain.SocialJetlag{SocialJetlagMinutes: 120}
+	insightsCtx.SleepDebtHours = 10
+	insightsCtx.Anomalies = []domain.SleepAnomaly{{Date: "2024-01-14"}, {Date: "2024-01-13"}}
+
+	output, err := NewRulesClient().GenerateInsights(context.Background(), insightsCtx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(output.Observations) != 6 || len(output.Guidance) != 5 {
+		t.Errorf("got %d observations and %d guidance items, want 6 and 5", len(output.Observations), len(output.Guidance))
+	}
+	// The first finding sets the focus
+	if !strings.Contains(output.Summary, "getting enough total sleep") {
+		t.Errorf("unexpected summary: %s", output.Summary)
+	}
+}
+
+func TestRulesProvider(t *testing.T) {
+	client, err := NewProvider(ProviderRules, ProviderConfig{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	generator, err := client.(Describer).Describe(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if generator.Provider != ProviderRules || generator.PromptVersion != RulesVersion {
+		t.Errorf("unexpected generator: %+v", generator)
+	}
+}
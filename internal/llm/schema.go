@@ -0,0 +1,159 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/go-playground/validator/v10"
+)
+
+// insightsSchemaName names the structured output format sent to the model.
+const insightsSchemaName = "sleep_insights"
+
+// InsightsOutputSchema is the JSON Schema of domain.LLMInsightsOutput.
+var InsightsOutputSchema = JSONSchema(reflect.TypeOf(domain.LLMInsightsOutput{}))
+
+// JSONSchema builds a JSON Schema for t from its json tags, in the subset
+// accepted by strict structured outputs: every property is required and no
+// others are allowed. The validate rules (non-empty strings, item counts) are
+// left out because strict mode rejects minLength, minItems and maxItems;
+// parseInsightsOutput enforces them on every reply instead.
+func JSONSchema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	schema := map[string]any{}
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := map[string]any{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := jsonName(field)
+			if name == "" {
+				continue
+			}
+			properties[name] = JSONSchema(field.Type)
+			required = append(required, name)
+		}
+		schema["type"] = "object"
+		schema["properties"] = properties
+		schema["required"] = required
+		schema["additionalProperties"] = false
+	case reflect.Slice, reflect.Array:
+		schema["type"] = "array"
+		schema["items"] = JSONSchema(t.Elem())
+	case reflect.String:
+		schema["type"] = "string"
+	case reflect.Bool:
+		schema["type"] = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		schema["type"] = "integer"
+	case reflect.Float32, reflect.Float64:
+		schema["type"] = "number"
+	}
+	return schema
+}
+
+// jsonName returns the JSON property name of field, or "" if it is not
+// serialized.
+func jsonName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+var outputValidator = func() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(jsonName)
+	return v
+}()
+
+// parseInsightsOutput extracts the insights JSON from a model reply and
+// checks it against the rules of the system prompt. Replies wrapped in code
+// fences or surrounded by text are accepted.
+func parseInsightsOutput(content string) (*domain.LLMInsightsOutput, error) {
+	object, err := extractJSONObject(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var output domain.LLMInsightsOutput
+	if err := json.Unmarshal(object, &output); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	if err := outputValidator.Struct(output); err != nil {
+		var fieldErrors validator.ValidationErrors
+		if !errors.As(err, &fieldErrors) {
+			return nil, err
+		}
+		messages := make([]string, 0, len(fieldErrors))
+		for _, fe := range fieldErrors {
+			messages = append(messages, outputErrorMessage(fe))
+		}
+		return nil, errors.New(strings.Join(messages, "; "))
+	}
+
+	return &output, nil
+}
+
+func outputErrorMessage(fe validator.FieldError) string {
+	field := fe.Field()
+	switch {
+	case fe.Tag() == "required":
+		return field + " must not be empty"
+	case fe.Kind() == reflect.Slice && fe.Tag() == "min":
+		return fmt.Sprintf("%s must have at least %s items, got %d", field, fe.Param(), reflect.ValueOf(fe.Value()).Len())
+	case fe.Kind() == reflect.Slice && fe.Tag() == "max":
+		return fmt.Sprintf("%s must have at most %s items, got %d", field, fe.Param(), reflect.ValueOf(fe.Value()).Len())
+	default:
+		return fmt.Sprintf("%s fails %s=%s", field, fe.Tag(), fe.Param())
+	}
+}
+
+// extractJSONObject returns the first JSON object in content, after removing
+// any Markdown code fence around it.
+func extractJSONObject(content string) (json.RawMessage, error) {
+	content = stripCodeFence(strings.TrimSpace(content))
+
+	start := strings.Index(content, "{")
+	if start < 0 {
+		return nil, errors.New("no JSON object in reply")
+	}
+
+	// The decoder stops at the end of the first value, ignoring any text after it
+	var object json.RawMessage
+	if err := json.NewDecoder(strings.NewReader(content[start:])).Decode(&object); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	return object, nil
+}
+
+// stripCodeFence removes a Markdown code fence (``` or ```json) around s.
+func stripCodeFence(s string) string {
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	// Drop the language tag on the opening line
+	if newline := strings.IndexByte(s, '\n'); newline >= 0 {
+		s = s[newline+1:]
+	}
+	s = strings.TrimSpace(s)
+	return strings.TrimSpace(strings.TrimSuffix(s, "```"))
+}
@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/blaisecz/sleep-tracker/internal/llm/llmtest"
+)
+
+func TestInsightsOutputSchema(t *testing.T) {
+	want := `{
+		"type": "object",
+		"additionalProperties": false,
+		"required": ["summary", "observations", "guidance"],
+		"properties": {
+			"summary": {"type": "string"},
+			"observations": {"type": "array", "items": {"type": "string"}},
+			"guidance": {"type": "array", "items": {"type": "string"}}
+		}
+	}`
+
+	got, err := json.Marshal(InsightsOutputSchema)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var gotValue, wantValue any
+	json.Unmarshal(got, &gotValue)
+	json.Unmarshal([]byte(want), &wantValue)
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("schema = %s", got)
+	}
+}
+
+func TestParseInsightsOutput(t *testing.T) {
+	items := func(n int) string {
+		quoted := make([]string, n)
+		for i := range quoted {
+			quoted[i] = `"item"`
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	}
+	output := func(observations, guidance int) string {
+		return `{"summary": "Steady sleep.", "observations": ` + items(observations) + `, "guidance": ` + items(guidance) + `}`
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "plain", content: llmtest.DefaultReply},
+		{name: "json fence", content: "```json\n" + output(3, 3) + "\n```"},
+		{name: "bare fence", content: "```\n" + output(6, 5) + "\n```"},
+		{name: "surrounding text", content: "Here are your insights:\n" + output(4, 4) + "\nLet me know if you need more."},
+		{name: "braces inside strings", content: `{"summary": "Use {curly} braces}", "observations": ` + items(3) + `, "guidance": ` + items(3) + `} trailing }`},
+		{name: "no object", content: "I cannot help with that.", wantErr: "no JSON object"},
+		{name: "truncated", content: `{"summary": "Steady`, wantErr: "invalid JSON"},
+		{name: "too few observations", content: output(2, 3), wantErr: "observations must have at least 3 items, got 2"},
+		{name: "too many guidance items", content: output(3, 6), wantErr: "guidance must have at most 5 items, got 6"},
+		{name: "empty summary", content: `{"summary": "", "observations": ` + items(3) + `, "guidance": ` + items(3) + `}`, wantErr: "summary must not be empty"},
+		{name: "empty item", content: `{"summary": "s", "observations": ["a", "", "c"], "guidance": ` + items(3) + `}`, wantErr: "observations[1] must not be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseInsightsOutput(tt.content)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Summary == "" {
+				t.Errorf("unexpected output: %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InsightsCacheRepository interface {
+	// Get returns the user's cached insights, or domain.ErrNotFound.
+	Get(ctx context.Context, userID uuid.UUID) (*domain.InsightsCacheEntry, error)
+	// Save stores entry, replacing the user's previous entry.
+	Save(ctx context.Context, entry *domain.InsightsCacheEntry) error
+	// Delete drops the user's cached insights, if any.
+	Delete(ctx context.Context, userID uuid.UUID) error
+}
+
+type insightsCacheRepository struct {
+	db *gorm.DB
+}
+
+func NewInsightsCacheRepository(db *gorm.DB) InsightsCacheRepository {
+	return &insightsCacheRepository{db: db}
+}
+
+func (r *insightsCacheRepository) Get(ctx context.Context, userID uuid.UUID) (*domain.InsightsCacheEntry, error) {
+	var entry domain.InsightsCacheEntry
+	err := r.db.WithContext(ctx).First(&entry, "user_id = ?", userID).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *insightsCacheRepository) Save(ctx context.Context, entry *domain.InsightsCacheEntry) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			UpdateAll: true,
+		}).
+		Create(entry).Error
+}
+
+func (r *insightsCacheRepository) Delete(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&domain.InsightsCacheEntry{}, "user_id = ?", userID).Error
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SleepGoalRepository interface {
+	// Current returns the goal in force, or domain.ErrNotFound.
+	Current(ctx context.Context, userID uuid.UUID) (*domain.SleepGoal, error)
+	// ListByUser returns all versions of the user's goals, oldest first.
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.SleepGoal, error)
+	// Replace ends the goal in force at goal.EffectiveFrom and creates goal,
+	// in a single transaction.
+	Replace(ctx context.Context, goal *domain.SleepGoal) error
+	// End ends the goal in force at the given time. Fails with
+	// domain.ErrNotFound when no goal is in force.
+	End(ctx context.Context, userID uuid.UUID, at time.Time) error
+}
+
+type sleepGoalRepository struct {
+	db *gorm.DB
+}
+
+func NewSleepGoalRepository(db *gorm.DB) SleepGoalRepository {
+	return &sleepGoalRepository{db: db}
+}
+
+func (r *sleepGoalRepository) Current(ctx context.Context, userID uuid.UUID) (*domain.SleepGoal, error) {
+	var goal domain.SleepGoal
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND effective_to IS NULL", userID).
+		First(&goal).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &goal, nil
+}
+
+func (r *sleepGoalRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.SleepGoal, error) {
+	var goals []domain.SleepGoal
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("effective_from ASC").
+		Find(&goals).Error; err != nil {
+		return nil, err
+	}
+	return goals, nil
+}
+
+func (r *sleepGoalRepository) Replace(ctx context.Context, goal *domain.SleepGoal) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.SleepGoal{}).
+			Where("user_id = ? AND effective_to IS NULL", goal.UserID).
+			Update("effective_to", goal.EffectiveFrom).Error; err != nil {
+			return err
+		}
+		return tx.Create(goal).Error
+	})
+}
+
+func (r *sleepGoalRepository) End(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	result := r.db.WithContext(ctx).Model(&domain.SleepGoal{}).
+		Where("user_id = ? AND effective_to IS NULL", userID).
+		Update("effective_to", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
@@ -20,6 +20,34 @@ type SleepLogRepository interface {
 	GetByClientRequestID(ctx context.Context, userID uuid.UUID, clientRequestID string) (*domain.SleepLog, error)
 	// ListByEndRange returns all sleep logs for a user where EndAt is within [from, to].
 	ListByEndRange(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]domain.SleepLog, error)
+	// Delete soft-deletes a sleep log if its stored version still matches
+	// log.Version, and bumps the version.
+	Delete(ctx context.Context, log *domain.SleepLog) error
+	// GetDeletedByID returns a soft-deleted sleep log by ID.
+	GetDeletedByID(ctx context.Context, id uuid.UUID) (*domain.SleepLog, error)
+	// Restore clears the deleted marker of a soft-deleted sleep log if its
+	// stored version still matches log.Version, and bumps the version.
+	Restore(ctx context.Context, log *domain.SleepLog) error
+	// PurgeDeleted permanently removes logs soft-deleted before the given time.
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+	// CreateBatch inserts all logs in a single transaction.
+	CreateBatch(ctx context.Context, logs []*domain.SleepLog) error
+	// ListByClientRequestIDs returns the user's logs, including soft-deleted ones, holding any of the given client request IDs.
+	ListByClientRequestIDs(ctx context.Context, userID uuid.UUID, clientRequestIDs []string) ([]domain.SleepLog, error)
+	// ListOverlapping returns all sleep logs for a user that intersect [from, to).
+	ListOverlapping(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]domain.SleepLog, error)
+	// Iterate calls fn for every sleep log of the user matching the filter's
+	// time range, ordered by start_at ASC, without loading them all at once.
+	Iterate(ctx context.Context, userID uuid.UUID, filter domain.SleepLogFilter, fn func(*domain.SleepLog) error) error
+}
+
+// withDetails preloads a log's stages in chronological order and its factors with their tags.
+func withDetails(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Stages", func(db *gorm.DB) *gorm.DB {
+			return db.Order("start_at ASC")
+		}).
+		Preload("Factors.Tag")
 }
 
 type sleepLogRepository struct {
@@ -36,7 +64,7 @@ func (r *sleepLogRepository) Create(ctx context.Context, log *domain.SleepLog) e
 
 func (r *sleepLogRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.SleepLog, error) {
 	var log domain.SleepLog
-	err := r.db.WithContext(ctx).First(&log, "id = ?", id).Error
+	err := r.db.WithContext(ctx).Scopes(withDetails).First(&log, "id = ?", id).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, domain.ErrNotFound
@@ -48,6 +76,7 @@ func (r *sleepLogRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain
 
 func (r *sleepLogRepository) List(ctx context.Context, userID uuid.UUID, filter domain.SleepLogFilter) ([]domain.SleepLog, error) {
 	query := r.db.WithContext(ctx).
+		Scopes(withDetails).
 		Where("user_id = ?", userID).
 		Order("start_at DESC, id DESC")
 
@@ -59,6 +88,15 @@ func (r *sleepLogRepository) List(ctx context.Context, userID uuid.UUID, filter
 		query = query.Where("start_at <= ?", filter.To)
 	}
 
+	// Apply tag filter; tag names resolve to the catalogue or the log owner's tags
+	if filter.Tag != "" {
+		query = query.Where(
+			"EXISTS (SELECT 1 FROM sleep_log_factors f JOIN tags t ON t.id = f.tag_id "+
+				"WHERE f.sleep_log_id = sleep_logs.id AND t.name = ? AND (t.user_id IS NULL OR t.user_id = sleep_logs.user_id))",
+			filter.Tag,
+		)
+	}
+
 	// Apply cursor pagination
 	if filter.Cursor != "" {
 		cursor, err := pagination.DecodeCursor(filter.Cursor)
@@ -100,9 +138,13 @@ func (r *sleepLogRepository) HasOverlap(ctx context.Context, userID uuid.UUID, s
 	return count > 0, nil
 }
 
+// GetByClientRequestID includes soft-deleted logs, since the unique index on
+// client_request_id still covers them.
 func (r *sleepLogRepository) GetByClientRequestID(ctx context.Context, userID uuid.UUID, clientRequestID string) (*domain.SleepLog, error) {
 	var log domain.SleepLog
 	err := r.db.WithContext(ctx).
+		Unscoped().
+		Scopes(withDetails).
 		Where("user_id = ? AND client_request_id = ?", userID, clientRequestID).
 		First(&log).Error
 	if err != nil {
@@ -114,8 +156,59 @@ func (r *sleepLogRepository) GetByClientRequestID(ctx context.Context, userID uu
 	return &log, nil
 }
 
+// Update saves the log only if its stored version still matches log.Version,
+// then bumps the version. A concurrent update makes it fail with
+// domain.ErrPreconditionFailed instead of silently overwriting.
+// The stored stages and factors are replaced with log.Stages and log.Factors
+// in the same transaction.
 func (r *sleepLogRepository) Update(ctx context.Context, log *domain.SleepLog) error {
-	return r.db.WithContext(ctx).Save(log).Error
+	expected := log.Version
+	log.Version = expected + 1
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(log).
+			Where("version = ?", expected).
+			Select("start_at", "end_at", "quality", "type", "local_timezone", "client_request_id", "version").
+			Updates(log)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrPreconditionFailed
+		}
+
+		if err := tx.Where("sleep_log_id = ?", log.ID).Delete(&domain.SleepStage{}).Error; err != nil {
+			return err
+		}
+		if len(log.Stages) > 0 {
+			for i := range log.Stages {
+				log.Stages[i].ID = uuid.Nil
+				log.Stages[i].SleepLogID = log.ID
+			}
+			if err := tx.Create(&log.Stages).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("sleep_log_id = ?", log.ID).Delete(&domain.SleepLogFactor{}).Error; err != nil {
+			return err
+		}
+		if len(log.Factors) > 0 {
+			for i := range log.Factors {
+				log.Factors[i].ID = uuid.Nil
+				log.Factors[i].SleepLogID = log.ID
+			}
+			if err := tx.Create(&log.Factors).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Version = expected
+		return err
+	}
+	return nil
 }
 
 // HasOverlapExcluding checks for overlapping sleep periods for the user,
@@ -140,6 +233,7 @@ func (r *sleepLogRepository) HasOverlapExcluding(ctx context.Context, userID uui
 func (r *sleepLogRepository) ListByEndRange(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]domain.SleepLog, error) {
 	var logs []domain.SleepLog
 	if err := r.db.WithContext(ctx).
+		Scopes(withDetails).
 		Where("user_id = ?", userID).
 		Where("end_at >= ?", from).
 		Where("end_at <= ?", to).
@@ -149,3 +243,157 @@ func (r *sleepLogRepository) ListByEndRange(ctx context.Context, userID uuid.UUI
 	}
 	return logs, nil
 }
+
+// Delete uses the same version guard as Update, so deleting a log that was
+// changed concurrently fails with domain.ErrPreconditionFailed.
+func (r *sleepLogRepository) Delete(ctx context.Context, log *domain.SleepLog) error {
+	deletedAt := gorm.DeletedAt{Time: time.Now().UTC(), Valid: true}
+	result := r.db.WithContext(ctx).
+		Model(log).
+		Where("version = ?", log.Version).
+		UpdateColumns(map[string]any{"deleted_at": deletedAt, "version": log.Version + 1})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrPreconditionFailed
+	}
+	log.DeletedAt = deletedAt
+	log.Version++
+	return nil
+}
+
+func (r *sleepLogRepository) GetDeletedByID(ctx context.Context, id uuid.UUID) (*domain.SleepLog, error) {
+	var log domain.SleepLog
+	err := r.db.WithContext(ctx).
+		Unscoped().
+		Scopes(withDetails).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		First(&log).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &log, nil
+}
+
+func (r *sleepLogRepository) Restore(ctx context.Context, log *domain.SleepLog) error {
+	result := r.db.WithContext(ctx).
+		Unscoped().
+		Model(log).
+		Where("version = ?", log.Version).
+		UpdateColumns(map[string]any{"deleted_at": nil, "version": log.Version + 1})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrPreconditionFailed
+	}
+	log.DeletedAt = gorm.DeletedAt{}
+	log.Version++
+	return nil
+}
+
+// PurgeDeleted hard-deletes logs whose deleted_at is older than before and
+// returns the number of rows removed.
+func (r *sleepLogRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Delete(&domain.SleepLog{})
+	return result.RowsAffected, result.Error
+}
+
+// createBatchSize bounds the number of rows per INSERT statement.
+const createBatchSize = 100
+
+func (r *sleepLogRepository) CreateBatch(ctx context.Context, logs []*domain.SleepLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(logs, createBatchSize).Error
+	})
+}
+
+// ListByClientRequestIDs includes soft-deleted logs, for the same reason as
+// GetByClientRequestID.
+func (r *sleepLogRepository) ListByClientRequestIDs(ctx context.Context, userID uuid.UUID, clientRequestIDs []string) ([]domain.SleepLog, error) {
+	if len(clientRequestIDs) == 0 {
+		return nil, nil
+	}
+	var logs []domain.SleepLog
+	if err := r.db.WithContext(ctx).
+		Unscoped().
+		Scopes(withDetails).
+		Where("user_id = ?", userID).
+		Where("client_request_id IN ?", clientRequestIDs).
+		Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// ListOverlapping uses the same intersection rule as HasOverlap, so a whole
+// batch can be checked against stored data with one query.
+func (r *sleepLogRepository) ListOverlapping(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]domain.SleepLog, error) {
+	var logs []domain.SleepLog
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("start_at < ?", to).
+		Where("end_at > ?", from).
+		Order("start_at ASC").
+		Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// iterateBatchSize is the number of rows Iterate fetches per query.
+const iterateBatchSize = 500
+
+// Iterate walks the matching logs in keyset-paginated batches, so memory use
+// stays bounded regardless of history size. It stops at the first error
+// returned by fn.
+func (r *sleepLogRepository) Iterate(ctx context.Context, userID uuid.UUID, filter domain.SleepLogFilter, fn func(*domain.SleepLog) error) error {
+	var cursor *pagination.Cursor
+	for {
+		query := r.db.WithContext(ctx).
+			Scopes(withDetails).
+			Where("user_id = ?", userID).
+			Order("start_at ASC, id ASC").
+			Limit(iterateBatchSize)
+
+		if filter.From != nil {
+			query = query.Where("start_at >= ?", filter.From)
+		}
+		if filter.To != nil {
+			query = query.Where("start_at <= ?", filter.To)
+		}
+		if cursor != nil {
+			query = query.Where(
+				"(start_at > ?) OR (start_at = ? AND id > ?)",
+				cursor.StartAt, cursor.StartAt, cursor.ID,
+			)
+		}
+
+		var logs []domain.SleepLog
+		if err := query.Find(&logs).Error; err != nil {
+			return err
+		}
+
+		for i := range logs {
+			if err := fn(&logs[i]); err != nil {
+				return err
+			}
+		}
+
+		if len(logs) < iterateBatchSize {
+			return nil
+		}
+		last := logs[len(logs)-1]
+		cursor = &pagination.Cursor{ID: last.ID, StartAt: last.StartAt}
+	}
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TagRepository interface {
+	// EnsureCatalogue inserts the catalogue tags, updating the labels of existing ones.
+	EnsureCatalogue(ctx context.Context, tags []domain.Tag) error
+	Create(ctx context.Context, tag *domain.Tag) error
+	// ListForUser returns the catalogue tags followed by the user's own tags.
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]domain.Tag, error)
+	// GetByNames returns the catalogue and user tags with any of the given names.
+	GetByNames(ctx context.Context, userID uuid.UUID, names []string) ([]domain.Tag, error)
+}
+
+type tagRepository struct {
+	db *gorm.DB
+}
+
+func NewTagRepository(db *gorm.DB) TagRepository {
+	return &tagRepository{db: db}
+}
+
+func (r *tagRepository) EnsureCatalogue(ctx context.Context, tags []domain.Tag) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"label", "category", "unit"}),
+		}).
+		Create(&tags).Error
+}
+
+func (r *tagRepository) Create(ctx context.Context, tag *domain.Tag) error {
+	return r.db.WithContext(ctx).Create(tag).Error
+}
+
+func (r *tagRepository) ListForUser(ctx context.Context, userID uuid.UUID) ([]domain.Tag, error) {
+	var tags []domain.Tag
+	if err := r.db.WithContext(ctx).
+		Where("user_id IS NULL OR user_id = ?", userID).
+		Order("user_id IS NOT NULL, name ASC").
+		Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *tagRepository) GetByNames(ctx context.Context, userID uuid.UUID, names []string) ([]domain.Tag, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	var tags []domain.Tag
+	if err := r.db.WithContext(ctx).
+		Where("user_id IS NULL OR user_id = ?", userID).
+		Where("name IN ?", names).
+		Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
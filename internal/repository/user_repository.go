@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/blaisecz/sleep-tracker/internal/domain"
 	"github.com/google/uuid"
@@ -12,6 +13,11 @@ type UserRepository interface {
 	Create(ctx context.Context, user *domain.User) error
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error)
 	Exists(ctx context.Context, id uuid.UUID) (bool, error)
+	Update(ctx context.Context, user *domain.User) error
+	// ListActive returns up to limit users, oldest accounts first, with a
+	// sleep log ending in [from, to] and matching the timezone and tag of the
+	// filter. The chronotype of the filter is not applied.
+	ListActive(ctx context.Context, filter domain.CohortFilter, from, to time.Time, limit int) ([]domain.User, error)
 }
 
 type userRepository struct {
@@ -43,3 +49,32 @@ func (r *userRepository) Exists(ctx context.Context, id uuid.UUID) (bool, error)
 	err := r.db.WithContext(ctx).Model(&domain.User{}).Where("id = ?", id).Count(&count).Error
 	return count > 0, err
 }
+
+func (r *userRepository) Update(ctx context.Context, user *domain.User) error {
+	return r.db.WithContext(ctx).Save(user).Error
+}
+
+func (r *userRepository) ListActive(ctx context.Context, filter domain.CohortFilter, from, to time.Time, limit int) ([]domain.User, error) {
+	active := r.db.Table("sleep_logs").
+		Select("1").
+		Where("sleep_logs.user_id = users.id").
+		Where("sleep_logs.deleted_at IS NULL").
+		Where("sleep_logs.end_at BETWEEN ? AND ?", from, to)
+	if filter.Tag != "" {
+		active = active.
+			Joins("JOIN sleep_log_factors ON sleep_log_factors.sleep_log_id = sleep_logs.id").
+			Joins("JOIN tags ON tags.id = sleep_log_factors.tag_id").
+			Where("tags.name = ?", filter.Tag)
+	}
+
+	var users []domain.User
+	query := r.db.WithContext(ctx).
+		Where("EXISTS (?)", active).
+		Order("created_at ASC").
+		Limit(limit)
+	if filter.Timezone != "" {
+		query = query.Where("timezone = ?", filter.Timezone)
+	}
+	err := query.Find(&users).Error
+	return users, err
+}
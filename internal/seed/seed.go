@@ -15,7 +15,7 @@ const seededDays = 40
 
 // Run seeds the database with sample users and sleep logs. Safe to call multiple times.
 func Run(db *gorm.DB) error {
-	if err := db.AutoMigrate(&domain.User{}, &domain.SleepLog{}); err != nil {
+	if err := db.AutoMigrate(&domain.User{}, &domain.SleepLog{}, &domain.SleepStage{}, &domain.Tag{}, &domain.SleepLogFactor{}); err != nil {
 		return fmt.Errorf("failed to migrate: %w", err)
 	}
 
@@ -61,7 +61,8 @@ func seedSleepLogsForUser(db *gorm.DB, user domain.User, rng *rand.Rand) error {
 			ClientRequestID: &clientReqID,
 		}
 
-		if err := db.Where("client_request_id = ?", clientReqID).FirstOrCreate(&coreSleep).Error; err != nil {
+		// Unscoped so a seeded log the user deleted is not re-inserted (its client_request_id is still taken)
+		if err := db.Unscoped().Where("client_request_id = ?", clientReqID).FirstOrCreate(&coreSleep).Error; err != nil {
 			return fmt.Errorf("failed to create core sleep log: %w", err)
 		}
 
@@ -80,7 +81,7 @@ func seedSleepLogsForUser(db *gorm.DB, user domain.User, rng *rand.Rand) error {
 				ClientRequestID: &napClientReqID,
 			}
 
-			if err := db.Where("client_request_id = ?", napClientReqID).FirstOrCreate(&napLog).Error; err != nil {
+			if err := db.Unscoped().Where("client_request_id = ?", napClientReqID).FirstOrCreate(&napLog).Error; err != nil {
 				return fmt.Errorf("failed to create nap log: %w", err)
 			}
 		}
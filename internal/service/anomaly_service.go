@@ -0,0 +1,367 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/repository"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// DefaultAnomalyWindowDays is the default window of nights to score.
+	DefaultAnomalyWindowDays = 14
+
+	// AnomalyBaselineDays is the length of the rolling baseline before each night.
+	AnomalyBaselineDays = 28
+
+	// MinAnomalyBaselineNights is the minimum number of baseline nights
+	// needed to score a night.
+	MinAnomalyBaselineNights = 5
+
+	// AnomalyThreshold is the absolute robust z-score from which a measure
+	// is flagged (Iglewicz and Hoaglin).
+	AnomalyThreshold = 3.5
+
+	// Robust z-scores from which anomalies are graded moderate and severe.
+	anomalyModerateZ = 5.0
+	anomalySevereZ   = 7.0
+)
+
+// minAnomalyScales keeps robust z-scores finite when the baseline barely
+// varies, e.g. the same quality every night.
+var minAnomalyScales = map[domain.AnomalyMeasure]float64{
+	domain.AnomalyMeasureDuration: 0.25, // hours
+	domain.AnomalyMeasureBedtime:  15,   // minutes
+	domain.AnomalyMeasureMidSleep: 15,   // minutes
+	domain.AnomalyMeasureQuality:  0.5,
+}
+
+// AnomalyService flags nights that deviate from the user's usual sleep.
+type AnomalyService interface {
+	// Detect scores the nights of the window against the rolling baseline
+	// before each of them and returns the unusual ones.
+	Detect(ctx context.Context, userID uuid.UUID, windowDays int) (*domain.AnomaliesResponse, error)
+}
+
+type anomalyService struct {
+	sleepLogRepo repository.SleepLogRepository
+	userRepo     repository.UserRepository
+}
+
+// NewAnomalyService creates a new AnomalyService.
+func NewAnomalyService(sleepLogRepo repository.SleepLogRepository, userRepo repository.UserRepository) AnomalyService {
+	return &anomalyService{
+		sleepLogRepo: sleepLogRepo,
+		userRepo:     userRepo,
+	}
+}
+
+func (s *anomalyService) Detect(ctx context.Context, userID uuid.UUID, windowDays int) (*domain.AnomaliesResponse, error) {
+	tracer := otel.Tracer("sleep-tracker-api/anomalies")
+	ctx, span := tracer.Start(ctx, "AnomalyService.Detect")
+	defer span.End()
+
+	// Load the user for the workday calendar
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply defaults
+	if windowDays <= 0 {
+		windowDays = DefaultAnomalyWindowDays
+	}
+	span.SetAttributes(
+		attribute.String("user.id", userID.String()),
+		attribute.Int("window_days", windowDays),
+	)
+
+	// Calculate time windows
+	now := time.Now().UTC()
+	from := now.AddDate(0, 0, -windowDays)
+	baselineFrom := from.AddDate(0, 0, -AnomalyBaselineDays)
+
+	logs, err := s.sleepLogRepo.ListByEndRange(ctx, userID, baselineFrom, now)
+	if err != nil {
+		return nil, err
+	}
+
+	anomalies, scored := detectAnomalies(logs, from, user)
+
+	response := &domain.AnomaliesResponse{
+		BaselineDays: AnomalyBaselineDays,
+		Threshold:    AnomalyThreshold,
+		NightsScored: scored,
+		Anomalies:    anomalies,
+	}
+	response.Window.From = from
+	response.Window.To = now
+
+	span.SetAttributes(
+		attribute.Int("anomalies.scored", scored),
+		attribute.Int("anomalies.count", len(anomalies)),
+	)
+
+	return response, nil
+}
+
+// anomalyNight holds the measures of a core sleep.
+type anomalyNight struct {
+	log     domain.SleepLog
+	data    sleepData
+	weekend bool
+}
+
+// detectAnomalies scores the core sleeps ending at or after from against the
+// core sleeps of the AnomalyBaselineDays before each of them. Nights before
+// free days (weekend) and before workdays are compared separately when there
+// are enough nights of the same kind. The baseline is built here rather than
+// with ComputeWindow, which would take one window query per night, cannot
+// split weekend nights and does not report the MAD. Logs are filtered with
+// perSleepData like in computePerSleepMetrics. Returns the flagged nights,
+// most recent first, and the number of scored nights.
+func detectAnomalies(logs []domain.SleepLog, from time.Time, user *domain.User) ([]domain.SleepAnomaly, int) {
+	var nights []anomalyNight
+	for _, log := range logs {
+		if log.Type == domain.SleepTypeNap {
+			continue
+		}
+		data, ok := perSleepData(log)
+		if !ok {
+			continue
+		}
+		nights = append(nights, anomalyNight{
+			log:     log,
+			data:    data,
+			weekend: nightBeforeFreeDay(user, data.nightWeekday),
+		})
+	}
+	sort.Slice(nights, func(i, j int) bool { return nights[i].log.EndAt.Before(nights[j].log.EndAt) })
+
+	anomalies := []domain.SleepAnomaly{}
+	scored := 0
+	for i, night := range nights {
+		if night.log.EndAt.Before(from) {
+			continue
+		}
+
+		// Rolling baseline of the nights before this one
+		baselineFrom := night.log.EndAt.AddDate(0, 0, -AnomalyBaselineDays)
+		var all, same []anomalyNight
+		for _, other := range nights[:i] {
+			if other.log.EndAt.Before(baselineFrom) {
+				continue
+			}
+			all = append(all, other)
+			if other.weekend == night.weekend {
+				same = append(same, other)
+			}
+		}
+
+		baseline, seasonal := same, true
+		if len(same) < MinAnomalyBaselineNights {
+			baseline, seasonal = all, false
+		}
+		if len(baseline) < MinAnomalyBaselineNights {
+			continue
+		}
+		scored++
+
+		if anomaly := scoreNight(night, baseline, seasonal); anomaly != nil {
+			anomalies = append(anomalies, *anomaly)
+		}
+	}
+
+	// Most recent first
+	for i, j := 0, len(anomalies)-1; i < j; i, j = i+1, j-1 {
+		anomalies[i], anomalies[j] = anomalies[j], anomalies[i]
+	}
+	return anomalies, scored
+}
+
+// scoreNight computes the robust z-score of each measure and returns the
+// night if any of them reaches AnomalyThreshold.
+func scoreNight(night anomalyNight, baseline []anomalyNight, seasonal bool) *domain.SleepAnomaly {
+	durations := make([]float64, len(baseline))
+	qualities := make([]float64, len(baseline))
+	bedtimes := make([]float64, len(baseline))
+	midSleeps := make([]float64, len(baseline))
+	for i, b := range baseline {
+		durations[i] = b.data.durationHours
+		qualities[i] = float64(b.data.quality)
+		bedtimes[i] = float64(b.data.bedtimeMinutes)
+		midSleeps[i] = b.data.midSleepMinutes
+	}
+
+	scope := "across all nights"
+	if seasonal && night.weekend {
+		scope = "on weekend nights"
+	} else if seasonal {
+		scope = "on weekday nights"
+	}
+
+	bedtime := float64(night.data.bedtimeMinutes)
+	candidates := []struct {
+		measure  domain.AnomalyMeasure
+		value    float64
+		baseline []float64
+	}{
+		{domain.AnomalyMeasureDuration, night.data.durationHours, durations},
+		{domain.AnomalyMeasureBedtime, bedtime, unwrapAround(bedtimes, bedtime)},
+		{domain.AnomalyMeasureMidSleep, night.data.midSleepMinutes, unwrapAround(midSleeps, night.data.midSleepMinutes)},
+		{domain.AnomalyMeasureQuality, float64(night.data.quality), qualities},
+	}
+
+	var medians domain.AnomalyBaseline
+	var measures []domain.MeasureAnomaly
+	var reasons []string
+	var severity domain.AnomalySeverity
+	for _, c := range candidates {
+		z, med := robustZ(c.value, c.baseline, minAnomalyScales[c.measure])
+		reported := round2(med)
+		switch c.measure {
+		case domain.AnomalyMeasureDuration:
+			medians.DurationHours = reported
+		case domain.AnomalyMeasureBedtime:
+			// The median was unwrapped around the value
+			reported = round2(math.Mod(med+1440, 1440))
+			medians.BedtimeMinutes = reported
+		case domain.AnomalyMeasureMidSleep:
+			reported = round2(math.Mod(med+1440, 1440))
+			medians.MidSleepMinutes = reported
+		case domain.AnomalyMeasureQuality:
+			medians.Quality = reported
+		}
+		if math.Abs(z) < AnomalyThreshold {
+			continue
+		}
+		measure := domain.MeasureAnomaly{
+			Measure:        c.measure,
+			Value:          round2(c.value),
+			BaselineMedian: reported,
+			RobustZ:        round2(z),
+			Severity:       anomalySeverity(z),
+			Reason:         anomalyReason(c.measure, c.value, med, scope),
+		}
+		measures = append(measures, measure)
+		reasons = append(reasons, measure.Reason)
+		if severityRank(measure.Severity) > severityRank(severity) {
+			severity = measure.Severity
+		}
+	}
+	if len(measures) == 0 {
+		return nil
+	}
+
+	return &domain.SleepAnomaly{
+		SleepLogID:      night.log.ID,
+		Date:            night.data.localDate,
+		StartAt:         night.log.StartAt,
+		EndAt:           night.log.EndAt,
+		Weekend:         night.weekend,
+		BaselineNights:  len(baseline),
+		BaselineMedians: medians,
+		Seasonal:        seasonal,
+		Severity:        severity,
+		Reason:          strings.Join(reasons, "; "),
+		Measures:        measures,
+	}
+}
+
+// robustZ returns the robust z-score of x against the values, using the
+// median and the median absolute deviation scaled to a standard deviation
+// (at least minScale), and the median itself.
+func robustZ(x float64, values []float64, minScale float64) (float64, float64) {
+	med := medianFloat(values)
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - med)
+	}
+	scale := math.Max(1.4826*medianFloat(deviations), minScale)
+	return (x - med) / scale, med
+}
+
+// medianFloat calculates the median of a slice of floats.
+func medianFloat(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := sortedCopy(values)
+	n := len(sorted)
+	if n%2 == 0 {
+		return (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	return sorted[n/2]
+}
+
+func anomalySeverity(z float64) domain.AnomalySeverity {
+	switch abs := math.Abs(z); {
+	case abs >= anomalySevereZ:
+		return domain.AnomalySeveritySevere
+	case abs >= anomalyModerateZ:
+		return domain.AnomalySeverityModerate
+	default:
+		return domain.AnomalySeverityMild
+	}
+}
+
+func severityRank(severity domain.AnomalySeverity) int {
+	switch severity {
+	case domain.AnomalySeverityMild:
+		return 1
+	case domain.AnomalySeverityModerate:
+		return 2
+	case domain.AnomalySeveritySevere:
+		return 3
+	}
+	return 0
+}
+
+// anomalyReason describes the deviation of a measure in plain words. Clock
+// time medians must be unwrapped around the value.
+func anomalyReason(measure domain.AnomalyMeasure, value, med float64, scope string) string {
+	diff := value - med
+	switch measure {
+	case domain.AnomalyMeasureDuration:
+		return fmt.Sprintf("Slept %.1f h, %.1f h %s than the usual %.1f h %s",
+			value, math.Abs(diff), direction(diff, "more", "less"), med, scope)
+	case domain.AnomalyMeasureBedtime:
+		return fmt.Sprintf("Went to bed at %s, %s %s than the usual %s %s",
+			minutesToTimeString(int(value)), formatMinutes(diff), direction(diff, "later", "earlier"), minutesToTimeString(int(med)), scope)
+	case domain.AnomalyMeasureMidSleep:
+		return fmt.Sprintf("Mid-sleep at %s, %s %s than the usual %s %s",
+			minutesToTimeString(int(value)), formatMinutes(diff), direction(diff, "later", "earlier"), minutesToTimeString(int(med)), scope)
+	default:
+		return fmt.Sprintf("Quality %.0f, %.1f points %s than the usual %.1f %s",
+			value, math.Abs(diff), direction(diff, "higher", "lower"), med, scope)
+	}
+}
+
+func direction(diff float64, positive, negative string) string {
+	if diff < 0 {
+		return negative
+	}
+	return positive
+}
+
+// formatMinutes formats the magnitude of a time difference, e.g. "2 h 15 min".
+func formatMinutes(diff float64) string {
+	minutes := int(math.Round(math.Abs(diff)))
+	h, m := minutes/60, minutes%60
+	switch {
+	case h == 0:
+		return fmt.Sprintf("%d min", m)
+	case m == 0:
+		return fmt.Sprintf("%d h", h)
+	default:
+		return fmt.Sprintf("%d h %d min", h, m)
+	}
+}
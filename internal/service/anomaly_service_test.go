@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+// anomalyTestLog returns a core sleep starting at the given local clock time
+// on the day.
+func anomalyTestLog(day time.Time, bedtime time.Duration, hours float64, quality int) domain.SleepLog {
+	start := day.Add(bedtime)
+	return domain.SleepLog{
+		ID:      uuid.New(),
+		StartAt: start,
+		EndAt:   start.Add(time.Duration(hours * float64(time.Hour))),
+		Quality: quality,
+		Type:    domain.SleepTypeCore,
+	}
+}
+
+func TestDetectAnomalies(t *testing.T) {
+	// Monday
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var logs []domain.SleepLog
+	for i := 0; i < 28; i++ {
+		day := first.AddDate(0, 0, i)
+		if day.Weekday() == time.Friday || day.Weekday() == time.Saturday {
+			// Late weekend nights
+			logs = append(logs, anomalyTestLog(day, 25*time.Hour+time.Duration(i)*time.Minute, 8.5, 8))
+		} else {
+			logs = append(logs, anomalyTestLog(day, 23*time.Hour+time.Duration(i%3)*10*time.Minute, 7.5+float64(i%2)*0.25, 7))
+		}
+	}
+	from := first.AddDate(0, 0, 28)
+
+	// Monday with a very short night and a bad rating
+	short := anomalyTestLog(from, 23*time.Hour, 4, 3)
+	// Saturday night as late as the other weekends
+	usualWeekend := anomalyTestLog(from.AddDate(0, 0, 5), 25*time.Hour+30*time.Minute, 8.5, 8)
+	// Tuesday night with a weekend-like schedule
+	late := anomalyTestLog(from.AddDate(0, 0, 8), 25*time.Hour+30*time.Minute, 7.5, 7)
+	// Naps are never scored
+	nap := anomalyTestLog(from.AddDate(0, 0, 9), 14*time.Hour, 2, 1)
+	nap.Type = domain.SleepTypeNap
+	logs = append(logs, short, usualWeekend, late, nap)
+
+	// Score from the morning after the last baseline night
+	anomalies, scored := detectAnomalies(logs, from.Add(12*time.Hour), &domain.User{})
+	if scored != 3 {
+		t.Errorf("scored = %d, want 3", scored)
+	}
+	if len(anomalies) != 2 {
+		t.Fatalf("got %d anomalies, want 2: %+v", len(anomalies), anomalies)
+	}
+
+	// Most recent first
+	if anomalies[0].SleepLogID != late.ID || anomalies[1].SleepLogID != short.ID {
+		t.Fatalf("unexpected anomalies order: %+v", anomalies)
+	}
+
+	got := anomalies[0]
+	if got.Weekend || !got.Seasonal {
+		t.Errorf("late night should be compared with weekday nights: %+v", got)
+	}
+	measures := map[domain.AnomalyMeasure]domain.MeasureAnomaly{}
+	for _, m := range got.Measures {
+		measures[m.Measure] = m
+	}
+	bedtime, ok := measures[domain.AnomalyMeasureBedtime]
+	if !ok || bedtime.RobustZ <= 0 {
+		t.Fatalf("expected a late bedtime, got %+v", got.Measures)
+	}
+	if bedtime.Value != 90 || bedtime.BaselineMedian != 1390 {
+		t.Errorf("bedtime value/median = %v/%v, want 90/1390", bedtime.Value, bedtime.BaselineMedian)
+	}
+	if !strings.Contains(bedtime.Reason, "01:30") || !strings.Contains(bedtime.Reason, "later than the usual 23:10 on weekday nights") {
+		t.Errorf("unexpected reason %q", bedtime.Reason)
+	}
+	if _, ok := measures[domain.AnomalyMeasureDuration]; ok {
+		t.Errorf("duration was usual: %+v", got.Measures)
+	}
+	if got.BaselineMedians.BedtimeMinutes != bedtime.BaselineMedian {
+		t.Errorf("baseline bedtime median = %v, want the scored %v", got.BaselineMedians.BedtimeMinutes, bedtime.BaselineMedian)
+	}
+
+	got = anomalies[1]
+	if got.Severity != domain.AnomalySeveritySevere {
+		t.Errorf("Severity = %s, want SEVERE", got.Severity)
+	}
+	for _, m := range got.Measures {
+		if m.Measure == domain.AnomalyMeasureDuration && m.RobustZ >= 0 {
+			t.Errorf("short night should have a negative duration score: %+v", m)
+		}
+	}
+}
+
+func TestDetectAnomalies_NotEnoughBaseline(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	logs := []domain.SleepLog{
+		anomalyTestLog(day, 23*time.Hour, 8, 7),
+		anomalyTestLog(day.AddDate(0, 0, 1), 23*time.Hour, 8, 7),
+		anomalyTestLog(day.AddDate(0, 0, 2), 23*time.Hour, 3, 2),
+	}
+
+	anomalies, scored := detectAnomalies(logs, day.AddDate(0, 0, 2), &domain.User{})
+	if scored != 0 || len(anomalies) != 0 {
+		t.Errorf("expected nothing scored with %d baseline nights, got %d scored, %d anomalies", 2, scored, len(anomalies))
+	}
+}
+
+func TestRobustZ(t *testing.T) {
+	tests := []struct {
+		name       string
+		x          float64
+		values     []float64
+		minScale   float64
+		wantZ      float64
+		wantMedian float64
+	}{
+		{"median and MAD", 10, []float64{1, 2, 3, 4, 100}, 0, 7 / 1.4826, 3},
+		{"outlier does not move the median", 3, []float64{1, 2, 3, 4, 100}, 0, 0, 3},
+		{"minimum scale without spread", 5, []float64{7, 7, 7, 7, 7}, 0.5, -4, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z, med := robustZ(tt.x, tt.values, tt.minScale)
+			if round2(z) != round2(tt.wantZ) || med != tt.wantMedian {
+				t.Errorf("robustZ = %v, %v; want %v, %v", z, med, tt.wantZ, tt.wantMedian)
+			}
+		})
+	}
+}
+
+func TestUnwrapAround(t *testing.T) {
+	got := unwrapAround([]float64{1410, 30, 600}, 60)
+	want := []float64{-30, 30, 600}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("unwrapAround[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestAnomalyService_Detect(t *testing.T) {
+	repo := NewMockSleepLogRepository()
+	userRepo := NewMockUserRepository()
+	svc := NewAnomalyService(repo, userRepo)
+
+	userID := uuid.New()
+	userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for i := 30; i >= 1; i-- {
+		log := anomalyTestLog(today.AddDate(0, 0, -i), 23*time.Hour, 7.5, 7)
+		log.UserID = userID
+		if i == 2 {
+			log.EndAt = log.StartAt.Add(3 * time.Hour)
+		}
+		repo.logs[log.ID] = &log
+	}
+
+	result, err := svc.Detect(context.Background(), userID, 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.BaselineDays != AnomalyBaselineDays || result.Threshold != AnomalyThreshold {
+		t.Errorf("unexpected parameters: %+v", result)
+	}
+	if len(result.Anomalies) != 1 || result.Anomalies[0].Measures[0].Measure != domain.AnomalyMeasureDuration {
+		t.Fatalf("expected the short night to be flagged, got %+v", result.Anomalies)
+	}
+	if got := result.Anomalies[0].BaselineMedians; got.DurationHours != 7.5 || got.BedtimeMinutes != 1380 || got.Quality != 7 {
+		t.Errorf("baseline medians = %+v, want the usual 7.5 h from 23:00 at quality 7", got)
+	}
+
+	_, err = svc.Detect(context.Background(), uuid.New(), 7)
+	if !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"sort"
+	"math"
 	"time"
 
 	"github.com/blaisecz/sleep-tracker/internal/domain"
@@ -51,14 +51,11 @@ func (s *chronotypeService) Compute(ctx context.Context, userID uuid.UUID, windo
 	ctx, span := tracer.Start(ctx, "ChronotypeService.Compute")
 	defer span.End()
 
-	// Validate user exists
-	exists, err := s.userRepo.Exists(ctx, userID)
+	// Load the user for the workday calendar
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
-	if !exists {
-		return nil, domain.ErrNotFound
-	}
 
 	// Apply defaults
 	if windowDays <= 0 {
@@ -98,7 +95,8 @@ func (s *chronotypeService) Compute(ctx context.Context, userID uuid.UUID, windo
 	}
 
 	// Calculate mid-sleep minutes for each valid log
-	var midMinutes []int
+	var midMinutes []float64
+	var nights []chronotypeNight
 	for _, log := range logs {
 		// Convert to local timezone
 		loc := time.UTC
@@ -120,7 +118,12 @@ func (s *chronotypeService) Compute(ctx context.Context, userID uuid.UUID, windo
 		// Calculate mid-sleep time
 		midSleep := startLocal.Add(time.Duration(durationMinutes/2) * time.Minute)
 		midMin := midSleepMinutesAfterMidnight(midSleep)
-		midMinutes = append(midMinutes, midMin)
+		midMinutes = append(midMinutes, float64(midMin))
+		nights = append(nights, chronotypeNight{
+			midSleepMinutes: midMin,
+			durationHours:   durationMinutes / 60,
+			freeDay:         nightBeforeFreeDay(user, nightWeekday(startLocal)),
+		})
 	}
 
 	// Build result
@@ -137,13 +140,22 @@ func (s *chronotypeService) Compute(ctx context.Context, userID uuid.UUID, windo
 		return result, nil
 	}
 
-	// Compute median of mid-sleep minutes
-	medianMid := median(midMinutes)
+	// Compute median of mid-sleep minutes around their circular mean, so
+	// nights on either side of midnight stay close
+	medianMid := roundClockMinutes(circularMedian(midMinutes))
 	result.MidSleepMinutesAfterMidnight = medianMid
 	result.MidSleepLocalTime = minutesToTimeString(medianMid)
 
-	// Classify chronotype
-	result.Chronotype = classifyChronotype(medianMid)
+	// Compare workdays with free days
+	result.SocialJetlag = computeSocialJetlag(nights, user.WorkdayCodes())
+
+	// Classify chronotype from MSFsc, which is not biased by workday alarms,
+	// and fall back to the median over all nights
+	if result.SocialJetlag != nil {
+		result.Chronotype = result.SocialJetlag.Chronotype
+	} else {
+		result.Chronotype = classifyChronotype(medianMid)
+	}
 
 	// Attach output payload for Langfuse
 	if outputJSON, err := json.Marshal(result); err == nil {
@@ -153,6 +165,98 @@ func (s *chronotypeService) Compute(ctx context.Context, userID uuid.UUID, windo
 	return result, nil
 }
 
+// MinSocialJetlagNights is the minimum number of nights before workdays and
+// before free days needed for the social jetlag calculation.
+const MinSocialJetlagNights = 2
+
+// chronotypeNight holds the timing of a sleep used for the chronotype.
+type chronotypeNight struct {
+	midSleepMinutes int
+	durationHours   float64
+	freeDay         bool
+}
+
+// nightBeforeFreeDay reports whether the night of the weekday (see
+// nightWeekday) precedes a free day in the user's calendar.
+func nightBeforeFreeDay(user *domain.User, night time.Weekday) bool {
+	return !user.IsWorkday((night + 1) % 7)
+}
+
+// computeSocialJetlag applies the MCTQ to the nights: MSW and MSF are the
+// median mid-sleep before workdays and before free days, and MSFsc corrects
+// MSF for oversleeping on free days,
+//
+//	MSFsc = MSF - (SDf - SDweek) / 2, with SDweek = (SDw*WD + SDf*FD) / 7,
+//
+// when free-day sleep (SDf) is longer than workday sleep (SDw). Social
+// jetlag is |MSF - MSW|. Returns nil without both kinds of days in the
+// calendar or with fewer than MinSocialJetlagNights of either kind.
+func computeSocialJetlag(nights []chronotypeNight, workdays []string) *domain.SocialJetlag {
+	if len(workdays) == 0 || len(workdays) == 7 {
+		return nil
+	}
+
+	var workMid, freeMid []float64
+	var workHours, freeHours float64
+	for _, night := range nights {
+		mid := float64(night.midSleepMinutes)
+		if night.freeDay {
+			freeMid = append(freeMid, mid)
+			freeHours += night.durationHours
+		} else {
+			workMid = append(workMid, mid)
+			workHours += night.durationHours
+		}
+	}
+	if len(workMid) < MinSocialJetlagNights || len(freeMid) < MinSocialJetlagNights {
+		return nil
+	}
+
+	msw := roundClockMinutes(circularMedian(workMid))
+	msf := roundClockMinutes(circularMedian(freeMid))
+	sdw := workHours / float64(len(workMid))
+	sdf := freeHours / float64(len(freeMid))
+
+	msfsc := float64(msf)
+	if sdf > sdw {
+		wd := float64(len(workdays))
+		sdWeek := (sdw*wd + sdf*(7-wd)) / 7
+		msfsc -= (sdf - sdWeek) * 60 / 2
+	}
+	msfscMinutes := int(math.Round(msfsc))
+
+	return &domain.SocialJetlag{
+		Workdays:                            workdays,
+		WorkdayNights:                       len(workMid),
+		FreeDayNights:                       len(freeMid),
+		WorkdayMidSleepLocalTime:            minutesToTimeString(msw),
+		WorkdayMidSleepMinutesAfterMidnight: msw,
+		FreeDayMidSleepLocalTime:            minutesToTimeString(msf),
+		FreeDayMidSleepMinutesAfterMidnight: msf,
+		WorkdaySleepHours:                   round2(sdw),
+		FreeDaySleepHours:                   round2(sdf),
+		MSFscLocalTime:                      minutesToTimeString(msfscMinutes),
+		MSFscMinutesAfterMidnight:           clockMinutes(msfscMinutes),
+		SocialJetlagMinutes:                 int(math.Abs(float64(signedClockMinutes(msf - msw)))),
+		Chronotype:                          classifyChronotype(msfscMinutes),
+	}
+}
+
+// signedClockMinutes maps minutes after midnight to (-720, 720], so that
+// 23:30 is -30.
+func signedClockMinutes(minutes int) int {
+	m := clockMinutes(minutes)
+	if m > 720 {
+		m -= 1440
+	}
+	return m
+}
+
+// clockMinutes maps minutes to [0, 1440).
+func clockMinutes(minutes int) int {
+	return ((minutes % 1440) + 1440) % 1440
+}
+
 // midSleepMinutesAfterMidnight calculates minutes after midnight for a given time.
 // Handles times that span midnight (e.g., 11 PM to 7 AM).
 func midSleepMinutesAfterMidnight(t time.Time) int {
@@ -161,21 +265,9 @@ func midSleepMinutesAfterMidnight(t time.Time) int {
 	return hour*60 + minute
 }
 
-// median calculates the median of a slice of integers.
-func median(values []int) int {
-	if len(values) == 0 {
-		return 0
-	}
-
-	sorted := make([]int, len(values))
-	copy(sorted, values)
-	sort.Ints(sorted)
-
-	n := len(sorted)
-	if n%2 == 0 {
-		return (sorted[n/2-1] + sorted[n/2]) / 2
-	}
-	return sorted[n/2]
+// roundClockMinutes rounds clock minutes to whole minutes in [0, 1440).
+func roundClockMinutes(minutes float64) int {
+	return clockMinutes(int(math.Round(minutes)))
 }
 
 // minutesToTimeString converts minutes after midnight to HH:MM format.
@@ -187,8 +279,14 @@ func minutesToTimeString(minutes int) string {
 	return fmt.Sprintf("%02d:%02d", h, m)
 }
 
-// classifyChronotype determines chronotype based on mid-sleep minutes.
+// classifyChronotype determines chronotype based on mid-sleep minutes. Mid-
+// sleep from 18:00 to midnight counts as before midnight, so 23:40 is an
+// early bird rather than a night owl.
 func classifyChronotype(midMinutes int) domain.ChronotypeType {
+	midMinutes = clockMinutes(midMinutes)
+	if midMinutes >= 18*60 {
+		midMinutes -= 1440
+	}
 	if midMinutes < EarlyBirdThreshold {
 		return domain.ChronotypeEarlyBird
 	}
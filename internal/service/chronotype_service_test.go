@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+func TestComputeSocialJetlag(t *testing.T) {
+	work := chronotypeNight{midSleepMinutes: 150, durationHours: 7}
+	free := chronotypeNight{midSleepMinutes: 330, durationHours: 9, freeDay: true}
+
+	tests := []struct {
+		name     string
+		nights   []chronotypeNight
+		workdays []string
+		want     *domain.SocialJetlag
+	}{
+		{
+			name:     "oversleeping on free days",
+			nights:   []chronotypeNight{work, work, work, free, free},
+			workdays: domain.DefaultWorkdays,
+			want: &domain.SocialJetlag{
+				WorkdayNights:                       3,
+				FreeDayNights:                       2,
+				WorkdayMidSleepLocalTime:            "02:30",
+				WorkdayMidSleepMinutesAfterMidnight: 150,
+				FreeDayMidSleepLocalTime:            "05:30",
+				FreeDayMidSleepMinutesAfterMidnight: 330,
+				WorkdaySleepHours:                   7,
+				FreeDaySleepHours:                   9,
+				// SDweek = (7*5 + 9*2) / 7; MSFsc = 330 - (9 - SDweek) * 60 / 2
+				MSFscLocalTime:            "04:47",
+				MSFscMinutesAfterMidnight: 287,
+				SocialJetlagMinutes:       180,
+				Chronotype:                domain.ChronotypeNightOwl,
+			},
+		},
+		{
+			name: "mid-sleep around midnight",
+			nights: []chronotypeNight{
+				{midSleepMinutes: 1410, durationHours: 8},
+				{midSleepMinutes: 1430, durationHours: 8},
+				{midSleepMinutes: 20, durationHours: 7, freeDay: true},
+				{midSleepMinutes: 40, durationHours: 7, freeDay: true},
+			},
+			workdays: domain.DefaultWorkdays,
+			want: &domain.SocialJetlag{
+				WorkdayNights:                       2,
+				FreeDayNights:                       2,
+				WorkdayMidSleepLocalTime:            "23:40",
+				WorkdayMidSleepMinutesAfterMidnight: 1420,
+				FreeDayMidSleepLocalTime:            "00:30",
+				FreeDayMidSleepMinutesAfterMidnight: 30,
+				WorkdaySleepHours:                   8,
+				FreeDaySleepHours:                   7,
+				// No correction without oversleeping
+				MSFscLocalTime:            "00:30",
+				MSFscMinutesAfterMidnight: 30,
+				SocialJetlagMinutes:       50,
+				Chronotype:                domain.ChronotypeEarlyBird,
+			},
+		},
+		{
+			name:     "too few free-day nights",
+			nights:   []chronotypeNight{work, work, free},
+			workdays: domain.DefaultWorkdays,
+		},
+		{
+			name:     "no free days in the calendar",
+			nights:   []chronotypeNight{work, work, free, free},
+			workdays: []string{"MON", "TUE", "WED", "THU", "FRI", "SAT", "SUN"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computeSocialJetlag(tt.nights, tt.workdays)
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("expected nil, got %+v", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("expected a result, got nil")
+			}
+			got.Workdays = nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestChronotypeService_Compute_SocialJetlag(t *testing.T) {
+	repo := NewMockSleepLogRepository()
+	userRepo := NewMockUserRepository()
+	svc := NewChronotypeService(repo, userRepo)
+
+	userID := uuid.New()
+	user := &domain.User{ID: userID, Timezone: "UTC"}
+	// Works Saturday to Wednesday, so Wednesday and Thursday nights are free
+	user.SetWorkdays([]string{"SAT", "SUN", "MON", "TUE", "WED"})
+	userRepo.users[userID] = user
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for i := 1; i <= 14; i++ {
+		evening := today.AddDate(0, 0, -i)
+		start := evening.Add(23 * time.Hour)
+		if wake := evening.AddDate(0, 0, 1).Weekday(); wake == time.Thursday || wake == time.Friday {
+			start = evening.Add(25 * time.Hour)
+		}
+		if start.Add(8 * time.Hour).After(time.Now()) {
+			continue
+		}
+		log := &domain.SleepLog{ID: uuid.New(), UserID: userID, StartAt: start, EndAt: start.Add(8 * time.Hour), Quality: 7}
+		repo.logs[log.ID] = log
+	}
+
+	result, err := svc.Compute(context.Background(), userID, 30, 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	jetlag := result.SocialJetlag
+	if jetlag == nil {
+		t.Fatal("expected social jetlag")
+	}
+	if jetlag.WorkdayMidSleepLocalTime != "03:00" || jetlag.FreeDayMidSleepLocalTime != "05:00" {
+		t.Errorf("mid-sleep = %s/%s, want 03:00/05:00", jetlag.WorkdayMidSleepLocalTime, jetlag.FreeDayMidSleepLocalTime)
+	}
+	if jetlag.SocialJetlagMinutes != 120 {
+		t.Errorf("SocialJetlagMinutes = %d, want 120", jetlag.SocialJetlagMinutes)
+	}
+	// The 03:00 median mid-sleep is intermediate, but MSFsc at 05:00 decides
+	if result.MidSleepLocalTime != "03:00" || result.Chronotype != domain.ChronotypeNightOwl {
+		t.Errorf("chronotype = %s with median %s, want night_owl from MSFsc", result.Chronotype, result.MidSleepLocalTime)
+	}
+}
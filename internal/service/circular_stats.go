@@ -0,0 +1,110 @@
+package service
+
+import (
+	"math"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+)
+
+// minutesPerDay is the period of clock times.
+const minutesPerDay = 1440.0
+
+// circularMean returns the mean direction of clock times in minutes after
+// midnight, in [0, 1440), and the mean resultant length R in [0, 1]. R is 1
+// when all times are equal and close to 0 when they spread around the clock.
+func circularMean(minutes []float64) (float64, float64) {
+	if len(minutes) == 0 {
+		return 0, 0
+	}
+
+	var sinSum, cosSum float64
+	for _, m := range minutes {
+		angle := m / minutesPerDay * 2 * math.Pi
+		sinSum += math.Sin(angle)
+		cosSum += math.Cos(angle)
+	}
+
+	n := float64(len(minutes))
+	r := math.Min(math.Hypot(sinSum, cosSum)/n, 1)
+	mean := math.Atan2(sinSum, cosSum) / (2 * math.Pi) * minutesPerDay
+	return clockValue(mean), r
+}
+
+// circularStd converts a mean resultant length to the circular standard
+// deviation sqrt(-2 ln R) in minutes, capped at 12 hours.
+func circularStd(r float64) float64 {
+	if r >= 1 {
+		return 0
+	}
+	if r <= 0 {
+		return minutesPerDay / 2
+	}
+	return math.Min(math.Sqrt(-2*math.Log(r))*minutesPerDay/(2*math.Pi), minutesPerDay/2)
+}
+
+// circularMedian returns the median of clock times, taken around their
+// circular mean.
+func circularMedian(minutes []float64) float64 {
+	mean, _ := circularMean(minutes)
+	return clockValue(medianFloat(unwrapAround(minutes, mean)))
+}
+
+// computeClockStats calculates descriptive statistics for clock times in
+// minutes after midnight, using circular statistics so that times on either
+// side of midnight stay close.
+func computeClockStats(minutes []float64) domain.DescriptiveStats {
+	if len(minutes) == 0 {
+		return domain.DescriptiveStats{}
+	}
+
+	mean, r := circularMean(minutes)
+	stats := computeStats(unwrapAround(minutes, mean))
+	stats.Avg = clockValue(round2(mean))
+	stats.Std = round2(circularStd(r))
+	stats.Min = clockValue(stats.Min)
+	stats.Max = clockValue(stats.Max)
+	stats.P10 = clockValue(stats.P10)
+	stats.P25 = clockValue(stats.P25)
+	stats.Median = clockValue(stats.Median)
+	stats.P75 = clockValue(stats.P75)
+	stats.P90 = clockValue(stats.P90)
+	resultant := round2(r)
+	stats.ResultantLength = &resultant
+	return stats
+}
+
+// unwrapAround shifts clock times (minutes after midnight) by whole days so
+// that each lies within 12 hours of ref, which keeps times on either side of
+// midnight comparable.
+func unwrapAround(values []float64, ref float64) []float64 {
+	unwrapped := make([]float64, len(values))
+	for i, v := range values {
+		diff := math.Mod(v-ref+720, 1440)
+		if diff < 0 {
+			diff += 1440
+		}
+		unwrapped[i] = ref + diff - 720
+	}
+	return unwrapped
+}
+
+// clockValue maps minutes to [0, 1440).
+func clockValue(minutes float64) float64 {
+	v := math.Mod(minutes, minutesPerDay)
+	if v < 0 {
+		v += minutesPerDay
+	}
+	return v
+}
+
+// percentile returns the p-th percentile (0-100) of sorted values, linearly
+// interpolated between the closest ranks.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (rank-float64(lower))*(sorted[upper]-sorted[lower])
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+func TestComputeClockStats(t *testing.T) {
+	tests := []struct {
+		name       string
+		minutes    []float64
+		wantAvg    float64
+		wantStd    float64
+		wantMin    float64
+		wantMax    float64
+		wantMedian float64
+	}{
+		{
+			name:       "across midnight",
+			minutes:    []float64{1430, 10},
+			wantAvg:    0,
+			wantStd:    10,
+			wantMin:    1430,
+			wantMax:    10,
+			wantMedian: 0,
+		},
+		{
+			name:       "same time",
+			minutes:    []float64{1380, 1380, 1380},
+			wantAvg:    1380,
+			wantStd:    0,
+			wantMin:    1380,
+			wantMax:    1380,
+			wantMedian: 1380,
+		},
+		{
+			name:       "before midnight",
+			minutes:    []float64{1320, 1350, 1380},
+			wantAvg:    1350,
+			wantStd:    24.5,
+			wantMin:    1320,
+			wantMax:    1380,
+			wantMedian: 1350,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computeClockStats(tt.minutes)
+
+			if math.Abs(got.Avg-tt.wantAvg) > 0.01 {
+				t.Errorf("Avg = %v, want %v", got.Avg, tt.wantAvg)
+			}
+			if math.Abs(got.Std-tt.wantStd) > 0.5 {
+				t.Errorf("Std = %v, want about %v", got.Std, tt.wantStd)
+			}
+			if got.Min != tt.wantMin || got.Max != tt.wantMax {
+				t.Errorf("Min/Max = %v/%v, want %v/%v", got.Min, got.Max, tt.wantMin, tt.wantMax)
+			}
+			if math.Abs(got.Median-tt.wantMedian) > 0.01 {
+				t.Errorf("Median = %v, want %v", got.Median, tt.wantMedian)
+			}
+			if got.ResultantLength == nil || *got.ResultantLength <= 0.9 {
+				t.Errorf("ResultantLength = %v, want close to 1", got.ResultantLength)
+			}
+		})
+	}
+}
+
+func TestComputeStats_Percentiles(t *testing.T) {
+	got := computeStats([]float64{5, 3, 1, 4, 2})
+
+	want := [5]float64{1.4, 2, 3, 4, 4.6}
+	if [5]float64{got.P10, got.P25, got.Median, got.P75, got.P90} != want {
+		t.Errorf("percentiles = %v %v %v %v %v, want %v", got.P10, got.P25, got.Median, got.P75, got.P90, want)
+	}
+	if got.ResultantLength != nil {
+		t.Errorf("ResultantLength = %v, want nil for linear stats", *got.ResultantLength)
+	}
+}
+
+func TestComputeDerivedScores_BedtimesAcrossMidnight(t *testing.T) {
+	base := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	var logs []domain.SleepLog
+	// Bedtimes alternate between 23:50 and 00:10
+	for i := 0; i < 6; i++ {
+		start := base.AddDate(0, 0, i).Add(-10 * time.Minute)
+		if i%2 == 1 {
+			start = start.Add(20 * time.Minute)
+		}
+		logs = append(logs, domain.SleepLog{
+			ID:      uuid.New(),
+			StartAt: start,
+			EndAt:   start.Add(8 * time.Hour),
+			Quality: 7,
+			Type:    domain.SleepTypeCore,
+		})
+	}
+
+	perSleep := computePerSleepMetrics(logs)
+	scores := DefaultScoringModel().Score(perSleep, computeDailyOverallMetrics(logs, nil, base.AddDate(0, 0, 7)), nil)
+
+	if perSleep.Bedtime.Std > 15 {
+		t.Errorf("bedtime std = %v, want at most 15 minutes", perSleep.Bedtime.Std)
+	}
+	if scores.ConsistencyScore < 85 {
+		t.Errorf("ConsistencyScore = %v, want at least 85", scores.ConsistencyScore)
+	}
+	if perSleep.MidSleep.Avg != 240 {
+		t.Errorf("mid-sleep avg = %v, want 240", perSleep.MidSleep.Avg)
+	}
+}
+
+func TestClassifyChronotype(t *testing.T) {
+	tests := []struct {
+		minutes int
+		want    domain.ChronotypeType
+	}{
+		{1420, domain.ChronotypeEarlyBird},
+		{60, domain.ChronotypeEarlyBird},
+		{200, domain.ChronotypeIntermediate},
+		{300, domain.ChronotypeNightOwl},
+		{720, domain.ChronotypeNightOwl},
+	}
+
+	for _, tt := range tests {
+		if got := classifyChronotype(tt.minutes); got != tt.want {
+			t.Errorf("classifyChronotype(%d) = %v, want %v", tt.minutes, got, tt.want)
+		}
+	}
+}
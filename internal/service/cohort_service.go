@@ -0,0 +1,440 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/repository"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// DefaultCohortWindowDays is the default window for cohort analytics.
+	DefaultCohortWindowDays = 30
+
+	// DefaultMinCohortSize is the default smallest cohort that is reported.
+	DefaultMinCohortSize = 10
+
+	// DefaultMaxCohortUsers is the default number of users a cohort is
+	// built from.
+	DefaultMaxCohortUsers = 1000
+
+	// DefaultCohortSnapshotTTL is the default time a built cohort is reused.
+	DefaultCohortSnapshotTTL = time.Hour
+
+	// maxCohortSnapshots bounds the number of filters kept in memory.
+	maxCohortSnapshots = 256
+)
+
+// CohortService computes population baselines over groups of users.
+type CohortService interface {
+	// Metrics returns distributions of per-user metrics over the users
+	// matching the filter that have sleep in the window.
+	Metrics(ctx context.Context, filter domain.CohortFilter, windowDays int) (*domain.CohortMetricsResponse, error)
+	// Ranks returns the percentile ranks of a user's metrics within the
+	// cohort matching the filter.
+	Ranks(ctx context.Context, userID uuid.UUID, filter domain.CohortFilter, windowDays int) (*domain.CohortRanksResponse, error)
+}
+
+type cohortService struct {
+	userRepo          repository.UserRepository
+	metricsService    MetricsService
+	chronotypeService ChronotypeService
+	minCohortSize     int
+	maxUsers          int
+	snapshotTTL       time.Duration
+	now               func() time.Time
+
+	mu        sync.Mutex
+	snapshots map[cohortKey]*cohortSnapshot
+}
+
+// NewCohortService creates a new CohortService. Cohorts with fewer than
+// minCohortSize users are not reported, so that no individual can be picked
+// out of the numbers. A cohort is built from at most maxUsers users and
+// reused for snapshotTTL, so requests do not recompute the metrics of every
+// user.
+func NewCohortService(userRepo repository.UserRepository, metricsService MetricsService, chronotypeService ChronotypeService, minCohortSize, maxUsers int, snapshotTTL time.Duration) CohortService {
+	if minCohortSize < 1 {
+		minCohortSize = DefaultMinCohortSize
+	}
+	if maxUsers < 1 {
+		maxUsers = DefaultMaxCohortUsers
+	}
+	if snapshotTTL <= 0 {
+		snapshotTTL = DefaultCohortSnapshotTTL
+	}
+	return &cohortService{
+		userRepo:          userRepo,
+		metricsService:    metricsService,
+		chronotypeService: chronotypeService,
+		minCohortSize:     minCohortSize,
+		maxUsers:          maxUsers,
+		snapshotTTL:       snapshotTTL,
+		now:               time.Now,
+		snapshots:         make(map[cohortKey]*cohortSnapshot),
+	}
+}
+
+// cohortKey identifies a cohort snapshot.
+type cohortKey struct {
+	filter     domain.CohortFilter
+	windowDays int
+}
+
+// cohortSnapshot holds the sorted values of every cohort measure, in the
+// order of cohortMeasures. ready is closed once the snapshot is built.
+type cohortSnapshot struct {
+	ready    chan struct{}
+	err      error
+	from, to time.Time
+	size     int
+	values   [][]float64
+}
+
+// cohortMeasure is a per-user value compared across a cohort.
+type cohortMeasure struct {
+	value func(m *domain.WindowMetrics) *float64
+	stats func(c *domain.CohortMetrics) **domain.CohortDistribution
+	rank  func(r *domain.CohortRanks) **float64
+}
+
+var cohortMeasures = []cohortMeasure{
+	{
+		value: func(m *domain.WindowMetrics) *float64 { return perSleepValue(m, m.PerSleep.Duration.Avg) },
+		stats: func(c *domain.CohortMetrics) **domain.CohortDistribution { return &c.AvgDurationHours },
+		rank:  func(r *domain.CohortRanks) **float64 { return &r.AvgDurationHours },
+	},
+	{
+		value: func(m *domain.WindowMetrics) *float64 { return perSleepValue(m, m.PerSleep.Quality.Avg) },
+		stats: func(c *domain.CohortMetrics) **domain.CohortDistribution { return &c.AvgQuality },
+		rank:  func(r *domain.CohortRanks) **float64 { return &r.AvgQuality },
+	},
+	{
+		value: func(m *domain.WindowMetrics) *float64 { return perSleepValue(m, m.PerSleep.Bedtime.Std) },
+		stats: func(c *domain.CohortMetrics) **domain.CohortDistribution { return &c.BedtimeStdMinutes },
+		rank:  func(r *domain.CohortRanks) **float64 { return &r.BedtimeStdMinutes },
+	},
+	{
+		value: func(m *domain.WindowMetrics) *float64 { return dailyValue(m, m.DailyOverall.TotalDailyHours.Avg) },
+		stats: func(c *domain.CohortMetrics) **domain.CohortDistribution { return &c.AvgTotalDailyHours },
+		rank:  func(r *domain.CohortRanks) **float64 { return &r.AvgTotalDailyHours },
+	},
+	{
+		value: func(m *domain.WindowMetrics) *float64 { return dailyValue(m, m.DailyOverall.DailySufficiencyScore) },
+		stats: func(c *domain.CohortMetrics) **domain.CohortDistribution { return &c.DailySufficiencyScore },
+		rank:  func(r *domain.CohortRanks) **float64 { return &r.DailySufficiencyScore },
+	},
+	{
+		value: func(m *domain.WindowMetrics) *float64 { return perSleepValue(m, m.Scores.OverallSleepScore) },
+		stats: func(c *domain.CohortMetrics) **domain.CohortDistribution { return &c.OverallSleepScore },
+		rank:  func(r *domain.CohortRanks) **float64 { return &r.OverallSleepScore },
+	},
+	{
+		value: func(m *domain.WindowMetrics) *float64 { return m.Regularity.SRI },
+		stats: func(c *domain.CohortMetrics) **domain.CohortDistribution { return &c.SleepRegularityIndex },
+		rank:  func(r *domain.CohortRanks) **float64 { return &r.SleepRegularityIndex },
+	},
+}
+
+// perSleepValue returns v if the window has sleeps that pass the
+// computePerSleepMetrics filter.
+func perSleepValue(m *domain.WindowMetrics, v float64) *float64 {
+	if m.PerSleep.SleepCount == 0 {
+		return nil
+	}
+	return &v
+}
+
+// dailyValue returns v if the window has days with sleep.
+func dailyValue(m *domain.WindowMetrics, v float64) *float64 {
+	if m.DailyOverall.DaysCount == 0 {
+		return nil
+	}
+	return &v
+}
+
+func (s *cohortService) Metrics(ctx context.Context, filter domain.CohortFilter, windowDays int) (*domain.CohortMetricsResponse, error) {
+	tracer := otel.Tracer("sleep-tracker-api/cohorts")
+	ctx, span := tracer.Start(ctx, "CohortService.Metrics")
+	defer span.End()
+
+	windowDays = cohortWindowDays(windowDays)
+	setCohortAttributes(span.SetAttributes, filter, windowDays)
+
+	cohort, err := s.snapshot(ctx, filter, windowDays)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("cohort.size", cohort.size))
+	if cohort.size < s.minCohortSize {
+		return nil, s.tooSmall()
+	}
+
+	response := &domain.CohortMetricsResponse{
+		Filter:        filter,
+		CohortSize:    cohort.size,
+		MinCohortSize: s.minCohortSize,
+	}
+	response.Window.From = cohort.from
+	response.Window.To = cohort.to
+	for i, measure := range cohortMeasures {
+		values := cohort.values[i]
+		if len(values) < s.minCohortSize {
+			continue
+		}
+		distribution := cohortDistribution(values)
+		*measure.stats(&response.Metrics) = &distribution
+	}
+
+	return response, nil
+}
+
+func (s *cohortService) Ranks(ctx context.Context, userID uuid.UUID, filter domain.CohortFilter, windowDays int) (*domain.CohortRanksResponse, error) {
+	tracer := otel.Tracer("sleep-tracker-api/cohorts")
+	ctx, span := tracer.Start(ctx, "CohortService.Ranks")
+	defer span.End()
+
+	windowDays = cohortWindowDays(windowDays)
+	span.SetAttributes(attribute.String("user.id", userID.String()))
+	setCohortAttributes(span.SetAttributes, filter, windowDays)
+
+	// Validate user exists
+	exists, err := s.userRepo.Exists(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+
+	cohort, err := s.snapshot(ctx, filter, windowDays)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("cohort.size", cohort.size))
+	if cohort.size < s.minCohortSize {
+		return nil, s.tooSmall()
+	}
+
+	// The user's own metrics over the window of the snapshot
+	own, err := s.metricsService.ComputeWindow(ctx, userID, cohort.from, cohort.to)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &domain.CohortRanksResponse{
+		Filter:     filter,
+		CohortSize: cohort.size,
+	}
+	response.Window.From = cohort.from
+	response.Window.To = cohort.to
+	for i, measure := range cohortMeasures {
+		value := measure.value(own)
+		values := cohort.values[i]
+		if value == nil || len(values) < s.minCohortSize {
+			continue
+		}
+		rank := percentileRank(values, *value)
+		*measure.rank(&response.Ranks) = &rank
+	}
+
+	return response, nil
+}
+
+func (s *cohortService) tooSmall() error {
+	return fmt.Errorf("%w: at least %d users with sleep in the window are needed", domain.ErrCohortTooSmall, s.minCohortSize)
+}
+
+// snapshot returns the cohort of the filter and window, building it if
+// there is no snapshot younger than the TTL. Concurrent requests for the
+// same cohort wait for a single build.
+func (s *cohortService) snapshot(ctx context.Context, filter domain.CohortFilter, windowDays int) (*cohortSnapshot, error) {
+	key := cohortKey{filter: filter, windowDays: windowDays}
+	now := s.now()
+
+	s.mu.Lock()
+	snap, ok := s.snapshots[key]
+	if ok && snap.done() && now.Sub(snap.to) >= s.snapshotTTL {
+		ok = false
+	}
+	if !ok {
+		s.evictSnapshots(now)
+		snap = &cohortSnapshot{ready: make(chan struct{})}
+		s.snapshots[key] = snap
+		s.mu.Unlock()
+
+		// Other requests wait for this build, so it must not be cancelled
+		// along with the request that started it
+		s.buildSnapshot(context.WithoutCancel(ctx), key, snap, now)
+		return snap, snap.err
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-snap.ready:
+		return snap, snap.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// evictSnapshots drops expired snapshots and, if the cache is still full,
+// the oldest one. Must be called with s.mu held.
+func (s *cohortService) evictSnapshots(now time.Time) {
+	if len(s.snapshots) < maxCohortSnapshots {
+		return
+	}
+	var oldestKey cohortKey
+	var oldest *cohortSnapshot
+	for key, snap := range s.snapshots {
+		if !snap.done() {
+			continue
+		}
+		if now.Sub(snap.to) >= s.snapshotTTL {
+			delete(s.snapshots, key)
+			continue
+		}
+		if oldest == nil || snap.to.Before(oldest.to) {
+			oldestKey, oldest = key, snap
+		}
+	}
+	if len(s.snapshots) >= maxCohortSnapshots && oldest != nil {
+		delete(s.snapshots, oldestKey)
+	}
+}
+
+// buildSnapshot computes the window metrics of the users matching the
+// filter who have sleep in the window, up to maxUsers of them, and keeps
+// only the values of the cohort measures. A failed build is not cached.
+func (s *cohortService) buildSnapshot(ctx context.Context, key cohortKey, snap *cohortSnapshot, now time.Time) {
+	defer close(snap.ready)
+
+	snap.to = now.UTC()
+	snap.from = snap.to.AddDate(0, 0, -key.windowDays)
+	cohort, err := s.buildCohort(ctx, key.filter, key.windowDays, snap.from, snap.to)
+	if err != nil {
+		snap.err = err
+		s.mu.Lock()
+		if s.snapshots[key] == snap {
+			delete(s.snapshots, key)
+		}
+		s.mu.Unlock()
+		return
+	}
+
+	snap.size = len(cohort)
+	snap.values = make([][]float64, len(cohortMeasures))
+	for i, measure := range cohortMeasures {
+		snap.values[i] = sortedCopy(cohortValues(cohort, measure))
+	}
+}
+
+func (s *cohortSnapshot) done() bool {
+	select {
+	case <-s.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// buildCohort computes the window metrics of every user matching the filter
+// who has sleep in the window, among the first maxUsers such users.
+func (s *cohortService) buildCohort(ctx context.Context, filter domain.CohortFilter, windowDays int, from, to time.Time) ([]*domain.WindowMetrics, error) {
+	users, err := s.userRepo.ListActive(ctx, filter, from, to, s.maxUsers)
+	if err != nil {
+		return nil, err
+	}
+
+	var cohort []*domain.WindowMetrics
+	for _, user := range users {
+		if filter.Chronotype != "" {
+			chronotype, err := s.chronotypeService.Compute(ctx, user.ID, windowDays, DefaultChronotypeMinSleeps)
+			if err != nil {
+				return nil, err
+			}
+			if chronotype.Chronotype != filter.Chronotype {
+				continue
+			}
+		}
+
+		metrics, err := s.metricsService.ComputeWindow(ctx, user.ID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		if metrics.DailyOverall.DaysCount == 0 {
+			continue
+		}
+		if filter.Tag != "" && !windowHasTag(metrics, filter.Tag) {
+			continue
+		}
+		cohort = append(cohort, metrics)
+	}
+	return cohort, nil
+}
+
+// windowHasTag reports whether any sleep of the window was tagged with tag.
+func windowHasTag(metrics *domain.WindowMetrics, tag string) bool {
+	for _, factor := range metrics.Factors {
+		if factor.Tag == tag && factor.With.SleepCount > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// cohortDistribution summarizes the values like computeStats, leaving out
+// the minimum and maximum.
+func cohortDistribution(values []float64) domain.CohortDistribution {
+	stats := computeStats(values)
+	return domain.CohortDistribution{
+		Avg:    stats.Avg,
+		Std:    stats.Std,
+		P10:    stats.P10,
+		P25:    stats.P25,
+		Median: stats.Median,
+		P75:    stats.P75,
+		P90:    stats.P90,
+	}
+}
+
+func cohortValues(cohort []*domain.WindowMetrics, measure cohortMeasure) []float64 {
+	var values []float64
+	for _, metrics := range cohort {
+		if v := measure.value(metrics); v != nil {
+			values = append(values, *v)
+		}
+	}
+	return values
+}
+
+// percentileRank returns the percentage of sorted below v, counting values
+// equal to v as half, rounded to one decimal.
+func percentileRank(sorted []float64, v float64) float64 {
+	below := sort.SearchFloat64s(sorted, v)
+	equal := sort.SearchFloat64s(sorted, v+1e-9) - below
+	return round1(100 * (float64(below) + float64(equal)/2) / float64(len(sorted)))
+}
+
+func cohortWindowDays(windowDays int) int {
+	if windowDays <= 0 {
+		return DefaultCohortWindowDays
+	}
+	return windowDays
+}
+
+func setCohortAttributes(set func(...attribute.KeyValue), filter domain.CohortFilter, windowDays int) {
+	set(
+		attribute.String("cohort.timezone", filter.Timezone),
+		attribute.String("cohort.chronotype", string(filter.Chronotype)),
+		attribute.String("cohort.tag", filter.Tag),
+		attribute.Int("window_days", windowDays),
+	)
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+// newCohortTestService creates ten UTC users whose nights last 6h, 6h10m, ...
+// 7h30m, with the first three tagged with alcohol, and one Prague user
+// without sleep.
+func newCohortTestService(minCohortSize int) (CohortService, []uuid.UUID) {
+	repo := NewMockSleepLogRepository()
+	userRepo := NewMockUserRepository()
+	metrics := NewMetricsService(repo, userRepo, NewMockSleepGoalRepository(), nil)
+	svc := NewCohortService(userRepo, metrics, NewChronotypeService(repo, userRepo), minCohortSize, 0, 0)
+
+	alcohol := domain.CatalogueTags()[1]
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	var ids []uuid.UUID
+	for i := 0; i < 10; i++ {
+		userID := uuid.New()
+		ids = append(ids, userID)
+		userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+		for day := 1; day <= 3; day++ {
+			start := today.AddDate(0, 0, -day).Add(-time.Hour)
+			log := &domain.SleepLog{
+				ID:      uuid.New(),
+				UserID:  userID,
+				StartAt: start,
+				EndAt:   start.Add(6*time.Hour + time.Duration(i*10)*time.Minute),
+				Quality: 7,
+				Type:    domain.SleepTypeCore,
+			}
+			if i < 3 && day == 1 {
+				log.Factors = []domain.SleepLogFactor{{TagID: alcohol.ID, Tag: alcohol}}
+			}
+			repo.logs[log.ID] = log
+		}
+	}
+	prague := uuid.New()
+	userRepo.users[prague] = &domain.User{ID: prague, Timezone: "Europe/Prague"}
+
+	return svc, ids
+}
+
+func TestCohortService_Metrics(t *testing.T) {
+	svc, _ := newCohortTestService(5)
+
+	result, err := svc.Metrics(context.Background(), domain.CohortFilter{}, 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The Prague user has no sleep and is not part of the cohort
+	if result.CohortSize != 10 || result.MinCohortSize != 5 {
+		t.Errorf("unexpected size: %d (min %d)", result.CohortSize, result.MinCohortSize)
+	}
+
+	duration := result.Metrics.AvgDurationHours
+	if duration == nil {
+		t.Fatal("expected a duration distribution")
+	}
+	if duration.P10 != 6.15 || duration.P90 != 7.35 || duration.Median != 6.75 {
+		t.Errorf("unexpected duration distribution: %+v", duration)
+	}
+	if quality := result.Metrics.AvgQuality; quality == nil || quality.Avg != 7 {
+		t.Errorf("unexpected quality distribution: %+v", quality)
+	}
+}
+
+func TestCohortService_Metrics_Filters(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   domain.CohortFilter
+		min      int
+		wantSize int
+		wantErr  error
+	}{
+		{
+			name:     "tag",
+			filter:   domain.CohortFilter{Tag: "alcohol"},
+			min:      3,
+			wantSize: 3,
+		},
+		{
+			name:     "timezone",
+			filter:   domain.CohortFilter{Timezone: "UTC"},
+			min:      3,
+			wantSize: 10,
+		},
+		{
+			name:    "too small",
+			filter:  domain.CohortFilter{Tag: "alcohol"},
+			min:     5,
+			wantErr: domain.ErrCohortTooSmall,
+		},
+		{
+			name:    "no one with sleep",
+			filter:  domain.CohortFilter{Timezone: "Europe/Prague"},
+			min:     1,
+			wantErr: domain.ErrCohortTooSmall,
+		},
+		{
+			name:    "too few sleeps for a chronotype",
+			filter:  domain.CohortFilter{Chronotype: domain.ChronotypeIntermediate},
+			min:     1,
+			wantErr: domain.ErrCohortTooSmall,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _ := newCohortTestService(tt.min)
+
+			result, err := svc.Metrics(context.Background(), tt.filter, 30)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.CohortSize != tt.wantSize {
+				t.Errorf("CohortSize = %d, want %d", result.CohortSize, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestCohortService_Ranks(t *testing.T) {
+	svc, ids := newCohortTestService(5)
+
+	// The shortest sleeper is below no one and ties only with themselves
+	result, err := svc.Ranks(context.Background(), ids[0], domain.CohortFilter{}, 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.CohortSize != 10 {
+		t.Errorf("CohortSize = %d, want 10", result.CohortSize)
+	}
+	if got := deref(result.Ranks.AvgDurationHours); got != 5.0 {
+		t.Errorf("duration rank = %v, want 5", got)
+	}
+	// Everyone has the same quality
+	if got := deref(result.Ranks.AvgQuality); got != 50.0 {
+		t.Errorf("quality rank = %v, want 50", got)
+	}
+
+	result, err = svc.Ranks(context.Background(), ids[9], domain.CohortFilter{}, 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := deref(result.Ranks.AvgDurationHours); got != 95.0 {
+		t.Errorf("duration rank = %v, want 95", got)
+	}
+}
+
+func TestCohortService_Ranks_UserNotFound(t *testing.T) {
+	svc, _ := newCohortTestService(5)
+
+	_, err := svc.Ranks(context.Background(), uuid.New(), domain.CohortFilter{}, 30)
+	if !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestCohortService_Snapshot(t *testing.T) {
+	svc, ids := newCohortTestService(5)
+	cohort := svc.(*cohortService)
+	now := time.Now()
+	cohort.now = func() time.Time { return now }
+
+	first, err := svc.Metrics(context.Background(), domain.CohortFilter{}, 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Changes are not seen until the snapshot expires, and ranks share it
+	delete(cohort.userRepo.(*MockUserRepository).users, ids[9])
+	ranks, err := svc.Ranks(context.Background(), ids[0], domain.CohortFilter{}, 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ranks.CohortSize != 10 || !ranks.Window.To.Equal(first.Window.To) {
+		t.Errorf("expected the cached cohort of 10 users, got %d at %v", ranks.CohortSize, ranks.Window.To)
+	}
+
+	now = now.Add(DefaultCohortSnapshotTTL)
+	result, err := svc.Metrics(context.Background(), domain.CohortFilter{}, 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.CohortSize != 9 {
+		t.Errorf("CohortSize after expiry = %d, want 9", result.CohortSize)
+	}
+}
+
+func TestCohortService_MaxUsers(t *testing.T) {
+	svc, _ := newCohortTestService(5)
+	svc.(*cohortService).maxUsers = 6
+
+	result, err := svc.Metrics(context.Background(), domain.CohortFilter{Timezone: "UTC"}, 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.CohortSize != 6 {
+		t.Errorf("CohortSize = %d, want 6", result.CohortSize)
+	}
+}
+
+func TestPercentileRank(t *testing.T) {
+	tests := []struct {
+		name   string
+		sorted []float64
+		value  float64
+		want   float64
+	}{
+		{"lowest", []float64{1, 2, 3, 4}, 1, 12.5},
+		{"highest", []float64{1, 2, 3, 4}, 4, 87.5},
+		{"ties count half", []float64{1, 2, 2, 3}, 2, 50},
+		{"below all", []float64{1, 2, 3}, 0, 0},
+		{"above all", []float64{1, 2, 3}, 5, 100},
+		{"rounded", []float64{1, 2, 3}, 2, 50},
+		{"thirds", []float64{1, 2, 3}, 2.5, 66.7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentileRank(tt.sorted, tt.value); got != tt.want {
+				t.Errorf("percentileRank(%v, %v) = %v, want %v", tt.sorted, tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/repository"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// DefaultFactorImpactWindowDays is the default window for factor impact analysis.
+	DefaultFactorImpactWindowDays = 90
+
+	// BootstrapResamples is the number of resamples for confidence intervals.
+	BootstrapResamples = 1000
+
+	// FactorImpactConfidenceLevel is the confidence level of the intervals.
+	FactorImpactConfidenceLevel = 0.95
+
+	// MinFactorGroupSize is the minimum number of tagged and of untagged
+	// nights needed for effect sizes and intervals.
+	MinFactorGroupSize = 3
+
+	// bootstrapSeed makes resampling deterministic, so the same data always
+	// yields the same intervals.
+	bootstrapSeed = 20240115
+)
+
+// FactorImpactService estimates how contextual factors affect sleep.
+type FactorImpactService interface {
+	// Compute compares duration and quality on nights with each recorded
+	// factor against nights without it over the given window.
+	Compute(ctx context.Context, userID uuid.UUID, windowDays int) (*domain.FactorImpactResponse, error)
+}
+
+type factorImpactService struct {
+	sleepLogRepo repository.SleepLogRepository
+	userRepo     repository.UserRepository
+}
+
+// NewFactorImpactService creates a new FactorImpactService.
+func NewFactorImpactService(sleepLogRepo repository.SleepLogRepository, userRepo repository.UserRepository) FactorImpactService {
+	return &factorImpactService{
+		sleepLogRepo: sleepLogRepo,
+		userRepo:     userRepo,
+	}
+}
+
+func (s *factorImpactService) Compute(ctx context.Context, userID uuid.UUID, windowDays int) (*domain.FactorImpactResponse, error) {
+	tracer := otel.Tracer("sleep-tracker-api/factors")
+	ctx, span := tracer.Start(ctx, "FactorImpactService.Compute")
+	defer span.End()
+
+	// Validate user exists
+	exists, err := s.userRepo.Exists(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+
+	// Apply defaults
+	if windowDays <= 0 {
+		windowDays = DefaultFactorImpactWindowDays
+	}
+	span.SetAttributes(
+		attribute.String("user.id", userID.String()),
+		attribute.Int("window_days", windowDays),
+	)
+
+	// Calculate time window
+	now := time.Now().UTC()
+	from := now.AddDate(0, 0, -windowDays)
+
+	logs, err := s.sleepLogRepo.ListByEndRange(ctx, userID, from, now)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &domain.FactorImpactResponse{
+		ConfidenceLevel: FactorImpactConfidenceLevel,
+		Resamples:       BootstrapResamples,
+		MinGroupSize:    MinFactorGroupSize,
+		Factors:         computeFactorAnalyses(logs),
+	}
+	response.Window.From = from
+	response.Window.To = now
+
+	span.SetAttributes(attribute.Int("factors.count", len(response.Factors)))
+
+	return response, nil
+}
+
+// computeFactorAnalyses runs the impact analysis for every tag recorded on
+// the logs, ordered by tag name.
+func computeFactorAnalyses(logs []domain.SleepLog) []domain.FactorAnalysis {
+	analyses := []domain.FactorAnalysis{}
+	for _, group := range groupByFactor(logs) {
+		tagged := splitSleepData(group.tagged)
+		untagged := splitSleepData(group.untagged)
+
+		analyses = append(analyses, domain.FactorAnalysis{
+			Tag:           group.tag.Name,
+			Label:         group.tag.Label,
+			Category:      group.tag.Category,
+			TaggedCount:   len(group.tagged),
+			UntaggedCount: len(group.untagged),
+			Duration:      computeFactorEffect(tagged.durations, untagged.durations),
+			Quality:       computeFactorEffect(tagged.qualities, untagged.qualities),
+		})
+	}
+	return analyses
+}
+
+// sleepSamples holds the measures of a group of nights.
+type sleepSamples struct {
+	durations []float64
+	qualities []float64
+}
+
+func splitSleepData(data []sleepData) sleepSamples {
+	var samples sleepSamples
+	for _, d := range data {
+		samples.durations = append(samples.durations, d.durationHours)
+		samples.qualities = append(samples.qualities, float64(d.quality))
+	}
+	return samples
+}
+
+// computeFactorEffect describes both groups with computeStats and, when both
+// have at least MinFactorGroupSize values, adds the mean difference and
+// Cohen's d with percentile bootstrap confidence intervals.
+func computeFactorEffect(tagged, untagged []float64) domain.FactorEffect {
+	effect := domain.FactorEffect{
+		Tagged:   computeStats(tagged),
+		Untagged: computeStats(untagged),
+	}
+	if len(tagged) < MinFactorGroupSize || len(untagged) < MinFactorGroupSize {
+		return effect
+	}
+
+	diff := round2(mean(tagged) - mean(untagged))
+	d := round2(cohensD(tagged, untagged))
+	effect.MeanDiff = &diff
+	effect.EffectSize = &d
+
+	// Resample each group independently with replacement. Sorting first
+	// keeps the intervals independent of the order of the logs.
+	tagged, untagged = sortedCopy(tagged), sortedCopy(untagged)
+	rng := rand.New(rand.NewSource(bootstrapSeed))
+	diffs := make([]float64, BootstrapResamples)
+	ds := make([]float64, BootstrapResamples)
+	sampleT := make([]float64, len(tagged))
+	sampleU := make([]float64, len(untagged))
+	for i := 0; i < BootstrapResamples; i++ {
+		for j := range sampleT {
+			sampleT[j] = tagged[rng.Intn(len(tagged))]
+		}
+		for j := range sampleU {
+			sampleU[j] = untagged[rng.Intn(len(untagged))]
+		}
+		diffs[i] = mean(sampleT) - mean(sampleU)
+		ds[i] = cohensD(sampleT, sampleU)
+	}
+	effect.MeanDiffCI = percentileInterval(diffs, FactorImpactConfidenceLevel)
+	effect.EffectSizeCI = percentileInterval(ds, FactorImpactConfidenceLevel)
+
+	return effect
+}
+
+// cohensD returns the mean difference in units of the pooled standard
+// deviation, or 0 if both groups have no spread.
+func cohensD(a, b []float64) float64 {
+	na, nb := float64(len(a)), float64(len(b))
+	pooled := ((na-1)*variance(a) + (nb-1)*variance(b)) / (na + nb - 2)
+	if pooled <= 0 {
+		return 0
+	}
+	return (mean(a) - mean(b)) / math.Sqrt(pooled)
+}
+
+// percentileInterval returns the central interval holding the given share of
+// the values. values is sorted in place.
+func percentileInterval(values []float64, level float64) *domain.ConfidenceInterval {
+	sort.Float64s(values)
+	alpha := (1 - level) / 2
+	low := int(math.Floor(alpha * float64(len(values))))
+	high := int(math.Ceil((1-alpha)*float64(len(values)))) - 1
+	return &domain.ConfidenceInterval{
+		Low:  round2(values[low]),
+		High: round2(values[high]),
+	}
+}
+
+func sortedCopy(values []float64) []float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	return sorted
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// variance returns the sample variance.
+func variance(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	m := mean(values)
+	sumSquares := 0.0
+	for _, v := range values {
+		sumSquares += (v - m) * (v - m)
+	}
+	return sumSquares / float64(len(values)-1)
+}
+
+// round2 rounds to two decimals, like computeStats.
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+func TestFactorImpactService_Compute(t *testing.T) {
+	repo := NewMockSleepLogRepository()
+	userRepo := NewMockUserRepository()
+	svc := NewFactorImpactService(repo, userRepo)
+
+	userID := uuid.New()
+	userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+
+	alcohol := domain.CatalogueTags()[1]
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for i := 1; i <= 10; i++ {
+		start := today.AddDate(0, 0, -i).Add(-time.Hour)
+		log := &domain.SleepLog{
+			ID:      uuid.New(),
+			UserID:  userID,
+			StartAt: start,
+			Type:    domain.SleepTypeCore,
+		}
+		// Alcohol nights are shorter and worse, with a little spread in both groups
+		if i%2 == 0 {
+			log.EndAt = start.Add(6*time.Hour + time.Duration(i)*time.Minute)
+			log.Quality = 4 + i%4/2
+			log.Factors = []domain.SleepLogFactor{{TagID: alcohol.ID, Tag: alcohol}}
+		} else {
+			log.EndAt = start.Add(8*time.Hour + time.Duration(i)*time.Minute)
+			log.Quality = 7 + i%4/2
+		}
+		repo.logs[log.ID] = log
+	}
+
+	result, err := svc.Compute(context.Background(), userID, 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Resamples != BootstrapResamples || result.MinGroupSize != MinFactorGroupSize {
+		t.Errorf("unexpected parameters: %+v", result)
+	}
+	if len(result.Factors) != 1 {
+		t.Fatalf("got %d factors, want 1", len(result.Factors))
+	}
+
+	got := result.Factors[0]
+	if got.Tag != "alcohol" || got.TaggedCount != 5 || got.UntaggedCount != 5 {
+		t.Fatalf("unexpected factor: %+v", got)
+	}
+
+	for name, effect := range map[string]domain.FactorEffect{"duration": got.Duration, "quality": got.Quality} {
+		if effect.MeanDiff == nil || *effect.MeanDiff >= 0 {
+			t.Errorf("%s: MeanDiff = %v, want negative", name, effect.MeanDiff)
+			continue
+		}
+		if effect.EffectSize == nil || *effect.EffectSize >= 0 {
+			t.Errorf("%s: EffectSize = %v, want negative", name, effect.EffectSize)
+		}
+		ci := effect.MeanDiffCI
+		if ci == nil || ci.Low > *effect.MeanDiff || ci.High < *effect.MeanDiff {
+			t.Errorf("%s: MeanDiffCI = %+v does not contain %v", name, ci, *effect.MeanDiff)
+		}
+		if effect.EffectSizeCI == nil || effect.EffectSizeCI.Low > effect.EffectSizeCI.High {
+			t.Errorf("%s: EffectSizeCI = %+v", name, effect.EffectSizeCI)
+		}
+	}
+
+	// Group stats come from computeStats, as in /sleep/metrics
+	if got.Duration.Tagged.Avg >= got.Duration.Untagged.Avg {
+		t.Errorf("tagged avg %v should be below untagged avg %v", got.Duration.Tagged.Avg, got.Duration.Untagged.Avg)
+	}
+
+	// Resampling is seeded, so repeated calls agree
+	again, err := svc.Compute(context.Background(), userID, 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *again.Factors[0].Duration.MeanDiffCI != *got.Duration.MeanDiffCI {
+		t.Errorf("intervals differ between calls: %+v vs %+v", again.Factors[0].Duration.MeanDiffCI, got.Duration.MeanDiffCI)
+	}
+}
+
+func TestFactorImpactService_Compute_UserNotFound(t *testing.T) {
+	svc := NewFactorImpactService(NewMockSleepLogRepository(), NewMockUserRepository())
+
+	_, err := svc.Compute(context.Background(), uuid.New(), 30)
+	if !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestComputeFactorEffect(t *testing.T) {
+	tests := []struct {
+		name       string
+		tagged     []float64
+		untagged   []float64
+		wantEffect bool
+		wantDiff   float64
+		wantD      float64
+	}{
+		{
+			name:       "small tagged group",
+			tagged:     []float64{6, 7},
+			untagged:   []float64{8, 8, 8},
+			wantEffect: false,
+		},
+		{
+			name:       "clear difference",
+			tagged:     []float64{5, 6, 7},
+			untagged:   []float64{7, 8, 9},
+			wantEffect: true,
+			wantDiff:   -2,
+			wantD:      -2,
+		},
+		{
+			name:       "no spread",
+			tagged:     []float64{7, 7, 7},
+			untagged:   []float64{7, 7, 7},
+			wantEffect: true,
+			wantDiff:   0,
+			wantD:      0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			effect := computeFactorEffect(tt.tagged, tt.untagged)
+
+			if effect.Tagged != computeStats(tt.tagged) || effect.Untagged != computeStats(tt.untagged) {
+				t.Errorf("stats differ from computeStats: %+v / %+v", effect.Tagged, effect.Untagged)
+			}
+			if !tt.wantEffect {
+				if effect.MeanDiff != nil || effect.EffectSize != nil || effect.MeanDiffCI != nil || effect.EffectSizeCI != nil {
+					t.Errorf("expected no effect, got %+v", effect)
+				}
+				return
+			}
+			if effect.MeanDiff == nil || *effect.MeanDiff != tt.wantDiff {
+				t.Errorf("MeanDiff = %v, want %v", effect.MeanDiff, tt.wantDiff)
+			}
+			if effect.EffectSize == nil || *effect.EffectSize != tt.wantD {
+				t.Errorf("EffectSize = %v, want %v", effect.EffectSize, tt.wantD)
+			}
+			if effect.MeanDiffCI == nil || effect.EffectSizeCI == nil {
+				t.Fatalf("expected confidence intervals")
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/importer"
+	"github.com/blaisecz/sleep-tracker/internal/repository"
+	"github.com/google/uuid"
+)
+
+// ImportService imports sleep history from wearable vendor exports.
+type ImportService interface {
+	// Import parses the export and creates one sleep log per detected session.
+	// Sessions get stable client_request_ids, so importing the same export
+	// again returns the existing logs instead of creating duplicates.
+	// Unparseable exports fail with domain.ErrInvalidInput.
+	Import(ctx context.Context, userID uuid.UUID, source importer.Source, r io.Reader) ([]domain.BatchItemResult, error)
+}
+
+type importService struct {
+	sleepLogService SleepLogService
+	userRepo        repository.UserRepository
+}
+
+// NewImportService creates a new ImportService.
+func NewImportService(sleepLogService SleepLogService, userRepo repository.UserRepository) ImportService {
+	return &importService{
+		sleepLogService: sleepLogService,
+		userRepo:        userRepo,
+	}
+}
+
+func (s *importService) Import(ctx context.Context, userID uuid.UUID, source importer.Source, r io.Reader) ([]domain.BatchItemResult, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Local timestamps without an offset are interpreted in the user's timezone
+	loc := time.UTC
+	if user.Timezone != "" {
+		if l, err := time.LoadLocation(user.Timezone); err == nil {
+			loc = l
+		}
+	}
+
+	items, err := importer.Parse(source, r, loc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", domain.ErrInvalidInput, err)
+	}
+
+	// Exports can span years, so sessions are created in batch-sized chunks.
+	// Best effort keeps one conflicting night from blocking the rest.
+	results := make([]domain.BatchItemResult, 0, len(items))
+	for start := 0; start < len(items); start += domain.MaxBatchItems {
+		end := min(start+domain.MaxBatchItems, len(items))
+		chunk, err := s.sleepLogService.CreateBatch(ctx, userID, items[start:end], domain.BatchModeBestEffort)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, chunk...)
+	}
+
+	return results, nil
+}
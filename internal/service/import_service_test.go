@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/importer"
+	"github.com/google/uuid"
+)
+
+const fitbitExport = `[
+  {"startTime": "2024-01-15T23:00:00.000", "endTime": "2024-01-16T07:00:00.000", "levels": {"data": []}},
+  {"startTime": "2024-01-16T23:00:00.000", "endTime": "2024-01-17T07:00:00.000", "levels": {"data": []}}
+]`
+
+func TestImportService_Import(t *testing.T) {
+	userID := uuid.New()
+	userRepo := NewMockUserRepository()
+	userRepo.users[userID] = &domain.User{ID: userID, Timezone: "Europe/Prague"}
+	logRepo := NewMockSleepLogRepository()
+	svc := NewImportService(NewSleepLogService(logRepo, userRepo, NewMockTagRepository()), userRepo)
+
+	results, err := svc.Import(context.Background(), userID, importer.SourceFitbit, strings.NewReader(fitbitExport))
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Import() returned %d results, want 2", len(results))
+	}
+	for i, result := range results {
+		if result.Err != nil || result.Existing {
+			t.Errorf("results[%d] = %+v, want newly created", i, result)
+		}
+	}
+
+	// Fitbit times are local; the user's timezone (UTC+1) applies
+	if got := results[0].Log.StartAt.Hour(); got != 22 {
+		t.Errorf("StartAt hour = %d UTC, want 22", got)
+	}
+
+	// Importing the same export again is idempotent
+	results, err = svc.Import(context.Background(), userID, importer.SourceFitbit, strings.NewReader(fitbitExport))
+	if err != nil {
+		t.Fatalf("second Import() error = %v", err)
+	}
+	for i, result := range results {
+		if result.Err != nil || !result.Existing {
+			t.Errorf("re-import results[%d] = %+v, want existing", i, result)
+		}
+	}
+	if len(logRepo.logs) != 2 {
+		t.Errorf("stored %d logs, want 2", len(logRepo.logs))
+	}
+}
+
+func TestImportService_Import_Errors(t *testing.T) {
+	userID := uuid.New()
+	userRepo := NewMockUserRepository()
+	userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+	svc := NewImportService(NewSleepLogService(NewMockSleepLogRepository(), userRepo, NewMockTagRepository()), userRepo)
+
+	tests := []struct {
+		name    string
+		userID  uuid.UUID
+		input   string
+		wantErr error
+	}{
+		{"user not found", uuid.New(), fitbitExport, domain.ErrNotFound},
+		{"malformed export", userID, `[{"startTime": `, domain.ErrInvalidInput},
+		{"no sleep data", userID, `[]`, domain.ErrInvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Import(context.Background(), tt.userID, importer.SourceFitbit, strings.NewReader(tt.input))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Import() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
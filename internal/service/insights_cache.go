@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/repository"
+	"github.com/google/uuid"
+)
+
+// insightsFingerprint hashes the insights context and the generator. The
+// bounds of the history and recent windows move with every request, so they
+// are left out: the same sleep data gives the same fingerprint until a log
+// changes or leaves a window.
+func insightsFingerprint(insightsCtx *domain.InsightsContext, generator domain.InsightsGenerator) (string, error) {
+	stable := *insightsCtx
+	stable.History.From, stable.History.To = time.Time{}, time.Time{}
+	stable.Recent.From, stable.Recent.To = time.Time{}, time.Time{}
+
+	data, err := json.Marshal(struct {
+		Context   domain.InsightsContext   `json:"context"`
+		Generator domain.InsightsGenerator `json:"generator"`
+	}{stable, generator})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// InvalidateInsightsOnChange wraps a SleepLogService so that creating,
+// changing, deleting or restoring a user's sleep logs drops their cached
+// insights.
+func InvalidateInsightsOnChange(next SleepLogService, cacheRepo repository.InsightsCacheRepository) SleepLogService {
+	return &invalidatingSleepLogService{SleepLogService: next, cacheRepo: cacheRepo}
+}
+
+type invalidatingSleepLogService struct {
+	SleepLogService
+	cacheRepo repository.InsightsCacheRepository
+}
+
+func (s *invalidatingSleepLogService) Create(ctx context.Context, userID uuid.UUID, req *domain.CreateSleepLogRequest) (*domain.SleepLog, bool, error) {
+	sleepLog, existing, err := s.SleepLogService.Create(ctx, userID, req)
+	if err == nil && !existing {
+		s.invalidate(ctx, userID)
+	}
+	return sleepLog, existing, err
+}
+
+func (s *invalidatingSleepLogService) Update(ctx context.Context, userID uuid.UUID, logID uuid.UUID, req *domain.UpdateSleepLogRequest, ifMatch int) (*domain.SleepLog, error) {
+	sleepLog, err := s.SleepLogService.Update(ctx, userID, logID, req, ifMatch)
+	if err == nil {
+		s.invalidate(ctx, userID)
+	}
+	return sleepLog, err
+}
+
+func (s *invalidatingSleepLogService) Replace(ctx context.Context, userID uuid.UUID, logID uuid.UUID, req *domain.CreateSleepLogRequest, ifMatch int) (*domain.SleepLog, error) {
+	sleepLog, err := s.SleepLogService.Replace(ctx, userID, logID, req, ifMatch)
+	if err == nil {
+		s.invalidate(ctx, userID)
+	}
+	return sleepLog, err
+}
+
+func (s *invalidatingSleepLogService) Patch(ctx context.Context, userID uuid.UUID, logID uuid.UUID, patch []byte, ifMatch int) (*domain.SleepLog, error) {
+	sleepLog, err := s.SleepLogService.Patch(ctx, userID, logID, patch, ifMatch)
+	if err == nil {
+		s.invalidate(ctx, userID)
+	}
+	return sleepLog, err
+}
+
+func (s *invalidatingSleepLogService) Delete(ctx context.Context, userID uuid.UUID, logID uuid.UUID, ifMatch int) (*domain.SleepLog, error) {
+	sleepLog, err := s.SleepLogService.Delete(ctx, userID, logID, ifMatch)
+	if err == nil {
+		s.invalidate(ctx, userID)
+	}
+	return sleepLog, err
+}
+
+func (s *invalidatingSleepLogService) Restore(ctx context.Context, userID uuid.UUID, logID uuid.UUID, ifMatch int) (*domain.SleepLog, error) {
+	sleepLog, err := s.SleepLogService.Restore(ctx, userID, logID, ifMatch)
+	if err == nil {
+		s.invalidate(ctx, userID)
+	}
+	return sleepLog, err
+}
+
+func (s *invalidatingSleepLogService) CreateBatch(ctx context.Context, userID uuid.UUID, items []domain.CreateSleepLogRequest, mode domain.BatchMode) ([]domain.BatchItemResult, error) {
+	results, err := s.SleepLogService.CreateBatch(ctx, userID, items, mode)
+	for _, result := range results {
+		if result.Err == nil && !result.Existing {
+			s.invalidate(ctx, userID)
+			break
+		}
+	}
+	return results, err
+}
+
+// invalidate drops the user's cached insights. A failure is only logged: the
+// changed data also changes the fingerprint, so stale insights are not served.
+func (s *invalidatingSleepLogService) invalidate(ctx context.Context, userID uuid.UUID) {
+	if err := s.cacheRepo.Delete(ctx, userID); err != nil {
+		log.Printf("Insights cache: failed to invalidate user %s: %v", userID, err)
+	}
+}
@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"time"
 
 	"github.com/blaisecz/sleep-tracker/internal/domain"
@@ -22,42 +24,62 @@ const (
 
 // InsightsService generates comprehensive sleep insights.
 type InsightsService interface {
-	// Generate creates sleep insights for a user.
-	Generate(ctx context.Context, userID uuid.UUID) (*domain.InsightsResponse, error)
+	// Generate creates sleep insights for a user. Insights generated from the
+	// same data, model and prompts are served from the cache unless refresh
+	// is set.
+	Generate(ctx context.Context, userID uuid.UUID, refresh bool) (*domain.InsightsResponse, error)
 }
 
 type insightsService struct {
 	chronotypeService ChronotypeService
 	metricsService    MetricsService
+	anomalyService    AnomalyService
+	debtService       SleepDebtService
 	llmClient         llm.InsightsLLM
+	fallback          llm.InsightsLLM
 	sleepLogRepo      repository.SleepLogRepository
 	userRepo          repository.UserRepository
+	goalRepo          repository.SleepGoalRepository
+	cacheRepo         repository.InsightsCacheRepository
 }
 
-// NewInsightsService creates a new InsightsService.
+// NewInsightsService creates a new InsightsService. The fallback, usually
+// llm.NewRulesClient, writes the insights when the LLM fails; a nil fallback
+// returns the LLM error. A nil cacheRepo disables the insights cache.
 func NewInsightsService(
 	chronotypeService ChronotypeService,
 	metricsService MetricsService,
+	anomalyService AnomalyService,
+	debtService SleepDebtService,
 	llmClient llm.InsightsLLM,
+	fallback llm.InsightsLLM,
 	sleepLogRepo repository.SleepLogRepository,
 	userRepo repository.UserRepository,
+	goalRepo repository.SleepGoalRepository,
+	cacheRepo repository.InsightsCacheRepository,
 ) InsightsService {
 	return &insightsService{
 		chronotypeService: chronotypeService,
 		metricsService:    metricsService,
+		anomalyService:    anomalyService,
+		debtService:       debtService,
 		llmClient:         llmClient,
+		fallback:          fallback,
 		sleepLogRepo:      sleepLogRepo,
 		userRepo:          userRepo,
+		goalRepo:          goalRepo,
+		cacheRepo:         cacheRepo,
 	}
 }
 
-func (s *insightsService) Generate(ctx context.Context, userID uuid.UUID) (*domain.InsightsResponse, error) {
+func (s *insightsService) Generate(ctx context.Context, userID uuid.UUID, refresh bool) (*domain.InsightsResponse, error) {
 	tracer := otel.Tracer("sleep-tracker-api/insights")
 	ctx, span := tracer.Start(ctx, "InsightsService.Generate",
 		trace.WithAttributes(
 			attribute.String("user.id", userID.String()),
 			attribute.Int("history.window_days", HistoryWindowDays),
 			attribute.Int("recent.window_days", RecentWindowDays),
+			attribute.Bool("insights.refresh", refresh),
 		),
 	)
 	defer span.End()
@@ -111,37 +133,175 @@ func (s *insightsService) Generate(ctx context.Context, userID uuid.UUID) (*doma
 		return nil, err
 	}
 
+	// Flag unusual nights of the recent window
+	anomalies, err := s.anomalyService.Detect(ctx, userID, RecentWindowDays)
+	if err != nil {
+		return nil, err
+	}
+
+	// Current sleep debt with the default decay and cap
+	debt, err := s.debtService.Compute(ctx, userID, 1, DefaultSleepDebtDecay, DefaultSleepDebtCapHours)
+	if err != nil {
+		return nil, err
+	}
+
 	// Build insights context for LLM
 	insightsCtx := &domain.InsightsContext{
-		Chronotype: *chronotype,
-		History:    *historyMetrics,
-		Recent:     *recentMetrics,
-		LastNight:  *lastNightMetrics,
+		Chronotype:     *chronotype,
+		History:        *historyMetrics,
+		Recent:         *recentMetrics,
+		LastNight:      *lastNightMetrics,
+		Anomalies:      anomalies.Anomalies,
+		SleepDebtHours: debt.CurrentDebtHours,
+	}
+
+	// Include the goals in force, if any
+	goal, err := s.goalRepo.Current(ctx, userID)
+	if err != nil && err != domain.ErrNotFound {
+		return nil, err
+	}
+	if goal != nil {
+		goalResponse := goal.ToResponse()
+		insightsCtx.Goal = &goalResponse
 	}
 
+	// Serve the cached insights while the data and generator are unchanged
+	generator := describeGenerator(ctx, s.llmClient)
+	fingerprint, cacheable := s.fingerprint(insightsCtx, generator)
+	if cacheable && !refresh {
+		if cached := s.loadCached(ctx, userID, fingerprint); cached != nil {
+			span.SetAttributes(attribute.Bool("insights.cached", true))
+			return cached, nil
+		}
+	}
+	span.SetAttributes(attribute.Bool("insights.cached", false))
+
 	// Generate LLM insights
 	llmOutput, err := s.llmClient.GenerateInsights(ctx, insightsCtx)
 	if err != nil {
-		return nil, err
+		if s.fallback == nil || !llmFailed(err) || ctx.Err() != nil {
+			return nil, err
+		}
+
+		// Fall back to the rules. Their insights are not cached, so the LLM
+		// is tried again on the next request.
+		span.AddEvent("insights.fallback", trace.WithAttributes(
+			attribute.String("insights.fallback.error", err.Error()),
+		))
+		llmOutput, err = s.fallback.GenerateInsights(ctx, insightsCtx)
+		if err != nil {
+			return nil, err
+		}
+		generator = describeGenerator(ctx, s.fallback)
+		cacheable = false
+	}
+	if generator != nil {
+		span.SetAttributes(attribute.String("insights.generator", generator.Provider))
 	}
 
 	// Build response
 	response := &domain.InsightsResponse{
-		Chronotype: *chronotype,
-		Insights:   *llmOutput,
+		Chronotype:  *chronotype,
+		Insights:    *llmOutput,
+		Generator:   generator,
+		GeneratedAt: now,
 	}
 	response.Metrics.History = *historyMetrics
 	response.Metrics.Recent = *recentMetrics
 	response.Metrics.LastNight = *lastNightMetrics
 
+	// Keep the trace of the generation, so that feedback on cached insights
+	// is linked to it
+	if sc := span.SpanContext(); sc.IsValid() {
+		response.TraceID = sc.TraceID().String()
+	}
+
 	// Attach final response as Langfuse output
 	if outputJSON, err := json.Marshal(response); err == nil {
 		span.SetAttributes(attribute.String("langfuse.observation.output", string(outputJSON)))
+		if cacheable {
+			s.saveCached(ctx, userID, *generator, fingerprint, outputJSON, now)
+		}
 	}
 
 	return response, nil
 }
 
+// describeGenerator returns the generator of client, or nil if it does not
+// describe itself.
+func describeGenerator(ctx context.Context, client llm.InsightsLLM) *domain.InsightsGenerator {
+	describer, ok := client.(llm.Describer)
+	if !ok {
+		return nil
+	}
+	generator, err := describer.Describe(ctx)
+	if err != nil {
+		return nil
+	}
+	return &generator
+}
+
+// fingerprint returns the fingerprint of the insights, or false if they
+// cannot be cached: without a cache or a known generator.
+func (s *insightsService) fingerprint(insightsCtx *domain.InsightsContext, generator *domain.InsightsGenerator) (string, bool) {
+	if s.cacheRepo == nil || generator == nil {
+		return "", false
+	}
+	fingerprint, err := insightsFingerprint(insightsCtx, *generator)
+	if err != nil {
+		return "", false
+	}
+	return fingerprint, true
+}
+
+// llmFailed reports whether err means the LLM could not write the insights.
+func llmFailed(err error) bool {
+	return errors.Is(err, llm.ErrLLMUnavailable) ||
+		errors.Is(err, llm.ErrCircuitOpen) ||
+		errors.Is(err, llm.ErrLLMRequest) ||
+		errors.Is(err, llm.ErrLLMResponse)
+}
+
+// loadCached returns the user's cached insights if they have the given
+// fingerprint. Cache failures are logged and treated as a miss.
+func (s *insightsService) loadCached(ctx context.Context, userID uuid.UUID, fingerprint string) *domain.InsightsResponse {
+	entry, err := s.cacheRepo.Get(ctx, userID)
+	if err != nil {
+		if err != domain.ErrNotFound {
+			log.Printf("Insights cache: failed to load user %s: %v", userID, err)
+		}
+		return nil
+	}
+	if entry.Fingerprint != fingerprint {
+		return nil
+	}
+
+	var response domain.InsightsResponse
+	if err := json.Unmarshal([]byte(entry.Response), &response); err != nil {
+		log.Printf("Insights cache: invalid entry of user %s: %v", userID, err)
+		return nil
+	}
+	response.Cached = true
+	return &response
+}
+
+// saveCached stores the response as the user's cached insights. Failures are
+// logged, since the response itself is still valid.
+func (s *insightsService) saveCached(ctx context.Context, userID uuid.UUID, generator domain.InsightsGenerator, fingerprint string, responseJSON []byte, generatedAt time.Time) {
+	entry := &domain.InsightsCacheEntry{
+		UserID:        userID,
+		Fingerprint:   fingerprint,
+		Provider:      generator.Provider,
+		Model:         generator.Model,
+		PromptVersion: generator.PromptVersion,
+		Response:      string(responseJSON),
+		GeneratedAt:   generatedAt,
+	}
+	if err := s.cacheRepo.Save(ctx, entry); err != nil {
+		log.Printf("Insights cache: failed to save user %s: %v", userID, err)
+	}
+}
+
 // computeLastNightMetrics finds the most recent day with sleep data and computes metrics for it.
 func (s *insightsService) computeLastNightMetrics(ctx context.Context, userID uuid.UUID, now time.Time) (*domain.WindowMetrics, error) {
 	// Look back up to 7 days to find the most recent day with sleep
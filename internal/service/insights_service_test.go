@@ -0,0 +1,312 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/llm"
+	"github.com/blaisecz/sleep-tracker/internal/repository"
+	"github.com/google/uuid"
+)
+
+// countingLLM returns fixed insights, counts the calls and describes itself
+// with generator.
+type countingLLM struct {
+	calls     int
+	generator domain.InsightsGenerator
+}
+
+func (c *countingLLM) GenerateInsights(ctx context.Context, insightsCtx *domain.InsightsContext) (*domain.LLMInsightsOutput, error) {
+	c.calls++
+	return &domain.LLMInsightsOutput{
+		Summary:      "Steady sleep.",
+		Observations: []string{"a", "b", "c"},
+		Guidance:     []string{"d", "e", "f"},
+	}, nil
+}
+
+func (c *countingLLM) Describe(ctx context.Context) (domain.InsightsGenerator, error) {
+	return c.generator, nil
+}
+
+// undescribedLLM is a countingLLM that does not describe its generator.
+type undescribedLLM struct {
+	client *countingLLM
+}
+
+func (u undescribedLLM) GenerateInsights(ctx context.Context, insightsCtx *domain.InsightsContext) (*domain.LLMInsightsOutput, error) {
+	return u.client.GenerateInsights(ctx, insightsCtx)
+}
+
+func newTestInsightsService(llmClient, fallback llm.InsightsLLM, cacheRepo *MockInsightsCacheRepository) (InsightsService, *MockSleepLogRepository, uuid.UUID) {
+	repo := NewMockSleepLogRepository()
+	userRepo := NewMockUserRepository()
+	goalRepo := NewMockSleepGoalRepository()
+	metrics := NewMetricsService(repo, userRepo, goalRepo, nil)
+
+	userID := uuid.New()
+	userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for i := 1; i <= 5; i++ {
+		addTestLog(repo, userID, today.AddDate(0, 0, -i).Add(7*time.Hour), 7*time.Hour)
+	}
+
+	// A nil *MockInsightsCacheRepository would not be a nil interface
+	var cache repository.InsightsCacheRepository
+	if cacheRepo != nil {
+		cache = cacheRepo
+	}
+	svc := NewInsightsService(NewChronotypeService(repo, userRepo), metrics, NewAnomalyService(repo, userRepo),
+		NewSleepDebtService(repo, userRepo, goalRepo), llmClient, fallback, repo, userRepo, goalRepo, cache)
+	return svc, repo, userID
+}
+
+func addTestLog(repo *MockSleepLogRepository, userID uuid.UUID, end time.Time, duration time.Duration) {
+	log := &domain.SleepLog{ID: uuid.New(), UserID: userID, StartAt: end.Add(-duration), EndAt: end, Quality: 7, Type: domain.SleepTypeCore}
+	repo.logs[log.ID] = log
+}
+
+func TestInsightsService_Cache(t *testing.T) {
+	ctx := context.Background()
+	client := &countingLLM{generator: domain.InsightsGenerator{Provider: "openai", Model: "gpt-4o-mini", PromptVersion: "v1"}}
+	cacheRepo := NewMockInsightsCacheRepository()
+	svc, repo, userID := newTestInsightsService(client, nil, cacheRepo)
+
+	first, err := svc.Generate(ctx, userID, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.Cached || first.GeneratedAt.IsZero() || client.calls != 1 {
+		t.Fatalf("first call: cached %v, generated at %v, %d LLM calls", first.Cached, first.GeneratedAt, client.calls)
+	}
+	entry := cacheRepo.entries[userID]
+	if entry == nil || entry.Model != "gpt-4o-mini" || entry.PromptVersion != "v1" || len(entry.Fingerprint) != 64 {
+		t.Fatalf("unexpected cache entry: %+v", entry)
+	}
+
+	// Unchanged data is served from the cache
+	second, err := svc.Generate(ctx, userID, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !second.Cached || client.calls != 1 {
+		t.Errorf("second call: cached %v, %d LLM calls", second.Cached, client.calls)
+	}
+	if !second.GeneratedAt.Equal(first.GeneratedAt) || second.Insights.Summary != first.Insights.Summary {
+		t.Errorf("cached response differs: %+v", second)
+	}
+
+	// A refresh regenerates
+	if refreshed, err := svc.Generate(ctx, userID, true); err != nil || refreshed.Cached || client.calls != 2 {
+		t.Errorf("refresh: cached %v, %d LLM calls, err %v", refreshed.Cached, client.calls, err)
+	}
+
+	// New data changes the fingerprint
+	addTestLog(repo, userID, time.Now().UTC().Add(-time.Minute), 6*time.Hour)
+	if changed, err := svc.Generate(ctx, userID, false); err != nil || changed.Cached || client.calls != 3 {
+		t.Errorf("new data: cached %v, %d LLM calls, err %v", changed.Cached, client.calls, err)
+	}
+
+	// So does another prompt version
+	client.generator.PromptVersion = "v2"
+	if changed, err := svc.Generate(ctx, userID, false); err != nil || changed.Cached || client.calls != 4 {
+		t.Errorf("new prompt: cached %v, %d LLM calls, err %v", changed.Cached, client.calls, err)
+	}
+}
+
+func TestInsightsService_NotCached(t *testing.T) {
+	tests := []struct {
+		name      string
+		llm       func(*countingLLM) llm.InsightsLLM
+		cacheRepo *MockInsightsCacheRepository
+	}{
+		{"without cache", func(c *countingLLM) llm.InsightsLLM { return c }, nil},
+		{"without generator", func(c *countingLLM) llm.InsightsLLM { return undescribedLLM{c} }, NewMockInsightsCacheRepository()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &countingLLM{}
+			svc, _, userID := newTestInsightsService(tt.llm(client), nil, tt.cacheRepo)
+
+			for i := 0; i < 2; i++ {
+				response, err := svc.Generate(context.Background(), userID, false)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if response.Cached {
+					t.Error("expected no cached response")
+				}
+			}
+			if client.calls != 2 {
+				t.Errorf("LLM calls = %d, want 2", client.calls)
+			}
+		})
+	}
+}
+
+func TestInsightsFingerprint_IgnoresWindowBounds(t *testing.T) {
+	generator := domain.InsightsGenerator{Provider: "openai", Model: "gpt-4o-mini", PromptVersion: "v1"}
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	insightsCtx := func(now time.Time, lastNight time.Time) *domain.InsightsContext {
+		c := &domain.InsightsContext{SleepDebtHours: 2}
+		c.History.From, c.History.To = now.AddDate(0, 0, -30), now
+		c.Recent.From, c.Recent.To = now.AddDate(0, 0, -7), now
+		c.LastNight.From = lastNight
+		return c
+	}
+
+	base, _ := insightsFingerprint(insightsCtx(now, now), generator)
+	later, _ := insightsFingerprint(insightsCtx(now.Add(time.Hour), now), generator)
+	if base != later {
+		t.Error("moving rolling windows changed the fingerprint")
+	}
+	otherNight, _ := insightsFingerprint(insightsCtx(now, now.AddDate(0, 0, -1)), generator)
+	if base == otherNight {
+		t.Error("another last night kept the fingerprint")
+	}
+	generator.Model = "gpt-4o"
+	otherModel, _ := insightsFingerprint(insightsCtx(now, now), generator)
+	if base == otherModel {
+		t.Error("another model kept the fingerprint")
+	}
+}
+
+func TestInvalidateInsightsOnChange(t *testing.T) {
+	repo := NewMockSleepLogRepository()
+	userRepo := NewMockUserRepository()
+	cacheRepo := NewMockInsightsCacheRepository()
+	svc := InvalidateInsightsOnChange(NewSleepLogService(repo, userRepo, NewMockTagRepository()), cacheRepo)
+
+	userID := uuid.New()
+	userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+	ctx := context.Background()
+
+	start := time.Now().UTC().Add(-10 * time.Hour)
+	req := &domain.CreateSleepLogRequest{
+		StartAt:         start,
+		EndAt:           start.Add(7 * time.Hour),
+		Quality:         7,
+		ClientRequestID: strPtr("night-1"),
+	}
+	created, _, err := svc.Create(ctx, userID, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cacheRepo.deletes != 1 {
+		t.Errorf("create: %d invalidations, want 1", cacheRepo.deletes)
+	}
+
+	// Replaying the same request changes nothing
+	if _, existing, err := svc.Create(ctx, userID, req); err != nil || !existing || cacheRepo.deletes != 1 {
+		t.Errorf("replay: existing %v, %d invalidations, err %v", existing, cacheRepo.deletes, err)
+	}
+
+	// Failed changes keep the cache
+	if _, err := svc.Update(ctx, userID, uuid.New(), &domain.UpdateSleepLogRequest{Quality: intPtr(8)}, 0); !errors.Is(err, domain.ErrNotFound) || cacheRepo.deletes != 1 {
+		t.Errorf("failed update: %d invalidations, err %v", cacheRepo.deletes, err)
+	}
+
+	if _, err := svc.Update(ctx, userID, created.ID, &domain.UpdateSleepLogRequest{Quality: intPtr(8)}, 0); err != nil || cacheRepo.deletes != 2 {
+		t.Errorf("update: %d invalidations, err %v", cacheRepo.deletes, err)
+	}
+	if _, err := svc.Delete(ctx, userID, created.ID, 0); err != nil || cacheRepo.deletes != 3 {
+		t.Errorf("delete: %d invalidations, err %v", cacheRepo.deletes, err)
+	}
+}
+
+// failingLLM fails every call with err.
+type failingLLM struct {
+	err error
+}
+
+func (f failingLLM) GenerateInsights(context.Context, *domain.InsightsContext) (*domain.LLMInsightsOutput, error) {
+	return nil, f.err
+}
+
+func TestInsightsService_Fallback(t *testing.T) {
+	errDatabase := errors.New("database gone")
+
+	tests := []struct {
+		name          string
+		llmClient     llm.InsightsLLM
+		fallback      llm.InsightsLLM
+		wantErr       error
+		wantGenerator string
+	}{
+		{
+			name:          "LLM used when it answers",
+			llmClient:     &countingLLM{generator: domain.InsightsGenerator{Provider: "openai", Model: "gpt-4o-mini", PromptVersion: "v1"}},
+			fallback:      llm.NewRulesClient(),
+			wantGenerator: "openai",
+		},
+		{
+			name:          "LLM not configured",
+			llmClient:     llm.Unavailable(nil),
+			fallback:      llm.NewRulesClient(),
+			wantGenerator: llm.ProviderRules,
+		},
+		{
+			name:          "circuit open",
+			llmClient:     failingLLM{err: llm.ErrCircuitOpen},
+			fallback:      llm.NewRulesClient(),
+			wantGenerator: llm.ProviderRules,
+		},
+		{
+			name:          "invalid LLM output",
+			llmClient:     failingLLM{err: llm.ErrLLMResponse},
+			fallback:      llm.NewRulesClient(),
+			wantGenerator: llm.ProviderRules,
+		},
+		{
+			name:      "other errors are returned",
+			llmClient: failingLLM{err: errDatabase},
+			fallback:  llm.NewRulesClient(),
+			wantErr:   errDatabase,
+		},
+		{
+			name:      "without fallback",
+			llmClient: llm.Unavailable(nil),
+			wantErr:   llm.ErrLLMUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cacheRepo := NewMockInsightsCacheRepository()
+			svc, _, userID := newTestInsightsService(tt.llmClient, tt.fallback, cacheRepo)
+
+			response, err := svc.Generate(context.Background(), userID, false)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.Generator == nil || response.Generator.Provider != tt.wantGenerator {
+				t.Errorf("generator = %+v, want %s", response.Generator, tt.wantGenerator)
+			}
+			if err := validateInsights(response.Insights); err != nil {
+				t.Errorf("invalid insights: %v", err)
+			}
+
+			// Only LLM insights are cached, so the LLM is tried again
+			if _, cached := cacheRepo.entries[userID]; cached != (tt.wantGenerator != llm.ProviderRules) {
+				t.Errorf("cached = %v for generator %s", cached, tt.wantGenerator)
+			}
+		})
+	}
+}
+
+func validateInsights(output domain.LLMInsightsOutput) error {
+	if output.Summary == "" || len(output.Observations) < 3 || len(output.Guidance) < 3 {
+		return errors.New("missing summary, observations or guidance")
+	}
+	return nil
+}
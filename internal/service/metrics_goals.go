@@ -0,0 +1,89 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+)
+
+// WakeGoalToleranceMinutes is how far from the goal's wake time a sleep may
+// end and still meet it.
+const WakeGoalToleranceMinutes = 30
+
+// goalTimeline holds the versions of a user's goals, oldest first.
+type goalTimeline []domain.SleepGoal
+
+// at returns the goal in force at t, or the default goal of DefaultTargetHours.
+func (t goalTimeline) at(at time.Time) domain.SleepGoal {
+	for i := len(t) - 1; i >= 0; i-- {
+		if t[i].InForce(at) {
+			return t[i]
+		}
+	}
+	return domain.SleepGoal{TargetHours: DefaultTargetHours}
+}
+
+// computeGoalScores returns the percentage of core sleeps that started within
+// the bedtime window and that ended within WakeGoalToleranceMinutes of the
+// wake time of the goal in force when they ended. Logs are filtered like in
+// computePerSleepMetrics. A score is nil when no sleep had such a goal.
+func computeGoalScores(logs []domain.SleepLog, goals goalTimeline) (*float64, *float64) {
+	var bedtimeHits, bedtimeNights, wakeHits, wakeNights int
+	for _, log := range logs {
+		if log.Type == domain.SleepTypeNap {
+			continue
+		}
+		data, ok := perSleepData(log)
+		if !ok {
+			continue
+		}
+
+		goal := goals.at(log.EndAt)
+		if goal.BedtimeWindowStart != nil && goal.BedtimeWindowEnd != nil {
+			bedtimeNights++
+			if inClockWindow(data.bedtimeMinutes, clockTimeMinutes(*goal.BedtimeWindowStart), clockTimeMinutes(*goal.BedtimeWindowEnd)) {
+				bedtimeHits++
+			}
+		}
+		if goal.WakeTime != nil {
+			wakeNights++
+			wake := clockMinutes(data.bedtimeMinutes + int(math.Round(data.durationHours*60)))
+			if abs(signedClockMinutes(wake-clockTimeMinutes(*goal.WakeTime))) <= WakeGoalToleranceMinutes {
+				wakeHits++
+			}
+		}
+	}
+	return goalPercent(bedtimeHits, bedtimeNights), goalPercent(wakeHits, wakeNights)
+}
+
+func goalPercent(hits, nights int) *float64 {
+	if nights == 0 {
+		return nil
+	}
+	percent := math.Round(float64(hits)/float64(nights)*1000) / 10
+	return &percent
+}
+
+// inClockWindow reports whether the clock minute lies in [start, end], where
+// the window may wrap around midnight.
+func inClockWindow(minute, start, end int) bool {
+	return clockMinutes(minute-start) <= clockMinutes(end-start)
+}
+
+// clockTimeMinutes converts a validated "HH:MM" clock time to minutes after
+// midnight.
+func clockTimeMinutes(clock string) int {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0
+	}
+	return t.Hour()*60 + t.Minute()
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
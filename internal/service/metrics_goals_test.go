@@ -0,0 +1,89 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+func TestComputeDailyOverallMetrics_GoalHistory(t *testing.T) {
+	base := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	change := base.AddDate(0, 0, 3).Add(12 * time.Hour)
+	goals := goalTimeline{
+		{TargetHours: 9, EffectiveFrom: base.AddDate(0, -1, 0), EffectiveTo: &change},
+		{TargetHours: 7.5, NapAllowanceMinutes: intPtr(30), EffectiveFrom: change},
+	}
+
+	// Eight hours each night, plus a one-hour nap on the last day
+	logs := nightLogs(base, []time.Duration{23 * time.Hour, 23 * time.Hour, 23 * time.Hour, 23 * time.Hour, 23 * time.Hour, 23 * time.Hour}, 8*time.Hour)
+	napStart := base.AddDate(0, 0, 6).Add(14 * time.Hour)
+	logs = append(logs, domain.SleepLog{ID: uuid.New(), StartAt: napStart, EndAt: napStart.Add(time.Hour), Type: domain.SleepTypeNap})
+
+	got := computeDailyOverallMetrics(logs, goals, base.AddDate(0, 0, 7))
+
+	// Days ending on 2-4 March fall under the 9-hour goal, 5-7 March under the 7.5-hour goal
+	if got.TargetHours != 7.5 || got.DaysCount != 6 || got.DaysMeetingTarget != 3 {
+		t.Errorf("got target %v, %d days, %d meeting target; want 7.5, 6, 3", got.TargetHours, got.DaysCount, got.DaysMeetingTarget)
+	}
+	if got.DaysOverNapAllowance == nil || *got.DaysOverNapAllowance != 1 {
+		t.Errorf("DaysOverNapAllowance = %v, want 1", got.DaysOverNapAllowance)
+	}
+
+	// Without goals the default target applies and naps are not tracked
+	got = computeDailyOverallMetrics(logs, nil, base.AddDate(0, 0, 7))
+	if got.TargetHours != DefaultTargetHours || got.DaysMeetingTarget != 6 || got.DaysOverNapAllowance != nil {
+		t.Errorf("unexpected metrics without goals: %+v", got)
+	}
+}
+
+func TestComputeGoalScores(t *testing.T) {
+	base := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	goals := goalTimeline{{
+		TargetHours:        8,
+		BedtimeWindowStart: strPtr("23:30"),
+		BedtimeWindowEnd:   strPtr("00:30"),
+		WakeTime:           strPtr("08:00"),
+		EffectiveFrom:      base.AddDate(0, -1, 0),
+	}}
+
+	// Bedtimes 23:45, 00:15, 01:00 and 22:00, eight hours each
+	offsets := []time.Duration{23*time.Hour + 45*time.Minute, 24*time.Hour + 15*time.Minute, 25 * time.Hour, 22 * time.Hour}
+	logs := nightLogs(base, offsets, 8*time.Hour)
+
+	bedtime, wake := computeGoalScores(logs, goals)
+
+	if bedtime == nil || *bedtime != 50 {
+		t.Errorf("bedtime goal score = %v, want 50", deref(bedtime))
+	}
+	// Wake times 07:45, 08:15, 09:00 and 06:00
+	if wake == nil || *wake != 50 {
+		t.Errorf("wake goal score = %v, want 50", deref(wake))
+	}
+
+	bedtime, wake = computeGoalScores(logs, nil)
+	if bedtime != nil || wake != nil {
+		t.Errorf("expected no goal scores without goals, got %v / %v", deref(bedtime), deref(wake))
+	}
+}
+
+func TestComputeDerivedScores_GoalTarget(t *testing.T) {
+	perSleep := domain.PerSleepMetrics{SleepCount: 5, Duration: domain.DescriptiveStats{Avg: 8}}
+
+	tests := []struct {
+		target float64
+		want   float64
+	}{
+		{DefaultTargetHours, 75},
+		{8, 50},
+		{10, 0},
+	}
+
+	for _, tt := range tests {
+		daily := domain.DailyOverallMetrics{TargetHours: tt.target}
+		if got := DefaultScoringModel().Score(perSleep, daily, nil); got.SufficiencyScore != tt.want {
+			t.Errorf("target %v: SufficiencyScore = %v, want %v", tt.target, got.SufficiencyScore, tt.want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+func (s *metricsService) Compute(ctx context.Context, userID uuid.UUID, req domain.MetricsRequest) (*domain.MetricsResponse, error) {
+	// Apply defaults
+	if req.WindowDays <= 0 {
+		req.WindowDays = DefaultMetricsWindowDays
+	}
+	if req.Period == "" {
+		req.Period = domain.MetricsPeriodRolling
+	}
+
+	// Periods follow the user's calendar
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	loc := time.UTC
+	if l, err := time.LoadLocation(user.Timezone); err == nil {
+		loc = l
+	}
+
+	current, previous := resolveMetricsPeriod(req, loc, time.Now())
+
+	windowMetrics, err := s.ComputeWindow(ctx, userID, current.from, current.to)
+	if err != nil {
+		return nil, err
+	}
+	response := metricsResponse(windowMetrics, current.period)
+
+	if req.Compare {
+		previousMetrics, err := s.ComputeWindow(ctx, userID, previous.from, previous.to)
+		if err != nil {
+			return nil, err
+		}
+		// Factor comparisons are left to the current period
+		previousMetrics.Factors = nil
+		response.Previous = &domain.MetricsComparison{
+			Period:  previous.period,
+			Metrics: *previousMetrics,
+			Deltas:  computeMetricsDeltas(windowMetrics, previousMetrics),
+		}
+	}
+
+	return response, nil
+}
+
+func metricsResponse(windowMetrics *domain.WindowMetrics, period domain.MetricsPeriod) *domain.MetricsResponse {
+	response := &domain.MetricsResponse{
+		PerSleep:     windowMetrics.PerSleep,
+		DailyOverall: windowMetrics.DailyOverall,
+		Scores:       windowMetrics.Scores,
+		Regularity:   windowMetrics.Regularity,
+		Factors:      windowMetrics.Factors,
+		Period:       period,
+	}
+	response.Window.From = windowMetrics.From
+	response.Window.To = windowMetrics.To
+	return response
+}
+
+// metricsWindow is a resolved metrics period.
+type metricsWindow struct {
+	period   domain.MetricsPeriod
+	from, to time.Time
+}
+
+// resolveMetricsPeriod returns the window of the requested period and of the
+// period before it. Calendar periods run from local midnight to local
+// midnight; the end is capped at now, so the current week or month covers
+// the days so far. A rolling period without AsOf keeps the plain
+// "last window_days days from now", as before calendar periods.
+func resolveMetricsPeriod(req domain.MetricsRequest, loc *time.Location, now time.Time) (metricsWindow, metricsWindow) {
+	localNow := now.In(loc)
+	asOf := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, loc)
+	if req.AsOf != nil {
+		asOf = localDate(*req.AsOf, loc)
+	}
+
+	var start, end, previousStart time.Time
+	switch req.Period {
+	case domain.MetricsPeriodWeek:
+		// ISO weeks start on Monday
+		start = asOf.AddDate(0, 0, -((int(asOf.Weekday()) + 6) % 7))
+		end = start.AddDate(0, 0, 7)
+		previousStart = start.AddDate(0, 0, -7)
+	case domain.MetricsPeriodMonth:
+		start = time.Date(asOf.Year(), asOf.Month(), 1, 0, 0, 0, 0, loc)
+		end = start.AddDate(0, 1, 0)
+		previousStart = start.AddDate(0, -1, 0)
+	case domain.MetricsPeriodCustom:
+		start = localDate(*req.From, loc)
+		end = localDate(*req.To, loc).AddDate(0, 0, 1)
+		previousStart = start.AddDate(0, 0, -daysBetween(start, end))
+	default:
+		if req.AsOf == nil {
+			to := now.UTC()
+			from := to.AddDate(0, 0, -req.WindowDays)
+			previousFrom := from.AddDate(0, 0, -req.WindowDays)
+			return rollingWindow(from, to, loc), rollingWindow(previousFrom, from, loc)
+		}
+		end = asOf.AddDate(0, 0, 1)
+		start = end.AddDate(0, 0, -req.WindowDays)
+		previousStart = start.AddDate(0, 0, -req.WindowDays)
+	}
+
+	return calendarWindow(req.Period, start, end, now), calendarWindow(req.Period, previousStart, start, now)
+}
+
+// calendarWindow covers the local days from start up to end (exclusive).
+func calendarWindow(periodType domain.MetricsPeriodType, start, end, now time.Time) metricsWindow {
+	to := end
+	if to.After(now) {
+		to = now
+	}
+	if to.Before(start) {
+		to = start
+	}
+	return metricsWindow{
+		period: domain.MetricsPeriod{
+			Type:      periodType,
+			Timezone:  start.Location().String(),
+			StartDate: start.Format("2006-01-02"),
+			EndDate:   end.AddDate(0, 0, -1).Format("2006-01-02"),
+		},
+		from: start.UTC(),
+		to:   to.UTC(),
+	}
+}
+
+func rollingWindow(from, to time.Time, loc *time.Location) metricsWindow {
+	return metricsWindow{
+		period: domain.MetricsPeriod{
+			Type:      domain.MetricsPeriodRolling,
+			Timezone:  loc.String(),
+			StartDate: from.In(loc).Format("2006-01-02"),
+			EndDate:   to.In(loc).Format("2006-01-02"),
+		},
+		from: from,
+		to:   to,
+	}
+}
+
+// localDate returns local midnight of the calendar date of d.
+func localDate(d time.Time, loc *time.Location) time.Time {
+	return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, loc)
+}
+
+// daysBetween counts the calendar days from start to end, ignoring DST.
+func daysBetween(start, end time.Time) int {
+	s := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	e := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	return int(e.Sub(s).Hours() / 24)
+}
+
+// computeMetricsDeltas subtracts the previous period's values from the
+// current ones. Per-sleep deltas and scores need sleeps in both periods.
+func computeMetricsDeltas(current, previous *domain.WindowMetrics) domain.MetricsDeltas {
+	deltas := domain.MetricsDeltas{
+		SleepCount: current.PerSleep.SleepCount - previous.PerSleep.SleepCount,
+	}
+
+	if current.PerSleep.SleepCount > 0 && previous.PerSleep.SleepCount > 0 {
+		deltas.AvgDurationHours = delta(current.PerSleep.Duration.Avg, previous.PerSleep.Duration.Avg)
+		deltas.AvgQuality = delta(current.PerSleep.Quality.Avg, previous.PerSleep.Quality.Avg)
+		deltas.BedtimeStdMinutes = delta(current.PerSleep.Bedtime.Std, previous.PerSleep.Bedtime.Std)
+		deltas.ConsistencyScore = delta(current.Scores.ConsistencyScore, previous.Scores.ConsistencyScore)
+		deltas.SufficiencyScore = delta(current.Scores.SufficiencyScore, previous.Scores.SufficiencyScore)
+		deltas.OverallSleepScore = delta(current.Scores.OverallSleepScore, previous.Scores.OverallSleepScore)
+	}
+	if current.DailyOverall.DaysCount > 0 && previous.DailyOverall.DaysCount > 0 {
+		deltas.AvgTotalDailyHours = delta(current.DailyOverall.TotalDailyHours.Avg, previous.DailyOverall.TotalDailyHours.Avg)
+		deltas.DailySufficiencyScore = delta(current.DailyOverall.DailySufficiencyScore, previous.DailyOverall.DailySufficiencyScore)
+	}
+	if current.Regularity.SRI != nil && previous.Regularity.SRI != nil {
+		deltas.SleepRegularityIndex = delta(*current.Regularity.SRI, *previous.Regularity.SRI)
+	}
+
+	return deltas
+}
+
+func delta(current, previous float64) *float64 {
+	d := round2(current - previous)
+	return &d
+}
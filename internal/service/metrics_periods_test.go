@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+func TestResolveMetricsPeriod(t *testing.T) {
+	prague, err := time.LoadLocation("Europe/Prague")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+	// Wednesday 2024-03-20 10:00 in Prague
+	now := time.Date(2024, 3, 20, 9, 0, 0, 0, time.UTC)
+	date := func(s string) *time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return &d
+	}
+
+	tests := []struct {
+		name                 string
+		req                  domain.MetricsRequest
+		wantStart, wantEnd   string
+		wantFrom, wantTo     time.Time
+		wantPrevStart        string
+		wantPrevEnd          string
+		wantPrevFrom, prevTo time.Time
+	}{
+		{
+			name:          "this ISO week runs until now",
+			req:           domain.MetricsRequest{Period: domain.MetricsPeriodWeek},
+			wantStart:     "2024-03-18",
+			wantEnd:       "2024-03-24",
+			wantFrom:      time.Date(2024, 3, 17, 23, 0, 0, 0, time.UTC),
+			wantTo:        now,
+			wantPrevStart: "2024-03-11",
+			wantPrevEnd:   "2024-03-17",
+			wantPrevFrom:  time.Date(2024, 3, 10, 23, 0, 0, 0, time.UTC),
+			prevTo:        time.Date(2024, 3, 17, 23, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "last calendar month across the DST change",
+			req:           domain.MetricsRequest{Period: domain.MetricsPeriodMonth, AsOf: date("2024-02-10")},
+			wantStart:     "2024-02-01",
+			wantEnd:       "2024-02-29",
+			wantFrom:      time.Date(2024, 1, 31, 23, 0, 0, 0, time.UTC),
+			wantTo:        time.Date(2024, 2, 29, 23, 0, 0, 0, time.UTC),
+			wantPrevStart: "2024-01-01",
+			wantPrevEnd:   "2024-01-31",
+			wantPrevFrom:  time.Date(2023, 12, 31, 23, 0, 0, 0, time.UTC),
+			prevTo:        time.Date(2024, 1, 31, 23, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "custom range",
+			req:           domain.MetricsRequest{Period: domain.MetricsPeriodCustom, From: date("2024-03-25"), To: date("2024-04-03")},
+			wantStart:     "2024-03-25",
+			wantEnd:       "2024-04-03",
+			wantFrom:      time.Date(2024, 3, 24, 23, 0, 0, 0, time.UTC),
+			wantTo:        time.Date(2024, 3, 24, 23, 0, 0, 0, time.UTC), // in the future
+			wantPrevStart: "2024-03-15",
+			wantPrevEnd:   "2024-03-24",
+			wantPrevFrom:  time.Date(2024, 3, 14, 23, 0, 0, 0, time.UTC),
+			prevTo:        now,
+		},
+		{
+			name:          "rolling window ending on as_of",
+			req:           domain.MetricsRequest{Period: domain.MetricsPeriodRolling, WindowDays: 7, AsOf: date("2024-03-10")},
+			wantStart:     "2024-03-04",
+			wantEnd:       "2024-03-10",
+			wantFrom:      time.Date(2024, 3, 3, 23, 0, 0, 0, time.UTC),
+			wantTo:        time.Date(2024, 3, 10, 23, 0, 0, 0, time.UTC),
+			wantPrevStart: "2024-02-26",
+			wantPrevEnd:   "2024-03-03",
+			wantPrevFrom:  time.Date(2024, 2, 25, 23, 0, 0, 0, time.UTC),
+			prevTo:        time.Date(2024, 3, 3, 23, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "rolling window from now",
+			req:           domain.MetricsRequest{Period: domain.MetricsPeriodRolling, WindowDays: 7},
+			wantStart:     "2024-03-13",
+			wantEnd:       "2024-03-20",
+			wantFrom:      now.AddDate(0, 0, -7),
+			wantTo:        now,
+			wantPrevStart: "2024-03-06",
+			wantPrevEnd:   "2024-03-13",
+			wantPrevFrom:  now.AddDate(0, 0, -14),
+			prevTo:        now.AddDate(0, 0, -7),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, previous := resolveMetricsPeriod(tt.req, prague, now)
+
+			if current.period.StartDate != tt.wantStart || current.period.EndDate != tt.wantEnd {
+				t.Errorf("period = %s..%s, want %s..%s", current.period.StartDate, current.period.EndDate, tt.wantStart, tt.wantEnd)
+			}
+			if !current.from.Equal(tt.wantFrom) || !current.to.Equal(tt.wantTo) {
+				t.Errorf("window = %v..%v, want %v..%v", current.from, current.to, tt.wantFrom, tt.wantTo)
+			}
+			if previous.period.StartDate != tt.wantPrevStart || previous.period.EndDate != tt.wantPrevEnd {
+				t.Errorf("previous period = %s..%s, want %s..%s", previous.period.StartDate, previous.period.EndDate, tt.wantPrevStart, tt.wantPrevEnd)
+			}
+			if !previous.from.Equal(tt.wantPrevFrom) || !previous.to.Equal(tt.prevTo) {
+				t.Errorf("previous window = %v..%v, want %v..%v", previous.from, previous.to, tt.wantPrevFrom, tt.prevTo)
+			}
+			if current.period.Timezone != "Europe/Prague" || current.period.Type != tt.req.Period {
+				t.Errorf("unexpected period: %+v", current.period)
+			}
+		})
+	}
+}
+
+func TestMetricsService_Compute_Compare(t *testing.T) {
+	repo := NewMockSleepLogRepository()
+	userRepo := NewMockUserRepository()
+	svc := NewMetricsService(repo, userRepo, NewMockSleepGoalRepository(), nil)
+
+	userID := uuid.New()
+	userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+
+	// Six-hour nights in January, eight-hour nights in February
+	for day := 1; day <= 20; day++ {
+		for month, hours := range map[time.Month]int{time.January: 6, time.February: 8} {
+			start := time.Date(2024, month, day, 23, 0, 0, 0, time.UTC)
+			log := &domain.SleepLog{ID: uuid.New(), UserID: userID, StartAt: start, EndAt: start.Add(time.Duration(hours) * time.Hour), Quality: hours, Type: domain.SleepTypeCore}
+			repo.logs[log.ID] = log
+		}
+	}
+
+	asOf := time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC)
+	result, err := svc.Compute(context.Background(), userID, domain.MetricsRequest{Period: domain.MetricsPeriodMonth, AsOf: &asOf, Compare: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Period.StartDate != "2024-02-01" || result.Period.EndDate != "2024-02-29" {
+		t.Errorf("unexpected period: %+v", result.Period)
+	}
+	if result.PerSleep.SleepCount != 20 || result.PerSleep.Duration.Avg != 8 {
+		t.Errorf("current: %d sleeps averaging %v hours", result.PerSleep.SleepCount, result.PerSleep.Duration.Avg)
+	}
+	if result.Previous == nil {
+		t.Fatal("expected the previous period")
+	}
+	if result.Previous.Period.StartDate != "2024-01-01" || result.Previous.Metrics.PerSleep.Duration.Avg != 6 {
+		t.Errorf("unexpected previous period: %+v, avg %v", result.Previous.Period, result.Previous.Metrics.PerSleep.Duration.Avg)
+	}
+
+	deltas := result.Previous.Deltas
+	if deltas.AvgDurationHours == nil || *deltas.AvgDurationHours != 2 {
+		t.Errorf("AvgDurationHours delta = %v, want 2", deref(deltas.AvgDurationHours))
+	}
+	if deltas.AvgQuality == nil || *deltas.AvgQuality != 2 {
+		t.Errorf("AvgQuality delta = %v, want 2", deref(deltas.AvgQuality))
+	}
+	if deltas.SleepCount != 0 || deltas.OverallSleepScore == nil || *deltas.OverallSleepScore <= 0 {
+		t.Errorf("unexpected deltas: %+v", deltas)
+	}
+}
+
+func TestComputeMetricsDeltas_MissingData(t *testing.T) {
+	sri := 80.0
+	current := &domain.WindowMetrics{
+		PerSleep:   domain.PerSleepMetrics{SleepCount: 3, Duration: domain.DescriptiveStats{Avg: 7}},
+		Regularity: domain.SleepRegularity{SRI: &sri},
+	}
+	previous := &domain.WindowMetrics{}
+
+	deltas := computeMetricsDeltas(current, previous)
+
+	if deltas.SleepCount != 3 {
+		t.Errorf("SleepCount delta = %d, want 3", deltas.SleepCount)
+	}
+	if deltas.AvgDurationHours != nil || deltas.OverallSleepScore != nil || deltas.SleepRegularityIndex != nil || deltas.AvgTotalDailyHours != nil {
+		t.Errorf("expected no value deltas without previous data: %+v", deltas)
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func (s *metricsService) CompareScores(ctx context.Context, userID uuid.UUID, windowDays int, baseline, candidate ScoringModel) (*domain.ScoreComparisonResponse, error) {
+	tracer := otel.Tracer("sleep-tracker-api/metrics")
+	ctx, span := tracer.Start(ctx, "MetricsService.CompareScores")
+	defer span.End()
+
+	// Apply defaults
+	if windowDays <= 0 {
+		windowDays = DefaultMetricsWindowDays
+	}
+	if baseline == nil {
+		baseline = s.scoringModel
+	}
+	span.SetAttributes(
+		attribute.String("user.id", userID.String()),
+		attribute.Int("window_days", windowDays),
+		attribute.String("scoring.baseline", baseline.Name()+"@"+baseline.Version()),
+		attribute.String("scoring.candidate", candidate.Name()+"@"+candidate.Version()),
+	)
+
+	now := time.Now().UTC()
+	window, err := s.ComputeWindow(ctx, userID, now.AddDate(0, 0, -windowDays), now)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &domain.ScoreComparisonResponse{
+		Baseline:  rescore(window, baseline),
+		Candidate: rescore(window, candidate),
+	}
+	response.Window.From = window.From
+	response.Window.To = window.To
+	response.OverallDiff = round1(response.Candidate.OverallSleepScore - response.Baseline.OverallSleepScore)
+
+	return response, nil
+}
+
+// rescore applies model to the metrics of a window. The goal scores do not
+// depend on the model and are carried over.
+func rescore(window *domain.WindowMetrics, model ScoringModel) domain.DerivedScores {
+	scores := model.Score(window.PerSleep, window.DailyOverall, window.Regularity.SRI)
+	scores.BedtimeGoalScore = window.Scores.BedtimeGoalScore
+	scores.WakeGoalScore = window.Scores.WakeGoalScore
+	return scores
+}
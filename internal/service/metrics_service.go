@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"sort"
 	"time"
 
 	"github.com/blaisecz/sleep-tracker/internal/domain"
@@ -25,62 +26,40 @@ const (
 
 // MetricsService computes sleep metrics from sleep logs.
 type MetricsService interface {
-	// Compute calculates metrics for a user over the given window.
-	Compute(ctx context.Context, userID uuid.UUID, windowDays int) (*domain.MetricsResponse, error)
+	// Compute calculates metrics for a user over the requested period, and
+	// for the previous period in comparison mode.
+	Compute(ctx context.Context, userID uuid.UUID, req domain.MetricsRequest) (*domain.MetricsResponse, error)
 	// ComputeWindow calculates WindowMetrics for a specific time range.
 	ComputeWindow(ctx context.Context, userID uuid.UUID, from, to time.Time) (*domain.WindowMetrics, error)
+	// Trends returns a per-day series of a metric with moving averages,
+	// ending today in the user's timezone.
+	Trends(ctx context.Context, userID uuid.UUID, metric domain.TrendMetric, window, spanDays int) (*domain.TrendsResponse, error)
+	// CompareScores scores the same window with two models. A nil baseline
+	// uses the active model.
+	CompareScores(ctx context.Context, userID uuid.UUID, windowDays int, baseline, candidate ScoringModel) (*domain.ScoreComparisonResponse, error)
 }
 
 type metricsService struct {
 	sleepLogRepo repository.SleepLogRepository
 	userRepo     repository.UserRepository
+	goalRepo     repository.SleepGoalRepository
+	scoringModel ScoringModel
 }
 
-// NewMetricsService creates a new MetricsService.
-func NewMetricsService(sleepLogRepo repository.SleepLogRepository, userRepo repository.UserRepository) MetricsService {
+// NewMetricsService creates a new MetricsService. scoringModel computes the
+// derived scores; nil uses DefaultScoringModel.
+func NewMetricsService(sleepLogRepo repository.SleepLogRepository, userRepo repository.UserRepository, goalRepo repository.SleepGoalRepository, scoringModel ScoringModel) MetricsService {
+	if scoringModel == nil {
+		scoringModel = DefaultScoringModel()
+	}
 	return &metricsService{
 		sleepLogRepo: sleepLogRepo,
 		userRepo:     userRepo,
+		goalRepo:     goalRepo,
+		scoringModel: scoringModel,
 	}
 }
 
-func (s *metricsService) Compute(ctx context.Context, userID uuid.UUID, windowDays int) (*domain.MetricsResponse, error) {
-	// Validate user exists
-	exists, err := s.userRepo.Exists(ctx, userID)
-	if err != nil {
-		return nil, err
-	}
-	if !exists {
-		return nil, domain.ErrNotFound
-	}
-
-	// Apply defaults
-	if windowDays <= 0 {
-		windowDays = DefaultMetricsWindowDays
-	}
-
-	// Calculate time window
-	now := time.Now().UTC()
-	from := now.AddDate(0, 0, -windowDays)
-
-	// Compute window metrics
-	windowMetrics, err := s.ComputeWindow(ctx, userID, from, now)
-	if err != nil {
-		return nil, err
-	}
-
-	// Build response
-	response := &domain.MetricsResponse{
-		PerSleep:     windowMetrics.PerSleep,
-		DailyOverall: windowMetrics.DailyOverall,
-		Scores:       windowMetrics.Scores,
-	}
-	response.Window.From = windowMetrics.From
-	response.Window.To = windowMetrics.To
-
-	return response, nil
-}
-
 func (s *metricsService) ComputeWindow(ctx context.Context, userID uuid.UUID, from, to time.Time) (*domain.WindowMetrics, error) {
 	tracer := otel.Tracer("sleep-tracker-api/metrics")
 	ctx, span := tracer.Start(ctx, "MetricsService.ComputeWindow",
@@ -114,6 +93,23 @@ func (s *metricsService) ComputeWindow(ctx context.Context, userID uuid.UUID, fr
 		span.SetAttributes(attribute.String("langfuse.observation.input", string(inputJSON)))
 	}
 
+	// Load the user for the timezone of the sleep/wake series
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	loc := time.UTC
+	if l, err := time.LoadLocation(user.Timezone); err == nil {
+		loc = l
+	}
+
+	// Load the goal history, so each day is scored against the goal in force
+	goalHistory, err := s.goalRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	goals := goalTimeline(goalHistory)
+
 	// Fetch sleep logs in the window (by EndAt)
 	logs, err := s.sleepLogRepo.ListByEndRange(ctx, userID, from, to)
 	if err != nil {
@@ -129,10 +125,17 @@ func (s *metricsService) ComputeWindow(ctx context.Context, userID uuid.UUID, fr
 	result.PerSleep = computePerSleepMetrics(logs)
 
 	// Calculate per-day metrics
-	result.DailyOverall = computeDailyOverallMetrics(logs)
+	result.DailyOverall = computeDailyOverallMetrics(logs, goals, to)
+
+	// Calculate the Sleep Regularity Index from minute-level sleep/wake data
+	result.Regularity = computeSleepRegularity(logs, from, to, loc)
 
 	// Calculate derived scores
-	result.Scores = computeDerivedScores(result.PerSleep, result.DailyOverall)
+	result.Scores = s.scoringModel.Score(result.PerSleep, result.DailyOverall, result.Regularity.SRI)
+	result.Scores.BedtimeGoalScore, result.Scores.WakeGoalScore = computeGoalScores(logs, goals)
+
+	// Compare sleep with and without each factor
+	result.Factors = computeFactorImpacts(logs)
 
 	// Attach output payload for Langfuse
 	if outputJSON, err := json.Marshal(result); err == nil {
@@ -144,10 +147,12 @@ func (s *metricsService) ComputeWindow(ctx context.Context, userID uuid.UUID, fr
 
 // sleepData holds extracted data from a single sleep log.
 type sleepData struct {
-	durationHours  float64
-	bedtimeMinutes int
-	quality        int
-	localDate      string // YYYY-MM-DD format for grouping
+	durationHours   float64
+	bedtimeMinutes  int
+	midSleepMinutes float64 // midpoint of the sleep in minutes after midnight
+	quality         int
+	localDate       string // YYYY-MM-DD format for grouping
+	nightWeekday    time.Weekday
 }
 
 // extractSleepData extracts relevant data from a sleep log.
@@ -166,18 +171,36 @@ func extractSleepData(log domain.SleepLog) sleepData {
 	// Bedtime is minutes after midnight of the start time
 	bedtimeMinutes := startLocal.Hour()*60 + startLocal.Minute()
 
+	midSleepMinutes := clockValue(float64(bedtimeMinutes) + durationMinutes/2)
+
 	// Local date is based on EndAt (the day the sleep "belongs to")
 	localDate := endLocal.Format("2006-01-02")
 
 	return sleepData{
-		durationHours:  durationMinutes / 60.0,
-		bedtimeMinutes: bedtimeMinutes,
-		quality:        log.Quality,
-		localDate:      localDate,
+		durationHours:   durationMinutes / 60.0,
+		bedtimeMinutes:  bedtimeMinutes,
+		midSleepMinutes: midSleepMinutes,
+		quality:         log.Quality,
+		localDate:       localDate,
+		nightWeekday:    nightWeekday(startLocal),
 	}
 }
 
+// nightWeekday returns the weekday of the evening a sleep starting at the
+// local time belongs to. Sleep starting before noon belongs to the previous
+// evening.
+func nightWeekday(startLocal time.Time) time.Weekday {
+	return startLocal.Add(-12 * time.Hour).Weekday()
+}
+
 // computePerSleepMetrics calculates per-sleep statistics.
+// perSleepData extracts the measures of a log and reports whether it counts
+// towards the per-sleep metrics. Extremely short logs (< 90 minutes) do not.
+func perSleepData(log domain.SleepLog) (sleepData, bool) {
+	data := extractSleepData(log)
+	return data, data.durationHours >= float64(MinDurationMinutes)/60.0
+}
+
 func computePerSleepMetrics(logs []domain.SleepLog) domain.PerSleepMetrics {
 	result := domain.PerSleepMetrics{}
 
@@ -188,18 +211,18 @@ func computePerSleepMetrics(logs []domain.SleepLog) domain.PerSleepMetrics {
 	var durations []float64
 	var qualities []float64
 	var bedtimes []float64
+	var midSleeps []float64
 
 	for _, log := range logs {
-		data := extractSleepData(log)
-
-		// Filter out extremely short logs (< 90 minutes = 1.5 hours)
-		if data.durationHours < float64(MinDurationMinutes)/60.0 {
+		data, ok := perSleepData(log)
+		if !ok {
 			continue
 		}
 
 		durations = append(durations, data.durationHours)
 		qualities = append(qualities, float64(data.quality))
 		bedtimes = append(bedtimes, float64(data.bedtimeMinutes))
+		midSleeps = append(midSleeps, data.midSleepMinutes)
 	}
 
 	result.SleepCount = len(durations)
@@ -207,16 +230,166 @@ func computePerSleepMetrics(logs []domain.SleepLog) domain.PerSleepMetrics {
 	if len(durations) > 0 {
 		result.Duration = computeStats(durations)
 		result.Quality = computeStats(qualities)
-		result.Bedtime = computeStats(bedtimes)
+		// Clock times wrap at midnight, so use circular statistics
+		result.Bedtime = computeClockStats(bedtimes)
+		result.MidSleep = computeClockStats(midSleeps)
 	}
 
+	result.Stages = computeStageMetrics(logs)
+
 	return result
 }
 
-// computeDailyOverallMetrics calculates per-day total sleep statistics.
-func computeDailyOverallMetrics(logs []domain.SleepLog) domain.DailyOverallMetrics {
+// computeStageMetrics calculates stage statistics over the logs that have
+// stages. Total sleep time (TST) is the time in non-awake stages; deep and
+// REM percentages are shares of TST, WASO is the awake time between the first
+// and the last non-awake stage, and efficiency is TST over the logged period.
+// Returns nil if no log has stages.
+func computeStageMetrics(logs []domain.SleepLog) *domain.StageMetrics {
+	var deep, rem, waso, efficiency []float64
+
+	for _, log := range logs {
+		if len(log.Stages) == 0 {
+			continue
+		}
+
+		var tst, deepTime, remTime, awakeTime, awakeRun time.Duration
+		onset := false
+		for _, stage := range log.Stages {
+			d := stage.EndAt.Sub(stage.StartAt)
+			if stage.Stage == domain.SleepStageAwake {
+				// Awake time only counts once sleep resumes
+				if onset {
+					awakeRun += d
+				}
+				continue
+			}
+			onset = true
+			awakeTime += awakeRun
+			awakeRun = 0
+			tst += d
+			switch stage.Stage {
+			case domain.SleepStageDeep:
+				deepTime += d
+			case domain.SleepStageREM:
+				remTime += d
+			}
+		}
+
+		inBed := log.EndAt.Sub(log.StartAt)
+		if tst <= 0 || inBed <= 0 {
+			continue
+		}
+		deep = append(deep, float64(deepTime)/float64(tst)*100)
+		rem = append(rem, float64(remTime)/float64(tst)*100)
+		waso = append(waso, awakeTime.Minutes())
+		efficiency = append(efficiency, math.Min(100, float64(tst)/float64(inBed)*100))
+	}
+
+	if len(deep) == 0 {
+		return nil
+	}
+	return &domain.StageMetrics{
+		SleepCount:        len(deep),
+		DeepPercent:       computeStats(deep),
+		REMPercent:        computeStats(rem),
+		WASOMinutes:       computeStats(waso),
+		EfficiencyPercent: computeStats(efficiency),
+	}
+}
+
+// computeFactorImpacts compares the average duration and quality of the logs
+// with each recorded factor against the other logs of the window. Logs are
+// filtered like in computePerSleepMetrics. Results are ordered by tag name.
+func computeFactorImpacts(logs []domain.SleepLog) []domain.FactorImpact {
+	var impacts []domain.FactorImpact
+	for _, group := range groupByFactor(logs) {
+		impact := domain.FactorImpact{
+			Tag:      group.tag.Name,
+			Label:    group.tag.Label,
+			Category: group.tag.Category,
+			With:     computeFactorGroupStats(group.tagged),
+			Without:  computeFactorGroupStats(group.untagged),
+		}
+		if len(group.tagged) > 0 && len(group.untagged) > 0 {
+			durationDiff := math.Round((impact.With.AvgDurationHours-impact.Without.AvgDurationHours)*100) / 100
+			qualityDiff := math.Round((impact.With.AvgQuality-impact.Without.AvgQuality)*100) / 100
+			impact.DurationDiffHours = &durationDiff
+			impact.QualityDiff = &qualityDiff
+		}
+		impacts = append(impacts, impact)
+	}
+
+	return impacts
+}
+
+// factorGroup splits the logs of a window by the presence of one tag.
+type factorGroup struct {
+	tag      domain.Tag
+	tagged   []sleepData
+	untagged []sleepData
+}
+
+// groupByFactor splits the logs by each tag recorded on any of them, ordered
+// by tag name. Logs are filtered like in computePerSleepMetrics.
+func groupByFactor(logs []domain.SleepLog) []factorGroup {
+	var data []sleepData
+	var present []map[uuid.UUID]bool
+	tags := make(map[uuid.UUID]domain.Tag)
+
+	for _, log := range logs {
+		d := extractSleepData(log)
+		if d.durationHours < float64(MinDurationMinutes)/60.0 {
+			continue
+		}
+		tagIDs := make(map[uuid.UUID]bool, len(log.Factors))
+		for _, factor := range log.Factors {
+			tagIDs[factor.TagID] = true
+			tags[factor.TagID] = factor.Tag
+		}
+		data = append(data, d)
+		present = append(present, tagIDs)
+	}
+
+	groups := make([]factorGroup, 0, len(tags))
+	for tagID, tag := range tags {
+		group := factorGroup{tag: tag}
+		for i, d := range data {
+			if present[i][tagID] {
+				group.tagged = append(group.tagged, d)
+			} else {
+				group.untagged = append(group.untagged, d)
+			}
+		}
+		groups = append(groups, group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].tag.Name < groups[j].tag.Name })
+	return groups
+}
+
+// computeFactorGroupStats averages duration and quality over a group of logs.
+func computeFactorGroupStats(group []sleepData) domain.FactorGroupStats {
+	stats := domain.FactorGroupStats{SleepCount: len(group)}
+	if len(group) == 0 {
+		return stats
+	}
+	var duration, quality float64
+	for _, d := range group {
+		duration += d.durationHours
+		quality += float64(d.quality)
+	}
+	stats.AvgDurationHours = math.Round(duration/float64(len(group))*100) / 100
+	stats.AvgQuality = math.Round(quality/float64(len(group))*100) / 100
+	return stats
+}
+
+// computeDailyOverallMetrics calculates per-day total sleep statistics. Each
+// day is compared with the goal in force at the end of its last log;
+// TargetHours reports the target in force at the end of the window.
+func computeDailyOverallMetrics(logs []domain.SleepLog, goals goalTimeline, windowEnd time.Time) domain.DailyOverallMetrics {
 	result := domain.DailyOverallMetrics{
-		TargetHours: DefaultTargetHours,
+		TargetHours: goals.at(windowEnd).TargetHours,
 	}
 
 	if len(logs) == 0 {
@@ -224,11 +397,7 @@ func computeDailyOverallMetrics(logs []domain.SleepLog) domain.DailyOverallMetri
 	}
 
 	// Group logs by local date and sum durations
-	dailyTotals := make(map[string]float64)
-	for _, log := range logs {
-		data := extractSleepData(log)
-		dailyTotals[data.localDate] += data.durationHours
-	}
+	dailyTotals := groupDailyTotals(logs)
 
 	if len(dailyTotals) == 0 {
 		return result
@@ -237,11 +406,23 @@ func computeDailyOverallMetrics(logs []domain.SleepLog) domain.DailyOverallMetri
 	// Convert to slice for statistics
 	var totals []float64
 	daysMeetingTarget := 0
-	for _, total := range dailyTotals {
-		totals = append(totals, total)
-		if total >= DefaultTargetHours {
+	daysOverNapAllowance := 0
+	napAllowanceTracked := false
+	for _, day := range dailyTotals {
+		totals = append(totals, day.hours)
+		goal := goals.at(day.lastEnd)
+		if day.hours >= goal.TargetHours {
 			daysMeetingTarget++
 		}
+		if goal.NapAllowanceMinutes != nil {
+			napAllowanceTracked = true
+			if day.napHours*60 > float64(*goal.NapAllowanceMinutes) {
+				daysOverNapAllowance++
+			}
+		}
+	}
+	if napAllowanceTracked {
+		result.DaysOverNapAllowance = &daysOverNapAllowance
 	}
 
 	result.DaysCount = len(totals)
@@ -256,45 +437,32 @@ func computeDailyOverallMetrics(logs []domain.SleepLog) domain.DailyOverallMetri
 	return result
 }
 
-// computeDerivedScores calculates 0-100 scores from metrics.
-func computeDerivedScores(perSleep domain.PerSleepMetrics, dailyOverall domain.DailyOverallMetrics) domain.DerivedScores {
-	scores := domain.DerivedScores{}
-
-	// Consistency score: based on bedtime variability (lower std = higher score)
-	// Map std of 0-120 minutes to score of 100-0
-	if perSleep.SleepCount > 0 {
-		bedtimeStd := perSleep.Bedtime.Std
-		// Clamp to reasonable range
-		if bedtimeStd > 120 {
-			bedtimeStd = 120
+// dailyTotal is the sleep of one local day, core and naps combined.
+type dailyTotal struct {
+	hours    float64
+	napHours float64
+	lastEnd  time.Time // end of the day's last log
+}
+
+// groupDailyTotals sums the logs by the local date of their end time.
+func groupDailyTotals(logs []domain.SleepLog) map[string]*dailyTotal {
+	days := make(map[string]*dailyTotal)
+	for _, log := range logs {
+		data := extractSleepData(log)
+		day, ok := days[data.localDate]
+		if !ok {
+			day = &dailyTotal{}
+			days[data.localDate] = day
 		}
-		scores.ConsistencyScore = math.Round((1-bedtimeStd/120)*1000) / 10
-		if scores.ConsistencyScore < 0 {
-			scores.ConsistencyScore = 0
+		day.hours += data.durationHours
+		if log.Type == domain.SleepTypeNap {
+			day.napHours += data.durationHours
 		}
-	}
-
-	// Sufficiency score: based on average duration meeting target
-	// Map avg duration of 5-9 hours to score of 0-100
-	if perSleep.SleepCount > 0 {
-		avgDuration := perSleep.Duration.Avg
-		if avgDuration < 5 {
-			scores.SufficiencyScore = 0
-		} else if avgDuration >= 9 {
-			scores.SufficiencyScore = 100
-		} else {
-			scores.SufficiencyScore = math.Round((avgDuration-5)/4*1000) / 10
+		if log.EndAt.After(day.lastEnd) {
+			day.lastEnd = log.EndAt
 		}
 	}
-
-	// Overall sleep score: weighted combination
-	// 40% consistency, 30% sufficiency, 30% daily sufficiency
-	overall := (scores.ConsistencyScore*0.4 +
-		scores.SufficiencyScore*0.3 +
-		dailyOverall.DailySufficiencyScore*0.3)
-	scores.OverallSleepScore = clamp(math.Round(overall*10)/10, 0, 100)
-
-	return scores
+	return days
 }
 
 // computeStats calculates descriptive statistics for a slice of values.
@@ -333,11 +501,19 @@ func computeStats(values []float64) domain.DescriptiveStats {
 		std = math.Sqrt(sumSquares / float64(len(values)-1))
 	}
 
+	// Calculate percentiles
+	sorted := sortedCopy(values)
+
 	return domain.DescriptiveStats{
-		Avg: math.Round(avg*100) / 100,
-		Std: math.Round(std*100) / 100,
-		Min: math.Round(minVal*100) / 100,
-		Max: math.Round(maxVal*100) / 100,
+		Avg:    math.Round(avg*100) / 100,
+		Std:    math.Round(std*100) / 100,
+		Min:    math.Round(minVal*100) / 100,
+		Max:    math.Round(maxVal*100) / 100,
+		P10:    round2(percentile(sorted, 10)),
+		P25:    round2(percentile(sorted, 25)),
+		Median: round2(percentile(sorted, 50)),
+		P75:    round2(percentile(sorted, 75)),
+		P90:    round2(percentile(sorted, 90)),
 	}
 }
 
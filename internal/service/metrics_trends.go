@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// DefaultTrendWindow is the default short moving average window in days.
+	DefaultTrendWindow = 7
+
+	// LongTrendWindow is the long moving average window in days.
+	LongTrendWindow = 30
+
+	// DefaultTrendSpanDays is the default number of days in a trend series.
+	DefaultTrendSpanDays = 90
+)
+
+// trendUnits maps trend metrics to the unit of their values.
+var trendUnits = map[domain.TrendMetric]string{
+	domain.TrendMetricDuration:   "hours",
+	domain.TrendMetricQuality:    "score",
+	domain.TrendMetricBedtime:    "minutes",
+	domain.TrendMetricTotalDaily: "hours",
+}
+
+func (s *metricsService) Trends(ctx context.Context, userID uuid.UUID, metric domain.TrendMetric, window, spanDays int) (*domain.TrendsResponse, error) {
+	tracer := otel.Tracer("sleep-tracker-api/metrics")
+	ctx, span := tracer.Start(ctx, "MetricsService.Trends")
+	defer span.End()
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply defaults
+	if metric == "" {
+		metric = domain.TrendMetricDuration
+	}
+	if window <= 0 {
+		window = DefaultTrendWindow
+	}
+	if spanDays <= 0 {
+		spanDays = DefaultTrendSpanDays
+	}
+	span.SetAttributes(
+		attribute.String("user.id", userID.String()),
+		attribute.String("trend.metric", string(metric)),
+		attribute.Int("trend.window", window),
+		attribute.Int("trend.span_days", spanDays),
+	)
+
+	loc := time.UTC
+	if l, err := time.LoadLocation(user.Timezone); err == nil {
+		loc = l
+	}
+	now := time.Now()
+	localNow := now.In(loc)
+	today := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, time.UTC)
+
+	// Load enough history before the first day to warm up the averages. One
+	// extra day covers logs recorded in other timezones.
+	warmup := max(window, LongTrendWindow) - 1
+	first := today.AddDate(0, 0, -(spanDays - 1))
+	from := first.AddDate(0, 0, -warmup-1)
+
+	logs, err := s.sleepLogRepo.ListByEndRange(ctx, userID, from, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.TrendsResponse{
+		Metric:   metric,
+		Unit:     trendUnits[metric],
+		Window:   window,
+		SpanDays: spanDays,
+		Timezone: loc.String(),
+		From:     first.Format("2006-01-02"),
+		To:       today.Format("2006-01-02"),
+		Points:   computeTrendPoints(dailyTrendValues(logs, metric), today, spanDays, window),
+	}, nil
+}
+
+// dailyTrendValues returns the value of the metric for each local date, using
+// the local-date rule of extractSleepData. total_daily sums all logs of a day
+// like computeDailyOverallMetrics; the other metrics average the logs that
+// pass the computePerSleepMetrics filter.
+func dailyTrendValues(logs []domain.SleepLog, metric domain.TrendMetric) map[string]float64 {
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+
+	for _, log := range logs {
+		data := extractSleepData(log)
+		if metric == domain.TrendMetricTotalDaily {
+			sums[data.localDate] += data.durationHours
+			counts[data.localDate]++
+			continue
+		}
+
+		if data.durationHours < float64(MinDurationMinutes)/60.0 {
+			continue
+		}
+		var value float64
+		switch metric {
+		case domain.TrendMetricDuration:
+			value = data.durationHours
+		case domain.TrendMetricQuality:
+			value = float64(data.quality)
+		case domain.TrendMetricBedtime:
+			value = float64(continuousBedtime(data.bedtimeMinutes))
+		}
+		sums[data.localDate] += value
+		counts[data.localDate]++
+	}
+
+	values := make(map[string]float64, len(sums))
+	for date, sum := range sums {
+		if metric == domain.TrendMetricTotalDaily {
+			values[date] = sum
+		} else {
+			values[date] = sum / float64(counts[date])
+		}
+	}
+	return values
+}
+
+// continuousBedtime moves bedtimes before noon past 1440, so that bedtimes
+// on either side of midnight average sensibly (00:30 becomes 1470).
+func continuousBedtime(minutes int) int {
+	if minutes < 12*60 {
+		return minutes + 24*60
+	}
+	return minutes
+}
+
+// computeTrendPoints builds the series for the spanDays days ending at last.
+// The averages also use the days before the first point, so they are warm
+// from the start. Missing days are skipped by the averages, and the EWMA
+// (alpha = 2/(window+1)) carries over unchanged.
+func computeTrendPoints(values map[string]float64, last time.Time, spanDays, window int) []domain.TrendPoint {
+	warmup := max(window, LongTrendWindow) - 1
+	total := warmup + spanDays
+	start := last.AddDate(0, 0, -(total - 1))
+
+	series := make([]*float64, total)
+	dates := make([]string, total)
+	for i := range series {
+		dates[i] = start.AddDate(0, 0, i).Format("2006-01-02")
+		if v, ok := values[dates[i]]; ok {
+			series[i] = &v
+		}
+	}
+
+	alpha := 2 / float64(window+1)
+	var ewma *float64
+	points := make([]domain.TrendPoint, 0, spanDays)
+	for i, v := range series {
+		if v != nil {
+			next := *v
+			if ewma != nil {
+				next = alpha**v + (1-alpha)**ewma
+			}
+			ewma = &next
+		}
+		if i < warmup {
+			continue
+		}
+
+		points = append(points, domain.TrendPoint{
+			Date:    dates[i],
+			Value:   roundPtr(v),
+			Missing: v == nil,
+			SMA:     movingAverage(series[:i+1], window),
+			SMA30:   movingAverage(series[:i+1], LongTrendWindow),
+			EWMA:    roundPtr(ewma),
+		})
+	}
+	return points
+}
+
+// movingAverage averages the non-missing values among the last window
+// entries of series, or returns nil if all of them are missing.
+func movingAverage(series []*float64, window int) *float64 {
+	sum, n := 0.0, 0
+	for _, v := range series[max(0, len(series)-window):] {
+		if v != nil {
+			sum += *v
+			n++
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	avg := round2(sum / float64(n))
+	return &avg
+}
+
+func roundPtr(v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	r := round2(*v)
+	return &r
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+func TestDailyTrendValues(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+	logs := []domain.SleepLog{
+		{StartAt: at(1, 23, 30), EndAt: at(2, 7, 30), Quality: 8},
+		{StartAt: at(2, 14, 0), EndAt: at(2, 15, 0), Quality: 5},  // short nap
+		{StartAt: at(3, 0, 30), EndAt: at(3, 6, 30), Quality: 6},  // after midnight
+		{StartAt: at(3, 13, 0), EndAt: at(3, 15, 0), Quality: 10}, // long nap, same day
+	}
+
+	tests := []struct {
+		metric domain.TrendMetric
+		want   map[string]float64
+	}{
+		{domain.TrendMetricDuration, map[string]float64{"2024-01-02": 8, "2024-01-03": 4}},
+		{domain.TrendMetricQuality, map[string]float64{"2024-01-02": 8, "2024-01-03": 8}},
+		{domain.TrendMetricBedtime, map[string]float64{"2024-01-02": 1410, "2024-01-03": (1470 + 780) / 2.0}},
+		{domain.TrendMetricTotalDaily, map[string]float64{"2024-01-02": 9, "2024-01-03": 8}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.metric), func(t *testing.T) {
+			got := dailyTrendValues(logs, tt.metric)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for date, want := range tt.want {
+				if got[date] != want {
+					t.Errorf("%s: got %v, want %v", date, got[date], want)
+				}
+			}
+		})
+	}
+}
+
+func TestComputeTrendPoints(t *testing.T) {
+	last := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	values := map[string]float64{
+		"2024-03-06": 6,
+		"2024-03-07": 8,
+		// 2024-03-08 missing
+		"2024-03-09": 7,
+		"2024-03-10": 9,
+	}
+
+	points := computeTrendPoints(values, last, 5, 3)
+	if len(points) != 5 {
+		t.Fatalf("got %d points, want 5", len(points))
+	}
+	if points[0].Date != "2024-03-06" || points[4].Date != "2024-03-10" {
+		t.Fatalf("unexpected dates %s..%s", points[0].Date, points[4].Date)
+	}
+
+	missing := points[2]
+	if !missing.Missing || missing.Value != nil {
+		t.Errorf("2024-03-08 should be missing: %+v", missing)
+	}
+	if missing.SMA == nil || *missing.SMA != 7 {
+		t.Errorf("SMA on missing day = %v, want 7 (average of the other days)", missing.SMA)
+	}
+	if missing.EWMA == nil || *missing.EWMA != *points[1].EWMA {
+		t.Errorf("EWMA should carry over a missing day: %v vs %v", missing.EWMA, points[1].EWMA)
+	}
+
+	// window of 3 on 03-10 covers 03-08 (missing), 03-09 and 03-10
+	if got := points[4].SMA; got == nil || *got != 8 {
+		t.Errorf("SMA = %v, want 8", got)
+	}
+	// 30-day average covers all values
+	if got := points[4].SMA30; got == nil || *got != 7.5 {
+		t.Errorf("SMA30 = %v, want 7.5", got)
+	}
+	// alpha = 0.5: 6 -> 7 -> 7 -> 7 -> 8
+	if got := points[4].EWMA; got == nil || *got != 8 {
+		t.Errorf("EWMA = %v, want 8", got)
+	}
+
+	// Averages are absent until the first value
+	points = computeTrendPoints(map[string]float64{}, last, 2, 3)
+	if points[0].SMA != nil || points[0].SMA30 != nil || points[0].EWMA != nil {
+		t.Errorf("expected no averages without values: %+v", points[0])
+	}
+}
+
+func TestMetricsService_Trends(t *testing.T) {
+	repo := NewMockSleepLogRepository()
+	userRepo := NewMockUserRepository()
+	svc := NewMetricsService(repo, userRepo, NewMockSleepGoalRepository(), DefaultScoringModel())
+
+	userID := uuid.New()
+	userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+
+	// Sleep every other night, ending today and going back
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for i := 0; i < 10; i += 2 {
+		end := today.AddDate(0, 0, -i).Add(time.Minute)
+		log := &domain.SleepLog{ID: uuid.New(), UserID: userID, StartAt: end.Add(-8 * time.Hour), EndAt: end, Quality: 7}
+		repo.logs[log.ID] = log
+	}
+
+	result, err := svc.Trends(context.Background(), userID, domain.TrendMetricDuration, 0, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Window != DefaultTrendWindow || result.Unit != "hours" || len(result.Points) != 10 {
+		t.Fatalf("unexpected response: %+v", result)
+	}
+	if result.To != today.Format("2006-01-02") {
+		t.Errorf("To = %s, want today", result.To)
+	}
+	for i, point := range result.Points {
+		wantMissing := (len(result.Points)-1-i)%2 == 1
+		if point.Missing != wantMissing {
+			t.Errorf("%s: Missing = %v, want %v", point.Date, point.Missing, wantMissing)
+		}
+	}
+
+	_, err = svc.Trends(context.Background(), uuid.New(), domain.TrendMetricDuration, 7, 10)
+	if !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -2,10 +2,12 @@ package service
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	"github.com/blaisecz/sleep-tracker/internal/domain"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // MockSleepLogRepository is a mock implementation of SleepLogRepository
@@ -44,7 +46,7 @@ func (m *MockSleepLogRepository) GetByID(ctx context.Context, id uuid.UUID) (*do
 		return nil, m.err
 	}
 	log, ok := m.logs[id]
-	if !ok {
+	if !ok || log.DeletedAt.Valid {
 		return nil, domain.ErrNotFound
 	}
 	return log, nil
@@ -54,7 +56,12 @@ func (m *MockSleepLogRepository) Update(ctx context.Context, log *domain.SleepLo
 	if m.err != nil {
 		return m.err
 	}
+	log.Version++
 	m.logs[log.ID] = log
+	if log.ClientRequestID != nil {
+		key := log.UserID.String() + ":" + *log.ClientRequestID
+		m.clientRequestID[key] = log
+	}
 	return nil
 }
 
@@ -69,19 +76,32 @@ func (m *MockSleepLogRepository) List(ctx context.Context, userID uuid.UUID, fil
 	}
 	var result []domain.SleepLog
 	for _, log := range m.logs {
-		if log.UserID == userID {
+		if log.UserID == userID && !log.DeletedAt.Valid && hasFactor(log, filter.Tag) {
 			result = append(result, *log)
 		}
 	}
 	return result, nil
 }
 
+// hasFactor reports whether the log has a factor with the tag name; an empty name matches every log
+func hasFactor(log *domain.SleepLog, tag string) bool {
+	if tag == "" {
+		return true
+	}
+	for _, factor := range log.Factors {
+		if factor.Tag.Name == tag {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *MockSleepLogRepository) HasOverlap(ctx context.Context, userID uuid.UUID, startAt, endAt time.Time, sleepType domain.SleepType) (bool, error) {
 	if m.err != nil {
 		return false, m.err
 	}
 	for _, log := range m.logs {
-		if log.UserID != userID {
+		if log.UserID != userID || log.DeletedAt.Valid {
 			continue
 		}
 		// Check overlap: new period overlaps if start < existing.end AND end > existing.start
@@ -97,7 +117,7 @@ func (m *MockSleepLogRepository) HasOverlapExcluding(ctx context.Context, userID
 		return false, m.err
 	}
 	for _, log := range m.logs {
-		if log.UserID != userID || log.ID == excludeID {
+		if log.UserID != userID || log.ID == excludeID || log.DeletedAt.Valid {
 			continue
 		}
 		// Check overlap: new period overlaps if start < existing.end AND end > existing.start
@@ -126,13 +146,119 @@ func (m *MockSleepLogRepository) ListByEndRange(ctx context.Context, userID uuid
 	}
 	var result []domain.SleepLog
 	for _, log := range m.logs {
-		if log.UserID == userID && !log.EndAt.Before(from) && !log.EndAt.After(to) {
+		if log.UserID == userID && !log.DeletedAt.Valid && !log.EndAt.Before(from) && !log.EndAt.After(to) {
 			result = append(result, *log)
 		}
 	}
 	return result, nil
 }
 
+func (m *MockSleepLogRepository) Delete(ctx context.Context, log *domain.SleepLog) error {
+	if m.err != nil {
+		return m.err
+	}
+	log.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	log.Version++
+	m.logs[log.ID] = log
+	return nil
+}
+
+func (m *MockSleepLogRepository) GetDeletedByID(ctx context.Context, id uuid.UUID) (*domain.SleepLog, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	log, ok := m.logs[id]
+	if !ok || !log.DeletedAt.Valid {
+		return nil, domain.ErrNotFound
+	}
+	return log, nil
+}
+
+func (m *MockSleepLogRepository) Restore(ctx context.Context, log *domain.SleepLog) error {
+	if m.err != nil {
+		return m.err
+	}
+	log.DeletedAt = gorm.DeletedAt{}
+	log.Version++
+	m.logs[log.ID] = log
+	return nil
+}
+
+func (m *MockSleepLogRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	var purged int64
+	for id, log := range m.logs {
+		if log.DeletedAt.Valid && log.DeletedAt.Time.Before(before) {
+			delete(m.logs, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (m *MockSleepLogRepository) CreateBatch(ctx context.Context, logs []*domain.SleepLog) error {
+	if m.err != nil {
+		return m.err
+	}
+	for _, log := range logs {
+		if err := m.Create(ctx, log); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockSleepLogRepository) ListByClientRequestIDs(ctx context.Context, userID uuid.UUID, clientRequestIDs []string) ([]domain.SleepLog, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var result []domain.SleepLog
+	for _, id := range clientRequestIDs {
+		if log, ok := m.clientRequestID[userID.String()+":"+id]; ok {
+			result = append(result, *log)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockSleepLogRepository) ListOverlapping(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]domain.SleepLog, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var result []domain.SleepLog
+	for _, log := range m.logs {
+		if log.UserID == userID && !log.DeletedAt.Valid && log.StartAt.Before(to) && log.EndAt.After(from) {
+			result = append(result, *log)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockSleepLogRepository) Iterate(ctx context.Context, userID uuid.UUID, filter domain.SleepLogFilter, fn func(*domain.SleepLog) error) error {
+	if m.err != nil {
+		return m.err
+	}
+	var result []*domain.SleepLog
+	for _, log := range m.logs {
+		if log.UserID != userID || log.DeletedAt.Valid {
+			continue
+		}
+		if (filter.From != nil && log.StartAt.Before(*filter.From)) || (filter.To != nil && log.StartAt.After(*filter.To)) {
+			continue
+		}
+		result = append(result, log)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].StartAt.Before(result[j].StartAt) })
+	for _, log := range result {
+		if err := fn(log); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // MockUserRepository is a mock implementation of UserRepository
 type MockUserRepository struct {
 	users map[uuid.UUID]*domain.User
@@ -175,10 +301,85 @@ func (m *MockUserRepository) Exists(ctx context.Context, id uuid.UUID) (bool, er
 	return ok, nil
 }
 
+func (m *MockUserRepository) Update(ctx context.Context, user *domain.User) error {
+	if m.err != nil {
+		return m.err
+	}
+	if _, ok := m.users[user.ID]; !ok {
+		return domain.ErrNotFound
+	}
+	m.users[user.ID] = user
+	return nil
+}
+
+// ListActive applies the timezone and the limit; the mock has no sleep logs
+// to check activity and tags against.
+func (m *MockUserRepository) ListActive(ctx context.Context, filter domain.CohortFilter, from, to time.Time, limit int) ([]domain.User, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var users []domain.User
+	for _, user := range m.users {
+		if filter.Timezone == "" || user.Timezone == filter.Timezone {
+			users = append(users, *user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID.String() < users[j].ID.String() })
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
 func (m *MockUserRepository) SetError(err error) {
 	m.err = err
 }
 
+// MockSleepGoalRepository is a mock implementation of SleepGoalRepository
+type MockSleepGoalRepository struct {
+	goals []domain.SleepGoal
+}
+
+func NewMockSleepGoalRepository() *MockSleepGoalRepository {
+	return &MockSleepGoalRepository{}
+}
+
+func (m *MockSleepGoalRepository) Current(ctx context.Context, userID uuid.UUID) (*domain.SleepGoal, error) {
+	for i := range m.goals {
+		if m.goals[i].UserID == userID && m.goals[i].EffectiveTo == nil {
+			goal := m.goals[i]
+			return &goal, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (m *MockSleepGoalRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.SleepGoal, error) {
+	var goals []domain.SleepGoal
+	for _, goal := range m.goals {
+		if goal.UserID == userID {
+			goals = append(goals, goal)
+		}
+	}
+	return goals, nil
+}
+
+func (m *MockSleepGoalRepository) Replace(ctx context.Context, goal *domain.SleepGoal) error {
+	_ = m.End(ctx, goal.UserID, goal.EffectiveFrom)
+	m.goals = append(m.goals, *goal)
+	return nil
+}
+
+func (m *MockSleepGoalRepository) End(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	for i := range m.goals {
+		if m.goals[i].UserID == userID && m.goals[i].EffectiveTo == nil {
+			m.goals[i].EffectiveTo = &at
+			return nil
+		}
+	}
+	return domain.ErrNotFound
+}
+
 // Helper functions
 func strPtr(s string) *string {
 	return &s
@@ -195,3 +396,84 @@ func sleepTypePtr(t domain.SleepType) *domain.SleepType {
 func timePtr(t time.Time) *time.Time {
 	return &t
 }
+
+// MockTagRepository is a mock implementation of TagRepository, seeded with the catalogue
+type MockTagRepository struct {
+	tags []domain.Tag
+	err  error
+}
+
+func NewMockTagRepository() *MockTagRepository {
+	return &MockTagRepository{tags: domain.CatalogueTags()}
+}
+
+func (m *MockTagRepository) EnsureCatalogue(ctx context.Context, tags []domain.Tag) error {
+	return m.err
+}
+
+func (m *MockTagRepository) Create(ctx context.Context, tag *domain.Tag) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.tags = append(m.tags, *tag)
+	return nil
+}
+
+func (m *MockTagRepository) ListForUser(ctx context.Context, userID uuid.UUID) ([]domain.Tag, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var result []domain.Tag
+	for _, tag := range m.tags {
+		if tag.UserID == nil || *tag.UserID == userID {
+			result = append(result, tag)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockTagRepository) GetByNames(ctx context.Context, userID uuid.UUID, names []string) ([]domain.Tag, error) {
+	tags, err := m.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var result []domain.Tag
+	for _, tag := range tags {
+		for _, name := range names {
+			if tag.Name == name {
+				result = append(result, tag)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// MockInsightsCacheRepository is a mock implementation of InsightsCacheRepository
+type MockInsightsCacheRepository struct {
+	entries map[uuid.UUID]*domain.InsightsCacheEntry
+	deletes int
+}
+
+func NewMockInsightsCacheRepository() *MockInsightsCacheRepository {
+	return &MockInsightsCacheRepository{entries: make(map[uuid.UUID]*domain.InsightsCacheEntry)}
+}
+
+func (m *MockInsightsCacheRepository) Get(ctx context.Context, userID uuid.UUID) (*domain.InsightsCacheEntry, error) {
+	entry, ok := m.entries[userID]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return entry, nil
+}
+
+func (m *MockInsightsCacheRepository) Save(ctx context.Context, entry *domain.InsightsCacheEntry) error {
+	m.entries[entry.UserID] = entry
+	return nil
+}
+
+func (m *MockInsightsCacheRepository) Delete(ctx context.Context, userID uuid.UUID) error {
+	m.deletes++
+	delete(m.entries, userID)
+	return nil
+}
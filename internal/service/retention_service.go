@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/repository"
+)
+
+const (
+	// DefaultRetentionDays is how long soft-deleted sleep logs are kept before purging.
+	DefaultRetentionDays = 30
+
+	// DefaultPurgeInterval is how often the retention job runs.
+	DefaultPurgeInterval = 24 * time.Hour
+)
+
+// RetentionService permanently removes soft-deleted sleep logs once their
+// retention period has passed.
+type RetentionService interface {
+	// Purge removes logs deleted more than the retention period ago and returns how many were removed.
+	Purge(ctx context.Context) (int64, error)
+	// Run purges on every interval tick until ctx is cancelled.
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type retentionService struct {
+	sleepLogRepo  repository.SleepLogRepository
+	retentionDays int
+	now           func() time.Time
+}
+
+// NewRetentionService creates a new RetentionService.
+func NewRetentionService(sleepLogRepo repository.SleepLogRepository, retentionDays int) RetentionService {
+	if retentionDays <= 0 {
+		retentionDays = DefaultRetentionDays
+	}
+	return &retentionService{
+		sleepLogRepo:  sleepLogRepo,
+		retentionDays: retentionDays,
+		now:           time.Now,
+	}
+}
+
+func (s *retentionService) Purge(ctx context.Context) (int64, error) {
+	cutoff := s.now().UTC().AddDate(0, 0, -s.retentionDays)
+	return s.sleepLogRepo.PurgeDeleted(ctx, cutoff)
+}
+
+func (s *retentionService) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultPurgeInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := s.Purge(ctx)
+		if err != nil {
+			log.Printf("Retention: failed to purge deleted sleep logs: %v", err)
+		} else if purged > 0 {
+			log.Printf("Retention: purged %d deleted sleep logs", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
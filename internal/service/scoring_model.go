@@ -0,0 +1,146 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"gopkg.in/yaml.v2"
+)
+
+// ScoringModel turns window metrics into derived scores.
+type ScoringModel interface {
+	// Name and Version identify the model in DerivedScores.
+	Name() string
+	Version() string
+	// Score computes the consistency, sufficiency and overall scores. sri is
+	// nil when the window has too few day pairs.
+	Score(perSleep domain.PerSleepMetrics, dailyOverall domain.DailyOverallMetrics, sri *float64) domain.DerivedScores
+}
+
+// DefaultScoringConfig returns the built-in model: 40% regularity, 30%
+// sufficiency and 30% daily sufficiency. Consistency falls linearly from 100
+// at a bedtime std of 0 to 0 at 120 minutes, and sufficiency rises linearly
+// from 0 at 2 hours below the target to 100 at 2 hours above it.
+func DefaultScoringConfig() domain.ScoringModelConfig {
+	return domain.ScoringModelConfig{
+		Name:             "default",
+		Version:          "1",
+		RegularitySource: domain.RegularitySourceBedtimeStd,
+		Weights: domain.ScoringWeights{
+			Regularity:       0.4,
+			Sufficiency:      0.3,
+			DailySufficiency: 0.3,
+		},
+		Consistency: domain.ScoreCurve{Worst: 120, Best: 0, Exponent: 1},
+		Sufficiency: domain.ScoreCurve{Worst: -2, Best: 2, Exponent: 1},
+		OverallMin:  0,
+		OverallMax:  100,
+	}
+}
+
+// LoadScoringConfig reads a scoring model from a YAML (.yaml, .yml) or JSON
+// (.json) file. Fields missing from the file keep their values from base.
+// Unknown fields are rejected, so typos do not silently fall back.
+func LoadScoringConfig(path string, base domain.ScoringModelConfig) (domain.ScoringModelConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return domain.ScoringModelConfig{}, fmt.Errorf("read scoring model: %w", err)
+	}
+
+	cfg := base
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, &cfg)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&cfg)
+	default:
+		return domain.ScoringModelConfig{}, fmt.Errorf("scoring model %s: unsupported file type, use .yaml, .yml or .json", path)
+	}
+	if err != nil {
+		return domain.ScoringModelConfig{}, fmt.Errorf("parse scoring model %s: %w", path, err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return domain.ScoringModelConfig{}, err
+	}
+	return cfg, nil
+}
+
+type configScoringModel struct {
+	cfg domain.ScoringModelConfig
+}
+
+// NewScoringModel creates a ScoringModel from a config. Errors wrap
+// domain.ErrInvalidScoringModel.
+func NewScoringModel(cfg domain.ScoringModelConfig) (ScoringModel, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &configScoringModel{cfg: cfg}, nil
+}
+
+// DefaultScoringModel returns the model of DefaultScoringConfig.
+func DefaultScoringModel() ScoringModel {
+	return &configScoringModel{cfg: DefaultScoringConfig()}
+}
+
+func (m *configScoringModel) Name() string {
+	return m.cfg.Name
+}
+
+func (m *configScoringModel) Version() string {
+	return m.cfg.Version
+}
+
+func (m *configScoringModel) Score(perSleep domain.PerSleepMetrics, dailyOverall domain.DailyOverallMetrics, sri *float64) domain.DerivedScores {
+	scores := domain.DerivedScores{
+		SleepRegularityIndex: sri,
+		ScoringModel:         m.cfg.Name,
+		ScoringModelVersion:  m.cfg.Version,
+	}
+
+	if perSleep.SleepCount > 0 {
+		// Consistency score: based on bedtime variability (lower std = higher
+		// score). The std is circular, so bedtimes on either side of midnight
+		// count as close.
+		scores.ConsistencyScore = round1(scoreOnCurve(perSleep.Bedtime.Std, m.cfg.Consistency))
+
+		// Sufficiency score: based on average duration against the target
+		scores.SufficiencyScore = round1(scoreOnCurve(perSleep.Duration.Avg-dailyOverall.TargetHours, m.cfg.Sufficiency))
+	}
+
+	// Regularity component of the overall score
+	regularity := scores.ConsistencyScore
+	scores.RegularitySource = domain.RegularitySourceBedtimeStd
+	if m.cfg.RegularitySource == domain.RegularitySourceSRI && sri != nil {
+		regularity = clamp(*sri, 0, 100)
+		scores.RegularitySource = domain.RegularitySourceSRI
+	}
+
+	// Overall sleep score: weighted combination
+	w := m.cfg.Weights
+	overall := regularity*w.Regularity +
+		scores.SufficiencyScore*w.Sufficiency +
+		dailyOverall.DailySufficiencyScore*w.DailySufficiency
+	scores.OverallSleepScore = clamp(round1(overall), m.cfg.OverallMin, m.cfg.OverallMax)
+
+	return scores
+}
+
+// scoreOnCurve maps value onto 0-100 along the curve.
+func scoreOnCurve(value float64, curve domain.ScoreCurve) float64 {
+	t := clamp((value-curve.Worst)/(curve.Best-curve.Worst), 0, 1)
+	return 100 * math.Pow(t, curve.Exponent)
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+// scoringModelWith returns the default model with another regularity source.
+func scoringModelWith(source domain.RegularitySource) ScoringModel {
+	cfg := DefaultScoringConfig()
+	cfg.RegularitySource = source
+	return &configScoringModel{cfg: cfg}
+}
+
+func TestConfigScoringModel_Score(t *testing.T) {
+	perSleep := domain.PerSleepMetrics{
+		SleepCount: 5,
+		Duration:   domain.DescriptiveStats{Avg: 8},
+		Bedtime:    domain.DescriptiveStats{Std: 30},
+	}
+	daily := domain.DailyOverallMetrics{TargetHours: DefaultTargetHours, DailySufficiencyScore: 60}
+
+	strict := DefaultScoringConfig()
+	strict.Name, strict.Version = "strict", "2"
+	strict.Consistency = domain.ScoreCurve{Worst: 60, Best: 0, Exponent: 2}
+	strict.Weights = domain.ScoringWeights{Regularity: 0.5, Sufficiency: 0.5}
+
+	bounded := DefaultScoringConfig()
+	bounded.OverallMin, bounded.OverallMax = 20, 70
+
+	tests := []struct {
+		name            string
+		cfg             domain.ScoringModelConfig
+		wantConsistency float64
+		wantSufficiency float64
+		wantOverall     float64
+	}{
+		// 0.4*75 + 0.3*75 + 0.3*60
+		{"default", DefaultScoringConfig(), 75, 75, 70.5},
+		// consistency (1-30/60)^2 = 25%, overall 0.5*25 + 0.5*75
+		{"custom weights and curve", strict, 25, 75, 50},
+		{"overall bounds", bounded, 75, 75, 70},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model, err := NewScoringModel(tt.cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := model.Score(perSleep, daily, nil)
+
+			if got.ConsistencyScore != tt.wantConsistency || got.SufficiencyScore != tt.wantSufficiency {
+				t.Errorf("consistency/sufficiency = %v/%v, want %v/%v", got.ConsistencyScore, got.SufficiencyScore, tt.wantConsistency, tt.wantSufficiency)
+			}
+			if got.OverallSleepScore != tt.wantOverall {
+				t.Errorf("OverallSleepScore = %v, want %v", got.OverallSleepScore, tt.wantOverall)
+			}
+			if got.ScoringModel != tt.cfg.Name || got.ScoringModelVersion != tt.cfg.Version {
+				t.Errorf("model = %s@%s, want %s@%s", got.ScoringModel, got.ScoringModelVersion, tt.cfg.Name, tt.cfg.Version)
+			}
+		})
+	}
+}
+
+func TestScoringModelConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *domain.ScoringModelConfig)
+	}{
+		{"missing version", func(cfg *domain.ScoringModelConfig) { cfg.Version = "" }},
+		{"unknown regularity source", func(cfg *domain.ScoringModelConfig) { cfg.RegularitySource = "naps" }},
+		{"weights do not add up", func(cfg *domain.ScoringModelConfig) { cfg.Weights.Regularity = 0.5 }},
+		{"negative weight", func(cfg *domain.ScoringModelConfig) {
+			cfg.Weights = domain.ScoringWeights{Regularity: 1.2, Sufficiency: -0.2}
+		}},
+		{"flat curve", func(cfg *domain.ScoringModelConfig) { cfg.Sufficiency.Best = cfg.Sufficiency.Worst }},
+		{"zero exponent", func(cfg *domain.ScoringModelConfig) { cfg.Consistency.Exponent = 0 }},
+		{"inverted bounds", func(cfg *domain.ScoringModelConfig) { cfg.OverallMin = 100 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultScoringConfig()
+			tt.modify(&cfg)
+
+			if _, err := NewScoringModel(cfg); !errors.Is(err, domain.ErrInvalidScoringModel) {
+				t.Errorf("expected ErrInvalidScoringModel, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadScoringConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	base := DefaultScoringConfig()
+	base.RegularitySource = domain.RegularitySourceSRI
+
+	t.Run("yaml", func(t *testing.T) {
+		path := write("model.yaml", `
+name: sufficiency-heavy
+version: "2"
+weights:
+  regularity: 0.2
+  sufficiency: 0.5
+  daily_sufficiency: 0.3
+sufficiency:
+  worst: -3
+  best: 1
+  exponent: 1.5
+`)
+		cfg, err := LoadScoringConfig(path, base)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Name != "sufficiency-heavy" || cfg.Version != "2" || cfg.Weights.Sufficiency != 0.5 || cfg.Sufficiency.Exponent != 1.5 {
+			t.Errorf("unexpected config: %+v", cfg)
+		}
+		// Fields missing from the file keep the base values
+		if cfg.RegularitySource != domain.RegularitySourceSRI || cfg.Consistency != base.Consistency || cfg.OverallMax != 100 {
+			t.Errorf("base values lost: %+v", cfg)
+		}
+	})
+
+	t.Run("shipped default", func(t *testing.T) {
+		cfg, err := LoadScoringConfig("../../scoring/default.yaml", domain.ScoringModelConfig{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg != DefaultScoringConfig() {
+			t.Errorf("scoring/default.yaml differs from DefaultScoringConfig: %+v", cfg)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		path := write("model.json", `{"name": "json", "version": "3", "regularity_source": "bedtime_std"}`)
+		cfg, err := LoadScoringConfig(path, base)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Name != "json" || cfg.RegularitySource != domain.RegularitySourceBedtimeStd || cfg.Weights != base.Weights {
+			t.Errorf("unexpected config: %+v", cfg)
+		}
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		path := write("typo.yaml", "name: typo\nversion: \"1\"\nweight:\n  regularity: 1\n")
+		if _, err := LoadScoringConfig(path, base); err == nil {
+			t.Error("expected an error for an unknown field")
+		}
+	})
+
+	t.Run("invalid model", func(t *testing.T) {
+		path := write("invalid.json", `{"weights": {"regularity": 1, "sufficiency": 1, "daily_sufficiency": 0}}`)
+		if _, err := LoadScoringConfig(path, base); !errors.Is(err, domain.ErrInvalidScoringModel) {
+			t.Errorf("expected ErrInvalidScoringModel, got %v", err)
+		}
+	})
+
+	t.Run("unsupported extension", func(t *testing.T) {
+		path := write("model.toml", "name = \"toml\"")
+		if _, err := LoadScoringConfig(path, base); err == nil {
+			t.Error("expected an error for a .toml file")
+		}
+	})
+}
+
+func TestMetricsService_CompareScores(t *testing.T) {
+	repo := NewMockSleepLogRepository()
+	userRepo := NewMockUserRepository()
+	svc := NewMetricsService(repo, userRepo, NewMockSleepGoalRepository(), nil)
+
+	userID := uuid.New()
+	userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for i := 1; i <= 7; i++ {
+		start := today.AddDate(0, 0, -i).Add(-time.Hour)
+		log := &domain.SleepLog{ID: uuid.New(), UserID: userID, StartAt: start, EndAt: start.Add(6 * time.Hour), Quality: 6, Type: domain.SleepTypeCore}
+		repo.logs[log.ID] = log
+	}
+
+	candidateCfg := DefaultScoringConfig()
+	candidateCfg.Name, candidateCfg.Version = "regularity-only", "1"
+	candidateCfg.Weights = domain.ScoringWeights{Regularity: 1}
+	candidate, err := NewScoringModel(candidateCfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := svc.CompareScores(context.Background(), userID, 14, nil, candidate)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Baseline.ScoringModel != "default" || result.Candidate.ScoringModel != "regularity-only" {
+		t.Errorf("models = %s/%s", result.Baseline.ScoringModel, result.Candidate.ScoringModel)
+	}
+	// Same bedtime every night, so the regularity-only model scores 100
+	if result.Candidate.OverallSleepScore != 100 {
+		t.Errorf("candidate overall = %v, want 100", result.Candidate.OverallSleepScore)
+	}
+	if result.OverallDiff != round1(result.Candidate.OverallSleepScore-result.Baseline.OverallSleepScore) || result.OverallDiff <= 0 {
+		t.Errorf("OverallDiff = %v for %v vs %v", result.OverallDiff, result.Candidate.OverallSleepScore, result.Baseline.OverallSleepScore)
+	}
+
+	if _, err := svc.CompareScores(context.Background(), uuid.New(), 14, nil, candidate); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
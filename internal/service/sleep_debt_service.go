@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/repository"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// DefaultSleepDebtSpanDays is the default number of days in the debt series.
+	DefaultSleepDebtSpanDays = 30
+
+	// DefaultSleepDebtDecay is the default share of the balance forgiven each day.
+	DefaultSleepDebtDecay = 0.1
+
+	// DefaultSleepDebtCapHours is the default largest debt in hours.
+	DefaultSleepDebtCapHours = 20.0
+
+	// SleepDebtWarmupDays is the number of days before the series that feed
+	// the balance, so it does not start from zero.
+	SleepDebtWarmupDays = 30
+)
+
+// SleepDebtService tracks how much sleep a user owes.
+type SleepDebtService interface {
+	// Compute returns the daily running balance of total sleep minus the
+	// target for the spanDays days ending today in the user's timezone. Each
+	// day decay (0-1) of the balance is forgiven, and debt is capped at
+	// capHours.
+	Compute(ctx context.Context, userID uuid.UUID, spanDays int, decay, capHours float64) (*domain.SleepDebtResponse, error)
+}
+
+type sleepDebtService struct {
+	sleepLogRepo repository.SleepLogRepository
+	userRepo     repository.UserRepository
+	goalRepo     repository.SleepGoalRepository
+}
+
+// NewSleepDebtService creates a new SleepDebtService.
+func NewSleepDebtService(sleepLogRepo repository.SleepLogRepository, userRepo repository.UserRepository, goalRepo repository.SleepGoalRepository) SleepDebtService {
+	return &sleepDebtService{
+		sleepLogRepo: sleepLogRepo,
+		userRepo:     userRepo,
+		goalRepo:     goalRepo,
+	}
+}
+
+func (s *sleepDebtService) Compute(ctx context.Context, userID uuid.UUID, spanDays int, decay, capHours float64) (*domain.SleepDebtResponse, error) {
+	tracer := otel.Tracer("sleep-tracker-api/debt")
+	ctx, span := tracer.Start(ctx, "SleepDebtService.Compute")
+	defer span.End()
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply defaults
+	if spanDays <= 0 {
+		spanDays = DefaultSleepDebtSpanDays
+	}
+	if decay < 0 || decay >= 1 {
+		decay = DefaultSleepDebtDecay
+	}
+	if capHours <= 0 {
+		capHours = DefaultSleepDebtCapHours
+	}
+	span.SetAttributes(
+		attribute.String("user.id", userID.String()),
+		attribute.Int("debt.span_days", spanDays),
+		attribute.Float64("debt.decay", decay),
+		attribute.Float64("debt.cap_hours", capHours),
+	)
+
+	goalHistory, err := s.goalRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := time.UTC
+	if l, err := time.LoadLocation(user.Timezone); err == nil {
+		loc = l
+	}
+	now := time.Now()
+	localNow := now.In(loc)
+	today := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, time.UTC)
+
+	// One extra day covers logs recorded in other timezones, as in Trends
+	first := today.AddDate(0, 0, -(spanDays - 1))
+	from := first.AddDate(0, 0, -SleepDebtWarmupDays-1)
+
+	logs, err := s.sleepLogRepo.ListByEndRange(ctx, userID, from, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	points := computeSleepDebt(groupDailyTotals(logs), goalTimeline(goalHistory), today, spanDays, decay, capHours)
+
+	response := &domain.SleepDebtResponse{
+		Decay:    decay,
+		CapHours: capHours,
+		SpanDays: spanDays,
+		Timezone: loc.String(),
+		From:     first.Format("2006-01-02"),
+		To:       today.Format("2006-01-02"),
+		Points:   points,
+	}
+	if len(points) > 0 {
+		response.CurrentDebtHours = -points[len(points)-1].BalanceHours
+	}
+	span.SetAttributes(attribute.Float64("debt.current_hours", response.CurrentDebtHours))
+
+	return response, nil
+}
+
+// computeSleepDebt builds the balance for the spanDays days ending at last,
+// starting SleepDebtWarmupDays earlier from zero. Each day the balance keeps
+// 1-decay of its previous value and adds the day's total sleep minus the
+// target of the goal in force. Extra sleep pays off debt but is not banked,
+// and debt stops at capHours. Days without sleep only decay the balance, so
+// gaps in logging do not count as sleepless days.
+func computeSleepDebt(days map[string]*dailyTotal, goals goalTimeline, last time.Time, spanDays int, decay, capHours float64) []domain.SleepDebtPoint {
+	total := SleepDebtWarmupDays + spanDays
+	start := last.AddDate(0, 0, -(total - 1))
+
+	balance := 0.0
+	points := make([]domain.SleepDebtPoint, 0, spanDays)
+	for i := 0; i < total; i++ {
+		date := start.AddDate(0, 0, i)
+		key := date.Format("2006-01-02")
+
+		// Goals apply from the end of the local day at the latest
+		target := goals.at(date.AddDate(0, 0, 1).Add(-time.Nanosecond)).TargetHours
+		day, ok := days[key]
+		if ok {
+			target = goals.at(day.lastEnd).TargetHours
+		}
+
+		balance *= 1 - decay
+		if ok {
+			balance += day.hours - target
+		}
+		balance = clamp(balance, -capHours, 0)
+
+		if i < SleepDebtWarmupDays {
+			continue
+		}
+		point := domain.SleepDebtPoint{
+			Date:         key,
+			TargetHours:  target,
+			Missing:      !ok,
+			BalanceHours: round2(balance) + 0, // avoid -0 in JSON
+		}
+		if ok {
+			hours := round2(day.hours)
+			point.TotalHours = &hours
+		}
+		points = append(points, point)
+	}
+	return points
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+func TestComputeSleepDebt(t *testing.T) {
+	last := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	day := func(offset int, hours float64) (string, *dailyTotal) {
+		date := last.AddDate(0, 0, -offset)
+		return date.Format("2006-01-02"), &dailyTotal{hours: hours, lastEnd: date.Add(8 * time.Hour)}
+	}
+	days := func(entries map[int]float64) map[string]*dailyTotal {
+		m := make(map[string]*dailyTotal)
+		for offset, hours := range entries {
+			k, v := day(offset, hours)
+			m[k] = v
+		}
+		return m
+	}
+
+	tests := []struct {
+		name     string
+		days     map[string]*dailyTotal
+		decay    float64
+		capHours float64
+		want     []float64
+	}{
+		{
+			name:     "short nights add up without decay",
+			days:     days(map[int]float64{2: 6, 1: 5, 0: 6.5}),
+			capHours: 20,
+			want:     []float64{-1, -3, -3.5},
+		},
+		{
+			name:     "decay forgives a share each day",
+			days:     days(map[int]float64{2: 5, 1: 7, 0: 7}),
+			decay:    0.5,
+			capHours: 20,
+			want:     []float64{-2, -1, -0.5},
+		},
+		{
+			name:     "extra sleep pays off debt but is not banked",
+			days:     days(map[int]float64{2: 6, 1: 9, 0: 6}),
+			capHours: 20,
+			want:     []float64{-1, 0, -1},
+		},
+		{
+			name:     "missing days only decay",
+			days:     days(map[int]float64{2: 3}),
+			decay:    0.5,
+			capHours: 20,
+			want:     []float64{-4, -2, -1},
+		},
+		{
+			name:     "debt is capped",
+			days:     days(map[int]float64{2: 2, 1: 2, 0: 2}),
+			capHours: 8,
+			want:     []float64{-5, -8, -8},
+		},
+		{
+			name:     "warmup days carry into the series",
+			days:     days(map[int]float64{5: 4, 4: 4}),
+			capHours: 20,
+			want:     []float64{-6, -6, -6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points := computeSleepDebt(tt.days, nil, last, 3, tt.decay, tt.capHours)
+			if len(points) != len(tt.want) {
+				t.Fatalf("got %d points, want %d", len(points), len(tt.want))
+			}
+			for i, p := range points {
+				if p.BalanceHours != tt.want[i] {
+					t.Errorf("point %d (%s): balance = %v, want %v", i, p.Date, p.BalanceHours, tt.want[i])
+				}
+				if p.Missing != (p.TotalHours == nil) {
+					t.Errorf("point %d: Missing = %v with total %v", i, p.Missing, p.TotalHours)
+				}
+			}
+			if points[2].Date != "2024-03-10" {
+				t.Errorf("last date = %s, want 2024-03-10", points[2].Date)
+			}
+		})
+	}
+}
+
+func TestComputeSleepDebt_UsesGoalInForce(t *testing.T) {
+	last := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	days := map[string]*dailyTotal{
+		"2024-03-09": {hours: 7, lastEnd: time.Date(2024, 3, 9, 7, 0, 0, 0, time.UTC)},
+		"2024-03-10": {hours: 7, lastEnd: time.Date(2024, 3, 10, 7, 0, 0, 0, time.UTC)},
+	}
+	changed := time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC)
+	goals := goalTimeline{
+		{TargetHours: 8, EffectiveFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), EffectiveTo: &changed},
+		{TargetHours: 9, EffectiveFrom: changed},
+	}
+
+	points := computeSleepDebt(days, goals, last, 2, 0, 20)
+
+	if points[0].TargetHours != 8 || points[1].TargetHours != 9 {
+		t.Errorf("targets = %v, %v, want 8, 9", points[0].TargetHours, points[1].TargetHours)
+	}
+	if points[1].BalanceHours != -3 {
+		t.Errorf("balance = %v, want -3", points[1].BalanceHours)
+	}
+}
+
+func TestSleepDebtService_Compute(t *testing.T) {
+	repo := NewMockSleepLogRepository()
+	userRepo := NewMockUserRepository()
+	goalRepo := NewMockSleepGoalRepository()
+	svc := NewSleepDebtService(repo, userRepo, goalRepo)
+
+	userID := uuid.New()
+	userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+
+	// Five hours a night on the three days before today; today has no sleep
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for i := 1; i <= 3; i++ {
+		end := today.AddDate(0, 0, -i).Add(7 * time.Hour)
+		log := &domain.SleepLog{ID: uuid.New(), UserID: userID, StartAt: end.Add(-5 * time.Hour), EndAt: end, Quality: 6, Type: domain.SleepTypeCore}
+		repo.logs[log.ID] = log
+	}
+
+	result, err := svc.Compute(context.Background(), userID, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.SpanDays != DefaultSleepDebtSpanDays || result.CapHours != DefaultSleepDebtCapHours || result.Decay != 0 {
+		t.Errorf("unexpected parameters: span %d, decay %v, cap %v", result.SpanDays, result.Decay, result.CapHours)
+	}
+	if len(result.Points) != DefaultSleepDebtSpanDays || result.To != today.Format("2006-01-02") {
+		t.Fatalf("got %d points up to %s", len(result.Points), result.To)
+	}
+	if result.CurrentDebtHours != 6 {
+		t.Errorf("CurrentDebtHours = %v, want 6", result.CurrentDebtHours)
+	}
+}
+
+func TestSleepDebtService_Compute_UserNotFound(t *testing.T) {
+	svc := NewSleepDebtService(NewMockSleepLogRepository(), NewMockUserRepository(), NewMockSleepGoalRepository())
+
+	_, err := svc.Compute(context.Background(), uuid.New(), 30, DefaultSleepDebtDecay, DefaultSleepDebtCapHours)
+	if !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/repository"
+	"github.com/google/uuid"
+)
+
+// SleepGoalService manages a user's sleep goals and their history.
+type SleepGoalService interface {
+	// Get returns the goal in force, or domain.ErrGoalNotSet.
+	Get(ctx context.Context, userID uuid.UUID) (*domain.SleepGoal, error)
+	// Set replaces the goal in force with a new version effective now.
+	Set(ctx context.Context, userID uuid.UUID, req *domain.SleepGoalRequest) (*domain.SleepGoal, error)
+	// Delete ends the goal in force, so the defaults apply from now on.
+	// Fails with domain.ErrGoalNotSet when no goal is in force.
+	Delete(ctx context.Context, userID uuid.UUID) error
+	// History returns all versions of the user's goals, oldest first.
+	History(ctx context.Context, userID uuid.UUID) ([]domain.SleepGoal, error)
+}
+
+type sleepGoalService struct {
+	repo     repository.SleepGoalRepository
+	userRepo repository.UserRepository
+}
+
+// NewSleepGoalService creates a new SleepGoalService.
+func NewSleepGoalService(repo repository.SleepGoalRepository, userRepo repository.UserRepository) SleepGoalService {
+	return &sleepGoalService{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+func (s *sleepGoalService) Get(ctx context.Context, userID uuid.UUID) (*domain.SleepGoal, error) {
+	if err := s.checkUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	goal, err := s.repo.Current(ctx, userID)
+	if err == domain.ErrNotFound {
+		return nil, domain.ErrGoalNotSet
+	}
+	return goal, err
+}
+
+func (s *sleepGoalService) Set(ctx context.Context, userID uuid.UUID, req *domain.SleepGoalRequest) (*domain.SleepGoal, error) {
+	if err := s.checkUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	goal := &domain.SleepGoal{
+		ID:                  uuid.New(),
+		UserID:              userID,
+		TargetHours:         req.TargetHours,
+		BedtimeWindowStart:  req.BedtimeWindowStart,
+		BedtimeWindowEnd:    req.BedtimeWindowEnd,
+		WakeTime:            req.WakeTime,
+		NapAllowanceMinutes: req.NapAllowanceMinutes,
+		EffectiveFrom:       time.Now().UTC(),
+	}
+	if err := s.repo.Replace(ctx, goal); err != nil {
+		return nil, err
+	}
+
+	return goal, nil
+}
+
+func (s *sleepGoalService) Delete(ctx context.Context, userID uuid.UUID) error {
+	if err := s.checkUser(ctx, userID); err != nil {
+		return err
+	}
+
+	err := s.repo.End(ctx, userID, time.Now().UTC())
+	if err == domain.ErrNotFound {
+		return domain.ErrGoalNotSet
+	}
+	return err
+}
+
+func (s *sleepGoalService) History(ctx context.Context, userID uuid.UUID) ([]domain.SleepGoal, error) {
+	if err := s.checkUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListByUser(ctx, userID)
+}
+
+// checkUser returns domain.ErrNotFound if the user does not exist.
+func (s *sleepGoalService) checkUser(ctx context.Context, userID uuid.UUID) error {
+	exists, err := s.userRepo.Exists(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return domain.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+func TestSleepGoalService_History(t *testing.T) {
+	userRepo := NewMockUserRepository()
+	svc := NewSleepGoalService(NewMockSleepGoalRepository(), userRepo)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+
+	if _, err := svc.Get(ctx, userID); !errors.Is(err, domain.ErrGoalNotSet) {
+		t.Fatalf("Get() before any goal: expected ErrGoalNotSet, got %v", err)
+	}
+
+	if _, err := svc.Set(ctx, userID, &domain.SleepGoalRequest{TargetHours: 7.5}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := svc.Set(ctx, userID, &domain.SleepGoalRequest{TargetHours: 8, WakeTime: strPtr("07:00")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	current, err := svc.Get(ctx, userID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if current.TargetHours != 8 || current.ID != second.ID {
+		t.Errorf("Get() = %+v, want the latest goal", current)
+	}
+
+	history, err := svc.History(ctx, userID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("got %d versions, want 2", len(history))
+	}
+	if history[0].EffectiveTo == nil || !history[0].EffectiveTo.Equal(history[1].EffectiveFrom) {
+		t.Errorf("first goal should end when the second starts: %+v", history[0])
+	}
+
+	if err := svc.Delete(ctx, userID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.Get(ctx, userID); !errors.Is(err, domain.ErrGoalNotSet) {
+		t.Errorf("Get() after Delete: expected ErrGoalNotSet, got %v", err)
+	}
+	if err := svc.Delete(ctx, userID); !errors.Is(err, domain.ErrGoalNotSet) {
+		t.Errorf("second Delete: expected ErrGoalNotSet, got %v", err)
+	}
+	if history, _ := svc.History(ctx, userID); len(history) != 2 {
+		t.Errorf("Delete should keep the history, got %d versions", len(history))
+	}
+}
+
+func TestSleepGoalService_UserNotFound(t *testing.T) {
+	svc := NewSleepGoalService(NewMockSleepGoalRepository(), NewMockUserRepository())
+
+	_, err := svc.Set(context.Background(), uuid.New(), &domain.SleepGoalRequest{TargetHours: 8})
+	if !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
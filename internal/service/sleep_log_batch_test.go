@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestSleepLogService_CreateBatch(t *testing.T) {
+	userID := uuid.New()
+	night := func(day int) domain.CreateSleepLogRequest {
+		return domain.CreateSleepLogRequest{
+			StartAt: time.Date(2024, 1, day, 23, 0, 0, 0, time.UTC),
+			EndAt:   time.Date(2024, 1, day+1, 7, 0, 0, 0, time.UTC),
+			Quality: 7,
+			Type:    domain.SleepTypeCore,
+		}
+	}
+	withClientID := func(req domain.CreateSleepLogRequest, id string) domain.CreateSleepLogRequest {
+		req.ClientRequestID = strPtr(id)
+		return req
+	}
+
+	tests := []struct {
+		name        string
+		items       []domain.CreateSleepLogRequest
+		mode        domain.BatchMode
+		setup       func(*MockSleepLogRepository)
+		wantErrs    []error
+		wantCreated int
+	}{
+		{
+			name:        "all items created",
+			items:       []domain.CreateSleepLogRequest{night(1), night(2), night(3)},
+			mode:        domain.BatchModeAtomic,
+			wantErrs:    []error{nil, nil, nil},
+			wantCreated: 3,
+		},
+		{
+			name:        "overlap within batch aborts atomic batch",
+			items:       []domain.CreateSleepLogRequest{night(1), night(1), night(3)},
+			mode:        domain.BatchModeAtomic,
+			wantErrs:    []error{domain.ErrBatchAborted, domain.ErrOverlappingSleep, domain.ErrBatchAborted},
+			wantCreated: 0,
+		},
+		{
+			name:        "overlap within batch in best effort mode",
+			items:       []domain.CreateSleepLogRequest{night(1), night(1), night(3)},
+			mode:        domain.BatchModeBestEffort,
+			wantErrs:    []error{nil, domain.ErrOverlappingSleep, nil},
+			wantCreated: 2,
+		},
+		{
+			name:  "overlap with stored log",
+			items: []domain.CreateSleepLogRequest{night(1), night(2)},
+			mode:  domain.BatchModeBestEffort,
+			setup: func(repo *MockSleepLogRepository) {
+				repo.Create(context.Background(), &domain.SleepLog{
+					UserID:  userID,
+					StartAt: time.Date(2024, 1, 3, 1, 0, 0, 0, time.UTC),
+					EndAt:   time.Date(2024, 1, 3, 5, 0, 0, 0, time.UTC),
+					Type:    domain.SleepTypeCore,
+				})
+			},
+			wantErrs:    []error{nil, domain.ErrOverlappingSleep},
+			wantCreated: 1,
+		},
+		{
+			name:  "stored client_request_id returns existing log",
+			items: []domain.CreateSleepLogRequest{withClientID(night(1), "sync-1"), night(2)},
+			mode:  domain.BatchModeAtomic,
+			setup: func(repo *MockSleepLogRepository) {
+				req := night(1)
+				repo.Create(context.Background(), &domain.SleepLog{
+					UserID:          userID,
+					StartAt:         req.StartAt,
+					EndAt:           req.EndAt,
+					Type:            domain.SleepTypeCore,
+					ClientRequestID: strPtr("sync-1"),
+				})
+			},
+			wantErrs:    []error{nil, nil},
+			wantCreated: 1,
+		},
+		{
+			name:  "client_request_id of deleted log",
+			items: []domain.CreateSleepLogRequest{withClientID(night(1), "sync-1")},
+			mode:  domain.BatchModeBestEffort,
+			setup: func(repo *MockSleepLogRepository) {
+				repo.Create(context.Background(), &domain.SleepLog{
+					UserID:          userID,
+					StartAt:         time.Date(2023, 12, 1, 23, 0, 0, 0, time.UTC),
+					EndAt:           time.Date(2023, 12, 2, 7, 0, 0, 0, time.UTC),
+					Type:            domain.SleepTypeCore,
+					ClientRequestID: strPtr("sync-1"),
+					DeletedAt:       gorm.DeletedAt{Time: time.Now(), Valid: true},
+				})
+			},
+			wantErrs:    []error{domain.ErrDuplicateRequest},
+			wantCreated: 0,
+		},
+		{
+			name:        "client_request_id repeated within batch",
+			items:       []domain.CreateSleepLogRequest{withClientID(night(1), "sync-1"), withClientID(night(2), "sync-1")},
+			mode:        domain.BatchModeBestEffort,
+			wantErrs:    []error{nil, domain.ErrDuplicateRequest},
+			wantCreated: 1,
+		},
+		{
+			name: "end before start",
+			items: []domain.CreateSleepLogRequest{{
+				StartAt: time.Date(2024, 1, 2, 7, 0, 0, 0, time.UTC),
+				EndAt:   time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC),
+				Quality: 7,
+				Type:    domain.SleepTypeCore,
+			}},
+			mode:        domain.BatchModeAtomic,
+			wantErrs:    []error{domain.ErrInvalidInput},
+			wantCreated: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := NewMockUserRepository()
+			userRepo.users[userID] = &domain.User{ID: userID, Timezone: "Europe/Prague"}
+			logRepo := NewMockSleepLogRepository()
+			if tt.setup != nil {
+				tt.setup(logRepo)
+			}
+			before := len(logRepo.logs)
+
+			svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
+			results, err := svc.CreateBatch(context.Background(), userID, tt.items, tt.mode)
+			if err != nil {
+				t.Fatalf("CreateBatch() error = %v", err)
+			}
+
+			if len(results) != len(tt.items) {
+				t.Fatalf("CreateBatch() returned %d results, want %d", len(results), len(tt.items))
+			}
+			for i, result := range results {
+				if !errors.Is(result.Err, tt.wantErrs[i]) {
+					t.Errorf("results[%d].Err = %v, want %v", i, result.Err, tt.wantErrs[i])
+				}
+				if result.Err == nil && result.Log == nil {
+					t.Errorf("results[%d].Log is nil for successful item", i)
+				}
+			}
+			if created := len(logRepo.logs) - before; created != tt.wantCreated {
+				t.Errorf("created %d logs, want %d", created, tt.wantCreated)
+			}
+		})
+	}
+}
+
+func TestSleepLogService_CreateBatch_UserNotFound(t *testing.T) {
+	svc := NewSleepLogService(NewMockSleepLogRepository(), NewMockUserRepository(), NewMockTagRepository())
+
+	_, err := svc.CreateBatch(context.Background(), uuid.New(), []domain.CreateSleepLogRequest{{}}, domain.BatchModeAtomic)
+	if !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("CreateBatch() error = %v, want %v", err, domain.ErrNotFound)
+	}
+}
+
+func TestSleepLogService_CreateBatch_AppliesDefaultTimezone(t *testing.T) {
+	userID := uuid.New()
+	userRepo := NewMockUserRepository()
+	userRepo.users[userID] = &domain.User{ID: userID, Timezone: "Europe/Prague"}
+	svc := NewSleepLogService(NewMockSleepLogRepository(), userRepo, NewMockTagRepository())
+
+	results, err := svc.CreateBatch(context.Background(), userID, []domain.CreateSleepLogRequest{
+		{
+			StartAt: time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC),
+			EndAt:   time.Date(2024, 1, 2, 7, 0, 0, 0, time.UTC),
+			Quality: 7,
+			Type:    domain.SleepTypeCore,
+		},
+		{
+			StartAt:       time.Date(2024, 1, 2, 23, 0, 0, 0, time.UTC),
+			EndAt:         time.Date(2024, 1, 3, 7, 0, 0, 0, time.UTC),
+			Quality:       7,
+			Type:          domain.SleepTypeCore,
+			LocalTimezone: strPtr("America/New_York"),
+		},
+	}, domain.BatchModeAtomic)
+	if err != nil {
+		t.Fatalf("CreateBatch() error = %v", err)
+	}
+
+	if got := results[0].Log.LocalTimezone; got != "Europe/Prague" {
+		t.Errorf("results[0] LocalTimezone = %s, want Europe/Prague", got)
+	}
+	if got := results[1].Log.LocalTimezone; got != "America/New_York" {
+		t.Errorf("results[1] LocalTimezone = %s, want America/New_York", got)
+	}
+}
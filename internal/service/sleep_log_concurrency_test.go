@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+func TestSleepLogService_Update_IfMatch(t *testing.T) {
+	userID := uuid.New()
+	logID := uuid.New()
+
+	tests := []struct {
+		name        string
+		ifMatch     int
+		wantErr     error
+		wantVersion int
+	}{
+		{name: "no precondition", ifMatch: 0, wantErr: nil, wantVersion: 4},
+		{name: "matching version", ifMatch: 3, wantErr: nil, wantVersion: 4},
+		{name: "stale version", ifMatch: 2, wantErr: domain.ErrPreconditionFailed, wantVersion: 3},
+		{name: "weak tag never matches", ifMatch: -1, wantErr: domain.ErrPreconditionFailed, wantVersion: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := NewMockUserRepository()
+			userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+			logRepo := NewMockSleepLogRepository()
+			logRepo.logs[logID] = &domain.SleepLog{
+				ID:            logID,
+				UserID:        userID,
+				StartAt:       time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC),
+				EndAt:         time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC),
+				Quality:       7,
+				Type:          domain.SleepTypeCore,
+				LocalTimezone: "UTC",
+				Version:       3,
+			}
+
+			svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
+			_, err := svc.Update(context.Background(), userID, logID, &domain.UpdateSleepLogRequest{Quality: intPtr(9)}, tt.ifMatch)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Update() error = %v, want %v", err, tt.wantErr)
+			}
+			stored := logRepo.logs[logID]
+			if stored.Version != tt.wantVersion {
+				t.Errorf("Version = %d, want %d", stored.Version, tt.wantVersion)
+			}
+			if tt.wantErr != nil && stored.Quality != 7 {
+				t.Errorf("Quality = %d, want unchanged 7", stored.Quality)
+			}
+		})
+	}
+}
+
+func TestSleepLogService_Create_StartsAtVersionOne(t *testing.T) {
+	userID := uuid.New()
+	userRepo := NewMockUserRepository()
+	userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+	svc := NewSleepLogService(NewMockSleepLogRepository(), userRepo, NewMockTagRepository())
+
+	log, _, err := svc.Create(context.Background(), userID, &domain.CreateSleepLogRequest{
+		StartAt: time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC),
+		EndAt:   time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC),
+		Quality: 7,
+		Type:    domain.SleepTypeCore,
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if log.Version != 1 {
+		t.Fatalf("Version = %d, want 1", log.Version)
+	}
+}
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+func newDeleteTestLog(userID uuid.UUID) *domain.SleepLog {
+	return &domain.SleepLog{
+		ID:            uuid.New(),
+		UserID:        userID,
+		StartAt:       time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC),
+		EndAt:         time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC),
+		Quality:       7,
+		Type:          domain.SleepTypeCore,
+		LocalTimezone: "UTC",
+		Version:       1,
+	}
+}
+
+func TestSleepLogService_Delete(t *testing.T) {
+	userID := uuid.New()
+	otherUserID := uuid.New()
+
+	tests := []struct {
+		name    string
+		userID  uuid.UUID
+		logID   func(*domain.SleepLog) uuid.UUID
+		ifMatch int
+		wantErr error
+	}{
+		{
+			name:    "deletes own log",
+			userID:  userID,
+			logID:   func(l *domain.SleepLog) uuid.UUID { return l.ID },
+			wantErr: nil,
+		},
+		{
+			name:    "log owned by another user",
+			userID:  otherUserID,
+			logID:   func(l *domain.SleepLog) uuid.UUID { return l.ID },
+			wantErr: domain.ErrNotFound,
+		},
+		{
+			name:    "unknown log",
+			userID:  userID,
+			logID:   func(*domain.SleepLog) uuid.UUID { return uuid.New() },
+			wantErr: domain.ErrNotFound,
+		},
+		{
+			name:    "unknown user",
+			userID:  uuid.New(),
+			logID:   func(l *domain.SleepLog) uuid.UUID { return l.ID },
+			wantErr: domain.ErrNotFound,
+		},
+		{
+			name:    "matching If-Match",
+			userID:  userID,
+			logID:   func(l *domain.SleepLog) uuid.UUID { return l.ID },
+			ifMatch: 1,
+			wantErr: nil,
+		},
+		{
+			name:    "stale If-Match",
+			userID:  userID,
+			logID:   func(l *domain.SleepLog) uuid.UUID { return l.ID },
+			ifMatch: 3,
+			wantErr: domain.ErrPreconditionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := NewMockUserRepository()
+			userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+			userRepo.users[otherUserID] = &domain.User{ID: otherUserID, Timezone: "UTC"}
+			logRepo := NewMockSleepLogRepository()
+			log := newDeleteTestLog(userID)
+			logRepo.logs[log.ID] = log
+
+			svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
+			deleted, err := svc.Delete(context.Background(), tt.userID, tt.logID(log), tt.ifMatch)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Delete() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !logRepo.logs[log.ID].DeletedAt.Valid {
+				t.Fatal("expected log to be soft-deleted")
+			}
+			if tt.wantErr == nil && (logRepo.logs[log.ID].Version != 2 || deleted.Version != 2) {
+				t.Fatalf("Version = %d, returned %d, want 2", logRepo.logs[log.ID].Version, deleted.Version)
+			}
+			if tt.wantErr != nil && logRepo.logs[log.ID].DeletedAt.Valid {
+				t.Fatal("expected log to be kept")
+			}
+		})
+	}
+}
+
+func TestSleepLogService_Delete_ExcludedFromQueries(t *testing.T) {
+	userID := uuid.New()
+	userRepo := NewMockUserRepository()
+	userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+	logRepo := NewMockSleepLogRepository()
+	log := newDeleteTestLog(userID)
+	logRepo.logs[log.ID] = log
+
+	svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
+	ctx := context.Background()
+
+	if _, err := svc.Delete(ctx, userID, log.ID, 0); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	// Deleting again reports not found
+	if _, err := svc.Delete(ctx, userID, log.ID, 0); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("second Delete() error = %v, want ErrNotFound", err)
+	}
+
+	list, err := svc.List(ctx, userID, domain.SleepLogFilter{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(list.Data) != 0 {
+		t.Fatalf("List() returned %d logs, want 0", len(list.Data))
+	}
+
+	// The freed period can be logged again
+	_, _, err = svc.Create(ctx, userID, &domain.CreateSleepLogRequest{
+		StartAt: log.StartAt,
+		EndAt:   log.EndAt,
+		Quality: 6,
+		Type:    domain.SleepTypeCore,
+	})
+	if err != nil {
+		t.Fatalf("Create() over deleted period error = %v", err)
+	}
+}
+
+func TestSleepLogService_Restore(t *testing.T) {
+	userID := uuid.New()
+	otherUserID := uuid.New()
+
+	tests := []struct {
+		name    string
+		userID  uuid.UUID
+		setup   func(*MockSleepLogRepository, *domain.SleepLog)
+		ifMatch int
+		wantErr error
+	}{
+		{
+			name:    "restores deleted log",
+			userID:  userID,
+			setup:   func(*MockSleepLogRepository, *domain.SleepLog) {},
+			wantErr: nil,
+		},
+		{
+			name:    "log owned by another user",
+			userID:  otherUserID,
+			setup:   func(*MockSleepLogRepository, *domain.SleepLog) {},
+			wantErr: domain.ErrNotFound,
+		},
+		{
+			name:   "log not deleted",
+			userID: userID,
+			setup: func(repo *MockSleepLogRepository, log *domain.SleepLog) {
+				repo.Restore(context.Background(), log)
+			},
+			wantErr: domain.ErrNotFound,
+		},
+		{
+			name:   "period taken by a newer log",
+			userID: userID,
+			setup: func(repo *MockSleepLogRepository, log *domain.SleepLog) {
+				newer := newDeleteTestLog(userID)
+				newer.StartAt = log.StartAt.Add(time.Hour)
+				repo.logs[newer.ID] = newer
+			},
+			wantErr: domain.ErrOverlappingSleep,
+		},
+		{
+			name:    "If-Match of the deleted version",
+			userID:  userID,
+			setup:   func(*MockSleepLogRepository, *domain.SleepLog) {},
+			ifMatch: 2,
+			wantErr: nil,
+		},
+		{
+			name:    "If-Match of the version before the delete",
+			userID:  userID,
+			setup:   func(*MockSleepLogRepository, *domain.SleepLog) {},
+			ifMatch: 1,
+			wantErr: domain.ErrPreconditionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := NewMockUserRepository()
+			userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+			userRepo.users[otherUserID] = &domain.User{ID: otherUserID, Timezone: "UTC"}
+			logRepo := NewMockSleepLogRepository()
+			log := newDeleteTestLog(userID)
+			logRepo.logs[log.ID] = log
+			logRepo.Delete(context.Background(), log)
+			tt.setup(logRepo, log)
+
+			svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
+			restored, err := svc.Restore(context.Background(), tt.userID, log.ID, tt.ifMatch)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Restore() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && restored.DeletedAt.Valid {
+				t.Fatal("expected restored log to have no deleted marker")
+			}
+			if tt.wantErr == nil && restored.Version != 3 {
+				t.Fatalf("Version = %d, want 3", restored.Version)
+			}
+		})
+	}
+}
+
+func TestSleepLogService_Create_DeletedClientRequestID(t *testing.T) {
+	userID := uuid.New()
+	userRepo := NewMockUserRepository()
+	userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+	logRepo := NewMockSleepLogRepository()
+	svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
+	ctx := context.Background()
+
+	req := &domain.CreateSleepLogRequest{
+		StartAt:         time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC),
+		EndAt:           time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC),
+		Quality:         7,
+		Type:            domain.SleepTypeCore,
+		ClientRequestID: strPtr("retry-after-delete"),
+	}
+	log, _, err := svc.Create(ctx, userID, req)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := svc.Delete(ctx, userID, log.ID, 0); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	_, _, err = svc.Create(ctx, userID, req)
+	if !errors.Is(err, domain.ErrDuplicateRequest) {
+		t.Fatalf("Create() error = %v, want ErrDuplicateRequest", err)
+	}
+}
+
+func TestRetentionService_Purge(t *testing.T) {
+	userID := uuid.New()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	logRepo := NewMockSleepLogRepository()
+
+	old := newDeleteTestLog(userID)
+	old.DeletedAt.Time, old.DeletedAt.Valid = now.AddDate(0, 0, -31), true
+	recent := newDeleteTestLog(userID)
+	recent.DeletedAt.Time, recent.DeletedAt.Valid = now.AddDate(0, 0, -5), true
+	active := newDeleteTestLog(userID)
+	for _, log := range []*domain.SleepLog{old, recent, active} {
+		logRepo.logs[log.ID] = log
+	}
+
+	svc := &retentionService{sleepLogRepo: logRepo, retentionDays: 30, now: func() time.Time { return now }}
+	purged, err := svc.Purge(context.Background())
+	if err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if purged != 1 {
+		t.Fatalf("Purge() removed %d logs, want 1", purged)
+	}
+	if _, ok := logRepo.logs[old.ID]; ok {
+		t.Error("expected log deleted 31 days ago to be purged")
+	}
+	if _, ok := logRepo.logs[recent.ID]; !ok {
+		t.Error("expected log deleted 5 days ago to be kept")
+	}
+	if _, ok := logRepo.logs[active.ID]; !ok {
+		t.Error("expected active log to be kept")
+	}
+}
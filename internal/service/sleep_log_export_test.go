@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+func TestSleepLogService_Export(t *testing.T) {
+	userID := uuid.New()
+	otherUserID := uuid.New()
+
+	userRepo := NewMockUserRepository()
+	userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+	logRepo := NewMockSleepLogRepository()
+	for _, day := range []int{3, 1, 2} {
+		logRepo.Create(context.Background(), &domain.SleepLog{
+			UserID:  userID,
+			StartAt: time.Date(2024, 1, day, 23, 0, 0, 0, time.UTC),
+			EndAt:   time.Date(2024, 1, day+1, 7, 0, 0, 0, time.UTC),
+			Type:    domain.SleepTypeCore,
+		})
+	}
+	logRepo.Create(context.Background(), &domain.SleepLog{
+		UserID:  otherUserID,
+		StartAt: time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC),
+		EndAt:   time.Date(2024, 1, 2, 7, 0, 0, 0, time.UTC),
+		Type:    domain.SleepTypeCore,
+	})
+
+	svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
+
+	t.Run("streams logs oldest first", func(t *testing.T) {
+		var days []int
+		err := svc.Export(context.Background(), userID, domain.SleepLogFilter{}, func(log *domain.SleepLog) error {
+			days = append(days, log.StartAt.Day())
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Export() error = %v", err)
+		}
+		if len(days) != 3 || days[0] != 1 || days[1] != 2 || days[2] != 3 {
+			t.Errorf("Export() days = %v, want [1 2 3]", days)
+		}
+	})
+
+	t.Run("applies time range", func(t *testing.T) {
+		from := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+		count := 0
+		err := svc.Export(context.Background(), userID, domain.SleepLogFilter{From: &from}, func(log *domain.SleepLog) error {
+			count++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Export() error = %v", err)
+		}
+		if count != 2 {
+			t.Errorf("Export() streamed %d logs, want 2", count)
+		}
+	})
+
+	t.Run("stops on callback error", func(t *testing.T) {
+		stop := errors.New("client went away")
+		err := svc.Export(context.Background(), userID, domain.SleepLogFilter{}, func(log *domain.SleepLog) error {
+			return stop
+		})
+		if !errors.Is(err, stop) {
+			t.Errorf("Export() error = %v, want %v", err, stop)
+		}
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		err := svc.Export(context.Background(), uuid.New(), domain.SleepLogFilter{}, func(log *domain.SleepLog) error {
+			t.Error("callback should not be called")
+			return nil
+		})
+		if !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("Export() error = %v, want %v", err, domain.ErrNotFound)
+		}
+	})
+}
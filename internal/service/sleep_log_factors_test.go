@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestSleepLogService_Create_Factors(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name        string
+		factors     []domain.FactorRequest
+		wantErr     error
+		wantFactors []string
+	}{
+		{
+			name: "catalogue and user tags",
+			factors: []domain.FactorRequest{
+				{Tag: "caffeine", Amount: floatPtr(200), At: timePtr(time.Date(2024, 1, 15, 16, 0, 0, 0, time.UTC))},
+				{Tag: "melatonin"},
+			},
+			wantFactors: []string{"caffeine", "melatonin"},
+		},
+		{
+			name:    "unknown tag",
+			factors: []domain.FactorRequest{{Tag: "chamomile"}},
+			wantErr: domain.ErrInvalidFactors,
+		},
+		{
+			name:    "repeated tag",
+			factors: []domain.FactorRequest{{Tag: "alcohol"}, {Tag: "alcohol"}},
+			wantErr: domain.ErrInvalidFactors,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := NewMockUserRepository()
+			userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+			tagRepo := NewMockTagRepository()
+			tagRepo.tags = append(tagRepo.tags, domain.Tag{ID: uuid.New(), UserID: &userID, Name: "melatonin"})
+			svc := NewSleepLogService(NewMockSleepLogRepository(), userRepo, tagRepo)
+
+			log, _, err := svc.Create(context.Background(), userID, &domain.CreateSleepLogRequest{
+				StartAt: time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC),
+				EndAt:   time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC),
+				Quality: 7,
+				Type:    domain.SleepTypeCore,
+				Factors: tt.factors,
+			})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Create() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Create() unexpected error = %v", err)
+			}
+			if len(log.Factors) != len(tt.wantFactors) {
+				t.Fatalf("len(Factors) = %d, want %d", len(log.Factors), len(tt.wantFactors))
+			}
+			for i, name := range tt.wantFactors {
+				if log.Factors[i].Tag.Name != name || log.Factors[i].TagID == uuid.Nil {
+					t.Errorf("factor %d = %q (tag %s), want %q", i, log.Factors[i].Tag.Name, log.Factors[i].TagID, name)
+				}
+			}
+		})
+	}
+}
+
+func TestSleepLogService_Update_Factors(t *testing.T) {
+	userID := uuid.New()
+	logID := uuid.New()
+	caffeine := domain.CatalogueTags()[0]
+
+	tests := []struct {
+		name        string
+		req         *domain.UpdateSleepLogRequest
+		wantFactors []string
+	}{
+		{
+			name:        "factors are kept when omitted",
+			req:         &domain.UpdateSleepLogRequest{Quality: intPtr(6)},
+			wantFactors: []string{"caffeine"},
+		},
+		{
+			name:        "factors are replaced",
+			req:         &domain.UpdateSleepLogRequest{Factors: []domain.FactorRequest{{Tag: "illness"}, {Tag: "stress"}}},
+			wantFactors: []string{"illness", "stress"},
+		},
+		{
+			name:        "empty factors clear them",
+			req:         &domain.UpdateSleepLogRequest{Factors: []domain.FactorRequest{}},
+			wantFactors: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := NewMockUserRepository()
+			userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+			repo := NewMockSleepLogRepository()
+			repo.logs[logID] = &domain.SleepLog{
+				ID:            logID,
+				UserID:        userID,
+				StartAt:       time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC),
+				EndAt:         time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC),
+				Quality:       7,
+				Type:          domain.SleepTypeCore,
+				LocalTimezone: "UTC",
+				Version:       1,
+				Factors:       []domain.SleepLogFactor{{SleepLogID: logID, TagID: caffeine.ID, Tag: caffeine}},
+			}
+			svc := NewSleepLogService(repo, userRepo, NewMockTagRepository())
+
+			log, err := svc.Update(context.Background(), userID, logID, tt.req, 0)
+			if err != nil {
+				t.Fatalf("Update() unexpected error = %v", err)
+			}
+			if len(log.Factors) != len(tt.wantFactors) {
+				t.Fatalf("len(Factors) = %d, want %d", len(log.Factors), len(tt.wantFactors))
+			}
+			for i, name := range tt.wantFactors {
+				if log.Factors[i].Tag.Name != name {
+					t.Errorf("factor %d = %q, want %q", i, log.Factors[i].Tag.Name, name)
+				}
+			}
+		})
+	}
+}
+
+func TestSleepLogService_CreateBatch_Factors(t *testing.T) {
+	userID := uuid.New()
+	userRepo := NewMockUserRepository()
+	userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+	svc := NewSleepLogService(NewMockSleepLogRepository(), userRepo, NewMockTagRepository())
+
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 23, 0, 0, 0, time.UTC) }
+	items := []domain.CreateSleepLogRequest{
+		{StartAt: day(1), EndAt: day(1).Add(8 * time.Hour), Quality: 7, Type: domain.SleepTypeCore, Factors: []domain.FactorRequest{{Tag: "alcohol", Amount: floatPtr(2)}}},
+		{StartAt: day(2), EndAt: day(2).Add(8 * time.Hour), Quality: 7, Type: domain.SleepTypeCore, Factors: []domain.FactorRequest{{Tag: "chamomile"}}},
+	}
+
+	results, err := svc.CreateBatch(context.Background(), userID, items, domain.BatchModeBestEffort)
+	if err != nil {
+		t.Fatalf("CreateBatch() unexpected error = %v", err)
+	}
+	if results[0].Err != nil || len(results[0].Log.Factors) != 1 {
+		t.Errorf("item 0 = %+v, want a log with one factor", results[0])
+	}
+	if !errors.Is(results[1].Err, domain.ErrInvalidFactors) {
+		t.Errorf("item 1 error = %v, want ErrInvalidFactors", results[1].Err)
+	}
+}
+
+func TestComputeFactorImpacts(t *testing.T) {
+	tags := domain.CatalogueTags()
+	alcohol := tags[1]
+	exercise := tags[3]
+
+	night := func(day, hours, quality int, factors ...domain.Tag) domain.SleepLog {
+		start := time.Date(2024, 1, day, 23, 0, 0, 0, time.UTC)
+		log := domain.SleepLog{
+			StartAt: start,
+			EndAt:   start.Add(time.Duration(hours) * time.Hour),
+			Quality: quality,
+			Type:    domain.SleepTypeCore,
+		}
+		for _, tag := range factors {
+			log.Factors = append(log.Factors, domain.SleepLogFactor{TagID: tag.ID, Tag: tag})
+		}
+		return log
+	}
+
+	logs := []domain.SleepLog{
+		night(1, 6, 5, alcohol),
+		night(2, 6, 5, alcohol, exercise),
+		night(3, 8, 8),
+		night(4, 8, 8, exercise),
+	}
+
+	impacts := computeFactorImpacts(logs)
+	if len(impacts) != 2 {
+		t.Fatalf("got %d impacts, want 2", len(impacts))
+	}
+
+	got := impacts[0]
+	if got.Tag != "alcohol" {
+		t.Fatalf("impacts[0].Tag = %q, want alcohol (sorted by name)", got.Tag)
+	}
+	if got.With.SleepCount != 2 || got.Without.SleepCount != 2 {
+		t.Errorf("group sizes = %d/%d, want 2/2", got.With.SleepCount, got.Without.SleepCount)
+	}
+	if got.DurationDiffHours == nil || *got.DurationDiffHours != -2 {
+		t.Errorf("DurationDiffHours = %v, want -2", got.DurationDiffHours)
+	}
+	if got.QualityDiff == nil || *got.QualityDiff != -3 {
+		t.Errorf("QualityDiff = %v, want -3", got.QualityDiff)
+	}
+
+	if impacts[1].Tag != "exercise" || impacts[1].DurationDiffHours == nil || *impacts[1].DurationDiffHours != 0 {
+		t.Errorf("exercise impact = %+v, want no duration difference", impacts[1])
+	}
+
+	// A factor present on every log has nothing to compare against
+	impacts = computeFactorImpacts(logs[:2])
+	if impacts[0].DurationDiffHours != nil || impacts[0].QualityDiff != nil {
+		t.Errorf("differences should be absent without a comparison group: %+v", impacts[0])
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+func TestSleepLogService_Get(t *testing.T) {
+	userID := uuid.New()
+	otherUserID := uuid.New()
+	logID := uuid.New()
+
+	userRepo := NewMockUserRepository()
+	userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+	userRepo.users[otherUserID] = &domain.User{ID: otherUserID, Timezone: "UTC"}
+
+	logRepo := NewMockSleepLogRepository()
+	logRepo.logs[logID] = &domain.SleepLog{
+		ID:            logID,
+		UserID:        userID,
+		StartAt:       time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC),
+		EndAt:         time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC),
+		Quality:       7,
+		Type:          domain.SleepTypeCore,
+		LocalTimezone: "UTC",
+	}
+
+	svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
+
+	tests := []struct {
+		name    string
+		userID  uuid.UUID
+		logID   uuid.UUID
+		wantErr error
+	}{
+		{name: "own log", userID: userID, logID: logID, wantErr: nil},
+		{name: "log owned by another user", userID: otherUserID, logID: logID, wantErr: domain.ErrNotFound},
+		{name: "unknown log", userID: userID, logID: uuid.New(), wantErr: domain.ErrNotFound},
+		{name: "unknown user", userID: uuid.New(), logID: logID, wantErr: domain.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, err := svc.Get(context.Background(), tt.userID, tt.logID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Get() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && log.ID != tt.logID {
+				t.Fatalf("Get() returned log %v, want %v", log.ID, tt.logID)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+func TestSleepLogService_Patch(t *testing.T) {
+	userID := uuid.New()
+	logID := uuid.New()
+
+	tests := []struct {
+		name          string
+		patch         string
+		ifMatch       int
+		wantErr       error
+		wantFieldErrs bool
+		validate      func(*testing.T, *domain.SleepLog)
+	}{
+		{
+			name:  "patch quality keeps other fields",
+			patch: `{"quality": 9}`,
+			validate: func(t *testing.T, log *domain.SleepLog) {
+				if log.Quality != 9 {
+					t.Errorf("Quality = %d, want 9", log.Quality)
+				}
+				if log.LocalTimezone != "America/New_York" {
+					t.Errorf("LocalTimezone = %s, want America/New_York", log.LocalTimezone)
+				}
+				if log.ClientRequestID == nil || *log.ClientRequestID != "own-id" {
+					t.Errorf("ClientRequestID = %v, want own-id", log.ClientRequestID)
+				}
+				if log.Version != 4 {
+					t.Errorf("Version = %d, want 4", log.Version)
+				}
+			},
+		},
+		{
+			name:  "null clears client_request_id and timezone",
+			patch: `{"client_request_id": null, "local_timezone": null}`,
+			validate: func(t *testing.T, log *domain.SleepLog) {
+				if log.ClientRequestID != nil {
+					t.Errorf("ClientRequestID = %v, want nil", *log.ClientRequestID)
+				}
+				if log.LocalTimezone != "Europe/Prague" {
+					t.Errorf("LocalTimezone = %s, want user default Europe/Prague", log.LocalTimezone)
+				}
+			},
+		},
+		{
+			name:    "matching If-Match",
+			patch:   `{"quality": 9}`,
+			ifMatch: 3,
+		},
+		{
+			name:    "stale If-Match",
+			patch:   `{"quality": 9}`,
+			ifMatch: 2,
+			wantErr: domain.ErrPreconditionFailed,
+		},
+		{
+			name:          "null required field",
+			patch:         `{"quality": null}`,
+			wantFieldErrs: true,
+		},
+		{
+			name:          "end before start",
+			patch:         `{"end_at": "2024-01-15T22:00:00Z"}`,
+			wantFieldErrs: true,
+		},
+		{
+			name:    "unknown field",
+			patch:   `{"mood": "happy"}`,
+			wantErr: domain.ErrInvalidPatch,
+		},
+		{
+			name:    "invalid JSON",
+			patch:   `{invalid}`,
+			wantErr: domain.ErrInvalidPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := NewMockUserRepository()
+			userRepo.users[userID] = &domain.User{ID: userID, Timezone: "Europe/Prague"}
+			logRepo := NewMockSleepLogRepository()
+			logRepo.Create(context.Background(), &domain.SleepLog{
+				ID:              logID,
+				UserID:          userID,
+				StartAt:         time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC),
+				EndAt:           time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC),
+				Quality:         7,
+				Type:            domain.SleepTypeCore,
+				LocalTimezone:   "America/New_York",
+				ClientRequestID: strPtr("own-id"),
+				Version:         3,
+			})
+
+			svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
+			log, err := svc.Patch(context.Background(), userID, logID, []byte(tt.patch), tt.ifMatch)
+
+			var validationErr *ValidationError
+			if tt.wantFieldErrs {
+				if !errors.As(err, &validationErr) || len(validationErr.Fields) == 0 {
+					t.Fatalf("Patch() error = %v, want field errors", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Patch() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.validate != nil && err == nil {
+				tt.validate(t, log)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+func TestSleepLogService_Replace(t *testing.T) {
+	userID := uuid.New()
+	logID := uuid.New()
+	otherLogID := uuid.New()
+
+	baseReq := func() *domain.CreateSleepLogRequest {
+		return &domain.CreateSleepLogRequest{
+			StartAt:       time.Date(2024, 1, 15, 22, 0, 0, 0, time.UTC),
+			EndAt:         time.Date(2024, 1, 16, 6, 0, 0, 0, time.UTC),
+			Quality:       8,
+			Type:          domain.SleepTypeCore,
+			LocalTimezone: strPtr("America/New_York"),
+		}
+	}
+
+	tests := []struct {
+		name     string
+		req      func() *domain.CreateSleepLogRequest
+		ifMatch  int
+		wantErr  error
+		validate func(*testing.T, *domain.SleepLog)
+	}{
+		{
+			name:    "replaces all fields",
+			req:     baseReq,
+			wantErr: nil,
+			validate: func(t *testing.T, log *domain.SleepLog) {
+				if log.Quality != 8 || log.LocalTimezone != "America/New_York" {
+					t.Errorf("fields not replaced: %+v", log)
+				}
+				if log.ClientRequestID != nil {
+					t.Errorf("ClientRequestID = %v, want cleared", *log.ClientRequestID)
+				}
+				if log.Version != 2 {
+					t.Errorf("Version = %d, want 2", log.Version)
+				}
+			},
+		},
+		{
+			name: "nil timezone resets to user default",
+			req: func() *domain.CreateSleepLogRequest {
+				req := baseReq()
+				req.LocalTimezone = nil
+				return req
+			},
+			wantErr: nil,
+			validate: func(t *testing.T, log *domain.SleepLog) {
+				if log.LocalTimezone != "Europe/Prague" {
+					t.Errorf("LocalTimezone = %s, want Europe/Prague", log.LocalTimezone)
+				}
+			},
+		},
+		{
+			name: "keeps own client_request_id",
+			req: func() *domain.CreateSleepLogRequest {
+				req := baseReq()
+				req.ClientRequestID = strPtr("own-id")
+				return req
+			},
+			wantErr: nil,
+		},
+		{
+			name: "client_request_id of another log",
+			req: func() *domain.CreateSleepLogRequest {
+				req := baseReq()
+				req.ClientRequestID = strPtr("other-id")
+				return req
+			},
+			wantErr: domain.ErrDuplicateRequest,
+		},
+		{
+			name: "end before start",
+			req: func() *domain.CreateSleepLogRequest {
+				req := baseReq()
+				req.EndAt = req.StartAt.Add(-time.Hour)
+				return req
+			},
+			wantErr: domain.ErrInvalidInput,
+		},
+		{
+			name: "overlaps another log",
+			req: func() *domain.CreateSleepLogRequest {
+				req := baseReq()
+				req.EndAt = time.Date(2024, 1, 16, 23, 30, 0, 0, time.UTC)
+				return req
+			},
+			wantErr: domain.ErrOverlappingSleep,
+		},
+		{
+			name:    "stale version",
+			req:     baseReq,
+			ifMatch: 5,
+			wantErr: domain.ErrPreconditionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := NewMockUserRepository()
+			userRepo.users[userID] = &domain.User{ID: userID, Timezone: "Europe/Prague"}
+			logRepo := NewMockSleepLogRepository()
+			logRepo.Create(context.Background(), &domain.SleepLog{
+				ID:              logID,
+				UserID:          userID,
+				StartAt:         time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC),
+				EndAt:           time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC),
+				Quality:         5,
+				Type:            domain.SleepTypeCore,
+				LocalTimezone:   "UTC",
+				ClientRequestID: strPtr("own-id"),
+				Version:         1,
+			})
+			logRepo.Create(context.Background(), &domain.SleepLog{
+				ID:              otherLogID,
+				UserID:          userID,
+				StartAt:         time.Date(2024, 1, 16, 23, 0, 0, 0, time.UTC),
+				EndAt:           time.Date(2024, 1, 17, 7, 0, 0, 0, time.UTC),
+				Quality:         6,
+				Type:            domain.SleepTypeCore,
+				LocalTimezone:   "UTC",
+				ClientRequestID: strPtr("other-id"),
+				Version:         1,
+			})
+
+			svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
+			log, err := svc.Replace(context.Background(), userID, logID, tt.req(), tt.ifMatch)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Replace() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.validate != nil && err == nil {
+				tt.validate(t, log)
+			}
+		})
+	}
+}
@@ -1,29 +1,70 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/blaisecz/sleep-tracker/internal/domain"
 	"github.com/blaisecz/sleep-tracker/internal/repository"
+	"github.com/blaisecz/sleep-tracker/internal/validation"
+	"github.com/blaisecz/sleep-tracker/pkg/mergepatch"
 	"github.com/blaisecz/sleep-tracker/pkg/pagination"
 	"github.com/google/uuid"
 )
 
 type SleepLogService interface {
 	Create(ctx context.Context, userID uuid.UUID, req *domain.CreateSleepLogRequest) (*domain.SleepLog, bool, error)
-	Update(ctx context.Context, userID uuid.UUID, logID uuid.UUID, req *domain.UpdateSleepLogRequest) (*domain.SleepLog, error)
+	Get(ctx context.Context, userID uuid.UUID, logID uuid.UUID) (*domain.SleepLog, error)
+	// Update applies a partial update. A non-zero ifMatch must equal the stored
+	// version, otherwise domain.ErrPreconditionFailed is returned.
+	Update(ctx context.Context, userID uuid.UUID, logID uuid.UUID, req *domain.UpdateSleepLogRequest, ifMatch int) (*domain.SleepLog, error)
+	// Replace overwrites every field of a sleep log with the given document.
+	// A nil LocalTimezone resets it to the user's timezone and a nil
+	// ClientRequestID clears it.
+	Replace(ctx context.Context, userID uuid.UUID, logID uuid.UUID, req *domain.CreateSleepLogRequest, ifMatch int) (*domain.SleepLog, error)
+	// Patch applies an RFC 7396 merge patch to the log's create document and
+	// replaces the log with the result. Malformed patches fail with
+	// domain.ErrInvalidPatch and patched documents that fail validation
+	// with a *ValidationError.
+	Patch(ctx context.Context, userID uuid.UUID, logID uuid.UUID, patch []byte, ifMatch int) (*domain.SleepLog, error)
 	List(ctx context.Context, userID uuid.UUID, filter domain.SleepLogFilter) (*domain.SleepLogListResponse, error)
+	// Delete and Restore apply the same ifMatch precondition as Update and
+	// bump the version of the log. Both return the log with its new version.
+	Delete(ctx context.Context, userID uuid.UUID, logID uuid.UUID, ifMatch int) (*domain.SleepLog, error)
+	Restore(ctx context.Context, userID uuid.UUID, logID uuid.UUID, ifMatch int) (*domain.SleepLog, error)
+	// CreateBatch creates many sleep logs at once and returns one result per
+	// item, in request order. Item failures are reported in the results; the
+	// error is only set if the batch could not be processed at all.
+	CreateBatch(ctx context.Context, userID uuid.UUID, items []domain.CreateSleepLogRequest, mode domain.BatchMode) ([]domain.BatchItemResult, error)
+	// Export streams every sleep log of the user within the filter's time
+	// range to fn, oldest first. Pagination fields of the filter are ignored.
+	Export(ctx context.Context, userID uuid.UUID, filter domain.SleepLogFilter, fn func(*domain.SleepLog) error) error
+}
+
+// ValidationError reports the invalid fields of a request document that is
+// assembled by the service rather than decoded by the handler.
+type ValidationError struct {
+	Fields []validation.FieldError
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%d invalid fields", len(e.Fields))
 }
 
 type sleepLogService struct {
 	repo     repository.SleepLogRepository
 	userRepo repository.UserRepository
+	tagRepo  repository.TagRepository
 }
 
-func NewSleepLogService(repo repository.SleepLogRepository, userRepo repository.UserRepository) SleepLogService {
+func NewSleepLogService(repo repository.SleepLogRepository, userRepo repository.UserRepository, tagRepo repository.TagRepository) SleepLogService {
 	return &sleepLogService{
 		repo:     repo,
 		userRepo: userRepo,
+		tagRepo:  tagRepo,
 	}
 }
 
@@ -52,6 +93,18 @@ func (s *sleepLogService) Create(ctx context.Context, userID uuid.UUID, req *dom
 	startUTC := req.StartAt.UTC()
 	endUTC := req.EndAt.UTC()
 
+	// Stages must tile a part of the sleep period
+	stages := domain.NewSleepStages(req.Stages)
+	if err := domain.ValidateSleepStages(startUTC, endUTC, stages); err != nil {
+		return nil, false, err
+	}
+
+	// Factors must reference known tags
+	factors, err := s.resolveFactors(ctx, userID, req.Factors)
+	if err != nil {
+		return nil, false, err
+	}
+
 	// Check for idempotency (duplicate client_request_id)
 	if req.ClientRequestID != nil && *req.ClientRequestID != "" {
 		existing, err := s.repo.GetByClientRequestID(ctx, userID, *req.ClientRequestID)
@@ -59,6 +112,10 @@ func (s *sleepLogService) Create(ctx context.Context, userID uuid.UUID, req *dom
 			return nil, false, err
 		}
 		if existing != nil {
+			// A deleted log still owns its client_request_id; it must be restored instead
+			if existing.DeletedAt.Valid {
+				return nil, false, domain.ErrDuplicateRequest
+			}
 			return existing, true, nil // Return existing log
 		}
 	}
@@ -82,6 +139,9 @@ func (s *sleepLogService) Create(ctx context.Context, userID uuid.UUID, req *dom
 		Type:            req.Type,
 		LocalTimezone:   localTZ,
 		ClientRequestID: req.ClientRequestID,
+		Version:         1,
+		Stages:          stages,
+		Factors:         factors,
 	}
 
 	if err := s.repo.Create(ctx, log); err != nil {
@@ -91,8 +151,22 @@ func (s *sleepLogService) Create(ctx context.Context, userID uuid.UUID, req *dom
 	return log, false, nil
 }
 
+// Get returns a single sleep log owned by the user
+func (s *sleepLogService) Get(ctx context.Context, userID uuid.UUID, logID uuid.UUID) (*domain.SleepLog, error) {
+	// Check if user exists
+	exists, err := s.userRepo.Exists(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+
+	return s.getOwnedLog(ctx, userID, logID)
+}
+
 // Update updates an existing sleep log
-func (s *sleepLogService) Update(ctx context.Context, userID uuid.UUID, logID uuid.UUID, req *domain.UpdateSleepLogRequest) (*domain.SleepLog, error) {
+func (s *sleepLogService) Update(ctx context.Context, userID uuid.UUID, logID uuid.UUID, req *domain.UpdateSleepLogRequest, ifMatch int) (*domain.SleepLog, error) {
 	// Check if user exists
 	exists, err := s.userRepo.Exists(ctx, userID)
 	if err != nil {
@@ -102,15 +176,15 @@ func (s *sleepLogService) Update(ctx context.Context, userID uuid.UUID, logID uu
 		return nil, domain.ErrNotFound
 	}
 
-	// Get existing log
-	log, err := s.repo.GetByID(ctx, logID)
+	// Get existing log and verify ownership
+	log, err := s.getOwnedLog(ctx, userID, logID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Verify ownership
-	if log.UserID != userID {
-		return nil, domain.ErrNotFound
+	// Reject updates based on a stale representation
+	if ifMatch != 0 && log.Version != ifMatch {
+		return nil, domain.ErrPreconditionFailed
 	}
 
 	// Apply updates
@@ -129,12 +203,25 @@ func (s *sleepLogService) Update(ctx context.Context, userID uuid.UUID, logID uu
 	if req.LocalTimezone != nil && *req.LocalTimezone != "" {
 		log.LocalTimezone = *req.LocalTimezone
 	}
+	if req.Stages != nil {
+		log.Stages = domain.NewSleepStages(req.Stages)
+	}
+	if req.Factors != nil {
+		if log.Factors, err = s.resolveFactors(ctx, userID, req.Factors); err != nil {
+			return nil, err
+		}
+	}
 
 	// Validate end > start after applying updates
 	if !log.EndAt.After(log.StartAt) {
 		return nil, domain.ErrInvalidInput
 	}
 
+	// Kept stages must still lie within a moved period
+	if err := domain.ValidateSleepStages(log.StartAt, log.EndAt, log.Stages); err != nil {
+		return nil, err
+	}
+
 	// Check for overlapping sleep periods (excluding this log)
 	hasOverlap, err := s.repo.HasOverlapExcluding(ctx, userID, logID, log.StartAt, log.EndAt, log.Type)
 	if err != nil {
@@ -152,6 +239,123 @@ func (s *sleepLogService) Update(ctx context.Context, userID uuid.UUID, logID uu
 	return log, nil
 }
 
+// Replace overwrites an existing sleep log with a full document
+func (s *sleepLogService) Replace(ctx context.Context, userID uuid.UUID, logID uuid.UUID, req *domain.CreateSleepLogRequest, ifMatch int) (*domain.SleepLog, error) {
+	// Load user to confirm existence and get their home timezone
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get existing log and verify ownership
+	log, err := s.getOwnedLog(ctx, userID, logID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reject replacements based on a stale representation
+	if ifMatch != 0 && log.Version != ifMatch {
+		return nil, domain.ErrPreconditionFailed
+	}
+
+	// A missing timezone falls back to the user's default
+	localTZ := user.Timezone
+	if req.LocalTimezone != nil && *req.LocalTimezone != "" {
+		localTZ = *req.LocalTimezone
+	}
+	if localTZ == "" {
+		localTZ = "UTC"
+	}
+
+	// Normalize empty client_request_id to "not set"
+	clientRequestID := req.ClientRequestID
+	if clientRequestID != nil && *clientRequestID == "" {
+		clientRequestID = nil
+	}
+
+	// A new client_request_id must not belong to another log
+	if clientRequestID != nil && (log.ClientRequestID == nil || *log.ClientRequestID != *clientRequestID) {
+		existing, err := s.repo.GetByClientRequestID(ctx, userID, *clientRequestID)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil && existing.ID != log.ID {
+			return nil, domain.ErrDuplicateRequest
+		}
+	}
+
+	log.StartAt = req.StartAt.UTC()
+	log.EndAt = req.EndAt.UTC()
+	log.Quality = req.Quality
+	log.Type = req.Type
+	log.LocalTimezone = localTZ
+	log.ClientRequestID = clientRequestID
+	log.Stages = domain.NewSleepStages(req.Stages)
+	if log.Factors, err = s.resolveFactors(ctx, userID, req.Factors); err != nil {
+		return nil, err
+	}
+
+	// Validate end > start
+	if !log.EndAt.After(log.StartAt) {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := domain.ValidateSleepStages(log.StartAt, log.EndAt, log.Stages); err != nil {
+		return nil, err
+	}
+
+	// Check for overlapping sleep periods (excluding this log)
+	hasOverlap, err := s.repo.HasOverlapExcluding(ctx, userID, logID, log.StartAt, log.EndAt, log.Type)
+	if err != nil {
+		return nil, err
+	}
+	if hasOverlap {
+		return nil, domain.ErrOverlappingSleep
+	}
+
+	if err := s.repo.Update(ctx, log); err != nil {
+		return nil, err
+	}
+
+	return log, nil
+}
+
+// Patch merges the patch into the current state of the log and replaces it
+func (s *sleepLogService) Patch(ctx context.Context, userID uuid.UUID, logID uuid.UUID, patch []byte, ifMatch int) (*domain.SleepLog, error) {
+	// Load the current state; its version guards the replace below so a
+	// concurrent update between read and write results in ErrPreconditionFailed.
+	current, err := s.Get(ctx, userID, logID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reject patches based on a stale representation
+	if ifMatch != 0 && current.Version != ifMatch {
+		return nil, domain.ErrPreconditionFailed
+	}
+
+	original, err := json.Marshal(current.ToCreateRequest())
+	if err != nil {
+		return nil, err
+	}
+	patched, err := mergepatch.Apply(original, patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", domain.ErrInvalidPatch, err)
+	}
+
+	var req domain.CreateSleepLogRequest
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		return nil, fmt.Errorf("%w: %w", domain.ErrInvalidPatch, err)
+	}
+
+	if fieldErrors := validation.Validate(req); fieldErrors != nil {
+		return nil, &ValidationError{Fields: fieldErrors}
+	}
+
+	return s.Replace(ctx, userID, logID, &req, current.Version)
+}
+
 func (s *sleepLogService) List(ctx context.Context, userID uuid.UUID, filter domain.SleepLogFilter) (*domain.SleepLogListResponse, error) {
 	// Check if user exists
 	exists, err := s.userRepo.Exists(ctx, userID)
@@ -199,3 +403,311 @@ func (s *sleepLogService) List(ctx context.Context, userID uuid.UUID, filter dom
 
 	return response, nil
 }
+
+// Delete soft-deletes a sleep log so it no longer counts towards listings,
+// metrics or overlap checks. It can be restored until the retention job purges it.
+func (s *sleepLogService) Delete(ctx context.Context, userID uuid.UUID, logID uuid.UUID, ifMatch int) (*domain.SleepLog, error) {
+	// Check if user exists
+	exists, err := s.userRepo.Exists(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+
+	log, err := s.getOwnedLog(ctx, userID, logID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reject deletes based on a stale representation
+	if ifMatch != 0 && log.Version != ifMatch {
+		return nil, domain.ErrPreconditionFailed
+	}
+
+	if err := s.repo.Delete(ctx, log); err != nil {
+		return nil, err
+	}
+
+	return log, nil
+}
+
+// Restore brings back a soft-deleted sleep log. Restoring fails with
+// ErrOverlappingSleep if another log was recorded in the same period meanwhile.
+func (s *sleepLogService) Restore(ctx context.Context, userID uuid.UUID, logID uuid.UUID, ifMatch int) (*domain.SleepLog, error) {
+	// Check if user exists
+	exists, err := s.userRepo.Exists(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+
+	log, err := s.repo.GetDeletedByID(ctx, logID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify ownership
+	if log.UserID != userID {
+		return nil, domain.ErrNotFound
+	}
+
+	// Reject restores based on a stale representation
+	if ifMatch != 0 && log.Version != ifMatch {
+		return nil, domain.ErrPreconditionFailed
+	}
+
+	// Check for overlapping sleep periods logged while this one was deleted
+	hasOverlap, err := s.repo.HasOverlapExcluding(ctx, userID, logID, log.StartAt, log.EndAt, log.Type)
+	if err != nil {
+		return nil, err
+	}
+	if hasOverlap {
+		return nil, domain.ErrOverlappingSleep
+	}
+
+	if err := s.repo.Restore(ctx, log); err != nil {
+		return nil, err
+	}
+
+	return log, nil
+}
+
+// CreateBatch runs the same checks as Create for every item, but against the
+// stored logs and the preceding items of the batch with a constant number of
+// queries. In atomic mode nothing is inserted if any item fails and the
+// otherwise valid items fail with ErrBatchAborted.
+func (s *sleepLogService) CreateBatch(ctx context.Context, userID uuid.UUID, items []domain.CreateSleepLogRequest, mode domain.BatchMode) ([]domain.BatchItemResult, error) {
+	// Load user to confirm existence and get their home timezone
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve the tags of all items with a single query
+	var tagNames []string
+	for _, req := range items {
+		tagNames = append(tagNames, domain.FactorTagNames(req.Factors)...)
+	}
+	tags, err := s.loadTags(ctx, userID, tagNames)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]domain.BatchItemResult, len(items))
+	candidates := make([]*domain.SleepLog, len(items))
+	clientIDIndex := make(map[string]int)
+	var clientIDs []string
+	var from, to time.Time
+
+	for i, req := range items {
+		startUTC := req.StartAt.UTC()
+		endUTC := req.EndAt.UTC()
+		if !endUTC.After(startUTC) {
+			results[i].Err = domain.ErrInvalidInput
+			continue
+		}
+		stages := domain.NewSleepStages(req.Stages)
+		if err := domain.ValidateSleepStages(startUTC, endUTC, stages); err != nil {
+			results[i].Err = err
+			continue
+		}
+		factors, err := domain.NewSleepLogFactors(req.Factors, tags)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		localTZ := user.Timezone
+		if req.LocalTimezone != nil && *req.LocalTimezone != "" {
+			localTZ = *req.LocalTimezone
+		}
+		if localTZ == "" {
+			localTZ = "UTC"
+		}
+
+		// A client_request_id may only appear once per batch
+		clientRequestID := req.ClientRequestID
+		if clientRequestID != nil && *clientRequestID == "" {
+			clientRequestID = nil
+		}
+		if clientRequestID != nil {
+			if _, seen := clientIDIndex[*clientRequestID]; seen {
+				results[i].Err = domain.ErrDuplicateRequest
+				continue
+			}
+			clientIDIndex[*clientRequestID] = i
+			clientIDs = append(clientIDs, *clientRequestID)
+		}
+
+		candidates[i] = &domain.SleepLog{
+			ID:              uuid.New(),
+			UserID:          userID,
+			StartAt:         startUTC,
+			EndAt:           endUTC,
+			Quality:         req.Quality,
+			Type:            req.Type,
+			LocalTimezone:   localTZ,
+			ClientRequestID: clientRequestID,
+			Version:         1,
+			Stages:          stages,
+			Factors:         factors,
+		}
+		if from.IsZero() || startUTC.Before(from) {
+			from = startUTC
+		}
+		if endUTC.After(to) {
+			to = endUTC
+		}
+	}
+
+	// Check for idempotency (client_request_ids already stored)
+	existing, err := s.repo.ListByClientRequestIDs(ctx, userID, clientIDs)
+	if err != nil {
+		return nil, err
+	}
+	for j := range existing {
+		log := &existing[j]
+		i := clientIDIndex[*log.ClientRequestID]
+		candidates[i] = nil
+		// A deleted log still owns its client_request_id; it must be restored instead
+		if log.DeletedAt.Valid {
+			results[i].Err = domain.ErrDuplicateRequest
+			continue
+		}
+		results[i].Log = log
+		results[i].Existing = true
+	}
+
+	// Check for overlaps with stored logs and with earlier items of the batch
+	var stored []domain.SleepLog
+	if !from.IsZero() {
+		stored, err = s.repo.ListOverlapping(ctx, userID, from, to)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var accepted []*domain.SleepLog
+	var acceptedIdx []int
+	for i, log := range candidates {
+		if log == nil {
+			continue
+		}
+		if overlapsStored(log, stored) || overlapsAccepted(log, accepted) {
+			results[i].Err = domain.ErrOverlappingSleep
+			continue
+		}
+		accepted = append(accepted, log)
+		acceptedIdx = append(acceptedIdx, i)
+	}
+
+	if mode != domain.BatchModeBestEffort {
+		for _, result := range results {
+			if result.Err != nil {
+				for _, i := range acceptedIdx {
+					results[i].Err = domain.ErrBatchAborted
+				}
+				return results, nil
+			}
+		}
+		if err := s.repo.CreateBatch(ctx, accepted); err != nil {
+			return nil, err
+		}
+	} else if err := s.repo.CreateBatch(ctx, accepted); err != nil {
+		// Fall back to one insert per item so a single bad row
+		// (e.g. a concurrently created client_request_id) only fails itself
+		for n, log := range accepted {
+			if err := s.repo.Create(ctx, log); err != nil {
+				results[acceptedIdx[n]].Err = err
+				accepted[n] = nil
+			}
+		}
+	}
+
+	for n, log := range accepted {
+		if log != nil {
+			results[acceptedIdx[n]].Log = log
+		}
+	}
+
+	return results, nil
+}
+
+// overlapsStored reports whether log intersects any of the stored logs.
+func overlapsStored(log *domain.SleepLog, stored []domain.SleepLog) bool {
+	for i := range stored {
+		if log.StartAt.Before(stored[i].EndAt) && log.EndAt.After(stored[i].StartAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlapsAccepted reports whether log intersects any log accepted earlier in the batch.
+func overlapsAccepted(log *domain.SleepLog, accepted []*domain.SleepLog) bool {
+	for _, other := range accepted {
+		if log.StartAt.Before(other.EndAt) && log.EndAt.After(other.StartAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// Export checks the user before streaming, so a missing user is reported
+// before fn is called for the first time.
+func (s *sleepLogService) Export(ctx context.Context, userID uuid.UUID, filter domain.SleepLogFilter, fn func(*domain.SleepLog) error) error {
+	// Check if user exists
+	exists, err := s.userRepo.Exists(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return domain.ErrNotFound
+	}
+
+	return s.repo.Iterate(ctx, userID, filter, fn)
+}
+
+// resolveFactors turns factor requests into factors of the user's tags.
+func (s *sleepLogService) resolveFactors(ctx context.Context, userID uuid.UUID, reqs []domain.FactorRequest) ([]domain.SleepLogFactor, error) {
+	tags, err := s.loadTags(ctx, userID, domain.FactorTagNames(reqs))
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewSleepLogFactors(reqs, tags)
+}
+
+// loadTags returns the catalogue and user tags with the given names, keyed by name.
+func (s *sleepLogService) loadTags(ctx context.Context, userID uuid.UUID, names []string) (map[string]domain.Tag, error) {
+	tags := make(map[string]domain.Tag)
+	if len(names) == 0 {
+		return tags, nil
+	}
+	found, err := s.tagRepo.GetByNames(ctx, userID, names)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range found {
+		tags[tag.Name] = tag
+	}
+	return tags, nil
+}
+
+// getOwnedLog loads a sleep log and verifies it belongs to the user.
+// Logs owned by other users are reported as not found.
+func (s *sleepLogService) getOwnedLog(ctx context.Context, userID uuid.UUID, logID uuid.UUID) (*domain.SleepLog, error) {
+	log, err := s.repo.GetByID(ctx, logID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify ownership
+	if log.UserID != userID {
+		return nil, domain.ErrNotFound
+	}
+
+	return log, nil
+}
@@ -98,7 +98,7 @@ func TestSleepLogService_Create(t *testing.T) {
 				tt.setupLogs(logRepo)
 			}
 
-			svc := NewSleepLogService(logRepo, userRepo)
+			svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
 			log, isExisting, err := svc.Create(context.Background(), userID, tt.req)
 
 			if err != tt.wantErr {
@@ -137,7 +137,7 @@ func TestSleepLogService_List_DefaultsAndCursor(t *testing.T) {
 	logRepo := NewMockSleepLogRepository()
 	logRepo.listResult = logs
 
-	svc := NewSleepLogService(logRepo, userRepo)
+	svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
 
 	resp, err := svc.List(context.Background(), userID, domain.SleepLogFilter{})
 	if err != nil {
@@ -158,7 +158,7 @@ func TestSleepLogService_List_DefaultsAndCursor(t *testing.T) {
 func TestSleepLogService_Create_UserNotFound(t *testing.T) {
 	userRepo := NewMockUserRepository()
 	logRepo := NewMockSleepLogRepository()
-	svc := NewSleepLogService(logRepo, userRepo)
+	svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
 
 	req := &domain.CreateSleepLogRequest{
 		StartAt: time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC),
@@ -257,7 +257,7 @@ func TestSleepLogService_Create_TravelScenario(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			logRepo := NewMockSleepLogRepository()
-			svc := NewSleepLogService(logRepo, userRepo)
+			svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
 
 			log, isExisting, err := svc.Create(context.Background(), userID, tt.req)
 
@@ -341,7 +341,7 @@ func TestSleepLogService_Create_TimezoneEdgeCases(t *testing.T) {
 			userRepo := NewMockUserRepository()
 			userRepo.users[userID] = &domain.User{ID: userID, Timezone: tt.userTimezone}
 			logRepo := NewMockSleepLogRepository()
-			svc := NewSleepLogService(logRepo, userRepo)
+			svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
 
 			req := &domain.CreateSleepLogRequest{
 				StartAt:       time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC),
@@ -404,7 +404,7 @@ func TestSleepLogService_Create_LongSleepDurations(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			logRepo := NewMockSleepLogRepository()
-			svc := NewSleepLogService(logRepo, userRepo)
+			svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
 
 			req := &domain.CreateSleepLogRequest{
 				StartAt: tt.startAt,
@@ -438,7 +438,7 @@ func TestSleepLogService_Create_ClientRequestIDScopedPerUser(t *testing.T) {
 	userRepo.users[userB] = &domain.User{ID: userB, Timezone: "UTC"}
 
 	logRepo := NewMockSleepLogRepository()
-	svc := NewSleepLogService(logRepo, userRepo)
+	svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
 
 	clientReqID := "req-123"
 
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+func TestSleepLogService_Create_Stages(t *testing.T) {
+	userID := uuid.New()
+	start := time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		stages     []domain.SleepStageRequest
+		wantErr    error
+		wantStages int
+	}{
+		{
+			name: "stages are stored in order",
+			stages: []domain.SleepStageRequest{
+				{Stage: domain.SleepStageDeep, StartAt: start.Add(2 * time.Hour), EndAt: start.Add(4 * time.Hour)},
+				{Stage: domain.SleepStageLight, StartAt: start, EndAt: start.Add(2 * time.Hour)},
+			},
+			wantStages: 2,
+		},
+		{
+			name:       "no stages",
+			wantStages: 0,
+		},
+		{
+			name: "stages must not leave the sleep period",
+			stages: []domain.SleepStageRequest{
+				{Stage: domain.SleepStageREM, StartAt: end.Add(-time.Hour), EndAt: end.Add(time.Hour)},
+			},
+			wantErr: domain.ErrInvalidStages,
+		},
+		{
+			name: "stages must be contiguous",
+			stages: []domain.SleepStageRequest{
+				{Stage: domain.SleepStageLight, StartAt: start, EndAt: start.Add(time.Hour)},
+				{Stage: domain.SleepStageDeep, StartAt: start.Add(2 * time.Hour), EndAt: start.Add(3 * time.Hour)},
+			},
+			wantErr: domain.ErrInvalidStages,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := NewMockUserRepository()
+			userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+			svc := NewSleepLogService(NewMockSleepLogRepository(), userRepo, NewMockTagRepository())
+
+			log, _, err := svc.Create(context.Background(), userID, &domain.CreateSleepLogRequest{
+				StartAt: start,
+				EndAt:   end,
+				Quality: 8,
+				Type:    domain.SleepTypeCore,
+				Stages:  tt.stages,
+			})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Create() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Create() unexpected error = %v", err)
+			}
+			if len(log.Stages) != tt.wantStages {
+				t.Fatalf("len(Stages) = %d, want %d", len(log.Stages), tt.wantStages)
+			}
+			if tt.wantStages > 0 && log.Stages[0].Stage != domain.SleepStageLight {
+				t.Errorf("first stage = %s, want LIGHT", log.Stages[0].Stage)
+			}
+		})
+	}
+}
+
+func TestSleepLogService_Update_Stages(t *testing.T) {
+	userID := uuid.New()
+	logID := uuid.New()
+	start := time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC)
+
+	baseLog := domain.SleepLog{
+		ID:            logID,
+		UserID:        userID,
+		StartAt:       start,
+		EndAt:         end,
+		Quality:       7,
+		Type:          domain.SleepTypeCore,
+		LocalTimezone: "UTC",
+		Version:       1,
+		Stages: []domain.SleepStage{
+			{Stage: domain.SleepStageLight, StartAt: start, EndAt: start.Add(3 * time.Hour)},
+			{Stage: domain.SleepStageDeep, StartAt: start.Add(3 * time.Hour), EndAt: end},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		req        *domain.UpdateSleepLogRequest
+		wantErr    error
+		wantStages int
+	}{
+		{
+			name:       "stages are kept when omitted",
+			req:        &domain.UpdateSleepLogRequest{Quality: intPtr(9)},
+			wantStages: 2,
+		},
+		{
+			name: "stages are replaced",
+			req: &domain.UpdateSleepLogRequest{Stages: []domain.SleepStageRequest{
+				{Stage: domain.SleepStageREM, StartAt: start.Add(time.Hour), EndAt: start.Add(2 * time.Hour)},
+			}},
+			wantStages: 1,
+		},
+		{
+			name:       "empty stages clear them",
+			req:        &domain.UpdateSleepLogRequest{Stages: []domain.SleepStageRequest{}},
+			wantStages: 0,
+		},
+		{
+			name:    "kept stages must stay within a shortened period",
+			req:     &domain.UpdateSleepLogRequest{EndAt: timePtr(end.Add(-time.Hour))},
+			wantErr: domain.ErrInvalidStages,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := NewMockUserRepository()
+			userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+			repo := NewMockSleepLogRepository()
+			logCopy := baseLog
+			logCopy.Stages = append([]domain.SleepStage(nil), baseLog.Stages...)
+			repo.logs[logID] = &logCopy
+			svc := NewSleepLogService(repo, userRepo, NewMockTagRepository())
+
+			log, err := svc.Update(context.Background(), userID, logID, tt.req, 0)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Update() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Update() unexpected error = %v", err)
+			}
+			if len(log.Stages) != tt.wantStages {
+				t.Errorf("len(Stages) = %d, want %d", len(log.Stages), tt.wantStages)
+			}
+		})
+	}
+}
+
+func TestComputeStageMetrics(t *testing.T) {
+	start := time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC)
+	at := func(min int) time.Time { return start.Add(time.Duration(min) * time.Minute) }
+
+	logs := []domain.SleepLog{
+		{
+			// 8h in bed: 30m awake before onset, 6h asleep with 30m awake in between, 1h awake at the end
+			StartAt: at(0),
+			EndAt:   at(480),
+			Stages: []domain.SleepStage{
+				{Stage: domain.SleepStageAwake, StartAt: at(0), EndAt: at(30)},
+				{Stage: domain.SleepStageLight, StartAt: at(30), EndAt: at(150)},
+				{Stage: domain.SleepStageDeep, StartAt: at(150), EndAt: at(240)},
+				{Stage: domain.SleepStageAwake, StartAt: at(240), EndAt: at(270)},
+				{Stage: domain.SleepStageREM, StartAt: at(270), EndAt: at(420)},
+				{Stage: domain.SleepStageAwake, StartAt: at(420), EndAt: at(480)},
+			},
+		},
+		{
+			// Logs without stages are ignored
+			StartAt: at(1440),
+			EndAt:   at(1920),
+		},
+	}
+
+	got := computeStageMetrics(logs)
+	if got == nil {
+		t.Fatal("computeStageMetrics() = nil, want metrics")
+	}
+	if got.SleepCount != 1 {
+		t.Errorf("SleepCount = %d, want 1", got.SleepCount)
+	}
+	if got.DeepPercent.Avg != 25 {
+		t.Errorf("DeepPercent = %v, want 25", got.DeepPercent.Avg)
+	}
+	if got.REMPercent.Avg != 41.67 {
+		t.Errorf("REMPercent = %v, want 41.67", got.REMPercent.Avg)
+	}
+	if got.WASOMinutes.Avg != 30 {
+		t.Errorf("WASOMinutes = %v, want 30", got.WASOMinutes.Avg)
+	}
+	if got.EfficiencyPercent.Avg != 75 {
+		t.Errorf("EfficiencyPercent = %v, want 75", got.EfficiencyPercent.Avg)
+	}
+
+	if computeStageMetrics(logs[1:]) != nil {
+		t.Error("computeStageMetrics() without stages should be nil")
+	}
+}
@@ -202,8 +202,8 @@ func TestSleepLogService_Update(t *testing.T) {
 				tt.setupLogs(logRepo)
 			}
 
-			svc := NewSleepLogService(logRepo, userRepo)
-			log, err := svc.Update(context.Background(), userID, logID, tt.req)
+			svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
+			log, err := svc.Update(context.Background(), userID, logID, tt.req, 0)
 
 			if err != tt.wantErr {
 				t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
@@ -220,13 +220,13 @@ func TestSleepLogService_Update(t *testing.T) {
 func TestSleepLogService_Update_UserNotFound(t *testing.T) {
 	userRepo := NewMockUserRepository()
 	logRepo := NewMockSleepLogRepository()
-	svc := NewSleepLogService(logRepo, userRepo)
+	svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
 
 	req := &domain.UpdateSleepLogRequest{
 		Quality: intPtr(9),
 	}
 
-	_, err := svc.Update(context.Background(), uuid.New(), uuid.New(), req)
+	_, err := svc.Update(context.Background(), uuid.New(), uuid.New(), req, 0)
 	if err != domain.ErrNotFound {
 		t.Errorf("Update() error = %v, want %v", err, domain.ErrNotFound)
 	}
@@ -251,13 +251,13 @@ func TestSleepLogService_Update_WrongOwner(t *testing.T) {
 		Type:    domain.SleepTypeCore,
 	}
 
-	svc := NewSleepLogService(logRepo, userRepo)
+	svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
 
 	req := &domain.UpdateSleepLogRequest{
 		Quality: intPtr(9),
 	}
 
-	_, err := svc.Update(context.Background(), userID, logID, req)
+	_, err := svc.Update(context.Background(), userID, logID, req, 0)
 	if err != domain.ErrNotFound {
 		t.Errorf("Update() error = %v, want %v (ownership check)", err, domain.ErrNotFound)
 	}
@@ -359,8 +359,8 @@ func TestSleepLogService_Update_OverlapDetection(t *testing.T) {
 				tt.setupLogs(logRepo)
 			}
 
-			svc := NewSleepLogService(logRepo, userRepo)
-			_, err := svc.Update(context.Background(), userID, logID, tt.req)
+			svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
+			_, err := svc.Update(context.Background(), userID, logID, tt.req, 0)
 
 			if err != tt.wantErr {
 				t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
@@ -387,14 +387,14 @@ func TestSleepLogService_Update_EmptyTimezoneIgnored(t *testing.T) {
 		LocalTimezone: "Europe/Warsaw",
 	}
 
-	svc := NewSleepLogService(logRepo, userRepo)
+	svc := NewSleepLogService(logRepo, userRepo, NewMockTagRepository())
 
 	// Empty timezone should not change existing value
 	req := &domain.UpdateSleepLogRequest{
 		LocalTimezone: strPtr(""),
 	}
 
-	log, err := svc.Update(context.Background(), userID, logID, req)
+	log, err := svc.Update(context.Background(), userID, logID, req, 0)
 	if err != nil {
 		t.Fatalf("Update() error = %v", err)
 	}
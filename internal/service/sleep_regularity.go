@@ -0,0 +1,93 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+)
+
+// MinSRIDayPairs is the minimum number of consecutive recorded day pairs
+// needed for the Sleep Regularity Index.
+const MinSRIDayPairs = 3
+
+// computeSleepRegularity calculates the Sleep Regularity Index over the whole
+// local days of the window: the probability of being in the same sleep/wake
+// state at two time points 24 hours apart, scaled to -100 (opposite states)
+// to 100 (identical days). Each local day is rebuilt as a minute-level
+// sleep/wake vector from all logs, naps included. A day counts as recorded
+// when logs end on it and on the next day, so both its morning and its
+// evening are known. Only pairs of consecutive recorded days are compared,
+// so days without logs are not mistaken for a day awake. Minutes
+// are compared by local clock time, so DST changes do not shift the series.
+func computeSleepRegularity(logs []domain.SleepLog, from, to time.Time, loc *time.Location) domain.SleepRegularity {
+	result := domain.SleepRegularity{Timezone: loc.String()}
+
+	endDates := make(map[string]bool)
+	for _, log := range logs {
+		endDates[log.EndAt.In(loc).Format("2006-01-02")] = true
+	}
+
+	// Start at the first local midnight inside the window
+	fromLocal := from.In(loc)
+	first := time.Date(fromLocal.Year(), fromLocal.Month(), fromLocal.Day(), 0, 0, 0, 0, loc)
+	if first.Before(from) {
+		first = first.AddDate(0, 0, 1)
+	}
+
+	var previous []bool
+	same, total := 0, 0
+	for day := first; !day.AddDate(0, 0, 1).After(to); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+		if !endDates[day.Format("2006-01-02")] || !endDates[next.Format("2006-01-02")] {
+			previous = nil
+			continue
+		}
+		result.DaysRecorded++
+
+		states := sleepWakeMinutes(logs, day, loc)
+		if previous != nil {
+			result.DayPairs++
+			for m, asleep := range states {
+				if asleep == previous[m] {
+					same++
+				}
+				total++
+			}
+		}
+		previous = states
+	}
+
+	if result.DayPairs >= MinSRIDayPairs {
+		sri := math.Round((200*float64(same)/float64(total)-100)*10) / 10
+		result.SRI = &sri
+	}
+	return result
+}
+
+// sleepWakeMinutes returns whether the user was asleep at each minute of the
+// local day starting at day.
+func sleepWakeMinutes(logs []domain.SleepLog, day time.Time, loc *time.Location) []bool {
+	end := day.AddDate(0, 0, 1)
+	var overlapping []domain.SleepLog
+	for _, log := range logs {
+		if log.StartAt.Before(end) && log.EndAt.After(day) {
+			overlapping = append(overlapping, log)
+		}
+	}
+
+	states := make([]bool, 24*60)
+	if len(overlapping) == 0 {
+		return states
+	}
+	for m := range states {
+		at := time.Date(day.Year(), day.Month(), day.Day(), 0, m, 0, 0, loc)
+		for _, log := range overlapping {
+			if !at.Before(log.StartAt) && at.Before(log.EndAt) {
+				states[m] = true
+				break
+			}
+		}
+	}
+	return states
+}
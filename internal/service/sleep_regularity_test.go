@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+// nightLogs returns one log per night, starting on the evening of base plus
+// i days at the given offset from midnight.
+func nightLogs(base time.Time, offsets []time.Duration, duration time.Duration) []domain.SleepLog {
+	logs := make([]domain.SleepLog, len(offsets))
+	for i, offset := range offsets {
+		start := base.AddDate(0, 0, i).Add(offset)
+		logs[i] = domain.SleepLog{
+			ID:      uuid.New(),
+			StartAt: start,
+			EndAt:   start.Add(duration),
+			Quality: 7,
+			Type:    domain.SleepTypeCore,
+		}
+	}
+	return logs
+}
+
+func TestComputeSleepRegularity(t *testing.T) {
+	base := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	regular := []time.Duration{23 * time.Hour, 23 * time.Hour, 23 * time.Hour, 23 * time.Hour, 23 * time.Hour, 23 * time.Hour}
+	// Alternating 23:00 and 01:00 bedtimes: 4 of 24 hours differ between days
+	alternating := []time.Duration{23 * time.Hour, 25 * time.Hour, 23 * time.Hour, 25 * time.Hour, 23 * time.Hour, 25 * time.Hour}
+
+	tests := []struct {
+		name         string
+		logs         []domain.SleepLog
+		wantSRI      *float64
+		wantDays     int
+		wantDayPairs int
+	}{
+		{
+			name:         "same schedule every day",
+			logs:         nightLogs(base, regular, 8*time.Hour),
+			wantSRI:      floatPtr(100),
+			wantDays:     5,
+			wantDayPairs: 4,
+		},
+		{
+			name:         "alternating schedule",
+			logs:         nightLogs(base, alternating, 8*time.Hour),
+			wantSRI:      floatPtr(66.7),
+			wantDays:     5,
+			wantDayPairs: 4,
+		},
+		{
+			name:         "too few consecutive days",
+			logs:         append(nightLogs(base, regular[:2], 8*time.Hour), nightLogs(base.AddDate(0, 0, 4), regular[:2], 8*time.Hour)...),
+			wantSRI:      nil,
+			wantDays:     2,
+			wantDayPairs: 0,
+		},
+		{
+			name:         "no logs",
+			wantSRI:      nil,
+			wantDays:     0,
+			wantDayPairs: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computeSleepRegularity(tt.logs, base, base.AddDate(0, 0, 8), time.UTC)
+
+			if got.DaysRecorded != tt.wantDays || got.DayPairs != tt.wantDayPairs {
+				t.Errorf("days/pairs = %d/%d, want %d/%d", got.DaysRecorded, got.DayPairs, tt.wantDays, tt.wantDayPairs)
+			}
+			if (got.SRI == nil) != (tt.wantSRI == nil) || (got.SRI != nil && *got.SRI != *tt.wantSRI) {
+				t.Errorf("SRI = %v, want %v", deref(got.SRI), deref(tt.wantSRI))
+			}
+		})
+	}
+}
+
+func TestComputeSleepRegularity_LocalTimezone(t *testing.T) {
+	prague, err := time.LoadLocation("Europe/Prague")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	// 23:00 to 07:00 local time across the change to summer time on 31 March
+	var logs []domain.SleepLog
+	for day := 26; day <= 31; day++ {
+		start := time.Date(2024, 3, day, 23, 0, 0, 0, prague)
+		end := time.Date(2024, 3, day+1, 7, 0, 0, 0, prague)
+		logs = append(logs, domain.SleepLog{ID: uuid.New(), StartAt: start, EndAt: end, Type: domain.SleepTypeCore})
+	}
+
+	got := computeSleepRegularity(logs, time.Date(2024, 3, 26, 0, 0, 0, 0, prague), time.Date(2024, 4, 2, 0, 0, 0, 0, prague), prague)
+
+	if got.Timezone != "Europe/Prague" || got.SRI == nil || *got.SRI != 100 {
+		t.Errorf("got %+v with SRI %v, want 100 in Europe/Prague", got, deref(got.SRI))
+	}
+}
+
+func TestComputeDerivedScores_RegularitySource(t *testing.T) {
+	perSleep := domain.PerSleepMetrics{
+		SleepCount: 10,
+		Duration:   domain.DescriptiveStats{Avg: 9},
+		Bedtime:    domain.DescriptiveStats{Std: 60},
+	}
+	daily := domain.DailyOverallMetrics{TargetHours: DefaultTargetHours, DailySufficiencyScore: 100}
+	sri := 90.0
+
+	tests := []struct {
+		name        string
+		sri         *float64
+		source      domain.RegularitySource
+		wantSource  domain.RegularitySource
+		wantOverall float64
+	}{
+		{"bedtime std", &sri, domain.RegularitySourceBedtimeStd, domain.RegularitySourceBedtimeStd, 80},
+		{"sri", &sri, domain.RegularitySourceSRI, domain.RegularitySourceSRI, 96},
+		{"sri unavailable", nil, domain.RegularitySourceSRI, domain.RegularitySourceBedtimeStd, 80},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scoringModelWith(tt.source).Score(perSleep, daily, tt.sri)
+
+			if got.ConsistencyScore != 50 {
+				t.Errorf("ConsistencyScore = %v, want 50", got.ConsistencyScore)
+			}
+			if got.RegularitySource != tt.wantSource || got.OverallSleepScore != tt.wantOverall {
+				t.Errorf("source/overall = %v/%v, want %v/%v", got.RegularitySource, got.OverallSleepScore, tt.wantSource, tt.wantOverall)
+			}
+			if got.SleepRegularityIndex != tt.sri {
+				t.Errorf("SleepRegularityIndex = %v, want %v", got.SleepRegularityIndex, tt.sri)
+			}
+		})
+	}
+}
+
+func TestMetricsService_ComputeWindow_Regularity(t *testing.T) {
+	repo := NewMockSleepLogRepository()
+	userRepo := NewMockUserRepository()
+	svc := NewMetricsService(repo, userRepo, NewMockSleepGoalRepository(), scoringModelWith(domain.RegularitySourceSRI))
+
+	userID := uuid.New()
+	userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+
+	base := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, log := range nightLogs(base, []time.Duration{23 * time.Hour, 23 * time.Hour, 23 * time.Hour, 23 * time.Hour, 23 * time.Hour}, 8*time.Hour) {
+		log.UserID = userID
+		repo.logs[log.ID] = &log
+	}
+
+	got, err := svc.ComputeWindow(context.Background(), userID, base, base.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Regularity.SRI == nil || *got.Regularity.SRI != 100 {
+		t.Fatalf("SRI = %v, want 100", deref(got.Regularity.SRI))
+	}
+	if got.Scores.RegularitySource != domain.RegularitySourceSRI || got.Scores.SleepRegularityIndex == nil {
+		t.Errorf("scores do not use the SRI: %+v", got.Scores)
+	}
+}
+
+// deref returns the value behind p for messages, or nil.
+func deref(p *float64) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/blaisecz/sleep-tracker/internal/repository"
+	"github.com/google/uuid"
+)
+
+// TagService manages the factor tags available to a user.
+type TagService interface {
+	// List returns the catalogue tags and the user's own tags.
+	List(ctx context.Context, userID uuid.UUID) ([]domain.Tag, error)
+	// Create adds a user-defined tag. Names already used by the catalogue or
+	// another of the user's tags fail with domain.ErrConflict.
+	Create(ctx context.Context, userID uuid.UUID, req *domain.CreateTagRequest) (*domain.Tag, error)
+}
+
+type tagService struct {
+	repo     repository.TagRepository
+	userRepo repository.UserRepository
+}
+
+// NewTagService creates a new TagService.
+func NewTagService(repo repository.TagRepository, userRepo repository.UserRepository) TagService {
+	return &tagService{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+func (s *tagService) List(ctx context.Context, userID uuid.UUID) ([]domain.Tag, error) {
+	// Check if user exists
+	exists, err := s.userRepo.Exists(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+
+	return s.repo.ListForUser(ctx, userID)
+}
+
+func (s *tagService) Create(ctx context.Context, userID uuid.UUID, req *domain.CreateTagRequest) (*domain.Tag, error) {
+	// Check if user exists
+	exists, err := s.userRepo.Exists(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+
+	// Tag names are referenced without an owner, so they must be unambiguous
+	existing, err := s.repo.GetByNames(ctx, userID, []string{req.Name})
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return nil, domain.ErrConflict
+	}
+
+	tag := &domain.Tag{
+		ID:       uuid.New(),
+		UserID:   &userID,
+		Name:     req.Name,
+		Label:    req.Label,
+		Category: req.Category,
+		Unit:     req.Unit,
+	}
+	if tag.Label == "" {
+		tag.Label = req.Name
+	}
+	if tag.Category == "" {
+		tag.Category = domain.TagCategoryOther
+	}
+
+	if err := s.repo.Create(ctx, tag); err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
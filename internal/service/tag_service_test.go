@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/blaisecz/sleep-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+func TestTagService_Create(t *testing.T) {
+	userID := uuid.New()
+	otherUserID := uuid.New()
+
+	tests := []struct {
+		name         string
+		req          *domain.CreateTagRequest
+		wantErr      error
+		wantLabel    string
+		wantCategory domain.TagCategory
+	}{
+		{
+			name:         "new tag with defaults",
+			req:          &domain.CreateTagRequest{Name: "melatonin"},
+			wantLabel:    "melatonin",
+			wantCategory: domain.TagCategoryOther,
+		},
+		{
+			name:         "new tag with label and category",
+			req:          &domain.CreateTagRequest{Name: "sauna", Label: "Evening sauna", Category: domain.TagCategoryActivity},
+			wantLabel:    "Evening sauna",
+			wantCategory: domain.TagCategoryActivity,
+		},
+		{
+			name:    "catalogue name",
+			req:     &domain.CreateTagRequest{Name: "caffeine"},
+			wantErr: domain.ErrConflict,
+		},
+		{
+			name:    "name of an existing user tag",
+			req:     &domain.CreateTagRequest{Name: "cold_room"},
+			wantErr: domain.ErrConflict,
+		},
+		{
+			name:         "name used only by another user",
+			req:          &domain.CreateTagRequest{Name: "late_shift"},
+			wantLabel:    "late_shift",
+			wantCategory: domain.TagCategoryOther,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := NewMockUserRepository()
+			userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+			tagRepo := NewMockTagRepository()
+			tagRepo.tags = append(tagRepo.tags,
+				domain.Tag{ID: uuid.New(), UserID: &userID, Name: "cold_room", Category: domain.TagCategoryEnvironment},
+				domain.Tag{ID: uuid.New(), UserID: &otherUserID, Name: "late_shift", Category: domain.TagCategoryOther},
+			)
+			svc := NewTagService(tagRepo, userRepo)
+
+			tag, err := svc.Create(context.Background(), userID, tt.req)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Create() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Create() unexpected error = %v", err)
+			}
+			if tag.UserID == nil || *tag.UserID != userID {
+				t.Errorf("UserID = %v, want %v", tag.UserID, userID)
+			}
+			if tag.Label != tt.wantLabel {
+				t.Errorf("Label = %q, want %q", tag.Label, tt.wantLabel)
+			}
+			if tag.Category != tt.wantCategory {
+				t.Errorf("Category = %s, want %s", tag.Category, tt.wantCategory)
+			}
+		})
+	}
+}
+
+func TestTagService_List(t *testing.T) {
+	userID := uuid.New()
+	otherUserID := uuid.New()
+
+	userRepo := NewMockUserRepository()
+	userRepo.users[userID] = &domain.User{ID: userID, Timezone: "UTC"}
+	tagRepo := NewMockTagRepository()
+	tagRepo.tags = append(tagRepo.tags,
+		domain.Tag{ID: uuid.New(), UserID: &userID, Name: "cold_room"},
+		domain.Tag{ID: uuid.New(), UserID: &otherUserID, Name: "late_shift"},
+	)
+	svc := NewTagService(tagRepo, userRepo)
+
+	tags, err := svc.List(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("List() unexpected error = %v", err)
+	}
+	if want := len(domain.CatalogueTags()) + 1; len(tags) != want {
+		t.Errorf("List() returned %d tags, want %d", len(tags), want)
+	}
+	for _, tag := range tags {
+		if tag.Name == "late_shift" {
+			t.Error("List() returned another user's tag")
+		}
+	}
+
+	if _, err := svc.List(context.Background(), uuid.New()); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("List() for unknown user error = %v, want ErrNotFound", err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 type UserService interface {
 	Create(ctx context.Context, req *domain.CreateUserRequest) (*domain.User, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error)
+	UpdateWorkdays(ctx context.Context, id uuid.UUID, req *domain.UpdateWorkdaysRequest) (*domain.User, error)
 }
 
 type userService struct {
@@ -26,6 +27,9 @@ func (s *userService) Create(ctx context.Context, req *domain.CreateUserRequest)
 		ID:       uuid.New(),
 		Timezone: req.Timezone,
 	}
+	if req.Workdays != nil {
+		user.SetWorkdays(req.Workdays)
+	}
 
 	if err := s.repo.Create(ctx, user); err != nil {
 		return nil, err
@@ -37,3 +41,17 @@ func (s *userService) Create(ctx context.Context, req *domain.CreateUserRequest)
 func (s *userService) GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
 	return s.repo.GetByID(ctx, id)
 }
+
+func (s *userService) UpdateWorkdays(ctx context.Context, id uuid.UUID, req *domain.UpdateWorkdaysRequest) (*domain.User, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user.SetWorkdays(req.Workdays)
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,104 @@
+// Package validation validates domain request documents independently of
+// the transport that carried them.
+package validation
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var validate *validator.Validate
+
+// slugPattern matches tag names: lowercase letters, digits and underscores.
+var slugPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// clockPattern matches local clock times in HH:MM format.
+var clockPattern = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
+
+func init() {
+	validate = validator.New()
+
+	// Register custom timezone validator
+	validate.RegisterValidation("timezone", func(fl validator.FieldLevel) bool {
+		tz := fl.Field().String()
+		_, err := time.LoadLocation(tz)
+		return err == nil
+	})
+
+	// Register custom slug validator for tag names
+	validate.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+		return slugPattern.MatchString(fl.Field().String())
+	})
+
+	// Register custom clock time validator for goals
+	validate.RegisterValidation("clock", func(fl validator.FieldLevel) bool {
+		return clockPattern.MatchString(fl.Field().String())
+	})
+}
+
+// FieldError describes a single invalid field of a validated struct.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// Validate validates a struct and returns field errors
+func Validate(s interface{}) []FieldError {
+	err := validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrors []FieldError
+	for _, err := range err.(validator.ValidationErrors) {
+		fieldErrors = append(fieldErrors, FieldError{
+			Field:   toSnakeCase(err.Field()),
+			Message: getValidationMessage(err),
+		})
+	}
+	return fieldErrors
+}
+
+func getValidationMessage(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return "must be at least " + err.Param()
+	case "max":
+		return "must be at most " + err.Param()
+	case "oneof":
+		return "must be one of: " + err.Param()
+	case "required_with":
+		return "is required with " + toSnakeCase(err.Param())
+	case "gtfield":
+		return "must be greater than " + toSnakeCase(err.Param())
+	case "gte":
+		return "must be greater than or equal to " + err.Param()
+	case "timezone":
+		return "must be a valid IANA timezone"
+	case "clock":
+		return "must be a time in HH:MM format"
+	case "slug":
+		return "must start with a lowercase letter and contain only lowercase letters, digits and underscores"
+	default:
+		return "is invalid"
+	}
+}
+
+func toSnakeCase(s string) string {
+	var result []byte
+	for i, c := range s {
+		if c >= 'A' && c <= 'Z' {
+			if i > 0 {
+				result = append(result, '_')
+			}
+			result = append(result, byte(c+'a'-'A'))
+		} else {
+			result = append(result, byte(c))
+		}
+	}
+	return string(result)
+}
@@ -0,0 +1,59 @@
+package mergepatch
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// ContentType is the media type of an RFC 7396 JSON merge patch.
+const ContentType = "application/merge-patch+json"
+
+// Apply applies an RFC 7396 JSON merge patch to a JSON document and returns
+// the patched document. Object members set to null in the patch are removed;
+// any non-object patch value replaces the target wholesale.
+func Apply(original, patch []byte) ([]byte, error) {
+	target, err := decode(original)
+	if err != nil {
+		return nil, err
+	}
+	patchValue, err := decode(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(merge(target, patchValue))
+}
+
+func merge(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = make(map[string]interface{})
+	}
+
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = merge(targetObj[key], value)
+	}
+
+	return targetObj
+}
+
+// decode keeps numbers as json.Number so large values survive the round trip.
+func decode(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,62 @@
+package mergepatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// Test cases from RFC 7396 Appendix A.
+func TestApply_RFC7396Examples(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		patch    string
+		want     string
+	}{
+		{name: "replace member", original: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "add member", original: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{name: "remove member", original: `{"a":"b"}`, patch: `{"a":null}`, want: `{}`},
+		{name: "remove one of two", original: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{name: "array replaces string", original: `{"a":["b"]}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "string replaces array", original: `{"a":"c"}`, patch: `{"a":["b"]}`, want: `{"a":["b"]}`},
+		{name: "nested merge", original: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, want: `{"a":{"b":"d"}}`},
+		{name: "array of objects replaced", original: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, want: `{"a":[1]}`},
+		{name: "array document replaced", original: `["a","b"]`, patch: `["c","d"]`, want: `["c","d"]`},
+		{name: "object replaced by array", original: `{"a":"b"}`, patch: `["c"]`, want: `["c"]`},
+		{name: "object replaced by null", original: `{"a":"foo"}`, patch: `null`, want: `null`},
+		{name: "object replaced by string", original: `{"a":"foo"}`, patch: `"bar"`, want: `"bar"`},
+		{name: "null member kept in nested object", original: `{"e":null}`, patch: `{"a":1}`, want: `{"a":1,"e":null}`},
+		{name: "array target becomes object", original: `[1,2]`, patch: `{"a":"b","c":null}`, want: `{"a":"b"}`},
+		{name: "deep new object", original: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, want: `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(tt.original), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+
+			var gotValue, wantValue interface{}
+			if err := json.Unmarshal(got, &gotValue); err != nil {
+				t.Fatalf("invalid result %s: %v", got, err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &wantValue); err != nil {
+				t.Fatalf("invalid expectation: %v", err)
+			}
+			if !reflect.DeepEqual(gotValue, wantValue) {
+				t.Fatalf("Apply() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApply_InvalidJSON(t *testing.T) {
+	if _, err := Apply([]byte(`{"a":1}`), []byte(`{invalid}`)); err == nil {
+		t.Fatal("expected error for invalid patch")
+	}
+	if _, err := Apply([]byte(`{invalid}`), []byte(`{}`)); err == nil {
+		t.Fatal("expected error for invalid original")
+	}
+}
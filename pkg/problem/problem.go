@@ -66,6 +66,30 @@ func Conflict(detail string) *Problem {
 	return New(http.StatusConflict, "conflict", "Conflict", detail)
 }
 
+func PayloadTooLarge(detail string) *Problem {
+	return New(http.StatusRequestEntityTooLarge, "payload-too-large", "Payload Too Large", detail)
+}
+
+func UnsupportedMediaType(detail string) *Problem {
+	return New(http.StatusUnsupportedMediaType, "unsupported-media-type", "Unsupported Media Type", detail)
+}
+
+func PreconditionFailed(detail string) *Problem {
+	return New(http.StatusPreconditionFailed, "precondition-failed", "Precondition Failed", detail)
+}
+
+func FailedDependency(detail string) *Problem {
+	return New(http.StatusFailedDependency, "failed-dependency", "Failed Dependency", detail)
+}
+
 func InternalError(detail string) *Problem {
 	return New(http.StatusInternalServerError, "internal-error", "Internal Server Error", detail)
 }
+
+func Unauthorized(detail string) *Problem {
+	return New(http.StatusUnauthorized, "unauthorized", "Unauthorized", detail)
+}
+
+func Forbidden(detail string) *Problem {
+	return New(http.StatusForbidden, "forbidden", "Forbidden", detail)
+}
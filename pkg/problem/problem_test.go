@@ -42,3 +42,47 @@ func TestProblemWrite(t *testing.T) {
         t.Fatalf("unexpected payload: %+v", decoded)
     }
 }
+
+func TestPreconditionFailed(t *testing.T) {
+    p := PreconditionFailed("stale version")
+
+    if p.Status != http.StatusPreconditionFailed {
+        t.Fatalf("unexpected status: %d", p.Status)
+    }
+    if got, want := p.Type, BaseURI+"/precondition-failed"; got != want {
+        t.Fatalf("unexpected type: got %q want %q", got, want)
+    }
+}
+
+func TestPayloadTooLarge(t *testing.T) {
+    p := PayloadTooLarge("body exceeds 1048576 bytes")
+
+    if p.Status != http.StatusRequestEntityTooLarge {
+        t.Fatalf("unexpected status: %d", p.Status)
+    }
+    if got, want := p.Type, BaseURI+"/payload-too-large"; got != want {
+        t.Fatalf("unexpected type: got %q want %q", got, want)
+    }
+}
+
+func TestUnsupportedMediaType(t *testing.T) {
+    p := UnsupportedMediaType("use application/merge-patch+json")
+
+    if p.Status != http.StatusUnsupportedMediaType {
+        t.Fatalf("unexpected status: %d", p.Status)
+    }
+    if got, want := p.Type, BaseURI+"/unsupported-media-type"; got != want {
+        t.Fatalf("unexpected type: got %q want %q", got, want)
+    }
+}
+
+func TestFailedDependency(t *testing.T) {
+    p := FailedDependency("batch aborted")
+
+    if p.Status != http.StatusFailedDependency {
+        t.Fatalf("unexpected status: %d", p.Status)
+    }
+    if got, want := p.Type, BaseURI+"/failed-dependency"; got != want {
+        t.Fatalf("unexpected type: got %q want %q", got, want)
+    }
+}
@@ -0,0 +1,41 @@
+// Fake LLM server for running insights without network access. It answers
+// OpenAI, OpenAI-compatible, Azure OpenAI and Anthropic requests with a fixed
+// reply. Point the API at it with, for example:
+//
+//	LLM_PROVIDER=openai_compatible OPENAI_COMPATIBLE_BASE_URL=http://localhost:8090/v1 OPENAI_COMPATIBLE_MODEL=fake
+//
+// Usage: go run scripts/fake-llm/main.go
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/blaisecz/sleep-tracker/internal/llm/llmtest"
+)
+
+func main() {
+	addr := getEnv("FAKE_LLM_ADDR", ":8090")
+
+	reply := ""
+	if path := os.Getenv("FAKE_LLM_REPLY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Failed to read reply file: %v", err)
+		}
+		reply = string(data)
+	}
+
+	log.Printf("Fake LLM listening on %s", addr)
+	if err := http.ListenAndServe(addr, llmtest.NewServer(reply)); err != nil {
+		log.Fatalf("Server failed: %v", err)
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}